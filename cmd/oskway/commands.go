@@ -0,0 +1,480 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/iotcore/osk-iotcore/internal/inject"
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+	"github.com/iotcore/osk-iotcore/ui"
+)
+
+// keyboardFlags holds the layout and theme selection shared by subcommands,
+// and the layouts for focused text fields
+type keyboardFlags struct {
+	layout         string
+	theme          string
+	contentLayouts string
+}
+
+// register adds the --layout and --theme flags to a flag set
+func (kf *keyboardFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&kf.layout, "layout", "", "keyboard layout to load (default qwerty)")
+	fs.StringVar(&kf.theme, "theme", "", "visual theme to load (default glass)")
+}
+
+// registerContentLayouts adds the --content-layouts flag to a flag set
+func (kf *keyboardFlags) registerContentLayouts(fs *flag.FlagSet) {
+	fs.StringVar(&kf.contentLayouts, "content-layouts", "", "comma-separated purpose=layout rules for focused text fields, ahead of the defaults")
+}
+
+// newKeyboard creates a keyboard with the selected layout and theme applied
+func (kf *keyboardFlags) newKeyboard() (*keyboard.Keyboard, error) {
+	kb, err := keyboard.New()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize keyboard: %w", err)
+	}
+
+	var layouts []string
+	if kf.layout != "" || kf.contentLayouts != "" {
+		if layouts, err = kb.ListAvailableLayouts(); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only an unknown name is a usage error; a layout that exists but
+	// fails to load is a runtime failure
+	if kf.layout != "" {
+		if !contains(layouts, kf.layout) {
+			return nil, &usageError{msg: fmt.Sprintf("layout %s not found", kf.layout)}
+		}
+		if err := kb.SwitchLayout(kf.layout); err != nil {
+			return nil, err
+		}
+	}
+
+	if kf.contentLayouts != "" {
+		rules, err := keyboard.ParseContentLayouts(kf.contentLayouts)
+		if err != nil {
+			return nil, &usageError{msg: err.Error()}
+		}
+		for _, rule := range rules {
+			if rule.Layout != "" && !contains(layouts, rule.Layout) {
+				return nil, &usageError{msg: fmt.Sprintf("layout %s for %s fields not found", rule.Layout, rule.Purpose)}
+			}
+		}
+		kb.SetContentLayouts(append(rules, kb.ContentLayouts()...))
+	}
+
+	if kf.theme != "" {
+		themes, err := kb.ListAvailableThemes()
+		if err != nil {
+			return nil, err
+		}
+		if !contains(themes, kf.theme) {
+			return nil, &usageError{msg: fmt.Sprintf("theme %s not found", kf.theme)}
+		}
+		if err := kb.LoadTheme(kf.theme); err != nil {
+			return nil, err
+		}
+	}
+
+	return kb, nil
+}
+
+// surfaceFlags holds the placement of the keyboard surface
+type surfaceFlags struct {
+	layer         string
+	anchor        string
+	margin        string
+	interactivity string
+	output        string
+	exclusiveZone *int
+}
+
+// register adds the surface placement flags to a flag set
+func (sf *surfaceFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&sf.layer, "layer", "top", "layer-shell layer: background, bottom, top or overlay")
+	fs.StringVar(&sf.anchor, "anchor", "bottom,left,right", "comma-separated output edges to anchor to")
+	fs.StringVar(&sf.margin, "margin", "0", "margin from the anchored edges: one value or top,right,bottom,left")
+	fs.StringVar(&sf.interactivity, "keyboard-interactivity", "none", "keyboard focus: none, exclusive or on-demand")
+	fs.StringVar(&sf.output, "output", "", "connector name of the output to show the keyboard on, such as HDMI-A-1 (default chosen by the compositor)")
+	fs.Func("exclusive-zone", "space reserved at the anchored edge; 0 to avoid other panels, -1 to ignore them (default keyboard height)", func(s string) error {
+		zone, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		sf.exclusiveZone = &zone
+		return nil
+	})
+}
+
+// config builds the surface configuration for a keyboard of the given size
+func (sf *surfaceFlags) config(width, height int) (wayland.SurfaceConfig, error) {
+	config := wayland.DefaultSurfaceConfig(width, height)
+
+	var err error
+	if config.Layer, err = wayland.ParseLayer(sf.layer); err != nil {
+		return config, &usageError{msg: err.Error()}
+	}
+	if config.Anchor, err = wayland.ParseAnchor(sf.anchor); err != nil {
+		return config, &usageError{msg: err.Error()}
+	}
+	if config.Margins, err = wayland.ParseMargins(sf.margin); err != nil {
+		return config, &usageError{msg: err.Error()}
+	}
+	if config.KeyboardInteractivity, err = wayland.ParseKeyboardInteractivity(sf.interactivity); err != nil {
+		return config, &usageError{msg: err.Error()}
+	}
+	if sf.exclusiveZone != nil {
+		config.ExclusiveZone = *sf.exclusiveZone
+	}
+	config.Output = sf.output
+	return config, nil
+}
+
+// newFlagSet creates a flag set for a subcommand
+func newFlagSet(name, args string, stderr io.Writer) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: oskway %s [flags] %s\n\nFlags:\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseFlags parses subcommand flags, converting parse failures to usage errors
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return &usageError{msg: err.Error()}
+	}
+	return nil
+}
+
+// runCommand runs the keyboard until interrupted
+func runCommand(args []string, stdout, stderr io.Writer) error {
+	var kf keyboardFlags
+	var sf surfaceFlags
+	fs := newFlagSet("run", "", stderr)
+	kf.register(fs)
+	kf.registerContentLayouts(fs)
+	sf.register(fs)
+	keyOutput := fs.String("key-output", strings.Join(inject.DefaultBackends, ","),
+		"comma-separated key output backends, tried in order: "+strings.Join(inject.Backends(), ", "))
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	backends, err := inject.ParseBackends(*keyOutput)
+	if err != nil {
+		return &usageError{msg: err.Error()}
+	}
+
+	kb, err := kf.newKeyboard()
+	if err != nil {
+		return err
+	}
+
+	layout := kb.GetLayout()
+	config, err := sf.config(layout.Width, layout.Height)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	app := ui.NewApp(kb)
+	app.SetSurfaceConfig(config)
+	app.SetKeyOutput(backends)
+	return app.Run(ctx)
+}
+
+// listCommand prints available layouts and/or themes, one per line
+func listCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("list", "[layouts|themes]", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	kb, err := keyboard.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize keyboard: %w", err)
+	}
+
+	what := "all"
+	if fs.NArg() > 0 {
+		what = fs.Arg(0)
+	}
+
+	switch what {
+	case "layouts":
+		return printNames(stdout, "", kb.ListAvailableLayouts)
+	case "themes":
+		return printNames(stdout, "", kb.ListAvailableThemes)
+	case "all":
+		if err := printNames(stdout, "Layouts:", kb.ListAvailableLayouts); err != nil {
+			return err
+		}
+		fmt.Fprintln(stdout)
+		return printNames(stdout, "Themes:", kb.ListAvailableThemes)
+	default:
+		return &usageError{msg: fmt.Sprintf("list: unknown category %q (want layouts or themes)", what)}
+	}
+}
+
+// printNames prints a sorted list of names with an optional heading
+func printNames(w io.Writer, heading string, list func() ([]string, error)) error {
+	names, err := list()
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+
+	indent := ""
+	if heading != "" {
+		fmt.Fprintln(w, heading)
+		indent = "  "
+	}
+	for _, name := range names {
+		fmt.Fprintf(w, "%s%s\n", indent, name)
+	}
+	return nil
+}
+
+// validateCommand validates layout and theme files
+func validateCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("validate", "[file.json ...]", stderr)
+	layoutsDir := fs.String("layouts-dir", "assets/layouts", "directory of layouts validated when no files are given")
+	themesDir := fs.String("themes-dir", "assets/themes", "directory of themes validated when no files are given")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		layouts, _ := filepath.Glob(filepath.Join(*layoutsDir, "*.json"))
+		themes, _ := filepath.Glob(filepath.Join(*themesDir, "*.json"))
+		files = append(layouts, themes...)
+		if len(files) == 0 {
+			return &usageError{msg: "validate: no layout or theme files found"}
+		}
+	}
+
+	failed := 0
+	for _, file := range files {
+		kind, err := validateFile(file)
+		if err != nil {
+			failed++
+			fmt.Fprintf(stdout, "✗ %s: %v\n", file, err)
+			continue
+		}
+		fmt.Fprintf(stdout, "✓ %s (%s)\n", file, kind)
+	}
+
+	if failed > 0 {
+		return &validationError{failed: failed}
+	}
+	return nil
+}
+
+// validateFile validates a single layout or theme file, returning its kind.
+// Files containing a "keys" array are treated as layouts.
+func validateFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return "", fmt.Errorf("invalid JSON: %w", err)
+	}
+
+	if _, isLayout := fields["keys"]; !isLayout {
+		theme, err := keyboard.ParseThemeFile(path)
+		if err != nil {
+			return "theme", err
+		}
+		return "theme", keyboard.ValidateTheme(theme)
+	}
+
+	parser := keyboard.NewLayoutParser(filepath.Dir(path))
+	layout, err := parser.ParseLayout(filepath.Base(path))
+	if err != nil {
+		return "layout", err
+	}
+	if err := parser.ValidateLayout(layout); err != nil {
+		return "layout", err
+	}
+	return "layout", validateLayoutGeometry(layout)
+}
+
+// validateLayoutGeometry checks that keys fit the layout and do not overlap
+func validateLayoutGeometry(layout *keyboard.Layout) error {
+	ids := make(map[string]bool)
+	for i, key := range layout.Keys {
+		if ids[key.ID] {
+			return fmt.Errorf("key %d: duplicate id %q", i, key.ID)
+		}
+		ids[key.ID] = true
+
+		if key.X+key.Width > layout.Width || key.Y+key.Height > layout.Height {
+			return fmt.Errorf("key %d (%s) extends beyond layout bounds", i, key.ID)
+		}
+
+		for j := i + 1; j < len(layout.Keys); j++ {
+			other := layout.Keys[j]
+			if key.X < other.X+other.Width && other.X < key.X+key.Width &&
+				key.Y < other.Y+other.Height && other.Y < key.Y+key.Height {
+				return fmt.Errorf("keys %s and %s overlap", key.ID, other.ID)
+			}
+		}
+	}
+	return nil
+}
+
+// screenshotCommand renders one or all layouts to PNG files
+func screenshotCommand(args []string, stdout, stderr io.Writer) error {
+	var kf keyboardFlags
+	fs := newFlagSet("screenshot", "", stderr)
+	kf.register(fs)
+	all := fs.Bool("all", false, "render every available layout")
+	output := fs.String("output", "screenshots", "output directory, or a .png file when rendering one layout")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	kb, err := kf.newKeyboard()
+	if err != nil {
+		return err
+	}
+
+	layouts := []string{kb.GetCurrentLayoutName()}
+	if *all || kf.layout == "" {
+		if layouts, err = kb.ListAvailableLayouts(); err != nil {
+			return err
+		}
+		sort.Strings(layouts)
+	}
+
+	single := strings.HasSuffix(*output, ".png")
+	if single && len(layouts) > 1 {
+		return &usageError{msg: "screenshot: --output must be a directory when rendering several layouts"}
+	}
+
+	for _, name := range layouts {
+		if err := kb.SwitchLayout(name); err != nil {
+			return err
+		}
+
+		path := *output
+		if !single {
+			path = filepath.Join(*output, name+".png")
+		}
+
+		if err := writeScreenshot(kb.GetLayout(), kb.GetTheme(), path); err != nil {
+			return fmt.Errorf("failed to render %s: %w", name, err)
+		}
+		fmt.Fprintf(stdout, "%s\n", path)
+	}
+
+	return nil
+}
+
+// layoutTestCommand loads every layout and exercises each of its keys
+func layoutTestCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("layout-test", "", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	kb, err := keyboard.New()
+	if err != nil {
+		return fmt.Errorf("failed to initialize keyboard: %w", err)
+	}
+
+	layouts, err := kb.ListAvailableLayouts()
+	if err != nil {
+		return err
+	}
+	sort.Strings(layouts)
+
+	failed := 0
+	for _, name := range layouts {
+		if err := exerciseLayout(kb, name); err != nil {
+			failed++
+			fmt.Fprintf(stdout, "✗ %s: %v\n", name, err)
+			continue
+		}
+		layout := kb.GetLayout()
+		fmt.Fprintf(stdout, "✓ %s (%dx%d, %d keys)\n", name, layout.Width, layout.Height, len(layout.Keys))
+	}
+
+	if failed > 0 {
+		return &validationError{failed: failed}
+	}
+	return nil
+}
+
+// exerciseLayout switches to a layout and presses and releases every key
+func exerciseLayout(kb *keyboard.Keyboard, name string) error {
+	if err := kb.SwitchLayout(name); err != nil {
+		return err
+	}
+
+	for _, key := range kb.GetLayout().Keys {
+		if err := kb.PressKey(key.ID); err != nil {
+			return fmt.Errorf("press %s: %w", key.ID, err)
+		}
+		if kb.GetKeyState(key.ID) != keyboard.KeyStatePressed {
+			return fmt.Errorf("key %s did not enter pressed state", key.ID)
+		}
+		if err := kb.ReleaseKey(key.ID); err != nil {
+			return fmt.Errorf("release %s: %w", key.ID, err)
+		}
+		if kb.GetKeyState(key.ID) != keyboard.KeyStateReleased {
+			return fmt.Errorf("key %s did not return to released state", key.ID)
+		}
+	}
+	return nil
+}
+
+// versionCommand prints build information
+func versionCommand(args []string, stdout, stderr io.Writer) error {
+	fs := newFlagSet("version", "", stderr)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return &usageError{msg: fmt.Sprintf("version: unexpected argument %q", fs.Arg(0))}
+	}
+
+	fmt.Fprintf(stdout, "oskway %s (commit %s, built %s)\n", Version, GitCommit, BuildDate)
+	return nil
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+// Command oskway is the on-screen keyboard executable. Besides running the
+// keyboard it provides tooling subcommands used in CI and on devices for
+// listing, validating and previewing layouts and themes.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Build information, set via -ldflags by scripts/build_release.sh
+var (
+	Version   = "dev"
+	BuildDate = "unknown"
+	GitCommit = "unknown"
+)
+
+// Exit codes returned by oskway. Scripts may rely on these values.
+const (
+	ExitOK         = 0 // Command completed successfully
+	ExitFailure    = 1 // Runtime failure (display, rendering, I/O)
+	ExitUsage      = 2 // Invalid command line
+	ExitValidation = 3 // One or more layouts or themes failed validation
+)
+
+// usageError reports an invalid command line
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+// validationError reports that one or more assets failed validation
+type validationError struct {
+	failed int
+}
+
+func (e *validationError) Error() string {
+	return fmt.Sprintf("%d item(s) failed validation", e.failed)
+}
+
+// command describes a single oskway subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, stdout, stderr io.Writer) error
+}
+
+// commands lists all subcommands in the order shown by help
+var commands = []command{
+	{name: "run", summary: "Run the on-screen keyboard (default)", run: runCommand},
+	{name: "list", summary: "List available layouts and themes", run: listCommand},
+	{name: "validate", summary: "Validate layout and theme files", run: validateCommand},
+	{name: "screenshot", summary: "Render layouts to PNG images", run: screenshotCommand},
+	{name: "layout-test", summary: "Load and exercise every layout", run: layoutTestCommand},
+	{name: "version", summary: "Print version information", run: versionCommand},
+}
+
+// legacyFlags maps the flag-style invocations documented before subcommands
+// existed onto their subcommand equivalents
+var legacyFlags = map[string]string{
+	"--layout-test": "layout-test",
+	"-layout-test":  "layout-test",
+	"--screenshot":  "screenshot",
+	"-screenshot":   "screenshot",
+	"--test":        "validate",
+	"-test":         "validate",
+	"--wayland-poc": "run",
+	"-wayland-poc":  "run",
+	"--version":     "version",
+	"-version":      "version",
+}
+
+func main() {
+	os.Exit(execute(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// execute runs the command line and returns the process exit code
+func execute(args []string, stdout, stderr io.Writer) int {
+	name, rest := splitCommand(args)
+
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(stdout)
+		return ExitOK
+	}
+
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(stderr, "oskway: unknown command %q\n\n", name)
+		printUsage(stderr)
+		return ExitUsage
+	}
+
+	return exitCode(cmd.run(rest, stdout, stderr), stderr)
+}
+
+// splitCommand extracts the subcommand name from the arguments. Invocations
+// without a subcommand run the keyboard, and legacy flags are translated.
+func splitCommand(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "run", nil
+	}
+
+	for i, arg := range args {
+		if name, ok := legacyFlags[arg]; ok {
+			rest := append(append([]string{}, args[:i]...), args[i+1:]...)
+			return name, rest
+		}
+	}
+
+	if len(args[0]) > 0 && args[0][0] == '-' && args[0] != "-h" && args[0] != "--help" {
+		return "run", args
+	}
+
+	return args[0], args[1:]
+}
+
+// findCommand looks up a subcommand by name
+func findCommand(name string) *command {
+	for i := range commands {
+		if commands[i].name == name {
+			return &commands[i]
+		}
+	}
+	return nil
+}
+
+// exitCode maps a command error onto a process exit code
+func exitCode(err error, stderr io.Writer) int {
+	if err == nil {
+		return ExitOK
+	}
+
+	var usageErr *usageError
+	var validationErr *validationError
+
+	switch {
+	case errors.Is(err, flag.ErrHelp):
+		return ExitOK
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(stderr, "oskway: %v\n", err)
+		return ExitUsage
+	case errors.As(err, &validationErr):
+		fmt.Fprintf(stderr, "oskway: %v\n", err)
+		return ExitValidation
+	default:
+		fmt.Fprintf(stderr, "oskway: %v\n", err)
+		return ExitFailure
+	}
+}
+
+// printUsage prints the top-level help text
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: oskway [command] [flags]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'oskway <command> -h' for command flags.")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintf(w, "  %d  success\n", ExitOK)
+	fmt.Fprintf(w, "  %d  runtime failure\n", ExitFailure)
+	fmt.Fprintf(w, "  %d  invalid command line\n", ExitUsage)
+	fmt.Fprintf(w, "  %d  validation failure\n", ExitValidation)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chdirRoot runs the test from the repository root so assets resolve
+func chdirRoot(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join("..", "..")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestExitCodes(t *testing.T) {
+	chdirRoot(t)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"help", []string{"help"}, ExitOK},
+		{"version", []string{"version"}, ExitOK},
+		{"version with unknown flag", []string{"version", "--bogus"}, ExitUsage},
+		{"version with operand", []string{"version", "now"}, ExitUsage},
+		{"list layouts", []string{"list", "layouts"}, ExitOK},
+		{"unknown command", []string{"frobnicate"}, ExitUsage},
+		{"unknown flag", []string{"list", "--bogus"}, ExitUsage},
+		{"unknown category", []string{"list", "fonts"}, ExitUsage},
+		{"unknown theme", []string{"screenshot", "--layout", "style_one", "--theme", "nope", "--output", t.TempDir()}, ExitUsage},
+		{"valid layout", []string{"validate", "assets/layouts/style_one.json"}, ExitOK},
+		{"valid theme", []string{"validate", "assets/themes/glass.json"}, ExitOK},
+		{"layout-test", []string{"--layout-test"}, ExitOK},
+		{"unknown anchor", []string{"run", "--anchor", "bottom,middle"}, ExitUsage},
+		{"bad exclusive zone", []string{"run", "--exclusive-zone", "tall"}, ExitUsage},
+		{"unknown layout", []string{"run", "--layout", "colemak"}, ExitUsage},
+		{"unknown content purpose", []string{"run", "--content-layouts", "e-mail=web"}, ExitUsage},
+		{"unknown content layout", []string{"run", "--content-layouts", "pin=keypad"}, ExitUsage},
+		{"unknown key output", []string{"run", "--key-output", "wayland,telepathy"}, ExitUsage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if got := execute(tt.args, &stdout, &stderr); got != tt.want {
+				t.Errorf("exit code = %d, want %d\nstdout: %s\nstderr: %s", got, tt.want, stdout.String(), stderr.String())
+			}
+		})
+	}
+}
+
+func TestValidateReportsInvalidLayout(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broken.json")
+	layout := `{"name": "broken", "width": 100, "height": 100, "keys": [
+		{"id": "a", "label": "A", "code": 30, "x": 0, "y": 0, "width": 60, "height": 60},
+		{"id": "b", "label": "B", "code": 48, "x": 30, "y": 0, "width": 60, "height": 60}
+	]}`
+	if err := os.WriteFile(path, []byte(layout), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if got := execute([]string{"validate", path}, &stdout, &stderr); got != ExitValidation {
+		t.Fatalf("exit code = %d, want %d", got, ExitValidation)
+	}
+	if !strings.Contains(stdout.String(), "overlap") {
+		t.Errorf("expected overlap to be reported, got %q", stdout.String())
+	}
+}
+
+func TestLayoutThatFailsToLoadIsARuntimeFailure(t *testing.T) {
+	chdirRoot(t)
+	glass, err := os.ReadFile("assets/themes/glass.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The layout exists, so the command line is valid, but cannot be parsed
+	dir := t.TempDir()
+	for path, data := range map[string]string{
+		"assets/themes/glass.json": string(glass),
+		"assets/layouts/torn.json": `{"name": "torn", "keys": [`,
+	} {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if got := execute([]string{"screenshot", "--layout", "torn", "--output", dir}, &stdout, &stderr); got != ExitFailure {
+		t.Errorf("exit code = %d, want %d\nstderr: %s", got, ExitFailure, stderr.String())
+	}
+}
+
+func TestScreenshotWritesPNG(t *testing.T) {
+	chdirRoot(t)
+
+	output := filepath.Join(t.TempDir(), "style_one.png")
+	var stdout, stderr bytes.Buffer
+	if got := execute([]string{"screenshot", "--layout", "style_one", "--output", output}, &stdout, &stderr); got != ExitOK {
+		t.Fatalf("exit code = %d, stderr: %s", got, stderr.String())
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("screenshot not written: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte("\x89PNG")) {
+		t.Error("screenshot is not a PNG file")
+	}
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"fmt"
+	"image/png"
+	"os"
+	"path/filepath"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+	"github.com/iotcore/osk-iotcore/ui"
+)
+
+// writeScreenshot renders a layout with the given theme and saves it as PNG
+func writeScreenshot(layout *keyboard.Layout, theme *keyboard.Theme, path string) error {
+	img, err := ui.Snapshot(layout, theme)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return file.Close()
+}
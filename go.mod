@@ -4,6 +4,11 @@ go 1.23.0
 
 toolchain go1.24.4
 
+require (
+	github.com/go-text/typesetting v0.3.5
+	golang.org/x/image v0.30.0
+)
+
 require (
 	golang.org/x/mod v0.26.0 // indirect
 	golang.org/x/sync v0.16.0 // indirect
@@ -0,0 +1,147 @@
+// Package inject delivers the keys pressed on the on-screen keyboard to
+// applications through pluggable backends. Backends register a factory
+// under a name; Open tries a configured chain of them in order, falling
+// back to the next when one is unavailable.
+package inject
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// KeyInjector delivers key presses and releases to applications
+type KeyInjector interface {
+	// HandleKey delivers a key pressed or released on the keyboard
+	HandleKey(event keyboard.KeyEvent)
+
+	// SetLayout prepares for the keys of layout, such as by uploading a
+	// keymap that can type its outputs. It is called before the first key
+	// and whenever the layout changes.
+	SetLayout(layout *keyboard.Layout) error
+
+	// Close releases any keys still held and frees the backend
+	Close()
+}
+
+// Config holds what backends may need to open
+type Config struct {
+	// Wayland is the compositor connection, or nil without one
+	Wayland wayland.WaylandClient
+}
+
+// Factory opens a backend. It returns an error when the backend is not
+// available in this session, so the next one can be tried.
+type Factory func(config Config) (KeyInjector, error)
+
+// DefaultBackends is the backend chain used unless configured otherwise:
+// the compositor's virtual keyboard, then XTest on an X server, then a
+// uinput device
+var DefaultBackends = []string{"wayland", "x11", "uinput"}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = make(map[string]Factory)
+)
+
+// Register makes a backend available under name. It panics if the name is
+// already registered.
+func Register(name string, factory Factory) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, exists := registry[name]; exists {
+		panic(fmt.Sprintf("inject: backend %s registered twice", name))
+	}
+	registry[name] = factory
+}
+
+// Backends returns the names of the registered backends, sorted
+func Backends() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseBackends parses a comma-separated backend chain such as
+// "wayland,uinput", checking that every backend is registered
+func ParseBackends(s string) ([]string, error) {
+	var names []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !registered(name) {
+			return nil, fmt.Errorf("unknown key output backend %q (available: %s)", name, strings.Join(Backends(), ", "))
+		}
+		names = append(names, name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no key output backend given")
+	}
+	return names, nil
+}
+
+func registered(name string) bool {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	_, exists := registry[name]
+	return exists
+}
+
+// Open opens the first available backend of the chain names, returning it
+// and its name. Unavailable backends are logged and skipped; the errors of
+// all of them are returned if none is available.
+func Open(names []string, config Config) (KeyInjector, string, error) {
+	var errs []error
+	for _, name := range names {
+		registryMutex.RLock()
+		factory, exists := registry[name]
+		registryMutex.RUnlock()
+		if !exists {
+			errs = append(errs, fmt.Errorf("unknown key output backend %q", name))
+			continue
+		}
+
+		injector, err := factory(config)
+		if err != nil {
+			log.Printf("Key output backend %s unavailable: %v", name, err)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			continue
+		}
+		return injector, name, nil
+	}
+	if len(errs) == 0 {
+		return nil, "", fmt.Errorf("no key output backend configured")
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+// Attach feeds the keys pressed and released on kb to injector, and keeps
+// it in step with the layout. The returned function detaches it.
+func Attach(kb *keyboard.Keyboard, injector KeyInjector) func() {
+	unsubscribeKeys := kb.Subscribe(injector.HandleKey)
+	unsubscribeLayout := kb.SubscribeLayout(func(layout *keyboard.Layout) {
+		if err := injector.SetLayout(layout); err != nil {
+			log.Printf("Failed to update the key output layout: %v", err)
+		}
+	})
+	if err := injector.SetLayout(kb.GetLayout()); err != nil {
+		log.Printf("Failed to update the key output layout: %v", err)
+	}
+	return func() {
+		unsubscribeKeys()
+		unsubscribeLayout()
+	}
+}
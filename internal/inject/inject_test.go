@@ -0,0 +1,82 @@
+package inject
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+func init() {
+	Register("test-unavailable", func(Config) (KeyInjector, error) {
+		return nil, errors.New("no such device")
+	})
+}
+
+func TestOpenFallsBackToNextBackend(t *testing.T) {
+	// Without a Wayland connection the wayland backend is unavailable too
+	injector, name, err := Open([]string{"test-unavailable", "wayland", "record"}, Config{})
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if _, ok := injector.(*Recorder); !ok || name != "record" {
+		t.Errorf("opened %s (%T), want record", name, injector)
+	}
+
+	_, _, err = Open([]string{"test-unavailable", "wayland"}, Config{})
+	if err == nil || !strings.Contains(err.Error(), "no such device") || !strings.Contains(err.Error(), "no Wayland connection") {
+		t.Errorf("Open error = %v, want the failure of every backend", err)
+	}
+}
+
+func TestParseBackends(t *testing.T) {
+	names, err := ParseBackends(" wayland, record ")
+	if err != nil || !reflect.DeepEqual(names, []string{"wayland", "record"}) {
+		t.Errorf("ParseBackends = %v, %v", names, err)
+	}
+	for _, s := range []string{"", "wayland,telepathy"} {
+		if _, err := ParseBackends(s); err == nil {
+			t.Errorf("ParseBackends(%q) succeeded", s)
+		}
+	}
+}
+
+func TestAttachFeedsKeysAndLayouts(t *testing.T) {
+	kb, err := keyboard.New()
+	if err != nil {
+		t.Fatalf("keyboard.New: %v", err)
+	}
+	recorder := NewRecorder()
+	detach := Attach(kb, recorder)
+
+	kb.PressKey("a")
+	kb.ReleaseKey("a")
+	kb.SetSensitive(true)
+	kb.PressKey("a")
+	if err := kb.LoadLayout("qwerty"); err != nil {
+		t.Fatal(err)
+	}
+
+	code := recorder.Keys()[0].Code
+	want := []RecordedKey{
+		{KeyID: "a", Code: code, State: keyboard.KeyStatePressed},
+		{KeyID: "a", Code: code, State: keyboard.KeyStateReleased},
+		// Keys typed in sensitive mode are not recorded
+		{State: keyboard.KeyStatePressed, Sensitive: true},
+	}
+	if got := recorder.Keys(); !reflect.DeepEqual(got, want) {
+		t.Errorf("keys = %+v, want %+v", got, want)
+	}
+	if got := recorder.Layouts(); !reflect.DeepEqual(got, []string{"qwerty", "qwerty"}) {
+		t.Errorf("layouts = %v, want the initial and reloaded layout", got)
+	}
+
+	detach()
+	recorder.Reset()
+	kb.ReleaseKey("a")
+	if keys := recorder.Keys(); len(keys) != 0 {
+		t.Errorf("detached recorder received %+v", keys)
+	}
+}
@@ -0,0 +1,95 @@
+package inject
+
+import (
+	"sync"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+func init() {
+	Register("record", func(Config) (KeyInjector, error) {
+		return NewRecorder(), nil
+	})
+}
+
+// RecordedKey is a key event captured by a Recorder. Keys typed in
+// sensitive mode are recorded without their ID and code.
+type RecordedKey struct {
+	KeyID     string
+	Code      int32
+	State     keyboard.KeyState
+	Sensitive bool
+}
+
+// Recorder is a backend that delivers nothing and captures the events it
+// is given, for assertions in tests
+type Recorder struct {
+	mutex   sync.Mutex
+	keys    []RecordedKey
+	layouts []string
+	closed  bool
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// HandleKey records event, unless the recorder is closed
+func (r *Recorder) HandleKey(event keyboard.KeyEvent) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.closed || event.Key == nil {
+		return
+	}
+	key := RecordedKey{State: event.State, Sensitive: event.Sensitive}
+	if !event.Sensitive {
+		key.KeyID, key.Code = event.Key.ID, event.Key.Code
+	}
+	r.keys = append(r.keys, key)
+}
+
+// SetLayout records the name of layout
+func (r *Recorder) SetLayout(layout *keyboard.Layout) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if layout != nil {
+		r.layouts = append(r.layouts, layout.Name)
+	}
+	return nil
+}
+
+// Close stops recording
+func (r *Recorder) Close() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.closed = true
+}
+
+// Keys returns the key events recorded so far
+func (r *Recorder) Keys() []RecordedKey {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]RecordedKey(nil), r.keys...)
+}
+
+// Layouts returns the names of the layouts set so far
+func (r *Recorder) Layouts() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string(nil), r.layouts...)
+}
+
+// Closed reports whether Close was called
+func (r *Recorder) Closed() bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.closed
+}
+
+// Reset discards the recorded events
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.keys, r.layouts = nil, nil
+}
@@ -0,0 +1,207 @@
+package inject
+
+import (
+	"sort"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// keyLeftShift is the evdev code of the Shift key pressed for characters
+// that need it
+const keyLeftShift = 42
+
+// modifierCodes are the evdev codes of the modifier keys that latch when
+// tapped. Caps Lock is left to toggle in the compositor.
+var modifierCodes = map[uint16]bool{
+	42:  true, // KEY_LEFTSHIFT
+	54:  true, // KEY_RIGHTSHIFT
+	29:  true, // KEY_LEFTCTRL
+	97:  true, // KEY_RIGHTCTRL
+	56:  true, // KEY_LEFTALT
+	100: true, // KEY_RIGHTALT
+	125: true, // KEY_LEFTMETA
+	126: true, // KEY_RIGHTMETA
+}
+
+// keySequencer turns the keys of the on-screen keyboard into presses and
+// releases of evdev keys, for backends that emulate a physical keyboard.
+// Holding a modifier while pressing another key applies it to that key,
+// while tapping it on its own keeps it down until the next key is
+// released. Keys with an Output are typed as the keys lookup finds for
+// each character.
+type keySequencer struct {
+	// send presses or releases a key on the emulated keyboard
+	send func(code uint16, down bool)
+	// lookup returns the key typing r and whether it needs Shift
+	lookup func(r rune) (code uint32, shift bool, ok bool)
+
+	// pressed holds every key down on the emulated keyboard
+	pressed map[uint16]bool
+	// held and latched hold the modifiers pressed on the keyboard, and
+	// those tapped and kept down for the next key
+	held    map[uint16]bool
+	latched map[uint16]bool
+	// chorded records that a key was typed while a modifier was held, so
+	// releasing the modifier does not latch it
+	chorded bool
+	// unlatched holds latched modifiers tapped again, whose release is
+	// ignored
+	unlatched map[uint16]bool
+}
+
+func newKeySequencer(send func(code uint16, down bool), lookup func(r rune) (uint32, bool, bool)) *keySequencer {
+	return &keySequencer{
+		send:      send,
+		lookup:    lookup,
+		pressed:   make(map[uint16]bool),
+		held:      make(map[uint16]bool),
+		latched:   make(map[uint16]bool),
+		unlatched: make(map[uint16]bool),
+	}
+}
+
+// handleKey sequences a key pressed or released on the keyboard
+func (s *keySequencer) handleKey(event keyboard.KeyEvent) {
+	key := event.Key
+	switch {
+	case len(key.Output) > 0:
+		if event.State == keyboard.KeyStatePressed {
+			s.typeOutput(key.Output)
+		}
+	case key.Code <= 0:
+		// Negative codes are layout actions, not evdev keys
+	case event.State == keyboard.KeyStatePressed:
+		s.press(uint16(key.Code))
+	default:
+		s.release(uint16(key.Code))
+	}
+}
+
+// releaseAll releases every key still down, so none stay stuck
+func (s *keySequencer) releaseAll() {
+	for _, code := range sortedCodes(s.pressed) {
+		s.write(code, false)
+	}
+	clear(s.held)
+	clear(s.latched)
+	clear(s.unlatched)
+	s.chorded = false
+}
+
+// press handles a key pressed on the keyboard
+func (s *keySequencer) press(code uint16) {
+	if !modifierCodes[code] {
+		if len(s.held) > 0 {
+			s.chorded = true
+		}
+		s.write(code, true)
+		return
+	}
+
+	// Tapping a latched modifier again unlatches it
+	if s.latched[code] {
+		delete(s.latched, code)
+		s.unlatched[code] = true
+		s.write(code, false)
+		return
+	}
+	s.held[code] = true
+	s.write(code, true)
+}
+
+// release handles a key released on the keyboard
+func (s *keySequencer) release(code uint16) {
+	if !modifierCodes[code] {
+		s.write(code, false)
+		s.releaseLatched()
+		return
+	}
+
+	if s.unlatched[code] {
+		delete(s.unlatched, code)
+		return
+	}
+	delete(s.held, code)
+	if s.chorded {
+		s.write(code, false)
+	} else {
+		s.latched[code] = true
+	}
+	if len(s.held) == 0 {
+		s.chorded = false
+	}
+}
+
+// releaseLatched releases the latched modifiers once a key was typed
+func (s *keySequencer) releaseLatched() {
+	for _, code := range sortedCodes(s.latched) {
+		delete(s.latched, code)
+		s.write(code, false)
+	}
+}
+
+// typeOutput taps the keys typing the level of levels chosen by Shift,
+// pressing or releasing Shift around each character as it needs
+func (s *keySequencer) typeOutput(levels keyboard.Levels) {
+	shifts := make([]uint16, 0, 2)
+	for _, code := range []uint16{42, 54} {
+		if s.pressed[code] {
+			shifts = append(shifts, code)
+		}
+	}
+	text := levels[0]
+	if len(shifts) > 0 && len(levels) > 1 {
+		text = levels[1]
+	}
+	if len(s.held) > 0 {
+		s.chorded = true
+	}
+
+	shifted := len(shifts) > 0
+	setShift := func(down bool) {
+		if down == shifted {
+			return
+		}
+		shifted = down
+		if len(shifts) == 0 {
+			s.write(keyLeftShift, down)
+			return
+		}
+		for _, code := range shifts {
+			s.write(code, down)
+		}
+	}
+
+	for _, r := range text {
+		code, shift, ok := s.lookup(r)
+		if !ok {
+			continue
+		}
+		setShift(shift)
+		s.write(uint16(code), true)
+		s.write(uint16(code), false)
+	}
+	setShift(len(shifts) > 0)
+	s.releaseLatched()
+}
+
+// write sends a key press or release and tracks which keys are down
+func (s *keySequencer) write(code uint16, down bool) {
+	if down {
+		s.pressed[code] = true
+	} else {
+		delete(s.pressed, code)
+	}
+	s.send(code, down)
+}
+
+// sortedCodes returns the keys of codes in ascending order, so keys are
+// released in a repeatable order
+func sortedCodes(codes map[uint16]bool) []uint16 {
+	sorted := make([]uint16, 0, len(codes))
+	for code := range codes {
+		sorted = append(sorted, code)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted
+}
@@ -0,0 +1,109 @@
+package inject
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+	"unsafe"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// Linux input event types and codes
+const (
+	evSyn     = 0x00
+	evKey     = 0x01
+	synReport = 0
+)
+
+// Key event values
+const (
+	keyUp   = 0
+	keyDown = 1
+)
+
+// timevalSize is the size of struct timeval, whose fields are longs
+const timevalSize = 2 * int(unsafe.Sizeof(uintptr(0)))
+
+// inputEventSize is the size of struct input_event: a timeval followed by
+// the type, code and value
+const inputEventSize = timevalSize + 8
+
+// UInput types keys by writing EV_KEY events to a kernel uinput device, so
+// it works under any compositor, or none. Key.Code values are evdev codes
+// and are written unchanged.
+//
+// Modifier keys behave as with the Wayland virtual keyboard: holding one
+// while pressing another key applies it to that key, while tapping it on
+// its own keeps it down until the next key is released. Keys with an
+// Output type the characters a US keymap has; others are skipped.
+//
+// A new device takes a moment to be opened by the compositor, and keys
+// written before then are lost. Opening waits for its event node to appear,
+// then a little longer; a compositor slower than that still misses the
+// first keys.
+type UInput struct {
+	mutex     sync.Mutex
+	device    io.WriteCloser
+	sequencer *keySequencer
+	closed    bool
+}
+
+// newUInput types into device, which receives struct input_event records
+// and is closed by Close
+func newUInput(device io.WriteCloser) *UInput {
+	u := &UInput{device: device}
+	u.sequencer = newKeySequencer(u.write, wayland.USKey)
+	return u
+}
+
+// HandleKey types a key pressed or released on the keyboard
+func (u *UInput) HandleKey(event keyboard.KeyEvent) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.closed {
+		return
+	}
+	u.sequencer.handleKey(event)
+}
+
+// SetLayout does nothing: evdev codes mean the same in every layout
+func (u *UInput) SetLayout(*keyboard.Layout) error {
+	return nil
+}
+
+// Close releases every key still down, so none stay stuck, and destroys
+// the device
+func (u *UInput) Close() {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+	if u.closed {
+		return
+	}
+	u.closed = true
+	u.sequencer.releaseAll()
+	u.device.Close()
+}
+
+// write sends a key event followed by a report. A failed write is dropped:
+// keys cannot be typed any other way. Callers must hold the mutex.
+func (u *UInput) write(code uint16, down bool) {
+	value := int32(keyUp)
+	if down {
+		value = keyDown
+	}
+	event := make([]byte, 0, 2*inputEventSize)
+	event = appendInputEvent(event, evKey, code, value)
+	event = appendInputEvent(event, evSyn, synReport, 0)
+	u.device.Write(event)
+}
+
+// appendInputEvent appends a struct input_event. The kernel timestamps
+// events written to a uinput device, so the time is left zero.
+func appendInputEvent(b []byte, eventType, code uint16, value int32) []byte {
+	b = append(b, make([]byte, timevalSize)...)
+	b = binary.NativeEndian.AppendUint16(b, eventType)
+	b = binary.NativeEndian.AppendUint16(b, code)
+	return binary.NativeEndian.AppendUint32(b, uint32(value))
+}
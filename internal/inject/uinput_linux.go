@@ -0,0 +1,147 @@
+//go:build linux
+
+package inject
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+func init() {
+	Register("uinput", openUInput)
+}
+
+// uinputPath is the uinput control device
+const uinputPath = "/dev/uinput"
+
+// uinput ioctl requests, from linux/uinput.h
+const (
+	uiDevCreate  = 0x5501     // _IO('U', 1)
+	uiDevDestroy = 0x5502     // _IO('U', 2)
+	uiDevSetup   = 0x405c5503 // _IOW('U', 3, struct uinput_setup)
+	uiSetEvBit   = 0x40045564 // _IOW('U', 100, int)
+	uiSetKeyBit  = 0x40045565 // _IOW('U', 101, int)
+	uiGetSysname = 0x8040552c // UI_GET_SYSNAME(64)
+)
+
+// A new device is only seen once udev has created its node and the
+// compositor has opened it; keys written before then are lost. openUInput
+// waits up to uinputNodeTimeout for the node, then uinputSettleDelay for
+// the compositor.
+const (
+	uinputNodeTimeout = time.Second
+	uinputSettleDelay = 100 * time.Millisecond
+)
+
+// uinputMaxKey is the highest key code the device reports, matching the
+// codes of the generated XKB keymaps
+const uinputMaxKey = 247
+
+// uinputSetup is struct uinput_setup
+type uinputSetup struct {
+	BusType      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	Name         [80]byte
+	FFEffectsMax uint32
+}
+
+// busVirtual is BUS_VIRTUAL
+const busVirtual = 0x06
+
+// uinputFile is a uinput device, destroyed when closed
+type uinputFile struct {
+	*os.File
+}
+
+// openUInput creates a virtual keyboard device. It needs write access to
+// /dev/uinput, usually through membership of the input group or a udev
+// rule.
+func openUInput(Config) (KeyInjector, error) {
+	file, err := os.OpenFile(uinputPath, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	device := &uinputFile{file}
+
+	if err := device.ioctl(uiSetEvBit, evKey); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to enable key events: %w", err)
+	}
+	for code := uintptr(1); code <= uinputMaxKey; code++ {
+		if err := device.ioctl(uiSetKeyBit, code); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to enable key %d: %w", code, err)
+		}
+	}
+
+	setup := uinputSetup{BusType: busVirtual, Vendor: 0x1209, Product: 0x05c0, Version: 1}
+	copy(setup.Name[:], "oskway virtual keyboard")
+	if err := device.ioctlPointer(uiDevSetup, unsafe.Pointer(&setup)); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to set up uinput device: %w", err)
+	}
+	if err := device.ioctl(uiDevCreate, 0); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to create uinput device: %w", err)
+	}
+	device.waitReady()
+	return newUInput(device), nil
+}
+
+// waitReady waits for udev to create the event node of the device, and
+// then briefly for the compositor to open it. Without the sysfs name of
+// the device, only the settle delay is waited.
+func (f *uinputFile) waitReady() {
+	var name [64]byte
+	if err := f.ioctlPointer(uiGetSysname, unsafe.Pointer(&name)); err == nil {
+		sysname := string(bytes.TrimRight(name[:], "\x00"))
+		for deadline := time.Now().Add(uinputNodeTimeout); time.Now().Before(deadline); {
+			if eventNodeExists(sysname) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	time.Sleep(uinputSettleDelay)
+}
+
+// eventNodeExists reports whether the /dev/input event node of the input
+// device called sysname in sysfs exists
+func eventNodeExists(sysname string) bool {
+	events, _ := filepath.Glob(filepath.Join("/sys/class/input", sysname, "event*"))
+	for _, event := range events {
+		if _, err := os.Stat(filepath.Join("/dev/input", filepath.Base(event))); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *uinputFile) ioctl(request, arg uintptr) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, arg); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// ioctlPointer is ioctl with a pointer argument, converted in the call so
+// that the memory stays valid
+func (f *uinputFile) ioctlPointer(request uintptr, arg unsafe.Pointer) error {
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), request, uintptr(arg)); errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Close destroys the device, so it disappears from the compositor's seat
+func (f *uinputFile) Close() error {
+	f.ioctl(uiDevDestroy, 0)
+	return f.File.Close()
+}
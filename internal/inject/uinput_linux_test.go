@@ -0,0 +1,20 @@
+package inject
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestUInputSetupMatchesKernelLayout(t *testing.T) {
+	// UI_DEV_SETUP encodes the size of struct uinput_setup
+	if size := unsafe.Sizeof(uinputSetup{}); size != uiDevSetup>>16&0x3fff {
+		t.Errorf("uinputSetup is %d bytes, want %d", size, uiDevSetup>>16&0x3fff)
+	}
+}
+
+func TestUInputGetSysnameRequest(t *testing.T) {
+	// UI_GET_SYSNAME is _IOC(_IOC_READ, 'U', 44, len)
+	if want := uintptr(2<<30 | 64<<16 | 'U'<<8 | 44); uiGetSysname != want {
+		t.Errorf("uiGetSysname = %#x, want %#x", uiGetSysname, want)
+	}
+}
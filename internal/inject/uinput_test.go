@@ -0,0 +1,149 @@
+package inject
+
+import (
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// fakeDevice decodes the events written to a uinput device
+type fakeDevice struct {
+	t      *testing.T
+	events []string
+	closed bool
+}
+
+func (d *fakeDevice) Write(b []byte) (int, error) {
+	if len(b)%inputEventSize != 0 {
+		d.t.Fatalf("wrote %d bytes, not a whole number of events", len(b))
+	}
+	for offset := 0; offset < len(b); offset += inputEventSize {
+		event := b[offset+timevalSize : offset+inputEventSize]
+		eventType := binary.NativeEndian.Uint16(event)
+		code := binary.NativeEndian.Uint16(event[2:])
+		value := int32(binary.NativeEndian.Uint32(event[4:]))
+		switch eventType {
+		case evKey:
+			d.events = append(d.events, fmt.Sprintf("%d %d", code, value))
+		case evSyn:
+			d.events = append(d.events, "syn")
+		default:
+			d.t.Errorf("unexpected event type %d", eventType)
+		}
+	}
+	return len(b), nil
+}
+
+func (d *fakeDevice) Close() error {
+	d.closed = true
+	return nil
+}
+
+// take returns the key events written since the last call, checking each
+// is followed by a report
+func (d *fakeDevice) take() []string {
+	d.t.Helper()
+	var keys []string
+	for i, event := range d.events {
+		if event == "syn" {
+			continue
+		}
+		if i+1 == len(d.events) || d.events[i+1] != "syn" {
+			d.t.Errorf("event %q is not followed by a report", event)
+		}
+		keys = append(keys, event)
+	}
+	d.events = nil
+	return keys
+}
+
+func newTestUInput(t *testing.T) (*UInput, *fakeDevice) {
+	device := &fakeDevice{t: t}
+	return newUInput(device), device
+}
+
+func expectEvents(t *testing.T, device *fakeDevice, want ...string) {
+	t.Helper()
+	if got := device.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func tap(u *UInput, key *keyboard.Key) {
+	u.HandleKey(keyboard.KeyEvent{Key: key, State: keyboard.KeyStatePressed})
+	u.HandleKey(keyboard.KeyEvent{Key: key, State: keyboard.KeyStateReleased})
+}
+
+var (
+	keyA     = &keyboard.Key{ID: "a", Code: 30}
+	keyShift = &keyboard.Key{ID: "shift", Code: 42}
+	keyCtrl  = &keyboard.Key{ID: "ctrl", Code: 29}
+)
+
+func TestUInputWritesKeyCodes(t *testing.T) {
+	u, device := newTestUInput(t)
+	tap(u, keyA)
+	tap(u, &keyboard.Key{ID: "numbers", Code: -1})
+	expectEvents(t, device, "30 1", "30 0")
+}
+
+func TestUInputSequencesModifiers(t *testing.T) {
+	u, device := newTestUInput(t)
+
+	// A tapped modifier stays down until the next key is released
+	tap(u, keyShift)
+	expectEvents(t, device, "42 1")
+	tap(u, keyA)
+	expectEvents(t, device, "30 1", "30 0", "42 0")
+
+	// Tapping it again before then releases it
+	tap(u, keyShift)
+	tap(u, keyShift)
+	expectEvents(t, device, "42 1", "42 0")
+
+	// A modifier held while typing applies to that key only
+	u.HandleKey(keyboard.KeyEvent{Key: keyCtrl, State: keyboard.KeyStatePressed})
+	tap(u, keyA)
+	u.HandleKey(keyboard.KeyEvent{Key: keyCtrl, State: keyboard.KeyStateReleased})
+	tap(u, keyA)
+	expectEvents(t, device, "29 1", "30 1", "30 0", "29 0", "30 1", "30 0")
+}
+
+func TestUInputTypesOutputWithUSKeys(t *testing.T) {
+	u, device := newTestUInput(t)
+
+	tap(u, &keyboard.Key{ID: "at", Output: keyboard.Levels{"@"}})
+	expectEvents(t, device, "42 1", "3 1", "3 0", "42 0")
+
+	// A latched Shift picks the second level, is lifted for characters
+	// typed without it, and is released after the output
+	tap(u, keyShift)
+	tap(u, &keyboard.Key{ID: "dotcom", Output: keyboard.Levels{".com", ".ORG"}})
+	expectEvents(t, device,
+		"42 1",
+		"42 0", "52 1", "52 0",
+		"42 1", "24 1", "24 0", "19 1", "19 0", "34 1", "34 0",
+		"42 0")
+
+	// Characters without a US key are skipped
+	tap(u, &keyboard.Key{ID: "euro", Output: keyboard.Levels{"€1"}})
+	expectEvents(t, device, "2 1", "2 0")
+}
+
+func TestUInputCloseReleasesKeysAndDevice(t *testing.T) {
+	u, device := newTestUInput(t)
+	tap(u, keyShift)
+	u.HandleKey(keyboard.KeyEvent{Key: keyA, State: keyboard.KeyStatePressed})
+	device.take()
+
+	u.Close()
+	expectEvents(t, device, "30 0", "42 0")
+	if !device.closed {
+		t.Error("device was not closed")
+	}
+	tap(u, keyA)
+	expectEvents(t, device)
+}
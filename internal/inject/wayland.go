@@ -0,0 +1,25 @@
+package inject
+
+import (
+	"fmt"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+)
+
+func init() {
+	Register("wayland", openWayland)
+}
+
+// openWayland types through the compositor's zwp_virtual_keyboard_v1
+func openWayland(config Config) (KeyInjector, error) {
+	if config.Wayland == nil {
+		return nil, fmt.Errorf("no Wayland connection")
+	}
+	vk, err := config.Wayland.VirtualKeyboard()
+	if err != nil {
+		return nil, err
+	}
+	return vk, nil
+}
+
+var _ KeyInjector = (*wayland.VirtualKeyboard)(nil)
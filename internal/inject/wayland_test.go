@@ -0,0 +1,55 @@
+package inject
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+	"github.com/iotcore/osk-iotcore/internal/wayland/waylandtest"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+func TestAttachTypesThroughVirtualKeyboard(t *testing.T) {
+	compositor := waylandtest.NewCompositor(t)
+	compositor.Setenv()
+	client, err := wayland.NewClient()
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	defer client.Close()
+
+	injector, name, err := Open([]string{"wayland"}, Config{Wayland: client})
+	if err != nil || name != "wayland" {
+		t.Fatalf("Open: %s, %v", name, err)
+	}
+	defer injector.Close()
+
+	kb, err := keyboard.New()
+	if err != nil {
+		t.Fatalf("keyboard.New: %v", err)
+	}
+	var code int32
+	for _, key := range kb.GetLayout().Keys {
+		if key.ID == "a" {
+			code = key.Code
+		}
+	}
+	detach := Attach(kb, injector)
+	defer detach()
+
+	kb.PressKey("a")
+	kb.ReleaseKey("a")
+	// Releasing a key that is not pressed sends nothing
+	kb.ReleaseKey("a")
+	if err := client.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	compositor.Wait(func() bool { return len(compositor.Keys()) >= 2 })
+	want := []waylandtest.Key{{Code: uint32(code), Pressed: true}, {Code: uint32(code)}}
+	if keys := compositor.Keys(); !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %+v, want %+v", keys, want)
+	}
+	if compositor.Keymap() == "" {
+		t.Error("no keymap uploaded before the keys")
+	}
+}
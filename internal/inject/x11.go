@@ -0,0 +1,79 @@
+package inject
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+func init() {
+	Register("x11", openX11)
+}
+
+// openX11 types through the XTEST extension of the X server the keyboard
+// is shown on
+func openX11(config Config) (KeyInjector, error) {
+	client, ok := config.Wayland.(*wayland.X11Client)
+	if !ok {
+		return nil, fmt.Errorf("not running on an X server")
+	}
+	xtest, err := client.XTest()
+	if err != nil {
+		return nil, err
+	}
+	return newXTestInjector(xtest), nil
+}
+
+// fakeKeyboard presses keys on an X server
+type fakeKeyboard interface {
+	FakeKey(code uint32, pressed bool)
+	Lookup(r rune) (code uint32, shift bool, ok bool)
+}
+
+// XTestInjector types keys on an X server as if they were pressed on a
+// physical keyboard, so they reach the focused window. Key.Code values are
+// evdev codes. Modifier keys behave as with UInput; keys with an Output
+// type the characters the server's keymap has, and others are skipped.
+type XTestInjector struct {
+	mutex     sync.Mutex
+	keys      fakeKeyboard
+	sequencer *keySequencer
+	closed    bool
+}
+
+func newXTestInjector(keys fakeKeyboard) *XTestInjector {
+	x := &XTestInjector{keys: keys}
+	x.sequencer = newKeySequencer(func(code uint16, down bool) {
+		keys.FakeKey(uint32(code), down)
+	}, keys.Lookup)
+	return x
+}
+
+// HandleKey types a key pressed or released on the keyboard
+func (x *XTestInjector) HandleKey(event keyboard.KeyEvent) {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if x.closed {
+		return
+	}
+	x.sequencer.handleKey(event)
+}
+
+// SetLayout does nothing: outputs are looked up in the server's keymap
+// as they are typed
+func (x *XTestInjector) SetLayout(*keyboard.Layout) error {
+	return nil
+}
+
+// Close releases every key still down, so none stay stuck
+func (x *XTestInjector) Close() {
+	x.mutex.Lock()
+	defer x.mutex.Unlock()
+	if x.closed {
+		return
+	}
+	x.closed = true
+	x.sequencer.releaseAll()
+}
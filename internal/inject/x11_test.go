@@ -0,0 +1,59 @@
+package inject
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// fakeXServer records fake key events and has a keymap typing "é" on the
+// E key's second level
+type fakeXServer struct {
+	events []string
+}
+
+func (s *fakeXServer) FakeKey(code uint32, pressed bool) {
+	value := 0
+	if pressed {
+		value = 1
+	}
+	s.events = append(s.events, fmt.Sprintf("%d %d", code, value))
+}
+
+func (s *fakeXServer) Lookup(r rune) (uint32, bool, bool) {
+	switch r {
+	case 'e':
+		return 18, false, true
+	case 'é':
+		return 18, true, true
+	}
+	return 0, false, false
+}
+
+func (s *fakeXServer) take() []string {
+	events := s.events
+	s.events = nil
+	return events
+}
+
+func TestXTestTypesOutputWithServerKeymap(t *testing.T) {
+	server := &fakeXServer{}
+	x := newXTestInjector(server)
+
+	x.HandleKey(keyboard.KeyEvent{Key: &keyboard.Key{ID: "e-acute", Output: keyboard.Levels{"eé€"}}, State: keyboard.KeyStatePressed})
+	want := []string{"18 1", "18 0", "42 1", "18 1", "18 0", "42 0"}
+	if got := server.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	// Keys still down are released on close, and none are typed after
+	x.HandleKey(keyboard.KeyEvent{Key: keyA, State: keyboard.KeyStatePressed})
+	server.take()
+	x.Close()
+	x.HandleKey(keyboard.KeyEvent{Key: keyA, State: keyboard.KeyStatePressed})
+	if got, want := server.take(), []string{"30 0"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events after close = %q, want %q", got, want)
+	}
+}
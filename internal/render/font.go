@@ -0,0 +1,250 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// DefaultFont is the name of the built-in font used when a theme does not
+// choose a typeface. It always ends a fallback chain.
+const DefaultFont = "go-regular"
+
+// atlasSize is the initial width and height of a glyph atlas
+const atlasSize = 256
+
+// maxCachedLayouts bounds the number of shaped labels kept by a font manager
+const maxCachedLayouts = 1024
+
+// FontSearchDirs lists the asset layers searched for font files, in order
+var FontSearchDirs = []string{
+	"assets/fonts",
+	"fonts",
+	"../assets/fonts",
+}
+
+// FontManager loads TrueType and OpenType fonts, shapes text through a
+// fallback chain of faces and caches glyph atlases for each font and pixel
+// size. Atlas pixels are shared with the texture manager so GPU backends can
+// upload them as textures.
+type FontManager struct {
+	textures   *TextureManager
+	searchDirs []string
+	emojiDirs  []string
+	faces      map[string]*font.Face
+	names      map[*font.Face]string
+	atlases    map[string]*GlyphAtlas
+	sprites    map[string]*sprite
+	layouts    map[string]*TextLayout
+	shaper     shaping.HarfbuzzShaper
+	segmenter  shaping.Segmenter
+	wrapper    shaping.LineWrapper
+	mutex      sync.Mutex
+}
+
+// NewFontManager creates a font manager that caches atlases in textures.
+// Fonts are looked up in FontSearchDirs when searchDirs is empty.
+func NewFontManager(textures *TextureManager, searchDirs ...string) *FontManager {
+	if len(searchDirs) == 0 {
+		searchDirs = FontSearchDirs
+	}
+	return &FontManager{
+		textures:   textures,
+		searchDirs: searchDirs,
+		emojiDirs:  EmojiSearchDirs,
+		faces:      make(map[string]*font.Face),
+		names:      make(map[*font.Face]string),
+		atlases:    make(map[string]*GlyphAtlas),
+		sprites:    make(map[string]*sprite),
+		layouts:    make(map[string]*TextLayout),
+	}
+}
+
+// Family checks that every font of a fallback chain can be loaded and
+// returns the chain with DefaultFont appended. Empty names are skipped.
+func (fm *FontManager) Family(names []string) ([]string, error) {
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+
+	chain, err := fm.chain(names)
+	if err != nil {
+		return nil, err
+	}
+	return chain.names, nil
+}
+
+// Atlas returns the glyph atlas for a font at the given pixel size, loading
+// the font on first use. An empty name selects DefaultFont.
+func (fm *FontManager) Atlas(name string, size float64) (*GlyphAtlas, error) {
+	if name == "" {
+		name = DefaultFont
+	}
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid font size %v", size)
+	}
+
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+
+	face, err := fm.load(name)
+	if err != nil {
+		return nil, err
+	}
+	return fm.atlas(face, size), nil
+}
+
+// Layout shapes text with the fallback chain family at the given pixel
+// size. Runs are split by script, direction and the first face in the chain
+// that covers each character, then ordered visually for bidirectional text.
+// Layouts are cached, so callers must not modify the result.
+func (fm *FontManager) Layout(family []string, size float64, text string) (*TextLayout, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid font size %v", size)
+	}
+
+	fm.mutex.Lock()
+	defer fm.mutex.Unlock()
+
+	chain, err := fm.chain(family)
+	if err != nil {
+		return nil, err
+	}
+
+	key := fmt.Sprintf("%s@%.2f:%s", strings.Join(chain.names, ","), size, text)
+	if layout, exists := fm.layouts[key]; exists {
+		return layout, nil
+	}
+
+	layout := fm.shape(chain, size, []rune(text))
+	if len(fm.layouts) >= maxCachedLayouts {
+		fm.layouts = make(map[string]*TextLayout)
+	}
+	fm.layouts[key] = layout
+	return layout, nil
+}
+
+// fontChain is a resolved fallback chain. It implements shaping.Fontmap by
+// picking the first face that has a glyph for a rune.
+type fontChain struct {
+	names []string
+	faces []*font.Face
+}
+
+// ResolveFace returns the first face in the chain covering r, or the
+// primary face when none does
+func (c *fontChain) ResolveFace(r rune) *font.Face {
+	for _, face := range c.faces {
+		if _, ok := face.NominalGlyph(r); ok {
+			return face
+		}
+	}
+	return c.faces[0]
+}
+
+// chain loads the faces of a fallback chain. Callers must hold the mutex.
+func (fm *FontManager) chain(names []string) (*fontChain, error) {
+	chain := &fontChain{}
+	seen := make(map[string]bool)
+	for _, name := range append(append([]string{}, names...), DefaultFont) {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		face, err := fm.load(name)
+		if err != nil {
+			return nil, err
+		}
+		chain.names = append(chain.names, name)
+		chain.faces = append(chain.faces, face)
+	}
+	return chain, nil
+}
+
+// atlas returns the atlas of face at size. Callers must hold the mutex.
+func (fm *FontManager) atlas(face *font.Face, size float64) *GlyphAtlas {
+	key := fmt.Sprintf("font:%s@%.2f", fm.names[face], size)
+	if atlas, exists := fm.atlases[key]; exists {
+		return atlas
+	}
+	atlas := newGlyphAtlas(key, face, size, fm.textures)
+	fm.atlases[key] = atlas
+	return atlas
+}
+
+// load parses a font by name, caching the result. Names may be the
+// built-in DefaultFont, a file path, or a file name found in the search
+// directories with or without its extension. Callers must hold the mutex.
+func (fm *FontManager) load(name string) (*font.Face, error) {
+	if face, exists := fm.faces[name]; exists {
+		return face, nil
+	}
+
+	var data []byte
+	if name == DefaultFont {
+		data = goregular.TTF
+	} else {
+		path, err := fm.resolve(name)
+		if err != nil {
+			return nil, err
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return nil, fmt.Errorf("failed to read font %s: %w", path, err)
+		}
+	}
+
+	face, err := parseFont(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse font %s: %w", name, err)
+	}
+
+	fm.faces[name] = face
+	fm.names[face] = name
+	return face, nil
+}
+
+// resolve finds the file for a font name
+func (fm *FontManager) resolve(name string) (string, error) {
+	candidates := []string{name}
+	if filepath.Ext(name) == "" {
+		for _, ext := range []string{".ttf", ".otf", ".ttc", ".otc"} {
+			candidates = append(candidates, name+ext)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if filepath.IsAbs(candidate) || strings.ContainsRune(candidate, filepath.Separator) {
+			if _, err := os.Stat(candidate); err == nil {
+				return candidate, nil
+			}
+			continue
+		}
+		for _, dir := range fm.searchDirs {
+			path := filepath.Join(dir, candidate)
+			if _, err := os.Stat(path); err == nil {
+				return path, nil
+			}
+		}
+	}
+
+	return "", fmt.Errorf("font %s not found", name)
+}
+
+// parseFont parses a single font or the first font of a collection
+func parseFont(data []byte) (*font.Face, error) {
+	faces, err := font.ParseTTC(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if len(faces) == 0 {
+		return nil, fmt.Errorf("font collection is empty")
+	}
+	return faces[0], nil
+}
@@ -0,0 +1,170 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-text/typesetting/di"
+	"golang.org/x/image/font/gofont/gomono"
+	"golang.org/x/image/math/fixed"
+)
+
+func TestFontManagerCachesAtlasesAndLayouts(t *testing.T) {
+	textures := NewTextureManager()
+	fm := NewFontManager(textures)
+
+	a, err := fm.Atlas("", 16)
+	if err != nil {
+		t.Fatalf("Atlas: %v", err)
+	}
+	b, err := fm.Atlas(DefaultFont, 16)
+	if err != nil {
+		t.Fatalf("Atlas: %v", err)
+	}
+	if a != b {
+		t.Error("default font atlas should be cached and shared")
+	}
+
+	c, err := fm.Atlas("", 32)
+	if err != nil {
+		t.Fatalf("Atlas: %v", err)
+	}
+	if c == a {
+		t.Error("different sizes should use different atlases")
+	}
+
+	first, err := fm.Layout(nil, 16, "QWERTY")
+	if err != nil {
+		t.Fatalf("Layout: %v", err)
+	}
+	second, _ := fm.Layout([]string{DefaultFont}, 16, "QWERTY")
+	if first != second {
+		t.Error("layouts of the same chain, size and text should be cached")
+	}
+	if _, ok := textures.GetTexture(a.name); !ok {
+		t.Error("atlas was not published to the texture manager")
+	}
+}
+
+func TestFontManagerLoadsFallbackChain(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "mono.ttf"), gomono.TTF, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	fm := NewFontManager(NewTextureManager(), dir)
+	family, err := fm.Family([]string{"mono", "", "mono"})
+	if err != nil {
+		t.Fatalf("Family: %v", err)
+	}
+	if len(family) != 2 || family[0] != "mono" || family[1] != DefaultFont {
+		t.Errorf("Family = %v, want [mono %s]", family, DefaultFont)
+	}
+
+	// Monospaced glyphs from the first font share one advance width
+	narrow, _ := fm.Layout(family, 20, "iiii")
+	wide, _ := fm.Layout(family, 20, "WWWW")
+	if narrow.Advance != wide.Advance {
+		t.Errorf("monospace widths differ: %v vs %v", narrow.Advance, wide.Advance)
+	}
+
+	if _, err := fm.Family([]string{"mono", "missing"}); err == nil {
+		t.Error("expected an error for a missing fallback font")
+	}
+}
+
+func TestGlyphAtlasGrowsWithLargeText(t *testing.T) {
+	fm := NewFontManager(NewTextureManager())
+	text := "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+	large, err := fm.Layout(nil, 96, text)
+	if err != nil {
+		t.Fatalf("Layout: %v", err)
+	}
+	width, height := large.Size()
+	if width <= 0 || height <= 0 {
+		t.Fatalf("Size = %dx%d", width, height)
+	}
+
+	atlas, _ := fm.Atlas("", 96)
+	if atlas.mask.Bounds().Dx() <= atlasSize {
+		t.Error("atlas should have grown to fit large glyphs")
+	}
+
+	small, _ := fm.Layout(nil, 12, text)
+	if w, _ := small.Size(); w >= width {
+		t.Errorf("12px text (%d) should be narrower than 96px text (%d)", w, width)
+	}
+}
+
+func TestLayoutFallsBackToEmojiSprites(t *testing.T) {
+	dir := t.TempDir()
+	sprite := image.NewRGBA(image.Rect(0, 0, 8, 8))
+	for i := range sprite.Pix {
+		sprite.Pix[i] = 0xff
+	}
+	for i := 0; i < len(sprite.Pix); i += 4 {
+		sprite.Pix[i+1], sprite.Pix[i+2] = 0, 0 // opaque red
+	}
+	file, err := os.Create(filepath.Join(dir, "1f60a.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(file, sprite); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	fm := NewFontManager(NewTextureManager())
+	fm.emojiDirs = []string{dir}
+
+	// The variation selector belongs to the emoji cluster
+	layout, err := fm.Layout(nil, 20, "😊️")
+	if err != nil {
+		t.Fatalf("Layout: %v", err)
+	}
+	if len(layout.glyphs) != 1 || layout.glyphs[0].image == nil {
+		t.Fatalf("expected a single sprite glyph, got %+v", layout.glyphs)
+	}
+	if layout.Missing != 0 {
+		t.Errorf("Missing = %d, want 0", layout.Missing)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, 40, 40))
+	layout.Draw(dst, fixed.P(4, 30), image.NewUniform(color.Black))
+	if c := dst.RGBAAt(4+layout.Advance.Round()/2, 20); c.R != 0xff || c.G != 0 {
+		t.Errorf("sprite pixel = %v, want red", c)
+	}
+
+	// Without a sprite, the emoji is the missing-glyph box
+	fm.emojiDirs = nil
+	if layout, _ := fm.Layout(nil, 20, "a🎤"); layout.Missing != 1 {
+		t.Errorf("Missing without a sprite = %d, want 1", layout.Missing)
+	}
+}
+
+func TestParagraphDirection(t *testing.T) {
+	tests := []struct {
+		text string
+		want di.Direction
+	}{
+		{"Shift", di.DirectionLTR},
+		{"123", di.DirectionLTR},
+		{"سلام", di.DirectionRTL},
+		{"1 שלום", di.DirectionRTL},
+		{"⇧ abc سلام", di.DirectionLTR},
+	}
+	for _, tt := range tests {
+		if got := paragraphDirection([]rune(tt.text)); got != tt.want {
+			t.Errorf("paragraphDirection(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+
+	if got := emojiName([]rune("👍🏽")); got != "1f44d-1f3fd" {
+		t.Errorf("emojiName = %q", got)
+	}
+}
@@ -0,0 +1,250 @@
+package render
+
+import (
+	"bytes"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"math"
+	"sync"
+
+	"github.com/go-text/typesetting/font"
+	ot "github.com/go-text/typesetting/font/opentype"
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/vector"
+)
+
+// glyph locates a rasterized outline glyph within an atlas
+type glyph struct {
+	// bounds is the glyph's rectangle in the atlas
+	bounds image.Rectangle
+	// offset is the top-left of the glyph relative to the pen position
+	offset image.Point
+}
+
+// GlyphAtlas rasterizes the glyphs of one face at one pixel size on demand
+// and packs outline glyphs into a single alpha texture using shelf packing.
+// Colour bitmap glyphs (CBDT, sbix) are kept as separate RGBA images.
+type GlyphAtlas struct {
+	name     string
+	face     *font.Face
+	size     float64
+	textures *TextureManager
+	mask     *image.Alpha
+	glyphs   map[font.GID]glyph
+	colors   map[colorKey]*image.RGBA
+	shelfX   int
+	shelfY   int
+	shelfH   int
+	dirty    bool
+	mutex    sync.Mutex
+}
+
+// colorKey identifies a colour glyph scaled to a destination size
+type colorKey struct {
+	gid  font.GID
+	size image.Point
+}
+
+// newGlyphAtlas creates an empty atlas for face at size pixels per em
+func newGlyphAtlas(name string, face *font.Face, size float64, textures *TextureManager) *GlyphAtlas {
+	atlas := &GlyphAtlas{
+		name:     name,
+		face:     face,
+		size:     size,
+		textures: textures,
+		mask:     image.NewAlpha(image.Rect(0, 0, atlasSize, atlasSize)),
+		glyphs:   make(map[font.GID]glyph),
+		colors:   make(map[colorKey]*image.RGBA),
+	}
+	atlas.publish()
+	return atlas
+}
+
+// HasGlyph reports whether the face maps r to a glyph
+func (a *GlyphAtlas) HasGlyph(r rune) bool {
+	_, ok := a.face.NominalGlyph(r)
+	return ok
+}
+
+// scale converts font units to pixels
+func (a *GlyphAtlas) scale() float64 {
+	return a.size / float64(a.face.Upem())
+}
+
+// draw draws glyph gid with its origin at dot, filling outline coverage
+// with src and blending over dst
+func (a *GlyphAtlas) draw(dst draw.Image, dot image.Point, gid font.GID, src image.Image) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	defer a.sync()
+
+	g := a.lookup(gid)
+	if g.bounds.Empty() {
+		return
+	}
+	origin := dot.Add(g.offset)
+	target := image.Rectangle{Min: origin, Max: origin.Add(g.bounds.Size())}
+	draw.DrawMask(dst, target, src, image.Point{}, a.mask, g.bounds.Min, draw.Over)
+}
+
+// colorGlyph returns the colour bitmap for gid scaled to size, or nil when
+// the face has no bitmap for the glyph
+func (a *GlyphAtlas) colorGlyph(gid font.GID, size image.Point) *image.RGBA {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	key := colorKey{gid, size}
+	if img, exists := a.colors[key]; exists {
+		return img
+	}
+
+	var img *image.RGBA
+	if bitmap, ok := a.face.GlyphDataBitmap(gid); ok && size.X > 0 && size.Y > 0 &&
+		(bitmap.Format == font.PNG || bitmap.Format == font.JPG) {
+		if decoded, _, err := image.Decode(bytes.NewReader(bitmap.Data)); err == nil {
+			img = scaleImage(decoded, size)
+		}
+	}
+
+	a.colors[key] = img
+	return img
+}
+
+// lookup returns the outline glyph for gid, rasterizing it into the atlas
+// on first use. Callers must hold the mutex.
+func (a *GlyphAtlas) lookup(gid font.GID) glyph {
+	if g, exists := a.glyphs[gid]; exists {
+		return g
+	}
+
+	var g glyph
+	if outline, ok := a.face.GlyphDataOutline(gid); ok && len(outline.Segments) > 0 {
+		rect, mask := rasterizeOutline(outline, a.scale())
+		if !rect.Empty() {
+			g.offset = rect.Min
+			g.bounds = a.allocate(rect.Dx(), rect.Dy())
+			draw.Draw(a.mask, g.bounds, mask, image.Point{}, draw.Src)
+			a.dirty = true
+		}
+	}
+
+	a.glyphs[gid] = g
+	return g
+}
+
+// rasterizeOutline renders an outline in font units at scale pixels per
+// unit. The returned rectangle is relative to the glyph origin with Y down.
+func rasterizeOutline(outline font.GlyphOutline, scale float64) (image.Rectangle, *image.Alpha) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, segment := range outline.Segments {
+		for _, p := range segment.ArgsSlice() {
+			x, y := float64(p.X)*scale, -float64(p.Y)*scale
+			minX, maxX = math.Min(minX, x), math.Max(maxX, x)
+			minY, maxY = math.Min(minY, y), math.Max(maxY, y)
+		}
+	}
+
+	rect := image.Rect(int(math.Floor(minX)), int(math.Floor(minY)), int(math.Ceil(maxX)), int(math.Ceil(maxY)))
+	if rect.Empty() {
+		return image.Rectangle{}, nil
+	}
+
+	ox, oy := float32(rect.Min.X), float32(rect.Min.Y)
+	point := func(p font.SegmentPoint) (float32, float32) {
+		return p.X*float32(scale) - ox, -p.Y*float32(scale) - oy
+	}
+
+	raster := vector.NewRasterizer(rect.Dx(), rect.Dy())
+	for _, segment := range outline.Segments {
+		switch segment.Op {
+		case ot.SegmentOpMoveTo:
+			raster.ClosePath()
+			raster.MoveTo(point(segment.Args[0]))
+		case ot.SegmentOpLineTo:
+			raster.LineTo(point(segment.Args[0]))
+		case ot.SegmentOpQuadTo:
+			x1, y1 := point(segment.Args[0])
+			x2, y2 := point(segment.Args[1])
+			raster.QuadTo(x1, y1, x2, y2)
+		case ot.SegmentOpCubeTo:
+			x1, y1 := point(segment.Args[0])
+			x2, y2 := point(segment.Args[1])
+			x3, y3 := point(segment.Args[2])
+			raster.CubeTo(x1, y1, x2, y2, x3, y3)
+		}
+	}
+	raster.ClosePath()
+
+	mask := image.NewAlpha(image.Rect(0, 0, rect.Dx(), rect.Dy()))
+	raster.Draw(mask, mask.Bounds(), image.Opaque, image.Point{})
+	return rect, mask
+}
+
+// scaleImage resamples img to size
+func scaleImage(img image.Image, size image.Point) *image.RGBA {
+	dst := image.NewRGBA(image.Rectangle{Max: size})
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, img.Bounds(), xdraw.Src, nil)
+	return dst
+}
+
+// allocate reserves a w×h rectangle in the atlas, growing it when full
+func (a *GlyphAtlas) allocate(w, h int) image.Rectangle {
+	const padding = 1
+	size := a.mask.Bounds().Size()
+
+	if a.shelfX+w+padding > size.X {
+		a.shelfY += a.shelfH
+		a.shelfX, a.shelfH = 0, 0
+	}
+	for a.shelfY+h+padding > a.mask.Bounds().Dy() || w+padding > a.mask.Bounds().Dx() {
+		a.grow()
+	}
+
+	rect := image.Rect(a.shelfX, a.shelfY, a.shelfX+w, a.shelfY+h)
+	a.shelfX += w + padding
+	if h+padding > a.shelfH {
+		a.shelfH = h + padding
+	}
+	return rect
+}
+
+// grow doubles the atlas, keeping existing glyphs in place
+func (a *GlyphAtlas) grow() {
+	old := a.mask
+	size := old.Bounds().Size()
+	a.mask = image.NewAlpha(image.Rect(0, 0, size.X*2, size.Y*2))
+	draw.Draw(a.mask, old.Bounds(), old, image.Point{}, draw.Src)
+}
+
+// sync publishes the atlas if glyphs were added since it was last published.
+// Callers must hold the mutex.
+func (a *GlyphAtlas) sync() {
+	if a.dirty {
+		a.publish()
+		a.dirty = false
+	}
+}
+
+// publish stores the atlas in the texture manager as a white texture whose
+// alpha channel holds glyph coverage
+func (a *GlyphAtlas) publish() {
+	if a.textures == nil {
+		return
+	}
+	bounds := a.mask.Bounds()
+	data := make([]byte, bounds.Dx()*bounds.Dy()*4)
+	for i, coverage := range a.mask.Pix {
+		data[i*4+0] = coverage
+		data[i*4+1] = coverage
+		data[i*4+2] = coverage
+		data[i*4+3] = coverage
+	}
+	a.textures.AddTexture(a.name, &Texture{
+		Width:  int32(bounds.Dx()),
+		Height: int32(bounds.Dy()),
+		Data:   data,
+	})
+}
@@ -2,14 +2,6 @@
 // using OpenGL/Vulkan.
 package render
 
-// Renderer represents a generic renderer interface
-// supporting basic rendering tasks.
-type Renderer interface {
-	Initialize() error
-	RenderText(x, y int, text string, color [4]float32) error
-	Close()
-}
-
 // OpenGLRenderer implements Renderer using OpenGL.
 type OpenGLRenderer struct {
 }
@@ -30,3 +22,68 @@ func (r *OpenGLRenderer) RenderText(x, y int, text string, color [4]float32) err
 func (r *OpenGLRenderer) Close() {
 	// Cleanup logic here
 }
+
+// Capabilities reports the optional features of the OpenGL renderer.
+func (r *OpenGLRenderer) Capabilities() Capabilities {
+	// No optional features until the OpenGL pipeline is implemented
+	return 0
+}
+
+// BeginFrame starts a new frame.
+func (r *OpenGLRenderer) BeginFrame(width, height int) error {
+	// OpenGL frame setup logic here
+	return nil
+}
+
+// EndFrame completes the current frame.
+func (r *OpenGLRenderer) EndFrame() error {
+	// OpenGL buffer swap logic here
+	return nil
+}
+
+// Clear fills the frame with color.
+func (r *OpenGLRenderer) Clear(color [4]float32) error {
+	return nil
+}
+
+// FillRoundedRect fills a rounded rectangle.
+func (r *OpenGLRenderer) FillRoundedRect(x, y, width, height int, radius float32, color [4]float32) error {
+	return nil
+}
+
+// StrokeRoundedRect draws a rounded rectangle border.
+func (r *OpenGLRenderer) StrokeRoundedRect(x, y, width, height int, radius, lineWidth float32, color [4]float32) error {
+	return nil
+}
+
+// DrawShadow draws a drop shadow.
+func (r *OpenGLRenderer) DrawShadow(x, y, width, height int, radius, blur float32, color [4]float32) error {
+	return nil
+}
+
+// DrawTexture draws a texture into the destination rectangle.
+func (r *OpenGLRenderer) DrawTexture(texture *Texture, x, y, width, height int) error {
+	return nil
+}
+
+// PushClip pushes a clip rectangle.
+func (r *OpenGLRenderer) PushClip(x, y, width, height int) {}
+
+// PopClip pops the current clip rectangle.
+func (r *OpenGLRenderer) PopClip() {}
+
+// PushTransform pushes a transform.
+func (r *OpenGLRenderer) PushTransform(t Transform) {}
+
+// PopTransform pops the current transform.
+func (r *OpenGLRenderer) PopTransform() {}
+
+// SetFont selects the font for text rendering.
+func (r *OpenGLRenderer) SetFont(family []string, size float32) error {
+	return nil
+}
+
+// MeasureText returns the size of rendered text.
+func (r *OpenGLRenderer) MeasureText(text string) (width, height int) {
+	return 0, 0
+}
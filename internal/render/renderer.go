@@ -0,0 +1,124 @@
+package render
+
+// Renderer represents a generic renderer interface
+// supporting basic rendering tasks.
+//
+// Drawing calls are made between BeginFrame and EndFrame. Coordinates are
+// in pixels and pass through the current transform before the current clip
+// rectangle is applied. Colours are straight (non-premultiplied) RGBA with
+// components in [0,1].
+type Renderer interface {
+	Initialize() error
+	Close()
+
+	// Capabilities reports which optional features the backend supports.
+	// Callers fall back to simpler drawing for unsupported features.
+	Capabilities() Capabilities
+
+	// BeginFrame starts a frame of the given size, resetting the clip and
+	// transform stacks. EndFrame completes the frame.
+	BeginFrame(width, height int) error
+	EndFrame() error
+
+	// Clear fills the current clip rectangle with color, replacing its
+	// contents. At the start of a frame the clip is the whole frame.
+	Clear(color [4]float32) error
+
+	// FillRoundedRect fills a rectangle with corners of the given radius.
+	FillRoundedRect(x, y, width, height int, radius float32, color [4]float32) error
+
+	// StrokeRoundedRect draws a border of lineWidth along the inside edge of
+	// a rounded rectangle.
+	StrokeRoundedRect(x, y, width, height int, radius, lineWidth float32, color [4]float32) error
+
+	// DrawShadow draws a soft drop shadow for a rounded rectangle.
+	DrawShadow(x, y, width, height int, radius, blur float32, color [4]float32) error
+
+	// DrawTexture draws a texture scaled to the destination rectangle.
+	DrawTexture(texture *Texture, x, y, width, height int) error
+
+	// PushClip restricts drawing to the intersection of the current clip and
+	// the given rectangle until the matching PopClip.
+	PushClip(x, y, width, height int)
+	PopClip()
+
+	// PushTransform composes t with the current transform until the
+	// matching PopTransform.
+	PushTransform(t Transform)
+	PopTransform()
+
+	// SetFont selects the fonts used by RenderText and MeasureText. Family
+	// is a fallback chain: each character is drawn with the first font that
+	// covers it, ending with the default font. Size is in pixels before the
+	// current transform is applied; backends rasterize glyphs at the
+	// transformed size.
+	SetFont(family []string, size float32) error
+
+	// RenderText renders text centred on the specified location with color.
+	RenderText(x, y int, text string, color [4]float32) error
+
+	// MeasureText returns the size of text as RenderText would draw it,
+	// before the current transform is applied.
+	MeasureText(text string) (width, height int)
+}
+
+// Capabilities is a set of optional renderer features
+type Capabilities uint32
+
+const (
+	// CapRoundedRects indicates corner radii are honoured
+	CapRoundedRects Capabilities = 1 << iota
+	// CapShadows indicates DrawShadow produces a soft shadow
+	CapShadows
+	// CapTextures indicates DrawTexture is supported
+	CapTextures
+	// CapClipping indicates PushClip restricts drawing
+	CapClipping
+	// CapTransforms indicates PushTransform affects drawing
+	CapTransforms
+	// CapAntialiasing indicates shape edges are anti-aliased
+	CapAntialiasing
+)
+
+// Has reports whether all capabilities in c are present
+func (caps Capabilities) Has(c Capabilities) bool {
+	return caps&c == c
+}
+
+// Transform is a 2D transform made of a scale followed by a translation
+type Transform struct {
+	ScaleX     float32
+	ScaleY     float32
+	TranslateX float32
+	TranslateY float32
+}
+
+// Identity returns the identity transform
+func Identity() Transform {
+	return Transform{ScaleX: 1, ScaleY: 1}
+}
+
+// Translate returns a transform that offsets by (x, y)
+func Translate(x, y float32) Transform {
+	return Transform{ScaleX: 1, ScaleY: 1, TranslateX: x, TranslateY: y}
+}
+
+// Scale returns a transform that scales by (sx, sy)
+func Scale(sx, sy float32) Transform {
+	return Transform{ScaleX: sx, ScaleY: sy}
+}
+
+// Then returns the transform that applies t followed by next
+func (t Transform) Then(next Transform) Transform {
+	return Transform{
+		ScaleX:     t.ScaleX * next.ScaleX,
+		ScaleY:     t.ScaleY * next.ScaleY,
+		TranslateX: t.TranslateX*next.ScaleX + next.TranslateX,
+		TranslateY: t.TranslateY*next.ScaleY + next.TranslateY,
+	}
+}
+
+// Apply transforms the point (x, y)
+func (t Transform) Apply(x, y float32) (float32, float32) {
+	return x*t.ScaleX + t.TranslateX, y*t.ScaleY + t.TranslateY
+}
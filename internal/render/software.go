@@ -0,0 +1,372 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+
+	"golang.org/x/image/draw"
+	"golang.org/x/image/math/fixed"
+)
+
+// SoftwareRenderer implements Renderer on the CPU into an image.RGBA.
+// It needs no GPU or compositor, which makes it suitable for headless
+// screenshots in CI and for shared-memory buffer presentation.
+type SoftwareRenderer struct {
+	width      int
+	height     int
+	img        *image.RGBA
+	fonts      *FontManager
+	family     []string
+	fontSize   float32
+	clips      []image.Rectangle
+	transforms []Transform
+}
+
+// defaultFontSize is the text size used until SetFont is called
+const defaultFontSize = 16
+
+// NewSoftwareRenderer creates a software renderer with the given canvas size
+func NewSoftwareRenderer(width, height int) *SoftwareRenderer {
+	return &SoftwareRenderer{
+		width:    width,
+		height:   height,
+		fonts:    NewFontManager(NewTextureManager()),
+		fontSize: defaultFontSize,
+	}
+}
+
+// Initialize allocates the canvas.
+func (r *SoftwareRenderer) Initialize() error {
+	if r.width <= 0 || r.height <= 0 {
+		return fmt.Errorf("invalid canvas size %dx%d", r.width, r.height)
+	}
+	r.img = image.NewRGBA(image.Rect(0, 0, r.width, r.height))
+	r.resetStacks()
+	return nil
+}
+
+// Close releases the canvas.
+func (r *SoftwareRenderer) Close() {
+	r.img = nil
+}
+
+// Capabilities reports the optional features of the software renderer.
+func (r *SoftwareRenderer) Capabilities() Capabilities {
+	return CapRoundedRects | CapShadows | CapTextures | CapClipping | CapTransforms | CapAntialiasing
+}
+
+// Image returns the canvas the renderer draws into
+func (r *SoftwareRenderer) Image() *image.RGBA {
+	return r.img
+}
+
+// Resize reallocates the canvas at a new size, discarding its contents
+func (r *SoftwareRenderer) Resize(width, height int) error {
+	r.width = width
+	r.height = height
+	return r.Initialize()
+}
+
+// BeginFrame starts a new frame, reallocating the canvas if the size changed.
+func (r *SoftwareRenderer) BeginFrame(width, height int) error {
+	if r.img == nil || width != r.width || height != r.height {
+		return r.Resize(width, height)
+	}
+	r.resetStacks()
+	return nil
+}
+
+// EndFrame completes the current frame.
+func (r *SoftwareRenderer) EndFrame() error {
+	if r.img == nil {
+		return fmt.Errorf("software renderer not initialized")
+	}
+	if len(r.clips) != 1 || len(r.transforms) != 1 {
+		return fmt.Errorf("unbalanced clip or transform stack at end of frame")
+	}
+	return nil
+}
+
+// Clear fills the current clip rectangle with color, replacing its contents.
+func (r *SoftwareRenderer) Clear(color [4]float32) error {
+	if r.img == nil {
+		return fmt.Errorf("software renderer not initialized")
+	}
+	draw.Draw(r.img, r.clip(), image.NewUniform(toRGBA(color)), image.Point{}, draw.Src)
+	return nil
+}
+
+// PushClip restricts drawing to the given rectangle within the current clip.
+func (r *SoftwareRenderer) PushClip(x, y, width, height int) {
+	x0, y0 := r.transform().Apply(float32(x), float32(y))
+	x1, y1 := r.transform().Apply(float32(x+width), float32(y+height))
+	rect := image.Rect(int(math.Floor(float64(x0))), int(math.Floor(float64(y0))),
+		int(math.Ceil(float64(x1))), int(math.Ceil(float64(y1))))
+	r.clips = append(r.clips, rect.Intersect(r.clip()))
+}
+
+// PopClip restores the previous clip rectangle.
+func (r *SoftwareRenderer) PopClip() {
+	if len(r.clips) > 1 {
+		r.clips = r.clips[:len(r.clips)-1]
+	}
+}
+
+// PushTransform composes t with the current transform.
+func (r *SoftwareRenderer) PushTransform(t Transform) {
+	r.transforms = append(r.transforms, t.Then(r.transform()))
+}
+
+// PopTransform restores the previous transform.
+func (r *SoftwareRenderer) PopTransform() {
+	if len(r.transforms) > 1 {
+		r.transforms = r.transforms[:len(r.transforms)-1]
+	}
+}
+
+// SetFont selects the font fallback chain used for text.
+func (r *SoftwareRenderer) SetFont(family []string, size float32) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid font size %v", size)
+	}
+	chain, err := r.fonts.Family(family)
+	if err != nil {
+		return err
+	}
+	r.family = chain
+	r.fontSize = size
+	return nil
+}
+
+// RenderText renders text centred on the specified location with color.
+func (r *SoftwareRenderer) RenderText(x, y int, text string, color [4]float32) error {
+	if r.img == nil {
+		return fmt.Errorf("software renderer not initialized")
+	}
+
+	t := r.transform()
+	layout, err := r.fonts.Layout(r.family, float64(r.fontSize*t.ScaleY), text)
+	if err != nil {
+		return err
+	}
+
+	cx, cy := t.Apply(float32(x), float32(y))
+	dot := fixed.Point26_6{
+		X: fixed.Int26_6(cx*64) - layout.Advance/2,
+		Y: fixed.Int26_6(cy*64) + (layout.Ascent-layout.Descent)/2,
+	}
+
+	layout.Draw(r.img.SubImage(r.clip()).(*image.RGBA), dot, image.NewUniform(toRGBA(color)))
+	return nil
+}
+
+// MeasureText returns the size of text as RenderText would draw it.
+func (r *SoftwareRenderer) MeasureText(text string) (width, height int) {
+	scale := r.transform().ScaleY
+	layout, err := r.fonts.Layout(r.family, float64(r.fontSize*scale), text)
+	if err != nil {
+		return 0, 0
+	}
+	w, h := layout.Size()
+	return int(float32(w)/scale + 0.5), int(float32(h)/scale + 0.5)
+}
+
+// FillRoundedRect fills an anti-aliased rectangle with rounded corners,
+// blending it over the existing contents.
+func (r *SoftwareRenderer) FillRoundedRect(x, y, width, height int, radius float32, color [4]float32) error {
+	box := r.newBox(x, y, width, height, radius)
+	return r.shade(box.bounds(1), color, func(px, py float64) float64 {
+		return clampUnit(0.5 - box.distance(px, py))
+	})
+}
+
+// StrokeRoundedRect draws an anti-aliased border of the given width along
+// the inside edge of a rounded rectangle.
+func (r *SoftwareRenderer) StrokeRoundedRect(x, y, width, height int, radius, lineWidth float32, color [4]float32) error {
+	if lineWidth <= 0 {
+		return nil
+	}
+	box := r.newBox(x, y, width, height, radius)
+	half := float64(lineWidth*r.uniformScale()) / 2
+	return r.shade(box.bounds(1), color, func(px, py float64) float64 {
+		d := box.distance(px, py) + half
+		return clampUnit(0.5 - (math.Abs(d) - half))
+	})
+}
+
+// DrawShadow draws a soft drop shadow for a rounded rectangle. The blur is
+// the approximate extent of the penumbra in pixels.
+func (r *SoftwareRenderer) DrawShadow(x, y, width, height int, radius, blur float32, color [4]float32) error {
+	if blur <= 0 {
+		return r.FillRoundedRect(x, y, width, height, radius, color)
+	}
+	box := r.newBox(x, y, width, height, radius)
+	sigma := float64(blur*r.uniformScale()) / 2
+	return r.shade(box.bounds(int(math.Ceil(2*sigma))+1), color, func(px, py float64) float64 {
+		return 0.5 * (1 - math.Erf(box.distance(px, py)/(sigma*math.Sqrt2)))
+	})
+}
+
+// DrawTexture draws a texture scaled to the destination rectangle with
+// bilinear filtering, blending it over the existing contents.
+func (r *SoftwareRenderer) DrawTexture(texture *Texture, x, y, width, height int) error {
+	if r.img == nil {
+		return fmt.Errorf("software renderer not initialized")
+	}
+	if texture == nil || texture.Width <= 0 || texture.Height <= 0 {
+		return fmt.Errorf("invalid texture")
+	}
+	if size := 4 * int(texture.Width) * int(texture.Height); len(texture.Data) < size {
+		return fmt.Errorf("texture of %dx%d has %d bytes of data, want %d",
+			texture.Width, texture.Height, len(texture.Data), size)
+	}
+
+	src := &image.RGBA{
+		Pix:    texture.Data,
+		Stride: int(texture.Width) * 4,
+		Rect:   image.Rect(0, 0, int(texture.Width), int(texture.Height)),
+	}
+
+	x0, y0 := r.transform().Apply(float32(x), float32(y))
+	x1, y1 := r.transform().Apply(float32(x+width), float32(y+height))
+	dst := image.Rect(int(x0+0.5), int(y0+0.5), int(x1+0.5), int(y1+0.5))
+
+	clipped := r.img.SubImage(r.clip()).(*image.RGBA)
+	draw.ApproxBiLinear.Scale(clipped, dst, src, src.Bounds(), draw.Over, nil)
+	return nil
+}
+
+// transform returns the current transform
+func (r *SoftwareRenderer) transform() Transform {
+	return r.transforms[len(r.transforms)-1]
+}
+
+// clip returns the current clip rectangle in canvas coordinates
+func (r *SoftwareRenderer) clip() image.Rectangle {
+	return r.clips[len(r.clips)-1]
+}
+
+// resetStacks resets the clip to the canvas and the transform to identity
+func (r *SoftwareRenderer) resetStacks() {
+	r.clips = append(r.clips[:0], image.Rect(0, 0, r.width, r.height))
+	r.transforms = append(r.transforms[:0], Identity())
+}
+
+// uniformScale returns the scale of lengths that belong to no axis, such
+// as radii, line widths and blur: the smaller of the two axis scales
+func (r *SoftwareRenderer) uniformScale() float32 {
+	t := r.transform()
+	return float32(math.Min(float64(t.ScaleX), float64(t.ScaleY)))
+}
+
+// newBox maps a rounded rectangle through the current transform
+func (r *SoftwareRenderer) newBox(x, y, width, height int, radius float32) roundedBox {
+	t := r.transform()
+	x0, y0 := t.Apply(float32(x), float32(y))
+	return newRoundedBox(float64(x0), float64(y0),
+		float64(float32(width)*t.ScaleX), float64(float32(height)*t.ScaleY), float64(radius*r.uniformScale()))
+}
+
+// shade blends color over every pixel in rect, weighted by the coverage
+// returned for the pixel centre.
+func (r *SoftwareRenderer) shade(rect image.Rectangle, c [4]float32, coverage func(px, py float64) float64) error {
+	if r.img == nil {
+		return fmt.Errorf("software renderer not initialized")
+	}
+	rect = rect.Intersect(r.clip())
+	for py := rect.Min.Y; py < rect.Max.Y; py++ {
+		for px := rect.Min.X; px < rect.Max.X; px++ {
+			cov := coverage(float64(px)+0.5, float64(py)+0.5)
+			if cov <= 0 {
+				continue
+			}
+			blendPixel(r.img, px, py, c, float32(cov))
+		}
+	}
+	return nil
+}
+
+// blendPixel composites a straight-alpha colour with extra coverage over the
+// premultiplied pixel at (x, y) using the source-over operator.
+func blendPixel(img *image.RGBA, x, y int, c [4]float32, coverage float32) {
+	a := clampUnit32(c[3]) * coverage
+	if a <= 0 {
+		return
+	}
+	i := img.PixOffset(x, y)
+	p := img.Pix[i : i+4 : i+4]
+	inv := 1 - a
+	p[0] = uint8(clampUnit32(c[0])*a*255 + float32(p[0])*inv + 0.5)
+	p[1] = uint8(clampUnit32(c[1])*a*255 + float32(p[1])*inv + 0.5)
+	p[2] = uint8(clampUnit32(c[2])*a*255 + float32(p[2])*inv + 0.5)
+	p[3] = uint8(a*255 + float32(p[3])*inv + 0.5)
+}
+
+// roundedBox describes a rounded rectangle by its centre, half extents and
+// corner radius for signed distance evaluation.
+type roundedBox struct {
+	cx, cy float64
+	hw, hh float64
+	radius float64
+}
+
+// newRoundedBox creates a rounded box, limiting the radius to half the
+// shorter side.
+func newRoundedBox(x, y, w, h, radius float64) roundedBox {
+	hw, hh := w/2, h/2
+	return roundedBox{
+		cx:     x + hw,
+		cy:     y + hh,
+		hw:     hw,
+		hh:     hh,
+		radius: math.Max(0, math.Min(radius, math.Min(hw, hh))),
+	}
+}
+
+// distance returns the signed distance from (px, py) to the box edge,
+// negative inside.
+func (b roundedBox) distance(px, py float64) float64 {
+	qx := math.Abs(px-b.cx) - b.hw + b.radius
+	qy := math.Abs(py-b.cy) - b.hh + b.radius
+	outside := math.Hypot(math.Max(qx, 0), math.Max(qy, 0))
+	inside := math.Min(math.Max(qx, qy), 0)
+	return outside + inside - b.radius
+}
+
+// bounds returns the pixel rectangle covered by the box, grown by pad pixels
+func (b roundedBox) bounds(pad int) image.Rectangle {
+	return image.Rect(
+		int(math.Floor(b.cx-b.hw))-pad,
+		int(math.Floor(b.cy-b.hh))-pad,
+		int(math.Ceil(b.cx+b.hw))+pad,
+		int(math.Ceil(b.cy+b.hh))+pad,
+	)
+}
+
+// toRGBA converts a straight-alpha colour with components in [0,1] to a
+// premultiplied color.RGBA.
+func toRGBA(c [4]float32) color.RGBA {
+	a := clampUnit32(c[3])
+	return color.RGBA{
+		R: uint8(clampUnit32(c[0])*a*255 + 0.5),
+		G: uint8(clampUnit32(c[1])*a*255 + 0.5),
+		B: uint8(clampUnit32(c[2])*a*255 + 0.5),
+		A: uint8(a*255 + 0.5),
+	}
+}
+
+func clampUnit(v float64) float64 {
+	return math.Max(0, math.Min(1, v))
+}
+
+func clampUnit32(v float32) float32 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
@@ -0,0 +1,168 @@
+package render
+
+import (
+	"image/color"
+	"testing"
+)
+
+func newTestRenderer(t *testing.T, w, h int) *SoftwareRenderer {
+	t.Helper()
+	r := NewSoftwareRenderer(w, h)
+	if err := r.Initialize(); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	return r
+}
+
+func TestSoftwareRendererFillRoundedRect(t *testing.T) {
+	r := newTestRenderer(t, 40, 40)
+	r.Clear([4]float32{0, 0, 0, 1})
+	r.FillRoundedRect(10, 10, 20, 20, 8, [4]float32{1, 0, 0, 1})
+
+	img := r.Image()
+	if got := img.RGBAAt(20, 20); got != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("centre pixel = %v, want opaque red", got)
+	}
+	if got := img.RGBAAt(10, 10); got.R > 64 {
+		t.Errorf("rounded corner pixel = %v, want mostly background", got)
+	}
+	if got := img.RGBAAt(5, 20); got != (color.RGBA{0, 0, 0, 255}) {
+		t.Errorf("outside pixel = %v, want background", got)
+	}
+}
+
+func TestSoftwareRendererAlphaBlending(t *testing.T) {
+	r := newTestRenderer(t, 4, 4)
+	r.Clear([4]float32{0, 0, 1, 1})
+	r.FillRoundedRect(0, 0, 4, 4, 0, [4]float32{1, 0, 0, 0.5})
+
+	got := r.Image().RGBAAt(1, 1)
+	if got.R < 126 || got.R > 129 || got.B < 126 || got.B > 129 || got.A != 255 {
+		t.Errorf("blended pixel = %v, want half red over blue", got)
+	}
+}
+
+func TestSoftwareRendererStrokeLeavesInteriorUntouched(t *testing.T) {
+	r := newTestRenderer(t, 30, 30)
+	r.Clear([4]float32{0, 0, 0, 0})
+	r.StrokeRoundedRect(5, 5, 20, 20, 0, 2, [4]float32{1, 1, 1, 1})
+
+	img := r.Image()
+	if got := img.RGBAAt(5, 15); got.A != 255 {
+		t.Errorf("border pixel alpha = %d, want 255", got.A)
+	}
+	if got := img.RGBAAt(15, 15); got.A != 0 {
+		t.Errorf("interior pixel alpha = %d, want 0", got.A)
+	}
+}
+
+func TestSoftwareRendererStrokeWidthIgnoresStretch(t *testing.T) {
+	r := newTestRenderer(t, 50, 30)
+	r.Clear([4]float32{0, 0, 0, 0})
+	r.PushTransform(Scale(4, 1))
+	r.StrokeRoundedRect(0, 5, 10, 20, 0, 2, [4]float32{1, 1, 1, 1})
+	r.PopTransform()
+
+	// Stretched sideways, the left border stays 2 pixels wide
+	img := r.Image()
+	if got := img.RGBAAt(1, 15).A; got != 255 {
+		t.Errorf("border pixel alpha = %d, want 255", got)
+	}
+	if got := img.RGBAAt(4, 15).A; got != 0 {
+		t.Errorf("pixel beside the border alpha = %d, want 0", got)
+	}
+}
+
+func TestSoftwareRendererShadowFalloff(t *testing.T) {
+	r := newTestRenderer(t, 60, 60)
+	r.Clear([4]float32{0, 0, 0, 0})
+	r.DrawShadow(20, 20, 20, 20, 4, 8, [4]float32{0, 0, 0, 1})
+
+	img := r.Image()
+	inside := img.RGBAAt(30, 30).A
+	edge := img.RGBAAt(20, 30).A
+	far := img.RGBAAt(8, 30).A
+	if !(inside > edge && edge > far) {
+		t.Errorf("shadow alpha should fall off outward: inside=%d edge=%d far=%d", inside, edge, far)
+	}
+}
+
+func TestSoftwareRendererRenderText(t *testing.T) {
+	r := newTestRenderer(t, 60, 30)
+	r.Clear([4]float32{0, 0, 0, 1})
+	if err := r.RenderText(30, 15, "Ab", [4]float32{1, 1, 1, 1}); err != nil {
+		t.Fatalf("RenderText: %v", err)
+	}
+
+	lit := 0
+	img := r.Image()
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 60; x++ {
+			if img.RGBAAt(x, y).R > 0 {
+				if x < 15 || x > 45 {
+					t.Fatalf("text pixel at x=%d is not centred", x)
+				}
+				lit++
+			}
+		}
+	}
+	if lit == 0 {
+		t.Error("RenderText drew nothing")
+	}
+}
+
+func TestSoftwareRendererClipAndTransform(t *testing.T) {
+	r := newTestRenderer(t, 40, 40)
+	if err := r.BeginFrame(40, 40); err != nil {
+		t.Fatal(err)
+	}
+	r.Clear([4]float32{0, 0, 0, 0})
+
+	r.PushTransform(Translate(10, 10))
+	r.PushClip(0, 0, 10, 10)
+	r.FillRoundedRect(0, 0, 30, 30, 0, [4]float32{1, 1, 1, 1})
+	r.PopClip()
+	r.PopTransform()
+
+	if err := r.EndFrame(); err != nil {
+		t.Fatalf("EndFrame: %v", err)
+	}
+
+	img := r.Image()
+	if got := img.RGBAAt(15, 15).A; got != 255 {
+		t.Errorf("pixel inside translated clip alpha = %d, want 255", got)
+	}
+	if got := img.RGBAAt(5, 5).A; got != 0 {
+		t.Errorf("pixel before translation alpha = %d, want 0", got)
+	}
+	if got := img.RGBAAt(25, 25).A; got != 0 {
+		t.Errorf("pixel outside clip alpha = %d, want 0", got)
+	}
+}
+
+func TestSoftwareRendererUnbalancedStacks(t *testing.T) {
+	r := newTestRenderer(t, 10, 10)
+	if err := r.BeginFrame(10, 10); err != nil {
+		t.Fatal(err)
+	}
+	r.PushClip(0, 0, 5, 5)
+	if err := r.EndFrame(); err == nil {
+		t.Error("EndFrame should report an unbalanced clip stack")
+	}
+}
+
+func TestTransformThen(t *testing.T) {
+	tr := Scale(2, 2).Then(Translate(5, 1))
+	x, y := tr.Apply(3, 4)
+	if x != 11 || y != 9 {
+		t.Errorf("Apply = (%v, %v), want (11, 9)", x, y)
+	}
+}
+
+func TestSoftwareRendererRejectsShortTexture(t *testing.T) {
+	r := newTestRenderer(t, 10, 10)
+	texture := &Texture{Width: 4, Height: 4, Data: make([]byte, 4*4*4-1)}
+	if err := r.DrawTexture(texture, 0, 0, 10, 10); err == nil {
+		t.Error("DrawTexture accepted a texture with too little data")
+	}
+}
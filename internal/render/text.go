@@ -0,0 +1,257 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/go-text/typesetting/di"
+	"github.com/go-text/typesetting/font"
+	"github.com/go-text/typesetting/shaping"
+	"golang.org/x/image/math/fixed"
+)
+
+// EmojiSearchDirs lists the asset layers searched for emoji sprites, in
+// order. Sprites are PNG files named after the lowercase hexadecimal code
+// points of the cluster joined by "-", without U+FE0F (as in Twemoji), and
+// are used for clusters that no font in the fallback chain covers.
+var EmojiSearchDirs = []string{
+	"assets/emoji",
+	"emoji",
+	"../assets/emoji",
+}
+
+// maxLineWidth is the wrapping width used for single line labels
+const maxLineWidth = 1 << 24
+
+// TextLayout is a line of shaped text ready to draw. Glyph positions are
+// relative to the start of the baseline.
+type TextLayout struct {
+	// Advance is the width of the line
+	Advance fixed.Int26_6
+	// Ascent and Descent are the largest line extents of the faces used,
+	// both positive
+	Ascent, Descent fixed.Int26_6
+	// Missing counts the glyphs drawn as the missing-glyph box, for
+	// characters with neither a glyph in the chain nor an emoji sprite
+	Missing int
+
+	glyphs []placedGlyph
+}
+
+// placedGlyph is a glyph positioned within a layout. Outline glyphs are
+// drawn from the atlas; colour glyphs and emoji sprites are drawn from
+// image into rect, which is relative to the glyph position.
+type placedGlyph struct {
+	dot   fixed.Point26_6
+	atlas *GlyphAtlas
+	gid   font.GID
+	image *image.RGBA
+	rect  image.Rectangle
+}
+
+// sprite is an emoji image loaded from the emoji search directories
+type sprite struct {
+	img    image.Image
+	scaled map[image.Point]*image.RGBA
+}
+
+// Size returns the pixel size of the line box
+func (l *TextLayout) Size() (width, height int) {
+	return l.Advance.Ceil(), (l.Ascent + l.Descent).Ceil()
+}
+
+// Draw draws the layout with its baseline starting at dot. Outline glyphs
+// are filled with src; colour glyphs and sprites keep their own colours.
+// Everything is blended over dst.
+func (l *TextLayout) Draw(dst draw.Image, dot fixed.Point26_6, src image.Image) {
+	for _, g := range l.glyphs {
+		p := dot.Add(g.dot)
+		origin := image.Pt(p.X.Round(), p.Y.Round())
+		if g.image != nil {
+			draw.Draw(dst, g.rect.Add(origin), g.image, image.Point{}, draw.Over)
+		} else {
+			g.atlas.draw(dst, origin, g.gid, src)
+		}
+	}
+}
+
+// shape lays out text with chain at size. Callers must hold the mutex.
+func (fm *FontManager) shape(chain *fontChain, size float64, text []rune) *TextLayout {
+	layout := &TextLayout{}
+	if len(text) == 0 {
+		return layout
+	}
+
+	input := shaping.Input{
+		Text:      text,
+		RunStart:  0,
+		RunEnd:    len(text),
+		Direction: paragraphDirection(text),
+		Face:      chain.faces[0],
+		Size:      fixed.Int26_6(size * 64),
+	}
+
+	runs := fm.segmenter.Split(input, chain)
+	outputs := make([]shaping.Output, 0, len(runs))
+	for _, run := range runs {
+		outputs = append(outputs, fm.shaper.Shape(run))
+	}
+
+	lines, _ := fm.wrapper.WrapParagraph(shaping.WrapConfig{
+		Direction:                     input.Direction,
+		DisableTrailingWhitespaceTrim: true,
+	}, maxLineWidth, text, shaping.NewSliceIterator(outputs))
+
+	for _, line := range lines {
+		sort.SliceStable(line, func(i, j int) bool {
+			return line[i].VisualIndex < line[j].VisualIndex
+		})
+		for i := range line {
+			run := &line[i]
+			if run.LineBounds.Ascent > layout.Ascent {
+				layout.Ascent = run.LineBounds.Ascent
+			}
+			if -run.LineBounds.Descent > layout.Descent {
+				layout.Descent = -run.LineBounds.Descent
+			}
+		}
+	}
+
+	for _, line := range lines {
+		for i := range line {
+			fm.place(layout, &line[i], text, size)
+		}
+	}
+	return layout
+}
+
+// place appends the glyphs of a shaped run to layout. Clusters the face
+// cannot render fall back to emoji sprites. Callers must hold the mutex.
+func (fm *FontManager) place(layout *TextLayout, run *shaping.Output, text []rune, size float64) {
+	atlas := fm.atlas(run.Face, size)
+	lineHeight := layout.Ascent + layout.Descent
+
+	for i := 0; i < len(run.Glyphs); i++ {
+		g := run.Glyphs[i]
+		dot := fixed.Point26_6{X: layout.Advance + g.XOffset, Y: -g.YOffset}
+
+		if g.GlyphID == 0 {
+			cluster := text[g.TextIndex() : g.TextIndex()+g.RunesCount()]
+			if img := fm.sprite(cluster, lineHeight.Ceil()); img != nil {
+				bounds := img.Bounds().Add(image.Pt(0, -layout.Ascent.Round()))
+				layout.glyphs = append(layout.glyphs, placedGlyph{dot: fixed.Point26_6{X: layout.Advance}, image: img, rect: bounds})
+				layout.Advance += fixed.I(img.Bounds().Dx())
+				// Skip the remaining glyphs of the cluster
+				for i+1 < len(run.Glyphs) && run.Glyphs[i+1].TextIndex() == g.TextIndex() {
+					i++
+				}
+				continue
+			}
+		}
+
+		if g.GlyphID == 0 {
+			layout.Missing++
+		}
+
+		// Colour bitmap glyphs are scaled into the glyph's ink box
+		ink := image.Rect(g.XBearing.Round(), -g.YBearing.Round(),
+			(g.XBearing + g.Width).Round(), -(g.YBearing + g.Height).Round())
+		if img := atlas.colorGlyph(g.GlyphID, ink.Size()); img != nil {
+			layout.glyphs = append(layout.glyphs, placedGlyph{dot: dot, image: img, rect: ink})
+		} else {
+			atlas.mutex.Lock()
+			atlas.lookup(g.GlyphID)
+			atlas.sync()
+			atlas.mutex.Unlock()
+			layout.glyphs = append(layout.glyphs, placedGlyph{dot: dot, atlas: atlas, gid: g.GlyphID})
+		}
+		layout.Advance += g.Advance
+	}
+}
+
+// sprite returns the emoji sprite for cluster scaled to height pixels, or
+// nil when there is none. Callers must hold the mutex.
+func (fm *FontManager) sprite(cluster []rune, height int) *image.RGBA {
+	name := emojiName(cluster)
+	if name == "" || height <= 0 {
+		return nil
+	}
+
+	s, exists := fm.sprites[name]
+	if !exists {
+		s = &sprite{scaled: make(map[image.Point]*image.RGBA)}
+		for _, dir := range fm.emojiDirs {
+			if img, err := loadSprite(filepath.Join(dir, name+".png")); err == nil {
+				s.img = img
+				break
+			}
+		}
+		fm.sprites[name] = s
+	}
+	if s.img == nil {
+		return nil
+	}
+
+	bounds := s.img.Bounds()
+	size := image.Pt(max(1, height*bounds.Dx()/max(1, bounds.Dy())), height)
+	img, exists := s.scaled[size]
+	if !exists {
+		img = scaleImage(s.img, size)
+		s.scaled[size] = img
+		if fm.textures != nil {
+			fm.textures.AddTexture(fmt.Sprintf("emoji:%s@%d", name, height), &Texture{
+				Width:  int32(size.X),
+				Height: int32(size.Y),
+				Data:   img.Pix,
+			})
+		}
+	}
+	return img
+}
+
+// loadSprite decodes an emoji image
+func loadSprite(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode emoji %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// emojiName returns the sprite file name for a cluster, such as "1f44d-1f3fd"
+func emojiName(cluster []rune) string {
+	parts := make([]string, 0, len(cluster))
+	for _, r := range cluster {
+		if r == 0xFE0F || unicode.IsSpace(r) {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%x", r))
+	}
+	return strings.Join(parts, "-")
+}
+
+// paragraphDirection returns the direction of the first strong character,
+// defaulting to left to right
+func paragraphDirection(text []rune) di.Direction {
+	for _, r := range text {
+		switch {
+		case unicode.In(r, unicode.Arabic, unicode.Hebrew, unicode.Syriac, unicode.Thaana, unicode.Nko):
+			return di.DirectionRTL
+		case unicode.IsLetter(r):
+			return di.DirectionLTR
+		}
+	}
+	return di.DirectionLTR
+}
@@ -1,6 +1,7 @@
 package render
 
 import (
+	"fmt"
 	"image"
 	"image/draw"
 )
@@ -41,6 +42,11 @@ func (tm *TextureManager) LoadTexture(name string, img image.Image) (*Texture, e
 	return texture, nil
 }
 
+// AddTexture caches an already decoded texture under name
+func (tm *TextureManager) AddTexture(name string, texture *Texture) {
+	tm.textures[name] = texture
+}
+
 // GetTexture retrieves a cached texture by name
 func (tm *TextureManager) GetTexture(name string) (*Texture, bool) {
 	tex, exists := tm.textures[name]
@@ -49,9 +55,10 @@ func (tm *TextureManager) GetTexture(name string) (*Texture, bool) {
 
 // RenderTexture renders a texture at the specified coordinates
 func (tm *TextureManager) RenderTexture(renderer Renderer, texture *Texture, x, y, width, height int) error {
-	// Texture rendering logic would go here
-	// This is a placeholder that would be implemented based on the specific renderer
-	return nil
+	if !renderer.Capabilities().Has(CapTextures) {
+		return fmt.Errorf("renderer does not support textures")
+	}
+	return renderer.DrawTexture(texture, x, y, width, height)
 }
 
 // UnloadTexture removes a texture from memory
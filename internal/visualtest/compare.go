@@ -0,0 +1,118 @@
+// Package visualtest provides golden-image comparison for visual regression
+// tests. Images are compared per pixel using a perceptual colour distance so
+// that imperceptible anti-aliasing differences do not fail a test.
+package visualtest
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// maxYIQDelta is the largest possible squared YIQ distance between two colours
+const maxYIQDelta = 35215.0
+
+// Options controls how strictly two images are compared
+type Options struct {
+	// Threshold is the perceptual colour distance, in [0,1], above which a
+	// pixel is counted as different.
+	Threshold float64
+	// MaxDiffRatio is the fraction of differing pixels tolerated before the
+	// comparison fails.
+	MaxDiffRatio float64
+}
+
+// DefaultOptions returns the tolerances used by the repository's golden tests
+func DefaultOptions() Options {
+	return Options{
+		Threshold:    0.1,
+		MaxDiffRatio: 0.001,
+	}
+}
+
+// Result describes the outcome of comparing two images
+type Result struct {
+	DiffPixels   int
+	TotalPixels  int
+	SizeMismatch bool
+	// Diff highlights differing pixels in red over a faded copy of the
+	// expected image.
+	Diff *image.RGBA
+}
+
+// Ratio returns the fraction of pixels that differ
+func (r Result) Ratio() float64 {
+	if r.TotalPixels == 0 {
+		return 0
+	}
+	return float64(r.DiffPixels) / float64(r.TotalPixels)
+}
+
+// Passed reports whether the result is within the tolerance in opts
+func (r Result) Passed(opts Options) bool {
+	return !r.SizeMismatch && r.Ratio() <= opts.MaxDiffRatio
+}
+
+// Compare compares got against want pixel by pixel
+func Compare(got, want image.Image, opts Options) Result {
+	gb, wb := got.Bounds(), want.Bounds()
+	if gb.Dx() != wb.Dx() || gb.Dy() != wb.Dy() {
+		return Result{SizeMismatch: true, TotalPixels: wb.Dx() * wb.Dy()}
+	}
+
+	result := Result{
+		TotalPixels: wb.Dx() * wb.Dy(),
+		Diff:        image.NewRGBA(image.Rect(0, 0, wb.Dx(), wb.Dy())),
+	}
+	maxDelta := maxYIQDelta * opts.Threshold * opts.Threshold
+
+	for y := 0; y < wb.Dy(); y++ {
+		for x := 0; x < wb.Dx(); x++ {
+			g := color.RGBAModel.Convert(got.At(gb.Min.X+x, gb.Min.Y+y)).(color.RGBA)
+			w := color.RGBAModel.Convert(want.At(wb.Min.X+x, wb.Min.Y+y)).(color.RGBA)
+
+			if colorDelta(g, w) > maxDelta {
+				result.DiffPixels++
+				result.Diff.SetRGBA(x, y, color.RGBA{R: 255, A: 255})
+				continue
+			}
+
+			// Faded grayscale of the expected pixel for context
+			gray := uint8(255 - 0.1*(255-luma(w)))
+			result.Diff.SetRGBA(x, y, color.RGBA{R: gray, G: gray, B: gray, A: 255})
+		}
+	}
+
+	return result
+}
+
+// colorDelta returns the squared YIQ distance between two colours after
+// blending each over white, following the metric used by pixelmatch.
+func colorDelta(a, b color.RGBA) float64 {
+	if a == b {
+		return 0
+	}
+	ar, ag, ab := blendWhite(a)
+	br, bg, bb := blendWhite(b)
+
+	y := rgb2y(ar, ag, ab) - rgb2y(br, bg, bb)
+	i := rgb2i(ar, ag, ab) - rgb2i(br, bg, bb)
+	q := rgb2q(ar, ag, ab) - rgb2q(br, bg, bb)
+
+	return 0.5053*y*y + 0.299*i*i + 0.1957*q*q
+}
+
+// blendWhite composites a premultiplied colour over white
+func blendWhite(c color.RGBA) (float64, float64, float64) {
+	inv := 255 - float64(c.A)
+	return float64(c.R) + inv, float64(c.G) + inv, float64(c.B) + inv
+}
+
+func rgb2y(r, g, b float64) float64 { return r*0.29889531 + g*0.58662247 + b*0.11448223 }
+func rgb2i(r, g, b float64) float64 { return r*0.59597799 - g*0.27417610 - b*0.32180189 }
+func rgb2q(r, g, b float64) float64 { return r*0.21147017 - g*0.52261711 + b*0.31114694 }
+
+// luma returns the perceived brightness of a colour blended over white
+func luma(c color.RGBA) float64 {
+	return math.Min(255, rgb2y(blendWhite(c)))
+}
@@ -0,0 +1,50 @@
+package visualtest
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func solid(w, h int, c color.RGBA) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := 0; i < len(img.Pix); i += 4 {
+		img.Pix[i], img.Pix[i+1], img.Pix[i+2], img.Pix[i+3] = c.R, c.G, c.B, c.A
+	}
+	return img
+}
+
+func TestCompareIgnoresImperceptibleDifferences(t *testing.T) {
+	want := solid(10, 10, color.RGBA{100, 100, 100, 255})
+	got := solid(10, 10, color.RGBA{102, 101, 100, 255})
+
+	result := Compare(got, want, DefaultOptions())
+	if result.DiffPixels != 0 {
+		t.Errorf("DiffPixels = %d, want 0", result.DiffPixels)
+	}
+}
+
+func TestCompareCountsChangedPixels(t *testing.T) {
+	want := solid(10, 10, color.RGBA{0, 0, 0, 255})
+	got := solid(10, 10, color.RGBA{0, 0, 0, 255})
+	got.SetRGBA(3, 4, color.RGBA{255, 255, 255, 255})
+	got.SetRGBA(5, 6, color.RGBA{255, 0, 0, 255})
+
+	result := Compare(got, want, DefaultOptions())
+	if result.DiffPixels != 2 {
+		t.Fatalf("DiffPixels = %d, want 2", result.DiffPixels)
+	}
+	if result.Passed(DefaultOptions()) {
+		t.Error("2% difference should exceed the default tolerance")
+	}
+	if c := result.Diff.RGBAAt(3, 4); c != (color.RGBA{255, 0, 0, 255}) {
+		t.Errorf("diff image pixel = %v, want red", c)
+	}
+}
+
+func TestCompareSizeMismatch(t *testing.T) {
+	result := Compare(solid(10, 10, color.RGBA{}), solid(10, 12, color.RGBA{}), DefaultOptions())
+	if !result.SizeMismatch || result.Passed(DefaultOptions()) {
+		t.Error("images of different sizes must not pass")
+	}
+}
@@ -0,0 +1,111 @@
+package visualtest
+
+import (
+	"fmt"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Golden configures where golden images and failure artifacts are stored
+type Golden struct {
+	// Dir holds the reference images, one PNG per name.
+	Dir string
+	// ArtifactDir receives actual, expected and diff images for failures.
+	ArtifactDir string
+	// Update rewrites the reference images instead of comparing.
+	Update bool
+	// Options are the comparison tolerances.
+	Options Options
+}
+
+// Assert compares img with the golden image called name. When Update is set
+// the golden image is rewritten instead. On failure the actual, expected and
+// diff images are written to ArtifactDir.
+func (g Golden) Assert(t testing.TB, name string, img image.Image) {
+	t.Helper()
+
+	goldenPath := filepath.Join(g.Dir, name+".png")
+	if g.Update {
+		if err := SavePNG(goldenPath, img); err != nil {
+			t.Fatalf("failed to update golden image: %v", err)
+		}
+		t.Logf("updated golden image %s", goldenPath)
+		return
+	}
+
+	want, err := LoadPNG(goldenPath)
+	if err != nil {
+		t.Fatalf("failed to load golden image (run with -update-goldens to create it): %v", err)
+	}
+
+	result := Compare(img, want, g.Options)
+	if result.Passed(g.Options) {
+		return
+	}
+
+	if err := g.writeArtifacts(name, img, want, result); err != nil {
+		t.Errorf("failed to write diff artifacts: %v", err)
+	}
+
+	if result.SizeMismatch {
+		t.Fatalf("%s: size %v differs from golden %v; artifacts in %s",
+			name, img.Bounds().Size(), want.Bounds().Size(), g.ArtifactDir)
+	}
+	t.Fatalf("%s: %d of %d pixels differ (%.3f%%, tolerance %.3f%%); artifacts in %s",
+		name, result.DiffPixels, result.TotalPixels,
+		result.Ratio()*100, g.Options.MaxDiffRatio*100, g.ArtifactDir)
+}
+
+// writeArtifacts saves the images needed to investigate a failure
+func (g Golden) writeArtifacts(name string, got, want image.Image, result Result) error {
+	artifacts := map[string]image.Image{
+		name + "_actual.png":   got,
+		name + "_expected.png": want,
+	}
+	if result.Diff != nil {
+		artifacts[name+"_diff.png"] = result.Diff
+	}
+
+	for file, img := range artifacts {
+		if err := SavePNG(filepath.Join(g.ArtifactDir, file), img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadPNG decodes the PNG image at path
+func LoadPNG(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	img, err := png.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", path, err)
+	}
+	return img, nil
+}
+
+// SavePNG encodes img as PNG at path, creating parent directories
+func SavePNG(path string, img image.Image) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,129 @@
+package wayland
+
+import (
+	"image"
+)
+
+const (
+	// minBuffers is the number of buffers allocated up front; one is
+	// drawn into while the compositor reads the other
+	minBuffers = 2
+	// maxBuffers bounds the chain when the compositor holds on to both
+	// buffers, for example while it is still scanning one out
+	maxBuffers = 3
+	// maxStaleRegions is the number of stale regions kept per buffer
+	// before they are collapsed into their bounding box
+	maxStaleRegions = 8
+)
+
+// bufferSlot is one buffer of a swapchain. stale lists the regions whose
+// pixels are older than the latest frame and must be copied before the
+// buffer is attached again.
+type bufferSlot struct {
+	index  int
+	offset int
+	busy   bool
+	stale  []image.Rectangle
+}
+
+// swapchain tracks which shared-memory buffers the compositor is reading
+// and which parts of each are out of date. It holds no Wayland objects, so
+// the client keeps a wl_buffer for each slot alongside it.
+type swapchain struct {
+	width  int
+	height int
+	stride int
+	slots  []*bufferSlot
+}
+
+// newSwapchain creates an empty swapchain for buffers of the given size
+func newSwapchain(width, height int) *swapchain {
+	return &swapchain{width: width, height: height, stride: width * 4}
+}
+
+// bufferSize returns the size in bytes of one buffer
+func (s *swapchain) bufferSize() int {
+	return s.stride * s.height
+}
+
+// bounds returns the rectangle covered by a buffer
+func (s *swapchain) bounds() image.Rectangle {
+	return image.Rect(0, 0, s.width, s.height)
+}
+
+// acquire returns a buffer the compositor has released, adding one if all
+// are busy and the chain can grow. New buffers are entirely stale.
+func (s *swapchain) acquire() (*bufferSlot, bool) {
+	for _, slot := range s.slots {
+		if !slot.busy {
+			return slot, true
+		}
+	}
+	if len(s.slots) >= maxBuffers {
+		return nil, false
+	}
+
+	slot := &bufferSlot{
+		index:  len(s.slots),
+		offset: len(s.slots) * s.bufferSize(),
+		stale:  []image.Rectangle{s.bounds()},
+	}
+	s.slots = append(s.slots, slot)
+	return slot, true
+}
+
+// damage marks r as changed in every buffer
+func (s *swapchain) damage(r image.Rectangle) {
+	r = r.Intersect(s.bounds())
+	if r.Empty() {
+		return
+	}
+	for _, slot := range s.slots {
+		slot.addStale(r)
+	}
+}
+
+// addStale records r as out of date, collapsing the list when it grows
+func (b *bufferSlot) addStale(r image.Rectangle) {
+	for _, region := range b.stale {
+		if r.In(region) {
+			return
+		}
+	}
+	b.stale = append(b.stale, r)
+	if len(b.stale) > maxStaleRegions {
+		bounds := b.stale[0]
+		for _, region := range b.stale[1:] {
+			bounds = bounds.Union(region)
+		}
+		b.stale = append(b.stale[:0], bounds)
+	}
+}
+
+// copyPixels converts the stale regions of src into dst, which holds the
+// buffer in little-endian ARGB8888 with the given stride, and clears them.
+// Buffer coordinates are relative to the top-left corner of src.
+func (b *bufferSlot) copyPixels(dst []byte, stride int, src *image.RGBA) {
+	for _, region := range b.stale {
+		copyBGRA(dst, stride, src, region)
+	}
+	b.stale = b.stale[:0]
+}
+
+// copyBGRA converts region of src, relative to its top-left corner, into
+// dst, which holds little-endian ARGB8888 pixels with the given stride.
+// Both formats use premultiplied alpha, so only the channel order changes.
+func copyBGRA(dst []byte, stride int, src *image.RGBA, region image.Rectangle) {
+	origin := src.Rect.Min
+	region = region.Intersect(src.Rect.Sub(origin))
+	for y := region.Min.Y; y < region.Max.Y; y++ {
+		s := src.Pix[src.PixOffset(origin.X+region.Min.X, origin.Y+y):src.PixOffset(origin.X+region.Max.X, origin.Y+y)]
+		d := dst[y*stride+region.Min.X*4:]
+		for i := 0; i < len(s); i += 4 {
+			d[i+0] = s[i+2] // B
+			d[i+1] = s[i+1] // G
+			d[i+2] = s[i+0] // R
+			d[i+3] = s[i+3] // A
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package wayland
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestSwapchainReusesReleasedBuffers(t *testing.T) {
+	chain := newSwapchain(4, 2)
+
+	first, ok := chain.acquire()
+	if !ok || first.index != 0 || first.offset != 0 {
+		t.Fatalf("first buffer = %+v, %v", first, ok)
+	}
+	first.busy = true
+
+	second, ok := chain.acquire()
+	if !ok || second.index != 1 || second.offset != chain.bufferSize() {
+		t.Fatalf("second buffer = %+v, %v", second, ok)
+	}
+	second.busy = true
+
+	// Both are held by the compositor, so a third is added
+	third, ok := chain.acquire()
+	if !ok || third.index != 2 {
+		t.Fatalf("third buffer = %+v, %v", third, ok)
+	}
+	third.busy = true
+	if _, ok := chain.acquire(); ok {
+		t.Fatalf("chain grew beyond %d buffers", maxBuffers)
+	}
+
+	second.busy = false
+	if again, _ := chain.acquire(); again != second {
+		t.Errorf("acquire = %+v, want the released buffer", again)
+	}
+}
+
+func TestSwapchainCopiesOnlyStaleRegions(t *testing.T) {
+	chain := newSwapchain(4, 2)
+	slot, _ := chain.acquire()
+	pixels := make([]byte, chain.bufferSize())
+
+	src := image.NewRGBA(image.Rect(10, 10, 14, 12))
+	src.SetRGBA(10, 10, color.RGBA{R: 1, G: 2, B: 3, A: 4})
+
+	// A new buffer is copied in full, with red and blue swapped
+	slot.copyPixels(pixels, chain.stride, src)
+	if got := pixels[:4]; got[0] != 3 || got[1] != 2 || got[2] != 1 || got[3] != 4 {
+		t.Errorf("pixel = %v, want ARGB8888 [3 2 1 4]", got)
+	}
+	if len(slot.stale) != 0 {
+		t.Errorf("stale = %v after copy", slot.stale)
+	}
+
+	// Later copies only touch damaged pixels, in buffer coordinates
+	src.SetRGBA(10, 10, color.RGBA{A: 0xff})
+	src.SetRGBA(13, 11, color.RGBA{A: 0xff})
+	chain.damage(image.Rect(3, 1, 4, 2))
+	chain.damage(image.Rect(3, 1, 4, 2))
+	if len(slot.stale) != 1 {
+		t.Errorf("stale = %v, want one region", slot.stale)
+	}
+	slot.copyPixels(pixels, chain.stride, src)
+	if pixels[3] != 4 {
+		t.Error("undamaged pixel was copied")
+	}
+	if pixels[chain.stride+3*4+3] != 0xff {
+		t.Error("damaged pixel was not copied")
+	}
+}
@@ -0,0 +1,283 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#include <stdint.h>
+*/
+import "C"
+
+import (
+	"runtime/cgo"
+	"syscall"
+)
+
+// clientFor returns the client a C callback was registered for
+func clientFor(handle C.uintptr_t) *Client {
+	client, _ := cgo.Handle(handle).Value().(*Client)
+	return client
+}
+
+// oskFrameDone is called from the wl_callback listener when a frame
+// callback fires
+//
+//export oskFrameDone
+func oskFrameDone(handle C.uintptr_t, time C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.frameDone(uint32(time))
+	}
+}
+
+// oskBufferRelease is called when the compositor stops reading a buffer
+//
+//export oskBufferRelease
+func oskBufferRelease(handle C.uintptr_t) {
+	if buffer, ok := cgo.Handle(handle).Value().(*shmBuffer); ok {
+		buffer.release()
+	}
+}
+
+//export oskRegistryGlobal
+func oskRegistryGlobal(handle C.uintptr_t, name C.uint32_t, iface *C.char, version C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.global(uint32(name), C.GoString(iface), uint32(version))
+	}
+}
+
+//export oskRegistryGlobalRemove
+func oskRegistryGlobalRemove(handle C.uintptr_t, name C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.globalRemove(uint32(name))
+	}
+}
+
+//export oskSeatCapabilities
+func oskSeatCapabilities(handle C.uintptr_t, capabilities C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.capabilities(uint32(capabilities))
+	}
+}
+
+//export oskPointerMotion
+func oskPointerMotion(handle C.uintptr_t, time C.uint32_t, x, y C.int32_t) {
+	if client := clientFor(handle); client != nil {
+		client.pointerMotion(uint32(time), int32(x), int32(y))
+	}
+}
+
+//export oskPointerButton
+func oskPointerButton(handle C.uintptr_t, serial, time, button, state C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.pointerButton(uint32(serial), uint32(time), uint32(button), uint32(state))
+	}
+}
+
+//export oskPointerAxis
+func oskPointerAxis(handle C.uintptr_t, time, axis C.uint32_t, value C.int32_t) {
+	if client := clientFor(handle); client != nil {
+		client.pointerAxis(uint32(time), uint32(axis), int32(value))
+	}
+}
+
+//export oskPointerFrame
+func oskPointerFrame(handle C.uintptr_t) {
+	if client := clientFor(handle); client != nil {
+		client.pointerFrame()
+	}
+}
+
+// oskKeyboardKeymap takes ownership of fd, which is closed even when the
+// client is gone
+//
+//export oskKeyboardKeymap
+func oskKeyboardKeymap(handle C.uintptr_t, format C.uint32_t, fd C.int32_t, size C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.keyboardKeymap(uint32(format), int(fd), uint32(size))
+		return
+	}
+	syscall.Close(int(fd))
+}
+
+//export oskKeyboardKey
+func oskKeyboardKey(handle C.uintptr_t, serial, time, key, state C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.keyboardKey(uint32(serial), uint32(time), uint32(key), uint32(state))
+	}
+}
+
+//export oskTouchDown
+func oskTouchDown(handle C.uintptr_t, serial, time C.uint32_t, id, x, y C.int32_t) {
+	if client := clientFor(handle); client != nil {
+		client.touchPoint(TouchStateDown, uint32(serial), uint32(time), int32(id), int32(x), int32(y), true)
+	}
+}
+
+//export oskTouchUp
+func oskTouchUp(handle C.uintptr_t, serial, time C.uint32_t, id C.int32_t) {
+	if client := clientFor(handle); client != nil {
+		client.touchPoint(TouchStateUp, uint32(serial), uint32(time), int32(id), 0, 0, false)
+	}
+}
+
+//export oskTouchMotion
+func oskTouchMotion(handle C.uintptr_t, time C.uint32_t, id, x, y C.int32_t) {
+	if client := clientFor(handle); client != nil {
+		client.touchPoint(TouchStateMotion, 0, uint32(time), int32(id), int32(x), int32(y), true)
+	}
+}
+
+//export oskTouchCancel
+func oskTouchCancel(handle C.uintptr_t) {
+	if client := clientFor(handle); client != nil {
+		client.touchCancel()
+	}
+}
+
+//export oskShellConfigure
+func oskShellConfigure(handle C.uintptr_t, serial, width, height C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.shellConfigure(uint32(serial), uint32(width), uint32(height))
+	}
+}
+
+//export oskToplevelConfigure
+func oskToplevelConfigure(handle C.uintptr_t, width, height C.int32_t) {
+	if client := clientFor(handle); client != nil {
+		client.toplevelConfigure(int32(width), int32(height))
+	}
+}
+
+//export oskShellClosed
+func oskShellClosed(handle C.uintptr_t) {
+	if client := clientFor(handle); client != nil {
+		client.shellClosed()
+	}
+}
+
+// outputFor returns the output a C callback was registered for. Outputs
+// bound elsewhere carry no handle.
+func outputFor(handle C.uintptr_t) *output {
+	if handle == 0 {
+		return nil
+	}
+	o, _ := cgo.Handle(handle).Value().(*output)
+	return o
+}
+
+//export oskOutputGeometry
+func oskOutputGeometry(handle C.uintptr_t, x, y, physicalWidth, physicalHeight C.int32_t,
+	manufacturer, model *C.char, transform C.int32_t) {
+	if o := outputFor(handle); o != nil {
+		o.client.outputGeometry(&o.outputState, int32(x), int32(y), int32(physicalWidth), int32(physicalHeight),
+			C.GoString(manufacturer), C.GoString(model), int32(transform))
+	}
+}
+
+//export oskOutputMode
+func oskOutputMode(handle C.uintptr_t, flags C.uint32_t, width, height, refresh C.int32_t) {
+	if o := outputFor(handle); o != nil {
+		o.client.outputMode(&o.outputState, uint32(flags), int32(width), int32(height), int32(refresh))
+	}
+}
+
+//export oskOutputDone
+func oskOutputDone(handle C.uintptr_t) {
+	if o := outputFor(handle); o != nil {
+		o.client.outputDone(&o.outputState)
+	}
+}
+
+//export oskOutputScale
+func oskOutputScale(handle C.uintptr_t, factor C.int32_t) {
+	if o := outputFor(handle); o != nil {
+		o.client.outputScale(&o.outputState, int32(factor))
+	}
+}
+
+//export oskOutputName
+func oskOutputName(handle C.uintptr_t, name *C.char) {
+	if o := outputFor(handle); o != nil {
+		o.client.outputName(&o.outputState, C.GoString(name))
+	}
+}
+
+//export oskOutputDescription
+func oskOutputDescription(handle C.uintptr_t, description *C.char) {
+	if o := outputFor(handle); o != nil {
+		o.client.outputDescription(&o.outputState, C.GoString(description))
+	}
+}
+
+// oskSurfaceEnter is called when the surface enters or leaves the output
+// whose handle is given
+//
+//export oskSurfaceEnter
+func oskSurfaceEnter(handle, outputHandle C.uintptr_t, entered C.int) {
+	client, o := clientFor(handle), outputFor(outputHandle)
+	if client != nil && o != nil {
+		client.outputEntered(&o.outputState, entered != 0)
+	}
+}
+
+//export oskPreferredScale
+func oskPreferredScale(handle C.uintptr_t, scale C.uint32_t) {
+	if client := clientFor(handle); client != nil {
+		client.fractionalScaleChanged(uint32(scale))
+	}
+}
+
+// inputMethodFor returns the input method a C callback was registered for
+func inputMethodFor(handle C.uintptr_t) *InputMethod {
+	im, _ := cgo.Handle(handle).Value().(*InputMethod)
+	return im
+}
+
+//export oskInputMethodActivate
+func oskInputMethodActivate(handle C.uintptr_t) {
+	if im := inputMethodFor(handle); im != nil {
+		im.activate()
+	}
+}
+
+//export oskInputMethodDeactivate
+func oskInputMethodDeactivate(handle C.uintptr_t) {
+	if im := inputMethodFor(handle); im != nil {
+		im.deactivate()
+	}
+}
+
+//export oskInputMethodSurroundingText
+func oskInputMethodSurroundingText(handle C.uintptr_t, text *C.char, cursor, anchor C.uint32_t) {
+	if im := inputMethodFor(handle); im != nil {
+		im.surroundingText(C.GoString(text), uint32(cursor), uint32(anchor))
+	}
+}
+
+//export oskInputMethodTextChangeCause
+func oskInputMethodTextChangeCause(handle C.uintptr_t, cause C.uint32_t) {
+	if im := inputMethodFor(handle); im != nil {
+		im.textChangeCause(uint32(cause))
+	}
+}
+
+//export oskInputMethodContentType
+func oskInputMethodContentType(handle C.uintptr_t, hint, purpose C.uint32_t) {
+	if im := inputMethodFor(handle); im != nil {
+		im.contentType(uint32(hint), uint32(purpose))
+	}
+}
+
+//export oskInputMethodDone
+func oskInputMethodDone(handle C.uintptr_t) {
+	if im := inputMethodFor(handle); im != nil {
+		im.done()
+	}
+}
+
+//export oskInputMethodUnavailable
+func oskInputMethodUnavailable(handle C.uintptr_t) {
+	if im := inputMethodFor(handle); im != nil {
+		im.unavailable()
+	}
+}
@@ -1,28 +1,151 @@
-//go:build !test
-// +build !test
+//go:build !test && !purego
+// +build !test,!purego
 
 package wayland
 
 import (
 	"fmt"
+	"image"
+	"runtime/cgo"
+	"sync"
+	"time"
 )
 
 /*
 #cgo pkg-config: wayland-client
 #cgo CFLAGS: -DWLR_USE_UNSTABLE
 #include <wayland-client.h>
+#include <poll.h>
+#include <stdint.h>
 #include <stdlib.h>
+
+extern void oskFrameDone(uintptr_t handle, uint32_t time);
+
+static void osk_frame_done(void *data, struct wl_callback *callback, uint32_t time) {
+	wl_callback_destroy(callback);
+	oskFrameDone((uintptr_t)data, time);
+}
+
+static const struct wl_callback_listener osk_frame_listener = {
+	.done = osk_frame_done,
+};
+
+static int osk_request_frame(struct wl_surface *surface, uintptr_t handle) {
+	struct wl_callback *callback = wl_surface_frame(surface);
+	if (callback == NULL) {
+		return -1;
+	}
+	wl_callback_add_listener(callback, &osk_frame_listener, (void *)handle);
+	wl_surface_commit(surface);
+	return 0;
+}
+
+// osk_dispatch reads whatever the connection holds and dispatches it. Unlike
+// wl_display_dispatch it never blocks, so it is safe to call after the
+// events announced by the fd watcher were already read by a roundtrip.
+static int osk_dispatch(struct wl_display *display) {
+	while (wl_display_prepare_read(display) != 0) {
+		if (wl_display_dispatch_pending(display) == -1) {
+			return -1;
+		}
+	}
+	if (wl_display_read_events(display) == -1) {
+		return -1;
+	}
+	return wl_display_dispatch_pending(display);
+}
+
+// osk_wait_readable polls fd for input, returning 1 when readable, 0 on
+// timeout and -1 on error or hang-up
+static int osk_wait_readable(int fd, int timeout) {
+	struct pollfd pfd = { .fd = fd, .events = POLLIN };
+	int ret = poll(&pfd, 1, timeout);
+	if (ret > 0 && (pfd.revents & (POLLERR | POLLHUP | POLLNVAL))) {
+		return -1;
+	}
+	return ret;
+}
 */
 import "C"
 
+// pollInterval bounds how long the fd watcher blocks before checking
+// whether the client was closed
+const pollInterval = 100 * time.Millisecond
+
 // Client represents a Wayland client connection
 type Client struct {
 	display    *C.struct_wl_display
 	registry   *C.struct_wl_registry
 	compositor *C.struct_wl_compositor
 	surface    *C.struct_wl_surface
-	shell      *C.struct_wl_shell
-	shellSurf  *C.struct_wl_shell_surface
+	shm        *C.struct_wl_shm
+	outputs    map[uint32]*output
+
+	// pool holds the buffers attached to the surface; retired holds
+	// buffers of earlier sizes the compositor has not released yet
+	pool    *shmPool
+	retired map[*shmBuffer]struct{}
+
+	// seat is the first seat announced; its devices exist while the seat
+	// has the matching capability
+	seat        *C.struct_wl_seat
+	seatName    uint32
+	seatVersion uint32
+	pointer     *C.struct_wl_pointer
+	keyboard    *C.struct_wl_keyboard
+	touch       *C.struct_wl_touch
+
+	// globals maps registry names to interfaces; pointer and touch
+	// positions are kept for events that carry no coordinates
+	globals  map[uint32]string
+	pointerX int32
+	pointerY int32
+	touches  map[int32][2]int32
+
+	// The surface role is a layer-shell panel, or an xdg_toplevel when
+	// the compositor has no layer-shell; pending* hold the size from the
+	// last xdg_toplevel configure
+	layerShell    *C.struct_zwlr_layer_shell_v1
+	layerSurface  *C.struct_zwlr_layer_surface_v1
+	wmBase        *C.struct_xdg_wm_base
+	xdgSurface    *C.struct_xdg_surface
+	toplevel      *C.struct_xdg_toplevel
+	pendingWidth  uint32
+	pendingHeight uint32
+	configured    bool
+	closed        bool
+	// mapped is set once a buffer is attached, until SetVisible(false)
+	mapped bool
+
+	// Frames are drawn at drawScale times the surface size and shown with
+	// bufferScale, or through viewport when the compositor offers
+	// fractional scaling. reportedScale is the scale last reported to be
+	// preferred, fractionalScale the one wp_fractional_scale_v1 sent, and
+	// pinnedOutput the name of the output the surface was placed on.
+	viewporter             *C.struct_wp_viewporter
+	viewport               *C.struct_wp_viewport
+	fractionalScaleManager *C.struct_wp_fractional_scale_manager_v1
+	fractionalScaleObject  *C.struct_wp_fractional_scale_v1
+	fractionalScale        float64
+	drawScale              float64
+	bufferScale            int32
+	reportedScale          float64
+	pinnedOutput           string
+
+	vkManager *C.struct_zwp_virtual_keyboard_manager_v1
+	imManager *C.struct_zwp_input_method_manager_v2
+
+	dispatcher *EventDispatcher
+
+	// handle lets C callbacks find the client
+	handle cgo.Handle
+	// events is signalled by the fd watcher; resume re-arms it after
+	// Dispatch has read the data
+	events  chan struct{}
+	resume  chan struct{}
+	frames  chan uint32
+	done    chan struct{}
+	watcher sync.WaitGroup
 }
 
 // NewClient creates a new Wayland client connection
@@ -33,8 +156,20 @@ func NewClient() (*Client, error) {
 	}
 
 	client := &Client{
-		display: display,
+		display:       display,
+		outputs:       make(map[uint32]*output),
+		globals:       make(map[uint32]string),
+		touches:       make(map[int32][2]int32),
+		retired:       make(map[*shmBuffer]struct{}),
+		events:        make(chan struct{}),
+		resume:        make(chan struct{}, 1),
+		frames:        make(chan uint32, 1),
+		done:          make(chan struct{}),
+		drawScale:     1,
+		bufferScale:   1,
+		reportedScale: 1,
 	}
+	client.handle = cgo.NewHandle(client)
 
 	// Get the registry
 	client.registry = C.wl_display_get_registry(display)
@@ -43,19 +178,87 @@ func NewClient() (*Client, error) {
 		return nil, fmt.Errorf("failed to get Wayland registry")
 	}
 
+	// Bind the globals announced in the initial burst
+	client.addRegistryListener()
+	if C.wl_display_roundtrip(display) == -1 {
+		client.Close()
+		return nil, fmt.Errorf("failed to receive Wayland globals")
+	}
+	if client.compositor == nil {
+		client.Close()
+		return nil, fmt.Errorf("compositor does not provide wl_compositor")
+	}
+	// Outputs send their state once bound; wait for it, so that the
+	// keyboard can be pinned to an output by name
+	if C.wl_display_roundtrip(display) == -1 {
+		client.Close()
+		return nil, fmt.Errorf("failed to receive Wayland outputs")
+	}
+
+	client.watcher.Add(1)
+	go client.watch(int(C.wl_display_get_fd(display)))
+
 	return client, nil
 }
 
+// watch signals events whenever the display fd becomes readable, waiting
+// for Dispatch to consume the data before polling again
+func (c *Client) watch(fd int) {
+	defer c.watcher.Done()
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		// Errors and hang-ups are signalled too so Dispatch reports them
+		if C.osk_wait_readable(C.int(fd), C.int(pollInterval/time.Millisecond)) == 0 {
+			continue
+		}
+
+		select {
+		case c.events <- struct{}{}:
+		case <-c.done:
+			return
+		}
+		select {
+		case <-c.resume:
+		case <-c.done:
+			return
+		}
+	}
+}
+
 // Close cleans up the Wayland client connection
 func (c *Client) Close() {
-	if c.shellSurf != nil {
-		C.wl_shell_surface_destroy(c.shellSurf)
+	if c.done != nil {
+		select {
+		case <-c.done:
+			return
+		default:
+			close(c.done)
+		}
+		c.watcher.Wait()
+		c.handle.Delete()
 	}
+	c.destroyShell()
+	c.destroyScale()
+	c.destroyVirtualKeyboardManager()
+	c.destroyInputMethodManager()
 	if c.surface != nil {
 		C.wl_surface_destroy(c.surface)
 	}
-	if c.shell != nil {
-		C.wl_shell_destroy(c.shell)
+	c.destroyBuffers()
+	if c.seat != nil {
+		c.releaseSeat()
+	}
+	for name, output := range c.outputs {
+		c.releaseOutput(output)
+		delete(c.outputs, name)
+	}
+	if c.shm != nil {
+		C.wl_shm_destroy(c.shm)
 	}
 	if c.compositor != nil {
 		C.wl_compositor_destroy(c.compositor)
@@ -64,33 +267,70 @@ func (c *Client) Close() {
 		C.wl_registry_destroy(c.registry)
 	}
 	if c.display != nil {
+		// Send the destroy requests, and key releases from a closed virtual
+		// keyboard, before the connection goes away
+		C.wl_display_flush(c.display)
 		C.wl_display_disconnect(c.display)
 	}
 }
 
-// CreateSurface creates a new Wayland surface
-func (c *Client) CreateSurface() error {
-	if c.compositor == nil {
-		return fmt.Errorf("compositor not available")
-	}
-
-	c.surface = C.wl_compositor_create_surface(c.compositor)
+// Damage marks a region of the surface as changed. It is ignored until a
+// surface has been created.
+func (c *Client) Damage(x, y, width, height int) error {
 	if c.surface == nil {
-		return fmt.Errorf("failed to create surface")
+		return nil
 	}
 
+	if c.pool != nil {
+		c.pool.chain.damage(bufferRect(image.Rect(x, y, x+width, y+height), c.drawScale))
+	}
+	C.wl_surface_damage(c.surface, C.int32_t(x), C.int32_t(y), C.int32_t(width), C.int32_t(height))
 	return nil
 }
 
-// Dispatch processes pending Wayland events
+// Dispatch reads and processes the events available on the connection
+// without blocking, and re-arms the fd watcher
 func (c *Client) Dispatch() error {
-	ret := C.wl_display_dispatch(c.display)
+	ret := C.osk_dispatch(c.display)
+	select {
+	case c.resume <- struct{}{}:
+	default:
+	}
 	if ret == -1 {
 		return fmt.Errorf("failed to dispatch Wayland events")
 	}
 	return nil
 }
 
+// DispatchPending processes events already read from the connection
+func (c *Client) DispatchPending() error {
+	ret := C.wl_display_dispatch_pending(c.display)
+	if ret == -1 {
+		return fmt.Errorf("failed to dispatch pending Wayland events")
+	}
+	return nil
+}
+
+// Events returns a channel signalled when the display fd is readable
+func (c *Client) Events() <-chan struct{} {
+	return c.events
+}
+
+// RequestFrame requests a frame callback and commits the surface. Without
+// a mapped surface there is nothing to throttle against, and compositors
+// send no frame callbacks for one, so a frame is delivered straight away;
+// drawing it attaches the buffer that maps the surface.
+func (c *Client) RequestFrame() error {
+	if c.surface == nil || !c.mapped {
+		c.frameDone(0)
+		return nil
+	}
+	if C.osk_request_frame(c.surface, C.uintptr_t(c.handle)) != 0 {
+		return fmt.Errorf("failed to request frame callback")
+	}
+	return nil
+}
+
 // Flush sends buffered requests to the Wayland server
 func (c *Client) Flush() error {
 	ret := C.wl_display_flush(c.display)
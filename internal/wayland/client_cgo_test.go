@@ -0,0 +1,23 @@
+//go:build cgo && !test && !purego
+// +build cgo,!test,!purego
+
+package wayland
+
+import (
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/waylandtest"
+)
+
+// connectTestClient connects a client to a compositor the test set up.
+// libwayland finds the connection in WAYLAND_SOCKET.
+func connectTestClient(t *testing.T, compositor *waylandtest.Compositor) *Client {
+	t.Helper()
+	compositor.Setenv()
+	client, err := NewClient()
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
@@ -0,0 +1,310 @@
+package wayland
+
+import (
+	"image"
+	"image/color"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/waylandtest"
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// The tests here run against whichever client the build selects: the
+// libwayland one, or the pure-Go one with the purego or test tag or
+// without cgo. Each provides connectTestClient.
+
+// newTestClient connects a client to a test compositor
+func newTestClient(t *testing.T) (*Client, *waylandtest.Compositor) {
+	t.Helper()
+	compositor := waylandtest.NewCompositor(t)
+	return connectTestClient(t, compositor), compositor
+}
+
+// pump dispatches the client's events until cond holds
+func pump(t *testing.T, client *Client, cond func() bool) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for !cond() {
+		if err := client.Flush(); err != nil {
+			t.Fatalf("Flush: %v", err)
+		}
+		select {
+		case <-client.Events():
+			if err := client.Dispatch(); err != nil {
+				t.Fatalf("Dispatch: %v", err)
+			}
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("timed out waiting for the compositor")
+		}
+	}
+}
+
+// receive moves the input and shell events sent to dispatcher into events
+// and reports whether there are at least n. Registry events and pointer
+// frames are left out.
+func receive(dispatcher *EventDispatcher, events *[]Event, n int) bool {
+	for {
+		event, ok := dispatcher.Next()
+		if !ok {
+			return len(*events) >= n
+		}
+		switch event.(type) {
+		case *RegistryEvent, *PointerFrameEvent:
+		default:
+			*events = append(*events, event)
+		}
+	}
+}
+
+func TestClientBindsGlobals(t *testing.T) {
+	client, _ := newTestClient(t)
+	if client.compositor == nil || client.shm == nil || client.seat == nil || client.layerShell == nil ||
+		client.vkManager == nil || client.imManager == nil {
+		t.Fatal("not every global was bound")
+	}
+	if len(client.outputs) != 1 {
+		t.Errorf("bound %d outputs, want 1", len(client.outputs))
+	}
+}
+
+func TestClientPresentsAndTranslatesInput(t *testing.T) {
+	client, compositor := newTestClient(t)
+	dispatcher := NewEventDispatcher()
+	client.SetEventDispatcher(dispatcher)
+
+	if err := client.CreateSurface(DefaultSurfaceConfig(640, 200)); err != nil {
+		t.Fatalf("CreateSurface: %v", err)
+	}
+	var events []Event
+	pump(t, client, func() bool { return receive(dispatcher, &events, 1) })
+	if shell := events[0].(*ShellEvent); shell.Width != waylandtest.OutputWidth || shell.Height != 200 {
+		t.Errorf("configured size = %dx%d, want %dx200", shell.Width, shell.Height, waylandtest.OutputWidth)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	pixel := color.RGBA{R: 0x11, G: 0x22, B: 0x33, A: 0xff}
+	img.SetRGBA(1, 0, pixel)
+	if err := client.Attach(img); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	client.Flush()
+	compositor.Wait(func() bool { return compositor.LastFrame() != nil })
+	if got := compositor.LastFrame().Image.RGBAAt(1, 0); got != pixel {
+		t.Errorf("committed pixel = %v, want %v", got, pixel)
+	}
+	if state, _ := compositor.LayerSurface(); !state.Acked || !state.Mapped {
+		t.Errorf("layer surface = %+v, want acked and mapped", state)
+	}
+
+	pump(t, client, func() bool { return client.pointer != nil && client.touch != nil })
+	compositor.Click(10.5, 20)
+	compositor.Scroll(wire.WlPointerAxisVerticalScroll, 2.5)
+	compositor.Tap(30, 40)
+
+	events = nil
+	pump(t, client, func() bool { return receive(dispatcher, &events, 6) })
+	if p, ok := events[0].(*PointerMotionEvent); !ok || p.X != 10 || p.Y != 20 {
+		t.Errorf("enter = %+v", events[0])
+	}
+	if p, ok := events[1].(*PointerButtonEvent); !ok || p.Button != 1 || p.State != 1 || p.X != 10 {
+		t.Errorf("press = %+v", events[1])
+	}
+	if p, ok := events[2].(*PointerButtonEvent); !ok || p.Button != 1 || p.State != 0 {
+		t.Errorf("release = %+v", events[2])
+	}
+	if a, ok := events[3].(*PointerAxisEvent); !ok || a.Axis != AxisVertical || a.Value != 2.5 {
+		t.Errorf("scroll = %+v", events[3])
+	}
+	if e, ok := events[4].(*TouchEvent); !ok || e.State != TouchStateDown || e.X != 30 || e.Y != 40 {
+		t.Errorf("touch down = %+v", events[4])
+	}
+	if e, ok := events[5].(*TouchEvent); !ok || e.State != TouchStateUp || e.X != 30 {
+		t.Errorf("touch up = %+v", events[5])
+	}
+}
+
+func TestClientDeliversFramesBeforeTheSurfaceIsMapped(t *testing.T) {
+	client, compositor := newTestClient(t)
+	if err := client.CreateSurface(DefaultSurfaceConfig(640, 200)); err != nil {
+		t.Fatalf("CreateSurface: %v", err)
+	}
+
+	// Compositors send no frame callbacks for an unmapped surface
+	if err := client.RequestFrame(); err != nil {
+		t.Fatalf("RequestFrame: %v", err)
+	}
+	select {
+	case <-client.Frames():
+	default:
+		t.Fatal("no frame delivered for the unmapped surface")
+	}
+
+	if err := client.Attach(image.NewRGBA(image.Rect(0, 0, 1280, 200))); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	if err := client.RequestFrame(); err != nil {
+		t.Fatalf("RequestFrame: %v", err)
+	}
+	select {
+	case <-client.Frames():
+		t.Fatal("frame delivered for the mapped surface before the compositor sent one")
+	default:
+	}
+	pump(t, client, func() bool { return len(client.Frames()) > 0 })
+	if frames := compositor.Frames(); len(frames) == 0 {
+		t.Error("no buffer committed")
+	}
+}
+
+func TestClientPinsToAnOutputAndFollowsItsScale(t *testing.T) {
+	compositor := waylandtest.NewCompositor(t)
+	compositor.AddOutput(waylandtest.Output{Name: "DSI-1", Width: 800, Height: 1280, Scale: 2,
+		Transform: wire.WlOutputTransform90})
+	client := connectTestClient(t, compositor)
+	dispatcher := NewEventDispatcher()
+	client.SetEventDispatcher(dispatcher)
+
+	portrait, err := client.outputNamed("DSI-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info := portrait.info; info.Width != 800 || info.Scale != 2 || info.Transform != wire.WlOutputTransform90 ||
+		info.Description != "waylandtest output DSI-1" {
+		t.Errorf("output = %+v", info)
+	}
+
+	config := DefaultSurfaceConfig(640, 300)
+	config.Output = "HDMI-A-9"
+	if err := client.CreateSurface(config); err == nil || !strings.Contains(err.Error(), "DSI-1, WL-1") {
+		t.Errorf("CreateSurface on a missing output: %v, want the outputs listed", err)
+	}
+	config.Output = "DSI-1"
+	if err := client.CreateSurface(config); err != nil {
+		t.Fatalf("CreateSurface: %v", err)
+	}
+	var events []Event
+	pump(t, client, func() bool { return receive(dispatcher, &events, 2) })
+	if shell := events[0].(*ShellEvent); shell.Width != 800 {
+		t.Errorf("configured width = %d, want that of DSI-1", shell.Width)
+	}
+	if scale := events[1].(*ScaleEvent); scale.Scale != 2 {
+		t.Errorf("scale = %v, want 2", scale.Scale)
+	}
+	if state, _ := compositor.LayerSurface(); state.Output != "DSI-1" {
+		t.Errorf("layer surface placed on %q", state.Output)
+	}
+
+	if err := client.SetScale(1.5); err == nil {
+		t.Error("SetScale accepted a fractional scale without a viewport")
+	}
+	if err := client.SetScale(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Attach(image.NewRGBA(image.Rect(0, 0, 1600, 600))); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	client.Flush()
+	compositor.Wait(func() bool { return compositor.LastFrame() != nil })
+	if frame := compositor.LastFrame(); frame.BufferScale != 2 {
+		t.Errorf("buffer scale = %d, want 2", frame.BufferScale)
+	}
+	pump(t, client, func() bool { return portrait.entered })
+
+	// Unplugged, the keyboard falls back to scale 1
+	compositor.RemoveOutput("DSI-1")
+	events = nil
+	pump(t, client, func() bool { return receive(dispatcher, &events, 2) })
+	if output := events[0].(*OutputEvent); !output.Removed || output.ID != portrait.info.ID {
+		t.Errorf("output event = %+v, want DSI-1 removed", output)
+	}
+	if scale := events[1].(*ScaleEvent); scale.Scale != 1 {
+		t.Errorf("scale = %v, want 1", scale.Scale)
+	}
+}
+
+func TestClientDrawsAtTheFractionalScale(t *testing.T) {
+	compositor := waylandtest.NewCompositor(t)
+	compositor.EnableFractionalScale(1.5)
+	client := connectTestClient(t, compositor)
+	dispatcher := NewEventDispatcher()
+	client.SetEventDispatcher(dispatcher)
+
+	if err := client.CreateSurface(DefaultSurfaceConfig(640, 200)); err != nil {
+		t.Fatalf("CreateSurface: %v", err)
+	}
+	var events []Event
+	pump(t, client, func() bool { return receive(dispatcher, &events, 2) })
+	if scale, ok := events[0].(*ScaleEvent); !ok || scale.Scale != 1.5 {
+		t.Errorf("first event = %+v, want the preferred scale", events[0])
+	}
+
+	if err := client.SetScale(1.5); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Attach(image.NewRGBA(image.Rect(0, 0, 1920, 300))); err != nil {
+		t.Fatalf("Attach: %v", err)
+	}
+	client.Flush()
+	compositor.Wait(func() bool { return compositor.LastFrame() != nil })
+	if frame := compositor.LastFrame(); frame.Destination != image.Pt(1280, 200) || frame.BufferScale != 1 {
+		t.Errorf("frame shown at %v with buffer scale %d, want the surface size", frame.Destination,
+			frame.BufferScale)
+	}
+
+	compositor.SetPreferredScale(1.25)
+	events = nil
+	pump(t, client, func() bool { return receive(dispatcher, &events, 1) })
+	if scale := events[0].(*ScaleEvent); scale.Scale != 1.25 {
+		t.Errorf("scale = %v, want 1.25", scale.Scale)
+	}
+}
+
+func TestKeymapIsReadAndItsFdClosed(t *testing.T) {
+	file, err := os.CreateTemp(t.TempDir(), "keymap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	const text = "xkb_keymap { };"
+	file.WriteString(text + "\x00")
+	fd, err := syscall.Dup(int(file.Fd()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dispatcher := NewEventDispatcher()
+	client := &Client{dispatcher: dispatcher}
+	client.keyboardKeymap(keymapFormatXKBv1, fd, uint32(len(text)+1))
+	if event, _ := dispatcher.Next(); *event.(*KeymapEvent) != (KeymapEvent{Format: keymapFormatXKBv1, Keymap: text}) {
+		t.Errorf("keymap = %+v", event)
+	}
+	if _, err := syscall.Pread(fd, make([]byte, 1), 0); err != syscall.EBADF {
+		t.Errorf("reading the keymap fd afterwards gave %v, want it closed", err)
+	}
+}
+
+func TestClientTypesThroughVirtualKeyboard(t *testing.T) {
+	client, compositor := newTestClient(t)
+	vk, err := client.VirtualKeyboard()
+	if err != nil {
+		t.Fatalf("VirtualKeyboard: %v", err)
+	}
+	defer vk.Close()
+
+	vk.Press(34)
+	vk.Release(34)
+	client.Flush()
+
+	compositor.Wait(func() bool { return len(compositor.Keys()) == 2 })
+	if compositor.Keymap() == "" {
+		t.Error("no keymap uploaded before the keys")
+	}
+	if keys := compositor.Keys(); keys[0] != (waylandtest.Key{Code: 34, Pressed: true}) || keys[1].Pressed {
+		t.Errorf("keys = %+v", keys)
+	}
+}
@@ -0,0 +1,158 @@
+package wayland
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of the application timers and of the mock
+// client's frame callbacks and scripted input. Tests use a VirtualClock so
+// that time passes only when they say so.
+type Clock interface {
+	Now() time.Time
+	// NewTimer returns a channel that receives the time once d has passed,
+	// and a function that stops the timer, reporting whether it was still
+	// pending
+	NewTimer(d time.Duration) (<-chan time.Time, func() bool)
+	// AfterFunc calls fn once d has passed. The returned function cancels
+	// the call, reporting whether it was still pending.
+	AfterFunc(d time.Duration, fn func()) func() bool
+}
+
+// SystemClock is the wall clock
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	t := time.NewTimer(d)
+	return t.C, t.Stop
+}
+
+func (systemClock) AfterFunc(d time.Duration, fn func()) func() bool {
+	return time.AfterFunc(d, fn).Stop
+}
+
+// VirtualClock is a clock that only moves when Advance is called. Waiters
+// are woken by the goroutine calling Advance, in deadline order, each
+// seeing the clock at its own deadline. Waiting for a duration of 0 or less
+// returns at once.
+type VirtualClock struct {
+	mutex   sync.Mutex
+	now     time.Time
+	seq     uint64
+	waiters []*clockWaiter
+}
+
+// clockWaiter is a function waiting for a deadline. seq keeps waiters with
+// the same deadline in the order they were added.
+type clockWaiter struct {
+	when time.Time
+	seq  uint64
+	fn   func()
+}
+
+// NewVirtualClock creates a virtual clock stopped at the current time
+func NewVirtualClock() *VirtualClock {
+	return &VirtualClock{now: time.Now()}
+}
+
+// Now returns the virtual time
+func (c *VirtualClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+// NewTimer returns a channel that receives the virtual time once the clock
+// was advanced by d, and a function that stops the timer
+func (c *VirtualClock) NewTimer(d time.Duration) (<-chan time.Time, func() bool) {
+	ch := make(chan time.Time, 1)
+	stop := c.AfterFunc(d, func() { ch <- c.Now() })
+	return ch, stop
+}
+
+// AfterFunc calls fn once the clock was advanced by d. With d of 0 or less
+// fn is called before AfterFunc returns. The returned function removes
+// the waiter, so abandoned ones do not pile up.
+func (c *VirtualClock) AfterFunc(d time.Duration, fn func()) func() bool {
+	if d <= 0 {
+		fn()
+		return func() bool { return false }
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.seq++
+	w := &clockWaiter{when: c.now.Add(d), seq: c.seq, fn: fn}
+	c.waiters = append(c.waiters, w)
+	return func() bool { return c.remove(w) }
+}
+
+// remove removes a waiter, reporting whether it was still waiting
+func (c *VirtualClock) remove(w *clockWaiter) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for i, waiter := range c.waiters {
+		if waiter == w {
+			c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Waiting returns how many waiters the clock holds
+func (c *VirtualClock) Waiting() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.waiters)
+}
+
+// Next returns the time until the earliest waiter is due, if there is one
+func (c *VirtualClock) Next() (time.Duration, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if w := c.earliest(); w >= 0 {
+		return c.waiters[w].when.Sub(c.now), true
+	}
+	return 0, false
+}
+
+// Advance moves the clock forward by d, waking the waiters due on the way.
+// Waiters added by those woken are woken too if they fall due before the
+// clock stops.
+func (c *VirtualClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	end := c.now.Add(d)
+	for {
+		i := c.earliest()
+		if i < 0 || c.waiters[i].when.After(end) {
+			break
+		}
+		w := c.waiters[i]
+		c.waiters = append(c.waiters[:i], c.waiters[i+1:]...)
+		if w.when.After(c.now) {
+			c.now = w.when
+		}
+		c.mutex.Unlock()
+		w.fn()
+		c.mutex.Lock()
+	}
+	c.now = end
+	c.mutex.Unlock()
+}
+
+// earliest returns the index of the waiter due first, or -1. Callers must
+// hold the mutex.
+func (c *VirtualClock) earliest() int {
+	first := -1
+	for i, w := range c.waiters {
+		if first < 0 || w.when.Before(c.waiters[first].when) ||
+			w.when.Equal(c.waiters[first].when) && w.seq < c.waiters[first].seq {
+			first = i
+		}
+	}
+	return first
+}
@@ -0,0 +1,295 @@
+package wayland
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"sync"
+)
+
+// DefaultQueueSize is the number of events a dispatcher queues before its
+// overflow policy applies
+const DefaultQueueSize = 100
+
+// OverflowPolicy decides what SendEvent does when the queue is full
+type OverflowPolicy int
+
+const (
+	// OverflowCoalesce merges pointer and touch motion into the motion of
+	// the same pointer or touch point still queued, and queues every other
+	// event past the queue size. Presses and releases are never lost, so
+	// keys cannot get stuck.
+	OverflowCoalesce OverflowPolicy = iota
+	// OverflowBlock makes SendEvent wait until the queue has room. It must
+	// not be used when events are sent from the goroutine that dispatches
+	// them, as the Wayland clients do.
+	OverflowBlock
+	// OverflowDropOldest drops the event queued longest to make room
+	OverflowDropOldest
+)
+
+// ErrEventHandled may be returned by a handler to stop the event from
+// reaching the handlers of lower priority. DispatchEvent does not report
+// it.
+var ErrEventHandled = errors.New("event handled")
+
+// eventPointer is satisfied by the pointers to the event structs, the only
+// types events are sent as
+type eventPointer[T any] interface {
+	*T
+	Event
+}
+
+// DispatcherStats counts what happened to the events sent to a dispatcher
+type DispatcherStats struct {
+	// Queued is the number of events added to the queue; an event merged
+	// into one already queued is counted as coalesced instead
+	Queued    uint64
+	Dropped   uint64
+	Coalesced uint64
+	// Pending is the number of events waiting to be dispatched
+	Pending int
+}
+
+// registration is a handler with its priority
+type registration struct {
+	handle   func(Event) error
+	priority int
+}
+
+// EventDispatcher queues events from the Wayland clients and passes them
+// to the handlers registered for their type. It may be used from any
+// goroutine.
+type EventDispatcher struct {
+	mutex    sync.Mutex
+	handlers map[EventType][]*registration
+
+	queue  []Event
+	size   int
+	policy OverflowPolicy
+	ready  chan struct{}
+	room   *sync.Cond
+	stats  DispatcherStats
+}
+
+// NewEventDispatcher creates an event dispatcher queueing DefaultQueueSize
+// events and coalescing motion beyond that
+func NewEventDispatcher() *EventDispatcher {
+	return NewEventDispatcherWithPolicy(DefaultQueueSize, OverflowCoalesce)
+}
+
+// NewEventDispatcherWithPolicy creates an event dispatcher queueing size
+// events before policy applies
+func NewEventDispatcherWithPolicy(size int, policy OverflowPolicy) *EventDispatcher {
+	if size < 1 {
+		size = 1
+	}
+	ed := &EventDispatcher{
+		handlers: make(map[EventType][]*registration),
+		size:     size,
+		policy:   policy,
+		ready:    make(chan struct{}, 1),
+	}
+	ed.room = sync.NewCond(&ed.mutex)
+	return ed
+}
+
+// Subscribe adds a handler for the events of type E with priority 0. The
+// returned function removes it.
+func Subscribe[T any, E eventPointer[T]](ed *EventDispatcher, handler func(E) error) func() {
+	return SubscribePriority(ed, 0, handler)
+}
+
+// SubscribePriority adds a handler for the events of type E. Handlers of
+// higher priority run first, and those of equal priority in the order they
+// were added. The returned function removes the handler.
+func SubscribePriority[T any, E eventPointer[T]](ed *EventDispatcher, priority int, handler func(E) error) func() {
+	var zero T
+	return ed.register(E(&zero).Type(), priority, func(event Event) error {
+		return handler(event.(E))
+	})
+}
+
+// register adds a handler for events of eventType
+func (ed *EventDispatcher) register(eventType EventType, priority int, handle func(Event) error) func() {
+	ed.mutex.Lock()
+	defer ed.mutex.Unlock()
+
+	r := &registration{handle: handle, priority: priority}
+	handlers := append(slices.Clip(ed.handlers[eventType]), r)
+	slices.SortStableFunc(handlers, func(a, b *registration) int {
+		return cmp.Compare(b.priority, a.priority)
+	})
+	ed.handlers[eventType] = handlers
+
+	return func() {
+		ed.mutex.Lock()
+		defer ed.mutex.Unlock()
+		// The slice is replaced rather than changed, so that a dispatch
+		// in progress keeps the handlers it started with
+		ed.handlers[eventType] = slices.DeleteFunc(slices.Clone(ed.handlers[eventType]), func(h *registration) bool {
+			return h == r
+		})
+	}
+}
+
+// DispatchEvent passes an event to the handlers of its type, without the
+// dispatcher lock held, so that handlers may register and send events. It
+// stops early when a handler returns ErrEventHandled; other errors are
+// collected and the event still reaches the remaining handlers.
+func (ed *EventDispatcher) DispatchEvent(event Event) error {
+	ed.mutex.Lock()
+	handlers := ed.handlers[event.Type()]
+	ed.mutex.Unlock()
+
+	var errs []error
+	for _, r := range handlers {
+		err := r.handle(event)
+		if errors.Is(err, ErrEventHandled) {
+			break
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Events returns a channel that receives a value when events are queued.
+// The receiver must then take them with Next until it reports none are
+// left.
+func (ed *EventDispatcher) Events() <-chan struct{} {
+	return ed.ready
+}
+
+// Next removes the event queued longest from the queue
+func (ed *EventDispatcher) Next() (Event, bool) {
+	ed.mutex.Lock()
+	defer ed.mutex.Unlock()
+
+	if len(ed.queue) == 0 {
+		return nil, false
+	}
+	event := ed.queue[0]
+	ed.queue[0] = nil
+	ed.queue = ed.queue[1:]
+	ed.room.Signal()
+	return event, true
+}
+
+// DispatchQueued dispatches the queued events, including those sent while
+// doing so, until the queue is empty
+func (ed *EventDispatcher) DispatchQueued() error {
+	var errs []error
+	for {
+		event, ok := ed.Next()
+		if !ok {
+			return errors.Join(errs...)
+		}
+		if err := ed.DispatchEvent(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+}
+
+// SendEvent queues an event for dispatch, applying the overflow policy
+// when the queue is full
+func (ed *EventDispatcher) SendEvent(event Event) {
+	ed.mutex.Lock()
+	defer ed.mutex.Unlock()
+
+	if len(ed.queue) >= ed.size {
+		switch ed.policy {
+		case OverflowCoalesce:
+			if i := ed.coalescible(event); i >= 0 {
+				ed.queue[i] = event
+				ed.stats.Coalesced++
+				return
+			}
+		case OverflowBlock:
+			for len(ed.queue) >= ed.size {
+				ed.room.Wait()
+			}
+		case OverflowDropOldest:
+			ed.queue[0] = nil
+			ed.queue = ed.queue[1:]
+			ed.stats.Dropped++
+		}
+	}
+
+	ed.queue = append(ed.queue, event)
+	ed.stats.Queued++
+	select {
+	case ed.ready <- struct{}{}:
+	default:
+	}
+}
+
+// coalescible returns the index of the queued event that event may
+// replace, or -1. Pointer motion replaces the last queued motion of the
+// pointer, and touch motion that of its touch point, as long as no press
+// or release of it was queued since; pointer frames in between do not
+// count. A pointer frame replaces a pointer frame queued last. Callers must
+// hold the mutex.
+func (ed *EventDispatcher) coalescible(event Event) int {
+	source, kind := inputOf(event)
+	if kind == inputOther {
+		return -1
+	}
+	for i := len(ed.queue) - 1; i >= 0; i-- {
+		queued, queuedKind := inputOf(ed.queue[i])
+		if queued != source {
+			continue
+		}
+		switch {
+		case queuedKind == kind:
+			return i
+		case kind == inputMotion && queuedKind == inputFrame:
+			continue
+		}
+		return -1
+	}
+	return -1
+}
+
+// inputSource identifies the pointer or a touch point
+type inputSource struct {
+	touch   bool
+	touchID int32
+}
+
+// Kinds of input events told apart when coalescing
+const (
+	inputOther = iota
+	inputMotion
+	inputFrame
+)
+
+// inputOf returns the pointer or touch point an event belongs to, and
+// whether it is a motion or a frame. Other events are reported as belonging
+// to touch point -1, which matches no input.
+func inputOf(event Event) (inputSource, int) {
+	switch e := event.(type) {
+	case *PointerMotionEvent:
+		return inputSource{}, inputMotion
+	case *PointerFrameEvent:
+		return inputSource{}, inputFrame
+	case *PointerButtonEvent, *PointerAxisEvent:
+		return inputSource{}, inputOther
+	case *TouchEvent:
+		if e.State == TouchStateMotion {
+			return inputSource{touch: true, touchID: e.ID}, inputMotion
+		}
+		return inputSource{touch: true, touchID: e.ID}, inputOther
+	}
+	return inputSource{touch: true, touchID: -1}, inputOther
+}
+
+// Stats returns the event counters
+func (ed *EventDispatcher) Stats() DispatcherStats {
+	ed.mutex.Lock()
+	defer ed.mutex.Unlock()
+	stats := ed.stats
+	stats.Pending = len(ed.queue)
+	return stats
+}
@@ -0,0 +1,186 @@
+package wayland
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// pointer returns a motion to x, or a button event for a button other
+// than 0
+func pointer(x int32, button, state uint32) Event {
+	if button == 0 {
+		return &PointerMotionEvent{X: x}
+	}
+	return &PointerButtonEvent{X: x, Button: button, State: state}
+}
+
+func touch(id, x int32, state uint32) Event {
+	return &TouchEvent{ID: id, X: x, State: state}
+}
+
+// drain takes every queued event
+func drain(dispatcher *EventDispatcher) []Event {
+	var events []Event
+	for {
+		event, ok := dispatcher.Next()
+		if !ok {
+			return events
+		}
+		events = append(events, event)
+	}
+}
+
+func TestCoalescingKeepsPressesAndReleases(t *testing.T) {
+	dispatcher := NewEventDispatcherWithPolicy(2, OverflowCoalesce)
+	events := []Event{
+		pointer(1, 0, 0),
+		&PointerFrameEvent{},
+		touch(1, 1, TouchStateMotion),
+		pointer(2, 0, 0),              // merged into the first motion, past its frame
+		&PointerFrameEvent{},          // merged into the first frame
+		touch(2, 2, TouchStateMotion), // another touch point is not merged
+		touch(1, 3, TouchStateMotion), // merged into the first touch motion
+		pointer(3, 1, 1),              // a press is queued past the size
+		pointer(4, 0, 0),              // motion never moves past the press
+		&KeyboardEvent{Key: 30},
+	}
+	for _, event := range events {
+		dispatcher.SendEvent(event)
+	}
+
+	want := []Event{events[3], events[4], events[6], events[5], events[7], events[8], events[9]}
+	if got := drain(dispatcher); !slices.Equal(got, want) {
+		t.Errorf("queued %d events, want %d in order", len(got), len(want))
+	}
+	stats := dispatcher.Stats()
+	if stats.Queued != 7 || stats.Coalesced != 3 || stats.Dropped != 0 || stats.Pending != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestDropOldestMakesRoom(t *testing.T) {
+	dispatcher := NewEventDispatcherWithPolicy(2, OverflowDropOldest)
+	events := []Event{pointer(1, 1, 1), pointer(2, 1, 0), pointer(3, 1, 1)}
+	for _, event := range events {
+		dispatcher.SendEvent(event)
+	}
+	if stats := dispatcher.Stats(); stats.Dropped != 1 || stats.Pending != 2 {
+		t.Errorf("stats = %+v", stats)
+	}
+	if got := drain(dispatcher); !slices.Equal(got, events[1:]) {
+		t.Error("the oldest event was not the one dropped")
+	}
+}
+
+func TestBlockWaitsForRoom(t *testing.T) {
+	dispatcher := NewEventDispatcherWithPolicy(1, OverflowBlock)
+	dispatcher.SendEvent(pointer(1, 0, 0))
+
+	sent := make(chan struct{})
+	go func() {
+		dispatcher.SendEvent(pointer(2, 0, 0))
+		close(sent)
+	}()
+	select {
+	case <-sent:
+		t.Fatal("SendEvent did not wait for room")
+	default:
+	}
+
+	<-dispatcher.Events()
+	dispatcher.Next()
+	<-sent
+	if stats := dispatcher.Stats(); stats.Queued != 2 || stats.Pending != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestHandlersRunByPriorityAndCanBeRemoved(t *testing.T) {
+	dispatcher := NewEventDispatcher()
+	var order []string
+	handler := func(name string, err error) func(*PointerButtonEvent) error {
+		return func(*PointerButtonEvent) error {
+			order = append(order, name)
+			return err
+		}
+	}
+	failure := errors.New("failed")
+
+	Subscribe(dispatcher, handler("first", failure))
+	removeSecond := Subscribe(dispatcher, handler("second", nil))
+	SubscribePriority(dispatcher, 10, handler("urgent", nil))
+	SubscribePriority(dispatcher, -10, handler("last", nil))
+	// Handlers only see the events of their type
+	Subscribe(dispatcher, func(*PointerMotionEvent) error {
+		order = append(order, "motion")
+		return nil
+	})
+
+	if err := dispatcher.DispatchEvent(pointer(0, 1, 1)); !errors.Is(err, failure) {
+		t.Errorf("DispatchEvent = %v, want the handler error", err)
+	}
+	if want := []string{"urgent", "first", "second", "last"}; !slices.Equal(order, want) {
+		t.Errorf("handlers ran as %v, want %v", order, want)
+	}
+
+	// A handler that consumes the event hides it from those after it
+	removeSecond()
+	SubscribePriority(dispatcher, 5, handler("grab", ErrEventHandled))
+	order = nil
+	if err := dispatcher.DispatchEvent(pointer(0, 1, 0)); err != nil {
+		t.Errorf("DispatchEvent = %v after the event was handled", err)
+	}
+	if want := []string{"urgent", "grab"}; !slices.Equal(order, want) {
+		t.Errorf("handlers ran as %v, want %v", order, want)
+	}
+}
+
+func TestDispatcherIsSafeForConcurrentUse(t *testing.T) {
+	dispatcher := NewEventDispatcherWithPolicy(8, OverflowCoalesce)
+	var mutex sync.Mutex
+	handled := 0
+	Subscribe(dispatcher, func(*TouchEvent) error {
+		mutex.Lock()
+		handled++
+		mutex.Unlock()
+		return nil
+	})
+
+	var wg sync.WaitGroup
+	for id := int32(0); id < 4; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := int32(0); i < 100; i++ {
+				dispatcher.SendEvent(touch(id, i, TouchStateMotion))
+				if i%10 == 0 {
+					remove := Subscribe(dispatcher, func(*TouchEvent) error { return nil })
+					remove()
+				}
+			}
+			dispatcher.SendEvent(touch(id, 0, TouchStateUp))
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for finished := false; !finished; {
+		select {
+		case <-dispatcher.Events():
+		case <-done:
+			finished = true
+		}
+		if err := dispatcher.DispatchQueued(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stats := dispatcher.Stats()
+	if uint64(handled) != stats.Queued || stats.Queued+stats.Coalesced != 404 || stats.Dropped != 0 {
+		t.Errorf("handled %d events, stats = %+v", handled, stats)
+	}
+}
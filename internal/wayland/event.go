@@ -0,0 +1,205 @@
+package wayland
+
+// EventType identifies the kind of an Event
+type EventType uint32
+
+// Event types, one per event struct
+const (
+	EventTypeRegistry EventType = iota
+	EventTypeShell
+	EventTypeOutput
+	EventTypeScale
+	EventTypeKeymap
+	EventTypeKeyboard
+	EventTypePointerMotion
+	EventTypePointerButton
+	EventTypePointerAxis
+	EventTypePointerFrame
+	EventTypeTouch
+	EventTypeInputMethod
+)
+
+var eventTypeNames = [...]string{
+	EventTypeRegistry:      "registry",
+	EventTypeShell:         "shell",
+	EventTypeOutput:        "output",
+	EventTypeScale:         "scale",
+	EventTypeKeymap:        "keymap",
+	EventTypeKeyboard:      "keyboard",
+	EventTypePointerMotion: "pointer motion",
+	EventTypePointerButton: "pointer button",
+	EventTypePointerAxis:   "pointer axis",
+	EventTypePointerFrame:  "pointer frame",
+	EventTypeTouch:         "touch",
+	EventTypeInputMethod:   "input method",
+}
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	if int(t) < len(eventTypeNames) {
+		return eventTypeNames[t]
+	}
+	return "unknown"
+}
+
+// Event is an event the clients send to an EventDispatcher. The set of
+// events is closed: only the event structs of this package implement it,
+// always as pointers, so handlers subscribe to one of them by type with
+// Subscribe and never have to assert it.
+type Event interface {
+	// Type returns the kind of the event
+	Type() EventType
+	// sealed keeps other packages from adding events
+	sealed()
+}
+
+// Touch point states reported in TouchEvent.State
+const (
+	TouchStateDown uint32 = iota
+	TouchStateUp
+	TouchStateMotion
+	TouchStateCancel
+)
+
+// Scroll axes reported in PointerAxisEvent.Axis
+const (
+	AxisVertical uint32 = iota
+	AxisHorizontal
+)
+
+// RegistryEvent reports a global the compositor announced
+type RegistryEvent struct {
+	Name      uint32
+	Interface string
+	Version   uint32
+}
+
+// ShellEvent reports a configure or close of the keyboard surface. Width
+// and Height are the size the compositor assigned, or 0 where the client
+// chooses.
+type ShellEvent struct {
+	Width  uint32
+	Height uint32
+	Closed bool
+}
+
+// OutputEvent reports the state of an output once the compositor has sent
+// all of it, and again whenever it changes. Removed is set instead when the
+// output goes away.
+type OutputEvent struct {
+	// ID is the registry name of the output. Name is its connector name,
+	// such as "HDMI-A-1", for compositors that send one.
+	ID          uint32
+	Name        string
+	Description string
+	Make        string
+	Model       string
+	// X and Y are the position of the output in the compositor space
+	X, Y int32
+	// Width and Height are the size of the current mode in pixels, and
+	// Refresh its rate in mHz
+	Width, Height int32
+	Refresh       int32
+	// PhysicalWidth and PhysicalHeight are the size in millimetres
+	PhysicalWidth, PhysicalHeight int32
+	// Scale is the integer buffer scale the compositor suggests, and
+	// Transform the wl_output transform applied to the output
+	Scale     int32
+	Transform int32
+	Removed   bool
+}
+
+// ScaleEvent reports the scale the keyboard surface should be drawn at,
+// whenever it changes: the fractional scale the compositor prefers where it
+// offers wp_fractional_scale_v1, or else the largest scale of the outputs
+// the surface is shown on. Frames drawn at it are Scale times the surface
+// size and are presented with SetScale; input stays in surface pixels.
+type ScaleEvent struct {
+	Scale float64
+}
+
+// KeymapEvent carries the keymap of the physical keyboard. Format is the
+// wl_keyboard keymap format; Keymap is empty unless it is xkb_v1.
+type KeymapEvent struct {
+	Format uint32
+	Keymap string
+}
+
+// KeyboardEvent reports a physical key press or release
+type KeyboardEvent struct {
+	Serial uint32
+	Key    uint32
+	State  uint32
+	Time   uint32
+}
+
+// PointerMotionEvent reports the pointer entering the surface or moving
+// over it
+type PointerMotionEvent struct {
+	X    int32
+	Y    int32
+	Time uint32
+}
+
+// PointerButtonEvent reports a button press or release at the pointer
+// position. Button is 1 for left, 2 for middle and 3 for right; State is 1
+// while pressed.
+type PointerButtonEvent struct {
+	Serial uint32
+	X      int32
+	Y      int32
+	Button uint32
+	State  uint32
+	Time   uint32
+}
+
+// PointerAxisEvent reports scrolling along Axis, one of the Axis
+// constants. Value is the distance in surface pixels.
+type PointerAxisEvent struct {
+	Axis  uint32
+	Value float64
+	Time  uint32
+}
+
+// PointerFrameEvent ends a group of pointer events that belong together,
+// such as the motion and scrolling of one device event
+type PointerFrameEvent struct{}
+
+// TouchEvent contains touch event data. State is one of the TouchState
+// constants.
+type TouchEvent struct {
+	Serial uint32
+	ID     int32
+	X      int32
+	Y      int32
+	Time   uint32
+	State  uint32
+}
+
+// Type returns EventTypeRegistry; the other event structs return their
+// own types alike
+func (*RegistryEvent) Type() EventType      { return EventTypeRegistry }
+func (*ShellEvent) Type() EventType         { return EventTypeShell }
+func (*OutputEvent) Type() EventType        { return EventTypeOutput }
+func (*ScaleEvent) Type() EventType         { return EventTypeScale }
+func (*KeymapEvent) Type() EventType        { return EventTypeKeymap }
+func (*KeyboardEvent) Type() EventType      { return EventTypeKeyboard }
+func (*PointerMotionEvent) Type() EventType { return EventTypePointerMotion }
+func (*PointerButtonEvent) Type() EventType { return EventTypePointerButton }
+func (*PointerAxisEvent) Type() EventType   { return EventTypePointerAxis }
+func (*PointerFrameEvent) Type() EventType  { return EventTypePointerFrame }
+func (*TouchEvent) Type() EventType         { return EventTypeTouch }
+func (*InputMethodEvent) Type() EventType   { return EventTypeInputMethod }
+
+func (*RegistryEvent) sealed()      {}
+func (*ShellEvent) sealed()         {}
+func (*OutputEvent) sealed()        {}
+func (*ScaleEvent) sealed()         {}
+func (*KeymapEvent) sealed()        {}
+func (*KeyboardEvent) sealed()      {}
+func (*PointerMotionEvent) sealed() {}
+func (*PointerButtonEvent) sealed() {}
+func (*PointerAxisEvent) sealed()   {}
+func (*PointerFrameEvent) sealed()  {}
+func (*TouchEvent) sealed()         {}
+func (*InputMethodEvent) sealed()   {}
@@ -0,0 +1,150 @@
+package wayland
+
+import (
+	"bytes"
+	"syscall"
+)
+
+// Linux input event codes for pointer buttons
+const (
+	btnLeft   = 0x110
+	btnRight  = 0x111
+	btnMiddle = 0x112
+)
+
+// keymapFormatXKBv1 is the wl_keyboard keymap format of XKB text keymaps
+const keymapFormatXKBv1 = 1
+
+// SetEventDispatcher sets where translated input and registry events are
+// sent
+func (c *Client) SetEventDispatcher(dispatcher *EventDispatcher) {
+	c.dispatcher = dispatcher
+}
+
+// sendEvent forwards an event to the dispatcher, if one is set
+func (c *Client) sendEvent(event Event) {
+	if c.dispatcher != nil {
+		c.dispatcher.SendEvent(event)
+	}
+}
+
+// frameDone delivers a frame callback, replacing one not yet received
+func (c *Client) frameDone(time uint32) {
+	select {
+	case <-c.frames:
+	default:
+	}
+	c.frames <- time
+}
+
+// Frames returns the channel frame callbacks are delivered on
+func (c *Client) Frames() <-chan uint32 {
+	return c.frames
+}
+
+// pointerMotion records the pointer position and reports the motion
+func (c *Client) pointerMotion(time uint32, x, y int32) {
+	c.pointerX, c.pointerY = fixedToInt(x), fixedToInt(y)
+	c.sendEvent(&PointerMotionEvent{X: c.pointerX, Y: c.pointerY, Time: time})
+}
+
+// pointerButton reports a button press or release at the last pointer
+// position
+func (c *Client) pointerButton(serial, time, button, state uint32) {
+	c.sendEvent(&PointerButtonEvent{
+		Serial: serial,
+		X:      c.pointerX,
+		Y:      c.pointerY,
+		Button: translateButton(button),
+		State:  state,
+		Time:   time,
+	})
+}
+
+// pointerAxis reports scrolling by value, a wl_fixed_t
+func (c *Client) pointerAxis(time, axis uint32, value int32) {
+	c.sendEvent(&PointerAxisEvent{Axis: axis, Value: float64(value) / 256, Time: time})
+}
+
+// pointerFrame reports the end of a group of pointer events
+func (c *Client) pointerFrame() {
+	c.sendEvent(&PointerFrameEvent{})
+}
+
+// keyboardKeymap reads the keymap the compositor passed in fd and reports
+// it. The fd is closed either way.
+func (c *Client) keyboardKeymap(format uint32, fd int, size uint32) {
+	defer syscall.Close(fd)
+	event := &KeymapEvent{Format: format}
+	if format == keymapFormatXKBv1 {
+		data := make([]byte, size)
+		n, err := syscall.Pread(fd, data, 0)
+		if err != nil {
+			return
+		}
+		// The keymap is a NUL terminated string
+		data, _, _ = bytes.Cut(data[:n], []byte{0})
+		event.Keymap = string(data)
+	}
+	c.sendEvent(event)
+}
+
+// keyboardKey reports a physical key press or release
+func (c *Client) keyboardKey(serial, time, key, state uint32) {
+	c.sendEvent(&KeyboardEvent{Serial: serial, Key: key, State: state, Time: time})
+}
+
+// touchPoint reports a touch point change. Up and cancel events carry the
+// last known position of the point.
+func (c *Client) touchPoint(state, serial, time uint32, id int32, x, y int32, moved bool) {
+	position, known := c.touches[id]
+	if moved {
+		position = [2]int32{fixedToInt(x), fixedToInt(y)}
+		c.touches[id] = position
+	} else if !known {
+		return
+	}
+	if state == TouchStateUp || state == TouchStateCancel {
+		delete(c.touches, id)
+	}
+
+	c.sendEvent(&TouchEvent{Serial: serial, ID: id, X: position[0], Y: position[1], Time: time, State: state})
+}
+
+// touchCancel cancels every active touch point
+func (c *Client) touchCancel() {
+	for id := range c.touches {
+		c.touchPoint(TouchStateCancel, 0, 0, id, 0, 0, false)
+	}
+}
+
+// translateButton maps Linux button codes to the 1 left, 2 middle, 3 right
+// numbering used by widgets
+func translateButton(button uint32) uint32 {
+	switch button {
+	case btnLeft:
+		return 1
+	case btnMiddle:
+		return 2
+	case btnRight:
+		return 3
+	}
+	return button
+}
+
+// fixedToInt converts a wl_fixed_t (24.8 fixed point) to whole pixels
+func fixedToInt(v int32) int32 {
+	return v / 256
+}
+
+// toplevelConfigure records the size suggested by an xdg_toplevel
+// configure, applied with the xdg_surface configure that follows it
+func (c *Client) toplevelConfigure(width, height int32) {
+	c.pendingWidth, c.pendingHeight = uint32(max(width, 0)), uint32(max(height, 0))
+}
+
+// shellClosed reports that the compositor will no longer show the surface
+func (c *Client) shellClosed() {
+	c.closed = true
+	c.sendEvent(&ShellEvent{Closed: true})
+}
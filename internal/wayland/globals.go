@@ -0,0 +1,36 @@
+package wayland
+
+// Highest interface versions the client implements. Globals are bound at
+// the lower of these and the version the compositor advertises.
+const (
+	compositorVersion = 4
+	shmVersion        = 1
+	seatVersion       = 5
+	outputVersion     = 4
+)
+
+// negotiate returns the version to bind a global at
+func negotiate(advertised, supported uint32) uint32 {
+	if advertised < supported {
+		return advertised
+	}
+	return supported
+}
+
+// Highest shell versions the client implements
+const (
+	layerShellVersion = 4
+	wmBaseVersion     = 2
+)
+
+// Highest versions of the scaling globals the client implements
+const (
+	viewporterVersion             = 1
+	fractionalScaleManagerVersion = 1
+)
+
+// Highest versions of the text input managers the client implements
+const (
+	virtualKeyboardManagerVersion = 1
+	inputMethodManagerVersion     = 1
+)
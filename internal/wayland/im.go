@@ -0,0 +1,148 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#cgo pkg-config: wayland-client
+#cgo CFLAGS: -I${SRCDIR}/../../generated
+#cgo LDFLAGS: -L${SRCDIR}/../../generated -linput-method-protocol
+#include <wayland-client.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include "input-method-unstable-v2-client-protocol.h"
+
+extern void oskInputMethodActivate(uintptr_t handle);
+extern void oskInputMethodDeactivate(uintptr_t handle);
+extern void oskInputMethodSurroundingText(uintptr_t handle, char *text, uint32_t cursor, uint32_t anchor);
+extern void oskInputMethodTextChangeCause(uintptr_t handle, uint32_t cause);
+extern void oskInputMethodContentType(uintptr_t handle, uint32_t hint, uint32_t purpose);
+extern void oskInputMethodDone(uintptr_t handle);
+extern void oskInputMethodUnavailable(uintptr_t handle);
+
+static void osk_im_activate(void *data, struct zwp_input_method_v2 *im) {
+	oskInputMethodActivate((uintptr_t)data);
+}
+
+static void osk_im_deactivate(void *data, struct zwp_input_method_v2 *im) {
+	oskInputMethodDeactivate((uintptr_t)data);
+}
+
+static void osk_im_surrounding_text(void *data, struct zwp_input_method_v2 *im,
+		const char *text, uint32_t cursor, uint32_t anchor) {
+	oskInputMethodSurroundingText((uintptr_t)data, (char *)text, cursor, anchor);
+}
+
+static void osk_im_text_change_cause(void *data, struct zwp_input_method_v2 *im, uint32_t cause) {
+	oskInputMethodTextChangeCause((uintptr_t)data, cause);
+}
+
+static void osk_im_content_type(void *data, struct zwp_input_method_v2 *im,
+		uint32_t hint, uint32_t purpose) {
+	oskInputMethodContentType((uintptr_t)data, hint, purpose);
+}
+
+static void osk_im_done(void *data, struct zwp_input_method_v2 *im) {
+	oskInputMethodDone((uintptr_t)data);
+}
+
+static void osk_im_unavailable(void *data, struct zwp_input_method_v2 *im) {
+	oskInputMethodUnavailable((uintptr_t)data);
+}
+
+static const struct zwp_input_method_v2_listener osk_input_method_listener = {
+	.activate = osk_im_activate,
+	.deactivate = osk_im_deactivate,
+	.surrounding_text = osk_im_surrounding_text,
+	.text_change_cause = osk_im_text_change_cause,
+	.content_type = osk_im_content_type,
+	.done = osk_im_done,
+	.unavailable = osk_im_unavailable,
+};
+
+static struct zwp_input_method_v2 *osk_get_input_method(struct zwp_input_method_manager_v2 *manager,
+		struct wl_seat *seat, uintptr_t handle) {
+	struct zwp_input_method_v2 *im = zwp_input_method_manager_v2_get_input_method(manager, seat);
+	if (im != NULL) {
+		zwp_input_method_v2_add_listener(im, &osk_input_method_listener, (void *)handle);
+	}
+	return im;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"runtime/cgo"
+	"unsafe"
+)
+
+// wlInputMethod sends input method requests through libwayland
+type wlInputMethod struct {
+	im     *C.struct_zwp_input_method_v2
+	handle cgo.Handle
+}
+
+func (w *wlInputMethod) commitString(text string) {
+	ctext := C.CString(text)
+	defer C.free(unsafe.Pointer(ctext))
+	C.zwp_input_method_v2_commit_string(w.im, ctext)
+}
+
+func (w *wlInputMethod) setPreeditString(text string, cursorBegin, cursorEnd int32) {
+	ctext := C.CString(text)
+	defer C.free(unsafe.Pointer(ctext))
+	C.zwp_input_method_v2_set_preedit_string(w.im, ctext, C.int32_t(cursorBegin), C.int32_t(cursorEnd))
+}
+
+func (w *wlInputMethod) deleteSurroundingText(beforeLength, afterLength uint32) {
+	C.zwp_input_method_v2_delete_surrounding_text(w.im, C.uint32_t(beforeLength), C.uint32_t(afterLength))
+}
+
+func (w *wlInputMethod) commit(serial uint32) {
+	C.zwp_input_method_v2_commit(w.im, C.uint32_t(serial))
+}
+
+func (w *wlInputMethod) destroy() {
+	C.zwp_input_method_v2_destroy(w.im)
+	w.handle.Delete()
+}
+
+// bindInputMethodManager binds zwp_input_method_manager_v2
+func (c *Client) bindInputMethodManager(name, version uint32) {
+	if c.imManager != nil {
+		return
+	}
+	c.imManager = (*C.struct_zwp_input_method_manager_v2)(c.bind(name,
+		&C.zwp_input_method_manager_v2_interface, negotiate(version, inputMethodManagerVersion)))
+}
+
+// InputMethod makes the client the input method of the seat. Focus changes
+// of text inputs are reported as InputMethodEvents.
+func (c *Client) InputMethod() (*InputMethod, error) {
+	if c.imManager == nil {
+		return nil, fmt.Errorf("compositor does not provide zwp_input_method_manager_v2")
+	}
+	if c.seat == nil {
+		return nil, fmt.Errorf("no seat to serve")
+	}
+
+	protocol := &wlInputMethod{}
+	im := newInputMethod(protocol, c.sendEvent)
+	protocol.handle = cgo.NewHandle(im)
+	protocol.im = C.osk_get_input_method(c.imManager, c.seat, C.uintptr_t(protocol.handle))
+	if protocol.im == nil {
+		protocol.handle.Delete()
+		return nil, fmt.Errorf("failed to create input method")
+	}
+	return im, nil
+}
+
+// destroyInputMethodManager destroys the manager; input methods created
+// from it are destroyed by their owners
+func (c *Client) destroyInputMethodManager() {
+	if c.imManager != nil {
+		C.zwp_input_method_manager_v2_destroy(c.imManager)
+		c.imManager = nil
+	}
+}
@@ -0,0 +1,283 @@
+package wayland
+
+import (
+	"fmt"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// inputMethodProtocol is a zwp_input_method_v2 object. The client
+// implements it with libwayland; tests use a fake compositor that records
+// the requests.
+type inputMethodProtocol interface {
+	commitString(text string)
+	setPreeditString(text string, cursorBegin, cursorEnd int32)
+	deleteSurroundingText(beforeLength, afterLength uint32)
+	commit(serial uint32)
+	destroy()
+}
+
+// InputMethodEvent reports the state of the focused text field, applied by
+// a zwp_input_method_v2.done event. Unavailable is set instead when another
+// input method already serves the seat.
+type InputMethodEvent struct {
+	Active          bool
+	SurroundingText string
+	// Cursor and Anchor are byte offsets into SurroundingText; they differ
+	// when text is selected
+	Cursor      uint32
+	Anchor      uint32
+	ContentType keyboard.ContentType
+	Unavailable bool
+}
+
+// textInputState is the text-input state double-buffered by done events
+type textInputState struct {
+	active      bool
+	surrounding string
+	// hasSurrounding is false when the text input does not send its
+	// surrounding text
+	hasSurrounding bool
+	cursor         uint32
+	anchor         uint32
+	contentType    keyboard.ContentType
+	// externalChange is set when the text changed other than through the
+	// input method; it applies to one done event
+	externalChange bool
+}
+
+// changeCauseOther is zwp_text_input_v3.change_cause.other
+const changeCauseOther = 1
+
+// InputMethod is the input method for the seat, following the text-input-v3
+// model: events from the compositor change a pending state that each done
+// event applies, and text changes are requested with a commit carrying the
+// number of done events seen.
+type InputMethod struct {
+	protocol inputMethodProtocol
+	send     func(Event)
+
+	mutex   sync.Mutex
+	pending textInputState
+	current textInputState
+	serial  uint32
+	inert   bool
+	preedit string
+}
+
+// newInputMethod creates an input method that reports state changes
+// through send
+func newInputMethod(protocol inputMethodProtocol, send func(Event)) *InputMethod {
+	return &InputMethod{protocol: protocol, send: send}
+}
+
+// activate starts a pending state for a newly focused text input
+func (im *InputMethod) activate() {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.pending = textInputState{active: true}
+}
+
+// deactivate ends the pending state. The text of the field is forgotten,
+// and once applied no copy of it is kept.
+func (im *InputMethod) deactivate() {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.pending = textInputState{}
+}
+
+func (im *InputMethod) surroundingText(text string, cursor, anchor uint32) {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.pending.surrounding = text
+	im.pending.hasSurrounding = true
+	im.pending.cursor = cursor
+	im.pending.anchor = anchor
+}
+
+func (im *InputMethod) textChangeCause(cause uint32) {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.pending.externalChange = cause == changeCauseOther
+}
+
+func (im *InputMethod) contentType(hint, purpose uint32) {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.pending.contentType = keyboard.ContentType{
+		Hint:    keyboard.ContentHint(hint),
+		Purpose: keyboard.ContentPurpose(purpose),
+	}
+}
+
+// done applies the pending state and reports it
+func (im *InputMethod) done() {
+	im.mutex.Lock()
+	im.serial++
+	im.current = im.pending
+	im.pending.externalChange = false
+	// The preedit is dropped by the text input when it loses focus or its
+	// text is edited by other means
+	if !im.current.active || im.current.externalChange {
+		im.preedit = ""
+	}
+	// A text input may report its text after deactivate; it is not kept
+	if !im.current.active {
+		im.current, im.pending = textInputState{}, textInputState{}
+	}
+	event := &InputMethodEvent{
+		Active:          im.current.active,
+		SurroundingText: im.current.surrounding,
+		Cursor:          im.current.cursor,
+		Anchor:          im.current.anchor,
+		ContentType:     im.current.contentType,
+	}
+	im.mutex.Unlock()
+
+	im.send(event)
+}
+
+// unavailable marks the input method inert; another one serves the seat
+func (im *InputMethod) unavailable() {
+	im.mutex.Lock()
+	im.inert = true
+	im.current = textInputState{}
+	im.mutex.Unlock()
+
+	im.send(&InputMethodEvent{Unavailable: true})
+}
+
+// Active reports whether a text input is focused and accepting text
+func (im *InputMethod) Active() bool {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	return im.current.active && !im.inert
+}
+
+// CommitString inserts text at the cursor of the focused text input,
+// replacing the selection and any preedit text
+func (im *InputMethod) CommitString(text string) error {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	if err := im.checkActive(); err != nil {
+		return err
+	}
+
+	im.protocol.commitString(text)
+	im.protocol.commit(im.serial)
+	im.preedit = ""
+
+	// Track the text locally, so edits sent before the text input reports
+	// the new surrounding text still see it
+	if im.current.hasSurrounding {
+		start, end := im.selection()
+		im.current.surrounding = im.current.surrounding[:start] + text + im.current.surrounding[end:]
+		im.current.cursor = start + uint32(len(text))
+		im.current.anchor = im.current.cursor
+	}
+	return nil
+}
+
+// SetPreedit shows text being composed at the cursor, with the cursor
+// placed over the given byte range of it; -1 for both hides the cursor.
+// An empty text removes the preedit.
+func (im *InputMethod) SetPreedit(text string, cursorBegin, cursorEnd int32) error {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	if err := im.checkActive(); err != nil {
+		return err
+	}
+
+	im.protocol.setPreeditString(text, cursorBegin, cursorEnd)
+	im.protocol.commit(im.serial)
+	im.preedit = text
+	return nil
+}
+
+// Preedit returns the text being composed
+func (im *InputMethod) Preedit() string {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	return im.preedit
+}
+
+// DeleteSurroundingText deletes the given numbers of bytes before and after
+// the cursor
+func (im *InputMethod) DeleteSurroundingText(before, after uint32) error {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	if err := im.checkActive(); err != nil {
+		return err
+	}
+	im.deleteSurrounding(before, after)
+	return nil
+}
+
+// DeleteBackward deletes the character before the cursor. It reports false
+// when that character is unknown, or text is selected, so the caller can
+// send a Backspace key instead.
+func (im *InputMethod) DeleteBackward() bool {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	if im.checkActive() != nil || !im.current.hasSurrounding || im.preedit != "" {
+		return false
+	}
+	cursor := im.current.cursor
+	if cursor != im.current.anchor || cursor == 0 || int(cursor) > len(im.current.surrounding) {
+		return false
+	}
+
+	_, size := utf8.DecodeLastRuneInString(im.current.surrounding[:cursor])
+	im.deleteSurrounding(uint32(size), 0)
+	return true
+}
+
+// Close destroys the input method
+func (im *InputMethod) Close() {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.protocol.destroy()
+}
+
+// deleteSurrounding sends a deletion and applies it to the tracked text.
+// Callers must hold the mutex.
+func (im *InputMethod) deleteSurrounding(before, after uint32) {
+	im.protocol.deleteSurroundingText(before, after)
+	im.protocol.commit(im.serial)
+	im.preedit = ""
+
+	if im.current.hasSurrounding {
+		text := im.current.surrounding
+		cursor := min(im.current.cursor, uint32(len(text)))
+		start := cursor - min(before, cursor)
+		end := min(cursor+after, uint32(len(text)))
+		im.current.surrounding = text[:start] + text[end:]
+		im.current.cursor = start
+		im.current.anchor = start
+	}
+}
+
+// selection returns the byte range between the cursor and the anchor.
+// Callers must hold the mutex.
+func (im *InputMethod) selection() (uint32, uint32) {
+	length := uint32(len(im.current.surrounding))
+	start, end := min(im.current.cursor, length), min(im.current.anchor, length)
+	if start > end {
+		start, end = end, start
+	}
+	return start, end
+}
+
+// checkActive returns an error when no text input can receive text.
+// Callers must hold the mutex.
+func (im *InputMethod) checkActive() error {
+	if im.inert {
+		return fmt.Errorf("input method unavailable")
+	}
+	if !im.current.active {
+		return fmt.Errorf("no text input is focused")
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package wayland
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// fakeInputMethod records the zwp_input_method_v2 requests it receives
+type fakeInputMethod struct {
+	requests []string
+}
+
+func (f *fakeInputMethod) commitString(text string) {
+	f.requests = append(f.requests, fmt.Sprintf("commit_string %q", text))
+}
+
+func (f *fakeInputMethod) setPreeditString(text string, cursorBegin, cursorEnd int32) {
+	f.requests = append(f.requests, fmt.Sprintf("set_preedit_string %q %d %d", text, cursorBegin, cursorEnd))
+}
+
+func (f *fakeInputMethod) deleteSurroundingText(beforeLength, afterLength uint32) {
+	f.requests = append(f.requests, fmt.Sprintf("delete_surrounding_text %d %d", beforeLength, afterLength))
+}
+
+func (f *fakeInputMethod) commit(serial uint32) {
+	f.requests = append(f.requests, fmt.Sprintf("commit %d", serial))
+}
+
+func (f *fakeInputMethod) destroy() {
+	f.requests = append(f.requests, "destroy")
+}
+
+func (f *fakeInputMethod) take() []string {
+	requests := f.requests
+	f.requests = nil
+	return requests
+}
+
+func newTestInputMethod() (*InputMethod, *fakeInputMethod, *[]*InputMethodEvent) {
+	fake := &fakeInputMethod{}
+	var events []*InputMethodEvent
+	im := newInputMethod(fake, func(event Event) {
+		events = append(events, event.(*InputMethodEvent))
+	})
+	return im, fake, &events
+}
+
+// focus activates im on a text input holding text with the cursor at its end
+func focus(im *InputMethod, text string, purpose keyboard.ContentPurpose) {
+	im.activate()
+	im.surroundingText(text, uint32(len(text)), uint32(len(text)))
+	im.contentType(uint32(keyboard.ContentHintSpellcheck), uint32(purpose))
+	im.done()
+}
+
+func expectRequests(t *testing.T, fake *fakeInputMethod, want ...string) {
+	t.Helper()
+	if got := fake.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("requests = %q, want %q", got, want)
+	}
+}
+
+func TestInputMethodAppliesStateOnDone(t *testing.T) {
+	im, fake, events := newTestInputMethod()
+
+	im.activate()
+	im.contentType(uint32(keyboard.ContentHintSpellcheck), uint32(keyboard.ContentPurposeEmail))
+	if im.Active() || len(*events) != 0 {
+		t.Fatal("state was applied before done")
+	}
+	im.done()
+
+	want := &InputMethodEvent{
+		Active:      true,
+		ContentType: keyboard.ContentType{Hint: keyboard.ContentHintSpellcheck, Purpose: keyboard.ContentPurposeEmail},
+	}
+	if len(*events) != 1 || !reflect.DeepEqual((*events)[0], want) {
+		t.Fatalf("events = %+v, want %+v", *events, want)
+	}
+
+	// Commits carry the number of done events seen
+	im.done()
+	if err := im.CommitString("hi"); err != nil {
+		t.Fatalf("CommitString: %v", err)
+	}
+	expectRequests(t, fake, `commit_string "hi"`, "commit 2")
+
+	im.deactivate()
+	im.done()
+	if im.Active() || (*events)[2].Active {
+		t.Error("input method still active after deactivate")
+	}
+	if err := im.CommitString("x"); err == nil {
+		t.Error("CommitString succeeded without a focused text input")
+	}
+	expectRequests(t, fake)
+}
+
+func TestInputMethodActivateResetsState(t *testing.T) {
+	im, _, events := newTestInputMethod()
+	focus(im, "hello", keyboard.ContentPurposePassword)
+
+	im.deactivate()
+	im.done()
+	im.activate()
+	im.done()
+
+	last := (*events)[len(*events)-1]
+	if last.SurroundingText != "" || last.ContentType != (keyboard.ContentType{}) {
+		t.Errorf("state of the previous text input survived activate: %+v", last)
+	}
+}
+
+func TestInputMethodForgetsTextOnFocusLoss(t *testing.T) {
+	im, _, events := newTestInputMethod()
+	focus(im, "hunter2", keyboard.ContentPurposePassword)
+	if err := im.SetPreedit("x", 1, 1); err != nil {
+		t.Fatalf("SetPreedit: %v", err)
+	}
+
+	im.deactivate()
+	im.surroundingText("hunter2x", 8, 8)
+	im.done()
+
+	if last := (*events)[len(*events)-1]; last.SurroundingText != "" || last.ContentType.Sensitive() {
+		t.Errorf("focus loss reported %+v", last)
+	}
+	if im.current != (textInputState{}) || im.pending != (textInputState{}) || im.Preedit() != "" {
+		t.Errorf("text kept after focus loss: current %+v, pending %+v, preedit %q", im.current, im.pending, im.Preedit())
+	}
+}
+
+func TestInputMethodDeletesCharacterBeforeCursor(t *testing.T) {
+	im, fake, _ := newTestInputMethod()
+	focus(im, "n€", keyboard.ContentPurposeNormal)
+
+	// Lengths are in bytes, and the text is tracked until the text input
+	// reports it again
+	if !im.DeleteBackward() || !im.DeleteBackward() {
+		t.Fatal("DeleteBackward did not delete")
+	}
+	if im.DeleteBackward() {
+		t.Error("DeleteBackward deleted before the start of the text")
+	}
+	expectRequests(t, fake,
+		"delete_surrounding_text 3 0", "commit 1",
+		"delete_surrounding_text 1 0", "commit 1")
+
+	// With a selection the text input must handle Backspace itself
+	im.surroundingText("abc", 1, 3)
+	im.done()
+	if im.DeleteBackward() {
+		t.Error("DeleteBackward deleted a selection")
+	}
+}
+
+func TestInputMethodDropsPreeditOnExternalChange(t *testing.T) {
+	im, fake, _ := newTestInputMethod()
+	focus(im, "", keyboard.ContentPurposeNormal)
+
+	if err := im.SetPreedit("ab", 2, 2); err != nil {
+		t.Fatalf("SetPreedit: %v", err)
+	}
+	expectRequests(t, fake, `set_preedit_string "ab" 2 2`, "commit 1")
+	if im.DeleteBackward() {
+		t.Error("DeleteBackward deleted committed text while composing")
+	}
+
+	im.textChangeCause(changeCauseOther)
+	im.done()
+	if im.Preedit() != "" {
+		t.Errorf("preedit %q survived an external change", im.Preedit())
+	}
+}
+
+func TestVirtualKeyboardCommitsTextThroughInputMethod(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+	compositor.take()
+	im, fake, _ := newTestInputMethod()
+	vk.SetInputMethod(im)
+	focus(im, "", keyboard.ContentPurposeNormal)
+
+	press := func(key *keyboard.Key) {
+		vk.HandleKey(keyboard.KeyEvent{Key: key, State: keyboard.KeyStatePressed})
+		vk.HandleKey(keyboard.KeyEvent{Key: key, State: keyboard.KeyStateReleased})
+	}
+	a := &keyboard.Key{ID: "a", Code: 30}
+
+	vk.Press(42)
+	vk.Release(42)
+	compositor.take()
+	press(a)
+	press(&keyboard.Key{ID: "euro", Output: keyboard.Levels{"€"}})
+	press(&keyboard.Key{ID: "backspace", Code: keyBackspace})
+	expectRequests(t, fake,
+		`commit_string "A"`, "commit 1",
+		`commit_string "€"`, "commit 1",
+		"delete_surrounding_text 3 0", "commit 1")
+	// Only the latch being used up reaches the compositor
+	expectMessages(t, compositor, "modifiers 0 0 0 0")
+
+	// Shortcuts are sent as keys
+	vk.Press(29)
+	press(a)
+	vk.Release(29)
+	expectRequests(t, fake)
+	expectMessages(t, compositor, "key 29 1", "modifiers 4 0 0 0", "key 30 1", "key 30 0", "key 29 0", "modifiers 0 0 0 0")
+
+	// Keys without text are sent as keys too
+	press(&keyboard.Key{ID: "enter", Code: 28})
+	expectMessages(t, compositor, "key 28 1", "key 28 0")
+}
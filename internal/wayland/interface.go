@@ -1,39 +1,83 @@
 package wayland
 
 import (
+	"image"
+	"log"
 	"os"
 )
 
 // WaylandClient defines the interface for Wayland client implementations
 type WaylandClient interface {
 	Close()
-	CreateSurface() error
+	// CreateSurface creates the keyboard surface placed as config
+	// describes; size changes and closing are reported as ShellEvents
+	CreateSurface(config SurfaceConfig) error
 	Dispatch() error
+	// DispatchPending dispatches events that were already read from the
+	// connection without blocking
+	DispatchPending() error
 	Flush() error
+	// Events returns a channel that receives a value when the connection
+	// has data to read. The receiver must then call Dispatch, which will
+	// not block.
+	Events() <-chan struct{}
+	// RequestFrame asks the compositor for a frame callback and commits the
+	// surface. The callback time is delivered on Frames when it is a good
+	// time to draw the next frame.
+	RequestFrame() error
+	Frames() <-chan uint32
+	// SetEventDispatcher sets the dispatcher that receives translated
+	// input events
+	SetEventDispatcher(dispatcher *EventDispatcher)
+	// Damage marks a rectangle of the surface, in surface coordinates, as
+	// changed for the next commit
+	Damage(x, y, width, height int) error
+	// Resize prepares presentation buffers for a new surface size
+	Resize(width, height int) error
+	// Attach presents a rendered frame: img is copied into a free
+	// shared-memory buffer, which is attached and committed together with
+	// the damage reported since the last commit
+	Attach(img *image.RGBA) error
+	// SetScale sets the scale of the frames attached from now on, drawn at
+	// scale times the surface size, as last reported in a ScaleEvent
+	SetScale(scale float64) error
+	// VirtualKeyboard creates a virtual keyboard that types into the
+	// focused application
+	VirtualKeyboard() (*VirtualKeyboard, error)
+	// InputMethod makes the client the input method of the seat
+	InputMethod() (*InputMethod, error)
+	// SetVisible maps or unmaps the keyboard surface
+	SetVisible(visible bool) error
 }
 
-// NewClientInterface creates a new Wayland client interface
-// This will use mock implementation for testing or real implementation for production
+// NewClientInterface connects to the display server of the session: the
+// Wayland compositor when there is one, otherwise the X server named by
+// DISPLAY. The mock client is used when neither can be reached.
 func NewClientInterface() (WaylandClient, error) {
-	// Check if we have a Wayland display available
 	if isWaylandAvailable() {
-		// Try to create a real Wayland client
 		client, err := NewClient()
-		if err != nil {
-			// Fall back to mock if real client fails
-			return NewMockClient(), nil
+		if err == nil {
+			return client, nil
 		}
-		return client, nil
+		log.Printf("Failed to connect to the Wayland compositor: %v", err)
 	}
-	
-	// Use mock client for non-Wayland environments (like X11)
+
+	if isX11Available() {
+		client, err := connectX11()
+		if err == nil {
+			return client, nil
+		}
+		log.Printf("Failed to connect to the X server: %v", err)
+	}
+
 	return NewMockClient(), nil
 }
 
 // isWaylandAvailable checks if Wayland is available in the current environment
 func isWaylandAvailable() bool {
-	// Check for Wayland display environment variable
-	if os.Getenv("WAYLAND_DISPLAY") != "" {
+	// Check for Wayland display environment variable, or a connection
+	// handed over by the parent process
+	if os.Getenv("WAYLAND_DISPLAY") != "" || os.Getenv("WAYLAND_SOCKET") != "" {
 		return true
 	}
 	
@@ -45,3 +89,8 @@ func isWaylandAvailable() bool {
 	// Default to false for X11 or other environments
 	return false
 }
+
+// isX11Available checks if an X server is named in the environment
+func isX11Available() bool {
+	return os.Getenv("DISPLAY") != ""
+}
@@ -0,0 +1,312 @@
+package wayland
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// usKeysyms are the keysyms of the evdev codes layouts use, as in a US
+// layout, by level
+var usKeysyms = map[uint32][]string{
+	1:   {"Escape"},
+	2:   {"1", "exclam"},
+	3:   {"2", "at"},
+	4:   {"3", "numbersign"},
+	5:   {"4", "dollar"},
+	6:   {"5", "percent"},
+	7:   {"6", "asciicircum"},
+	8:   {"7", "ampersand"},
+	9:   {"8", "asterisk"},
+	10:  {"9", "parenleft"},
+	11:  {"0", "parenright"},
+	12:  {"minus", "underscore"},
+	13:  {"equal", "plus"},
+	14:  {"BackSpace"},
+	15:  {"Tab", "ISO_Left_Tab"},
+	16:  {"q", "Q"},
+	17:  {"w", "W"},
+	18:  {"e", "E"},
+	19:  {"r", "R"},
+	20:  {"t", "T"},
+	21:  {"y", "Y"},
+	22:  {"u", "U"},
+	23:  {"i", "I"},
+	24:  {"o", "O"},
+	25:  {"p", "P"},
+	26:  {"bracketleft", "braceleft"},
+	27:  {"bracketright", "braceright"},
+	28:  {"Return"},
+	29:  {"Control_L"},
+	30:  {"a", "A"},
+	31:  {"s", "S"},
+	32:  {"d", "D"},
+	33:  {"f", "F"},
+	34:  {"g", "G"},
+	35:  {"h", "H"},
+	36:  {"j", "J"},
+	37:  {"k", "K"},
+	38:  {"l", "L"},
+	39:  {"semicolon", "colon"},
+	40:  {"apostrophe", "quotedbl"},
+	41:  {"grave", "asciitilde"},
+	42:  {"Shift_L"},
+	43:  {"backslash", "bar"},
+	44:  {"z", "Z"},
+	45:  {"x", "X"},
+	46:  {"c", "C"},
+	47:  {"v", "V"},
+	48:  {"b", "B"},
+	49:  {"n", "N"},
+	50:  {"m", "M"},
+	51:  {"comma", "less"},
+	52:  {"period", "greater"},
+	53:  {"slash", "question"},
+	54:  {"Shift_R"},
+	56:  {"Alt_L"},
+	57:  {"space"},
+	58:  {"Caps_Lock"},
+	59:  {"F1"},
+	60:  {"F2"},
+	61:  {"F3"},
+	62:  {"F4"},
+	63:  {"F5"},
+	64:  {"F6"},
+	65:  {"F7"},
+	66:  {"F8"},
+	67:  {"F9"},
+	68:  {"F10"},
+	87:  {"F11"},
+	88:  {"F12"},
+	97:  {"Control_R"},
+	100: {"ISO_Level3_Shift"},
+	102: {"Home"},
+	103: {"Up"},
+	104: {"Prior"},
+	105: {"Left"},
+	106: {"Right"},
+	107: {"End"},
+	108: {"Down"},
+	109: {"Next"},
+	110: {"Insert"},
+	111: {"Delete"},
+	125: {"Super_L"},
+	126: {"Super_R"},
+	127: {"Menu"},
+}
+
+// keyBackspace is the evdev code of Backspace
+const keyBackspace = 14
+
+// usText is the text typed by the printable US keys, by level
+var usText = map[uint32][]string{
+	2:  {"1", "!"},
+	3:  {"2", "@"},
+	4:  {"3", "#"},
+	5:  {"4", "$"},
+	6:  {"5", "%"},
+	7:  {"6", "^"},
+	8:  {"7", "&"},
+	9:  {"8", "*"},
+	10: {"9", "("},
+	11: {"0", ")"},
+	12: {"-", "_"},
+	13: {"=", "+"},
+	16: {"q", "Q"},
+	17: {"w", "W"},
+	18: {"e", "E"},
+	19: {"r", "R"},
+	20: {"t", "T"},
+	21: {"y", "Y"},
+	22: {"u", "U"},
+	23: {"i", "I"},
+	24: {"o", "O"},
+	25: {"p", "P"},
+	26: {"[", "{"},
+	27: {"]", "}"},
+	30: {"a", "A"},
+	31: {"s", "S"},
+	32: {"d", "D"},
+	33: {"f", "F"},
+	34: {"g", "G"},
+	35: {"h", "H"},
+	36: {"j", "J"},
+	37: {"k", "K"},
+	38: {"l", "L"},
+	39: {";", ":"},
+	40: {"'", "\""},
+	41: {"`", "~"},
+	43: {"\\", "|"},
+	44: {"z", "Z"},
+	45: {"x", "X"},
+	46: {"c", "C"},
+	47: {"v", "V"},
+	48: {"b", "B"},
+	49: {"n", "N"},
+	50: {"m", "M"},
+	51: {",", "<"},
+	52: {".", ">"},
+	53: {"/", "?"},
+	57: {" "},
+}
+
+// usKeys maps the characters typed by the US keys to their code and level
+var usKeys = func() map[rune][2]uint32 {
+	keys := make(map[rune][2]uint32)
+	for code, levels := range usText {
+		for level, text := range levels {
+			keys[[]rune(text)[0]] = [2]uint32{code, uint32(level)}
+		}
+	}
+	for r, code := range controlCodes {
+		keys[r] = [2]uint32{code, 0}
+	}
+	return keys
+}()
+
+// USKey returns the evdev code of the US key typing r and whether Shift
+// must be held to type it. It reports false for characters no US key
+// types, so backends without a keymap of their own can type plain text.
+func USKey(r rune) (code uint32, shift bool, ok bool) {
+	key, ok := usKeys[r]
+	return key[0], key[1] == 1, ok
+}
+
+// modifierNames are the real modifiers in modifier_map statements
+var modifierNames = map[uint32]string{
+	modShift:   "Shift",
+	modLock:    "Lock",
+	modControl: "Control",
+	modMod1:    "Mod1",
+	modMod4:    "Mod4",
+	modMod5:    "Mod5",
+}
+
+// controlCodes are the US keys typing the control characters an output
+// may contain; other control characters cannot be typed
+var controlCodes = map[rune]uint32{
+	'\b': 14, // BackSpace
+	'\t': 15, // Tab
+	'\n': 28, // Return
+}
+
+// keymap is an XKB keymap generated for a layout. It holds the US keys, so
+// Key.Code values are sent unchanged, and gives every character of the
+// layout's outputs a key code of its own with a single level.
+type keymap struct {
+	// text is the keymap, NUL-terminated as compositors expect
+	text []byte
+	// outputs holds the codes to tap for each level of the outputs, by key ID
+	outputs map[string][][]uint32
+}
+
+// newKeymap generates the keymap for layout, which may be nil. Output
+// characters take the lowest codes not used by the US keys or the layout,
+// keeping them below 248 where possible: X11 clients behind Xwayland
+// cannot receive higher codes.
+func newKeymap(layout *keyboard.Layout) *keymap {
+	km := &keymap{outputs: make(map[string][][]uint32)}
+
+	used := make(map[uint32]bool)
+	if layout != nil {
+		for _, key := range layout.Keys {
+			if key.Code > 0 {
+				used[uint32(key.Code)] = true
+			}
+		}
+	}
+	nextCode := uint32(1)
+	allocate := func() uint32 {
+		for used[nextCode] || usKeysyms[nextCode] != nil {
+			nextCode++
+		}
+		used[nextCode] = true
+		return nextCode
+	}
+
+	symbols := make(map[uint32][]string, len(usKeysyms))
+	for code, keysyms := range usKeysyms {
+		symbols[code] = keysyms
+	}
+	runes := make(map[rune]uint32)
+
+	if layout != nil {
+		for _, key := range layout.Keys {
+			if len(key.Output) == 0 {
+				continue
+			}
+			levels := make([][]uint32, len(key.Output))
+			for i, text := range key.Output {
+				for _, r := range text {
+					if code, ok := controlCodes[r]; ok {
+						levels[i] = append(levels[i], code)
+						continue
+					}
+					if r < 0x20 || r == 0x7f {
+						continue
+					}
+					code, ok := runes[r]
+					if !ok {
+						code = allocate()
+						runes[r] = code
+						symbols[code] = []string{fmt.Sprintf("U%04X", r)}
+					}
+					levels[i] = append(levels[i], code)
+				}
+			}
+			km.outputs[key.ID] = levels
+		}
+	}
+
+	km.text = append([]byte(keymapText(symbols)), 0)
+	return km
+}
+
+// keymapText writes an XKB keymap with the given keysyms by evdev code.
+// XKB key codes are evdev codes plus 8.
+func keymapText(symbols map[uint32][]string) string {
+	codes := make([]uint32, 0, len(symbols))
+	for code := range symbols {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+
+	maximum := uint32(255)
+	if last := codes[len(codes)-1] + 8; last > maximum {
+		maximum = last
+	}
+
+	var b strings.Builder
+	b.WriteString("xkb_keymap {\n")
+	fmt.Fprintf(&b, "\txkb_keycodes \"oskway\" {\n\t\tminimum = 8;\n\t\tmaximum = %d;\n", maximum)
+	for _, code := range codes {
+		fmt.Fprintf(&b, "\t\t<I%d> = %d;\n", code+8, code+8)
+	}
+	b.WriteString("\t};\n")
+	b.WriteString("\txkb_types \"oskway\" { include \"complete\" };\n")
+	b.WriteString("\txkb_compat \"oskway\" { include \"complete\" };\n")
+	b.WriteString("\txkb_symbols \"oskway\" {\n")
+	for _, code := range codes {
+		fmt.Fprintf(&b, "\t\tkey <I%d> { [ %s ] };\n", code+8, strings.Join(symbols[code], ", "))
+	}
+
+	// Modifier keys are bound to the masks VirtualKeyboard sends
+	modifierKeys := make(map[uint32][]string)
+	for code, mask := range modifierMasks {
+		modifierKeys[mask] = append(modifierKeys[mask], fmt.Sprintf("<I%d>", code+8))
+	}
+	masks := make([]uint32, 0, len(modifierKeys))
+	for mask := range modifierKeys {
+		masks = append(masks, mask)
+	}
+	sort.Slice(masks, func(i, j int) bool { return masks[i] < masks[j] })
+	for _, mask := range masks {
+		keys := modifierKeys[mask]
+		sort.Strings(keys)
+		fmt.Fprintf(&b, "\t\tmodifier_map %s { %s };\n", modifierNames[mask], strings.Join(keys, ", "))
+	}
+	b.WriteString("\t};\n};\n")
+	return b.String()
+}
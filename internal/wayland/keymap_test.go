@@ -0,0 +1,129 @@
+package wayland
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// outputLayout has keys typing characters missing from a US keymap
+func outputLayout(t *testing.T) *keyboard.Layout {
+	t.Helper()
+	var layout keyboard.Layout
+	err := json.Unmarshal([]byte(`{
+		"name": "outputs", "width": 300, "height": 60,
+		"keys": [
+			{"id": "a", "label": "A", "code": 30},
+			{"id": "euro", "label": "€", "output": "€"},
+			{"id": "e_acute", "label": "é", "output": ["é", "É"]},
+			{"id": "smile", "label": "🙂", "code": -7, "output": "🙂"},
+			{"id": "euros", "label": "€€", "output": "€€\n"}
+		]
+	}`), &layout)
+	if err != nil {
+		t.Fatalf("json.Unmarshal: %v", err)
+	}
+	return &layout
+}
+
+func TestKeymapGivesEachOutputCharacterACode(t *testing.T) {
+	km := newKeymap(outputLayout(t))
+	text := string(km.text)
+
+	codes := make(map[uint32]bool)
+	for _, id := range []string{"euro", "e_acute", "smile"} {
+		for _, level := range km.outputs[id] {
+			if len(level) != 1 {
+				t.Fatalf("%s level codes = %v, want one code", id, level)
+			}
+			code := level[0]
+			if usKeysyms[code] != nil || code == 30 || codes[code] {
+				t.Errorf("%s was given code %d, which is already in use", id, code)
+			}
+			codes[code] = true
+		}
+	}
+
+	euro := km.outputs["euro"][0][0]
+	if want := fmt.Sprintf("key <I%d> { [ U20AC ] };", euro+8); !strings.Contains(text, want) {
+		t.Errorf("keymap lacks %q", want)
+	}
+	if want := fmt.Sprintf("key <I%d> { [ U1F642 ] };", km.outputs["smile"][0][0]+8); !strings.Contains(text, want) {
+		t.Errorf("keymap lacks %q", want)
+	}
+	if upper := km.outputs["e_acute"][1][0]; !strings.Contains(text, fmt.Sprintf("key <I%d> { [ U00C9 ] };", upper+8)) {
+		t.Errorf("keymap lacks the shifted level of e_acute")
+	}
+
+	// Repeated characters share a code, and control characters use theirs
+	if got, want := km.outputs["euros"][0], []uint32{euro, euro, 28}; !reflect.DeepEqual(got, want) {
+		t.Errorf("euros codes = %v, want %v", got, want)
+	}
+
+	for _, want := range []string{"key <I38> { [ a, A ] };", "modifier_map Shift { <I50>, <I62> };", "modifier_map Mod5 { <I108> };"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("keymap lacks %q", want)
+		}
+	}
+	if text[len(text)-1] != 0 {
+		t.Error("keymap is not NUL-terminated")
+	}
+}
+
+func TestVirtualKeyboardTypesOutputAtShiftLevel(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+	layout := outputLayout(t)
+	if err := vk.SetLayout(layout); err != nil {
+		t.Fatalf("SetLayout: %v", err)
+	}
+	expectMessages(t, compositor, "keymap", "keymap", "modifiers 0 0 0 0")
+	lower := vk.keymap.outputs["e_acute"][0][0]
+	upper := vk.keymap.outputs["e_acute"][1][0]
+
+	vk.HandleKey(keyboard.KeyEvent{Key: layout.Keys[2], State: keyboard.KeyStatePressed})
+	vk.HandleKey(keyboard.KeyEvent{Key: layout.Keys[2], State: keyboard.KeyStateReleased})
+	expectMessages(t, compositor, fmt.Sprintf("key %d 1", lower), fmt.Sprintf("key %d 0", lower))
+
+	// A latched Shift picks the second level, and is hidden while typing it
+	vk.Press(42)
+	vk.Release(42)
+	compositor.take()
+	vk.HandleKey(keyboard.KeyEvent{Key: layout.Keys[2], State: keyboard.KeyStatePressed})
+	expectMessages(t, compositor,
+		"modifiers 0 0 0 0",
+		fmt.Sprintf("key %d 1", upper), fmt.Sprintf("key %d 0", upper),
+		"modifiers 0 0 0 0")
+
+	// The euro has no shifted level, so Shift falls back to the first
+	vk.Press(42)
+	vk.Release(42)
+	compositor.take()
+	euro := vk.keymap.outputs["euro"][0][0]
+	vk.HandleKey(keyboard.KeyEvent{Key: layout.Keys[1], State: keyboard.KeyStatePressed})
+	expectMessages(t, compositor,
+		"modifiers 0 0 0 0",
+		fmt.Sprintf("key %d 1", euro), fmt.Sprintf("key %d 0", euro),
+		"modifiers 0 0 0 0")
+}
+
+func TestVirtualKeyboardUploadsKeymapOnlyWhenLayoutChanges(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+	compositor.take()
+
+	// A layout without outputs needs no more than the US keys
+	if err := vk.SetLayout(&keyboard.Layout{Keys: []*keyboard.Key{{ID: "a", Code: 30}}}); err != nil {
+		t.Fatalf("SetLayout: %v", err)
+	}
+	expectMessages(t, compositor)
+
+	vk.Press(30)
+	compositor.take()
+	if err := vk.SetLayout(outputLayout(t)); err != nil {
+		t.Fatalf("SetLayout: %v", err)
+	}
+	expectMessages(t, compositor, "key 30 0", "keymap", "modifiers 0 0 0 0")
+}
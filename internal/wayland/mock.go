@@ -2,49 +2,265 @@ package wayland
 
 import (
 	"fmt"
+	"image"
+	"sync"
 	"time"
 )
 
-// MockClient represents a mock Wayland client for testing
+// mockFrameInterval is the delay before a requested frame callback fires
+const mockFrameInterval = 16 * time.Millisecond // ~60 FPS
+
+// MockClient represents a mock Wayland client for testing. Tests can script
+// input with timestamps, which the client sends to its dispatcher when its
+// clock reaches them, and inspect the surfaces it created and how often it
+// was flushed.
 type MockClient struct {
-	running bool
+	events chan struct{}
+	frames chan uint32
+
+	dispatcher *EventDispatcher
+
+	mutex    sync.Mutex
+	running  bool
+	clock    Clock
+	start    time.Time
+	serial   uint32
+	pending  []Event
+	flushes  int
+	surfaces []SurfaceConfig
+	scale    float64
 }
 
-// NewMockClient creates a new mock Wayland client
+// NewMockClient creates a new mock Wayland client running on the system
+// clock
 func NewMockClient() *MockClient {
 	return &MockClient{
 		running: true,
+		events:  make(chan struct{}, 1),
+		frames:  make(chan uint32, 1),
+		clock:   SystemClock,
+		start:   time.Now(),
+		scale:   1,
+	}
+}
+
+// SetClock makes the client keep time with clock, from which the
+// timestamps of scripted input are counted. It must be called before any
+// input is queued or frame requested.
+func (c *MockClient) SetClock(clock Clock) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.clock = clock
+	c.start = clock.Now()
+}
+
+// Clock returns the clock the client keeps time with
+func (c *MockClient) Clock() Clock {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.clock
+}
+
+// now returns the time since the client started in milliseconds, as
+// carried by input events and frame callbacks
+func (c *MockClient) now() uint32 {
+	clock := c.Clock()
+	return uint32(clock.Now().Sub(c.start).Milliseconds())
+}
+
+// Queue scripts an event for when the clock reaches at after the client
+// started. Input events have their Time set to at and their Serial, where
+// they have one, numbered. Events due at the same time keep the order they
+// were queued in.
+func (c *MockClient) Queue(at time.Duration, event Event) {
+	c.mutex.Lock()
+	c.serial++
+	ms := uint32(at.Milliseconds())
+	switch e := event.(type) {
+	case *PointerMotionEvent:
+		e.Time = ms
+	case *PointerButtonEvent:
+		e.Time, e.Serial = ms, c.serial
+	case *PointerAxisEvent:
+		e.Time = ms
+	case *KeyboardEvent:
+		e.Time, e.Serial = ms, c.serial
+	case *TouchEvent:
+		e.Time, e.Serial = ms, c.serial
 	}
+	clock, due := c.clock, at-c.clock.Now().Sub(c.start)
+	c.mutex.Unlock()
+
+	// Once due the event is pending, and Events signalled so that the next
+	// Dispatch sends it
+	clock.AfterFunc(due, func() {
+		c.mutex.Lock()
+		c.pending = append(c.pending, event)
+		c.mutex.Unlock()
+		select {
+		case c.events <- struct{}{}:
+		default:
+		}
+	})
+}
+
+// Surfaces returns the configurations of the surfaces created so far,
+// oldest first
+func (c *MockClient) Surfaces() []SurfaceConfig {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return append([]SurfaceConfig(nil), c.surfaces...)
+}
+
+// Flushes returns how many times the client was flushed
+func (c *MockClient) Flushes() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.flushes
 }
 
 // Close cleans up the mock client
 func (c *MockClient) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	c.running = false
 }
 
-// CreateSurface creates a mock surface
-func (c *MockClient) CreateSurface() error {
-	fmt.Println("Mock: Created surface")
+// CreateSurface creates a mock surface and configures it at the requested
+// size, using the keyboard size where the compositor would choose
+func (c *MockClient) CreateSurface(config SurfaceConfig) error {
+	c.mutex.Lock()
+	c.surfaces = append(c.surfaces, config)
+	c.mutex.Unlock()
+	if c.dispatcher != nil {
+		c.dispatcher.SendEvent(&ShellEvent{Width: uint32(config.Width), Height: uint32(config.Height)})
+	}
 	return nil
 }
 
-// Dispatch processes mock events
+// Dispatch sends the scripted input that is due to the dispatcher
 func (c *MockClient) Dispatch() error {
-	if !c.running {
+	if !c.IsRunning() {
 		return fmt.Errorf("mock client closed")
 	}
-	
-	// Simulate event processing delay
-	time.Sleep(16 * time.Millisecond) // ~60 FPS
+	return c.DispatchPending()
+}
+
+// DispatchPending sends the scripted input that is due to the dispatcher
+func (c *MockClient) DispatchPending() error {
+	c.mutex.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.mutex.Unlock()
+
+	if c.dispatcher != nil {
+		for _, event := range pending {
+			c.dispatcher.SendEvent(event)
+		}
+	}
+	return nil
+}
+
+// Events returns a channel that receives a value when scripted input falls
+// due
+func (c *MockClient) Events() <-chan struct{} {
+	return c.events
+}
+
+// RequestFrame delivers a frame callback after one frame interval on the
+// client's clock
+func (c *MockClient) RequestFrame() error {
+	c.Clock().AfterFunc(mockFrameInterval, func() {
+		// Replace a callback that was not received yet
+		now := c.now()
+		for {
+			select {
+			case c.frames <- now:
+				return
+			default:
+				select {
+				case <-c.frames:
+				default:
+				}
+			}
+		}
+	})
+	return nil
+}
+
+// SetEventDispatcher sets the dispatcher mock input is sent to
+func (c *MockClient) SetEventDispatcher(dispatcher *EventDispatcher) {
+	c.dispatcher = dispatcher
+}
+
+// Frames returns the channel mock frame callbacks are delivered on
+func (c *MockClient) Frames() <-chan uint32 {
+	return c.frames
+}
+
+// Damage mock damage operation
+func (c *MockClient) Damage(x, y, width, height int) error {
+	return nil
+}
+
+// Resize mock buffer allocation
+func (c *MockClient) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid buffer size %dx%d", width, height)
+	}
+	return nil
+}
+
+// Attach mock presentation of a rendered frame
+func (c *MockClient) Attach(img *image.RGBA) error {
+	return c.Resize(img.Rect.Dx(), img.Rect.Dy())
+}
+
+// SetScale records the scale frames are drawn at
+func (c *MockClient) SetScale(scale float64) error {
+	if err := checkScale(scale, true); err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.scale = scale
+	return nil
+}
+
+// Scale returns the scale last set with SetScale
+func (c *MockClient) Scale() float64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.scale
+}
+
+// VirtualKeyboard reports that the mock cannot type into applications
+func (c *MockClient) VirtualKeyboard() (*VirtualKeyboard, error) {
+	return nil, fmt.Errorf("mock client has no virtual keyboard")
+}
+
+// InputMethod reports that the mock has no input method, so the keyboard
+// stays shown
+func (c *MockClient) InputMethod() (*InputMethod, error) {
+	return nil, fmt.Errorf("mock client has no input method")
+}
+
+// SetVisible accepts any visibility
+func (c *MockClient) SetVisible(visible bool) error {
 	return nil
 }
 
-// Flush mock flush operation
+// Flush counts the flush; the mock has no requests to send
 func (c *MockClient) Flush() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.flushes++
 	return nil
 }
 
 // IsRunning returns whether the mock client is running
 func (c *MockClient) IsRunning() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
 	return c.running
 }
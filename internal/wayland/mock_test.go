@@ -0,0 +1,101 @@
+package wayland
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestVirtualClockWakesWaitersInDeadlineOrder(t *testing.T) {
+	clock := NewVirtualClock()
+	start := clock.Now()
+	var woken []time.Duration
+	wake := func() { woken = append(woken, clock.Now().Sub(start)) }
+
+	clock.AfterFunc(30*time.Millisecond, wake)
+	clock.AfterFunc(10*time.Millisecond, func() {
+		wake()
+		// Waiters added while advancing are woken if they fall due
+		clock.AfterFunc(5*time.Millisecond, wake)
+		clock.AfterFunc(50*time.Millisecond, wake)
+	})
+	after, _ := clock.NewTimer(20 * time.Millisecond)
+	_, stop := clock.NewTimer(25 * time.Millisecond)
+	if !stop() || stop() {
+		t.Error("stop did not report the timer pending only once")
+	}
+
+	clock.Advance(40 * time.Millisecond)
+	ms := time.Millisecond
+	if want := []time.Duration{10 * ms, 15 * ms, 30 * ms}; !slices.Equal(woken, want) {
+		t.Errorf("woken at %v, want %v", woken, want)
+	}
+	if at := (<-after).Sub(start); at != 20*time.Millisecond {
+		t.Errorf("timer fired at %v, want 20ms", at)
+	}
+	if next, ok := clock.Next(); !ok || next != 20*time.Millisecond || clock.Waiting() != 1 {
+		t.Errorf("Next = %v, %v with %d waiters, want only the waiter at 60ms", next, ok, clock.Waiting())
+	}
+}
+
+func TestMockClientDeliversScriptedInputOnItsClock(t *testing.T) {
+	clock := NewVirtualClock()
+	client := NewMockClient()
+	client.SetClock(clock)
+	dispatcher := NewEventDispatcher()
+	client.SetEventDispatcher(dispatcher)
+
+	client.Queue(20*time.Millisecond, &KeyboardEvent{Key: 30, State: 1})
+	client.Queue(10*time.Millisecond, &TouchEvent{ID: 1, X: 5, Y: 6, State: TouchStateDown})
+	client.Queue(20*time.Millisecond, &PointerButtonEvent{X: 1, Y: 2, Button: 1, State: 1})
+	if err := client.CreateSurface(DefaultSurfaceConfig(100, 50)); err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Next() // the configure
+	client.Flush()
+
+	clock.Advance(15 * time.Millisecond)
+	<-client.Events()
+	client.Dispatch()
+	if touch := next(t, dispatcher).(*TouchEvent); touch.Time != 10 || touch.Serial != 2 || touch.X != 5 {
+		t.Errorf("touch = %+v", touch)
+	}
+
+	clock.Advance(5 * time.Millisecond)
+	client.Dispatch()
+	if key := next(t, dispatcher).(*KeyboardEvent); key.Time != 20 || key.Key != 30 {
+		t.Errorf("key = %+v, want the key queued first of those due at 20ms", key)
+	}
+	if pointer := next(t, dispatcher).(*PointerButtonEvent); pointer.Time != 20 || pointer.Serial != 3 {
+		t.Errorf("pointer = %+v", pointer)
+	}
+
+	// Frame callbacks wait for the clock too
+	client.RequestFrame()
+	select {
+	case <-client.Frames():
+		t.Fatal("frame callback before the frame interval passed")
+	default:
+	}
+	clock.Advance(mockFrameInterval)
+	if now := <-client.Frames(); now != 36 {
+		t.Errorf("frame callback time = %d, want 36", now)
+	}
+
+	if surfaces := client.Surfaces(); len(surfaces) != 1 || surfaces[0].Width != 100 {
+		t.Errorf("surfaces = %+v", surfaces)
+	}
+	if client.Flushes() != 1 {
+		t.Errorf("flushed %d times, want 1", client.Flushes())
+	}
+}
+
+// next takes the next queued event, which must be there
+func next(t *testing.T, dispatcher *EventDispatcher) Event {
+	t.Helper()
+	event, ok := dispatcher.Next()
+	if !ok {
+		t.Fatal("no event queued")
+	}
+	return event
+}
@@ -0,0 +1,223 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#cgo pkg-config: wayland-client
+#cgo CFLAGS: -I${SRCDIR}/../../generated
+#cgo LDFLAGS: -L${SRCDIR}/../../generated -lviewporter-protocol -lfractional-scale-protocol
+#include <wayland-client.h>
+#include <stdint.h>
+#include "viewporter-client-protocol.h"
+#include "fractional-scale-v1-client-protocol.h"
+
+extern void oskOutputGeometry(uintptr_t handle, int32_t x, int32_t y, int32_t physical_width,
+	int32_t physical_height, char *make, char *model, int32_t transform);
+extern void oskOutputMode(uintptr_t handle, uint32_t flags, int32_t width, int32_t height, int32_t refresh);
+extern void oskOutputDone(uintptr_t handle);
+extern void oskOutputScale(uintptr_t handle, int32_t factor);
+extern void oskOutputName(uintptr_t handle, char *name);
+extern void oskOutputDescription(uintptr_t handle, char *description);
+extern void oskSurfaceEnter(uintptr_t handle, uintptr_t output, int entered);
+extern void oskPreferredScale(uintptr_t handle, uint32_t scale);
+
+static void osk_output_geometry(void *data, struct wl_output *output, int32_t x, int32_t y,
+		int32_t physical_width, int32_t physical_height, int32_t subpixel,
+		const char *make, const char *model, int32_t transform) {
+	oskOutputGeometry((uintptr_t)data, x, y, physical_width, physical_height,
+		(char *)make, (char *)model, transform);
+}
+
+static void osk_output_mode(void *data, struct wl_output *output, uint32_t flags,
+		int32_t width, int32_t height, int32_t refresh) {
+	oskOutputMode((uintptr_t)data, flags, width, height, refresh);
+}
+
+static void osk_output_done(void *data, struct wl_output *output) {
+	oskOutputDone((uintptr_t)data);
+}
+
+static void osk_output_scale(void *data, struct wl_output *output, int32_t factor) {
+	oskOutputScale((uintptr_t)data, factor);
+}
+
+static void osk_output_name(void *data, struct wl_output *output, const char *name) {
+	oskOutputName((uintptr_t)data, (char *)name);
+}
+
+static void osk_output_description(void *data, struct wl_output *output, const char *description) {
+	oskOutputDescription((uintptr_t)data, (char *)description);
+}
+
+static const struct wl_output_listener osk_output_listener = {
+	.geometry = osk_output_geometry,
+	.mode = osk_output_mode,
+	.done = osk_output_done,
+	.scale = osk_output_scale,
+	.name = osk_output_name,
+	.description = osk_output_description,
+};
+
+static void osk_output_add_listener(struct wl_output *output, uintptr_t handle) {
+	wl_output_add_listener(output, &osk_output_listener, (void *)handle);
+}
+
+// osk_output_release sends the release request only on versions that have
+// it
+static void osk_output_release(struct wl_output *output) {
+	if (wl_output_get_version(output) >= WL_OUTPUT_RELEASE_SINCE_VERSION) {
+		wl_output_release(output);
+	} else {
+		wl_output_destroy(output);
+	}
+}
+
+// Outputs carry the handle of their Go state as user data, which is how
+// the surface listener tells them apart
+static void osk_surface_enter(void *data, struct wl_surface *surface, struct wl_output *output) {
+	if (output != NULL) {
+		oskSurfaceEnter((uintptr_t)data, (uintptr_t)wl_output_get_user_data(output), 1);
+	}
+}
+
+static void osk_surface_leave(void *data, struct wl_surface *surface, struct wl_output *output) {
+	if (output != NULL) {
+		oskSurfaceEnter((uintptr_t)data, (uintptr_t)wl_output_get_user_data(output), 0);
+	}
+}
+
+static const struct wl_surface_listener osk_surface_listener = {
+	.enter = osk_surface_enter,
+	.leave = osk_surface_leave,
+};
+
+static void osk_surface_add_listener(struct wl_surface *surface, uintptr_t handle) {
+	wl_surface_add_listener(surface, &osk_surface_listener, (void *)handle);
+}
+
+static void osk_preferred_scale(void *data, struct wp_fractional_scale_v1 *fractional_scale, uint32_t scale) {
+	oskPreferredScale((uintptr_t)data, scale);
+}
+
+static const struct wp_fractional_scale_v1_listener osk_fractional_scale_listener = {
+	.preferred_scale = osk_preferred_scale,
+};
+
+static struct wp_fractional_scale_v1 *osk_get_fractional_scale(struct wp_fractional_scale_manager_v1 *manager,
+		struct wl_surface *surface, uintptr_t handle) {
+	struct wp_fractional_scale_v1 *fractional_scale =
+		wp_fractional_scale_manager_v1_get_fractional_scale(manager, surface);
+	if (fractional_scale != NULL) {
+		wp_fractional_scale_v1_add_listener(fractional_scale, &osk_fractional_scale_listener, (void *)handle);
+	}
+	return fractional_scale;
+}
+*/
+import "C"
+
+import "runtime/cgo"
+
+// output is a bound wl_output and what it reported. Its handle is the
+// proxy user data, through which C callbacks find it.
+type output struct {
+	proxy  *C.struct_wl_output
+	client *Client
+	handle cgo.Handle
+	outputState
+}
+
+// bindOutput binds an output and tracks its state
+func (c *Client) bindOutput(name, version uint32) {
+	version = negotiate(version, outputVersion)
+	o := &output{client: c, outputState: newOutputState(name, version)}
+	o.proxy = (*C.struct_wl_output)(c.bind(name, &C.wl_output_interface, version))
+	o.handle = cgo.NewHandle(o)
+	C.osk_output_add_listener(o.proxy, C.uintptr_t(o.handle))
+	c.outputs[name] = o
+}
+
+// releaseOutput releases an output, using the release request when the
+// output version has it
+func (c *Client) releaseOutput(o *output) {
+	C.osk_output_release(o.proxy)
+	o.handle.Delete()
+}
+
+// bindViewporter binds wp_viewporter, which lets buffers of fractionally
+// scaled frames be shown at the surface size
+func (c *Client) bindViewporter(name, version uint32) {
+	if c.viewporter != nil {
+		return
+	}
+	c.viewporter = (*C.struct_wp_viewporter)(c.bind(name, &C.wp_viewporter_interface, negotiate(version, viewporterVersion)))
+}
+
+// bindFractionalScaleManager binds wp_fractional_scale_manager_v1
+func (c *Client) bindFractionalScaleManager(name, version uint32) {
+	if c.fractionalScaleManager != nil {
+		return
+	}
+	c.fractionalScaleManager = (*C.struct_wp_fractional_scale_manager_v1)(c.bind(name,
+		&C.wp_fractional_scale_manager_v1_interface, negotiate(version, fractionalScaleManagerVersion)))
+}
+
+// trackScale follows the outputs the new surface is shown on and, when the
+// compositor offers both fractional scaling and viewports, the fractional
+// scale it prefers for the surface
+func (c *Client) trackScale() {
+	C.osk_surface_add_listener(c.surface, C.uintptr_t(c.handle))
+	if c.viewporter == nil || c.fractionalScaleManager == nil {
+		return
+	}
+	c.viewport = C.wp_viewporter_get_viewport(c.viewporter, c.surface)
+	c.fractionalScaleObject = C.osk_get_fractional_scale(c.fractionalScaleManager, c.surface, C.uintptr_t(c.handle))
+}
+
+// SetScale sets the scale the frames attached from now on are drawn at.
+// Frames are scale times the surface size; whole scales are presented with
+// the surface buffer scale, fractional ones through a viewport, which is
+// only there when the compositor offers fractional scaling.
+func (c *Client) SetScale(scale float64) error {
+	if err := checkScale(scale, c.viewport != nil); err != nil {
+		return err
+	}
+	c.drawScale = scale
+	return nil
+}
+
+// applyScale tells the compositor the scale of a frame of width×height
+// about to be attached. The buffer scale is only changed along with a
+// buffer, since the attached one must be a multiple of it.
+func (c *Client) applyScale(width, height int) {
+	if c.viewport != nil {
+		w, h := viewportSize(width, height, c.drawScale)
+		C.wp_viewport_set_destination(c.viewport, C.int32_t(w), C.int32_t(h))
+		return
+	}
+	if scale := int32(c.drawScale); scale != c.bufferScale && C.wl_surface_get_version(c.surface) >= 3 {
+		C.wl_surface_set_buffer_scale(c.surface, C.int32_t(scale))
+		c.bufferScale = scale
+	}
+}
+
+// destroyScale destroys the fractional scale and viewport objects and
+// their globals
+func (c *Client) destroyScale() {
+	if c.fractionalScaleObject != nil {
+		C.wp_fractional_scale_v1_destroy(c.fractionalScaleObject)
+		c.fractionalScaleObject = nil
+	}
+	if c.viewport != nil {
+		C.wp_viewport_destroy(c.viewport)
+		c.viewport = nil
+	}
+	if c.fractionalScaleManager != nil {
+		C.wp_fractional_scale_manager_v1_destroy(c.fractionalScaleManager)
+		c.fractionalScaleManager = nil
+	}
+	if c.viewporter != nil {
+		C.wp_viewporter_destroy(c.viewporter)
+		c.viewporter = nil
+	}
+}
@@ -0,0 +1,208 @@
+package wayland
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"math"
+	"slices"
+	"strings"
+)
+
+// outputModeCurrent flags the wl_output mode in use
+const outputModeCurrent = 0x1
+
+// fractionalScaleDenominator is the denominator of the scales
+// wp_fractional_scale_v1 sends
+const fractionalScaleDenominator = 120
+
+// outputState is what the compositor said about an output. It is reported
+// once done ends a burst of changes; outputs bound before version 2 have no
+// done event and are reported after every change.
+type outputState struct {
+	info    OutputEvent
+	version uint32
+	// entered is set while the keyboard surface is shown on the output
+	entered bool
+}
+
+// newOutputState starts the state of the output with registry name id
+func newOutputState(id, version uint32) outputState {
+	return outputState{info: OutputEvent{ID: id, Scale: 1}, version: version}
+}
+
+// outputGeometry records the position, physical size and transform of an
+// output
+func (c *Client) outputGeometry(o *outputState, x, y, physicalWidth, physicalHeight int32,
+	manufacturer, model string, transform int32) {
+	o.info.X, o.info.Y = x, y
+	o.info.PhysicalWidth, o.info.PhysicalHeight = physicalWidth, physicalHeight
+	o.info.Make, o.info.Model = manufacturer, model
+	o.info.Transform = transform
+	c.outputChanged(o)
+}
+
+// outputMode records the current mode of an output; the other modes it
+// lists are ignored
+func (c *Client) outputMode(o *outputState, flags uint32, width, height, refresh int32) {
+	if flags&outputModeCurrent == 0 {
+		return
+	}
+	o.info.Width, o.info.Height, o.info.Refresh = width, height, refresh
+	c.outputChanged(o)
+}
+
+// outputScale records the buffer scale the compositor suggests for an
+// output
+func (c *Client) outputScale(o *outputState, factor int32) {
+	o.info.Scale = max(factor, 1)
+	c.outputChanged(o)
+}
+
+// outputName records the connector name of an output
+func (c *Client) outputName(o *outputState, name string) {
+	o.info.Name = name
+	c.outputChanged(o)
+}
+
+// outputDescription records the human readable description of an output
+func (c *Client) outputDescription(o *outputState, description string) {
+	o.info.Description = description
+	c.outputChanged(o)
+}
+
+// outputChanged reports outputs that send no done event after each change
+func (c *Client) outputChanged(o *outputState) {
+	if o.version < 2 {
+		c.outputDone(o)
+	}
+}
+
+// outputDone reports the state of an output, and the scale of the surface
+// if the output scale changed it
+func (c *Client) outputDone(o *outputState) {
+	info := o.info
+	c.sendEvent(&info)
+	c.updateScale()
+}
+
+// outputRemoved reports an output that went away. The caller has already
+// dropped it from the outputs.
+func (c *Client) outputRemoved(id uint32) {
+	c.sendEvent(&OutputEvent{ID: id, Removed: true})
+	c.updateScale()
+}
+
+// outputEntered records the surface being shown on an output or leaving
+// it
+func (c *Client) outputEntered(o *outputState, entered bool) {
+	o.entered = entered
+	c.updateScale()
+}
+
+// fractionalScaleChanged records the scale wp_fractional_scale_v1 prefers,
+// in 120ths
+func (c *Client) fractionalScaleChanged(numerator uint32) {
+	c.fractionalScale = float64(numerator) / fractionalScaleDenominator
+	c.updateScale()
+}
+
+// outputNamed returns the output with the given connector name. The error
+// lists the names there are.
+func (c *Client) outputNamed(name string) (*output, error) {
+	var names []string
+	for _, o := range c.outputs {
+		if o.info.Name == name {
+			return o, nil
+		}
+		if o.info.Name != "" {
+			names = append(names, o.info.Name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no output named %s: the compositor announced no named outputs", name)
+	}
+	slices.Sort(names)
+	return nil, fmt.Errorf("no output named %s, the outputs are %s", name, strings.Join(names, ", "))
+}
+
+// pinOutput finds the output config.Output names. Only layer-shell can
+// place the surface on an output; the xdg_toplevel fallback goes where the
+// compositor puts it.
+func (c *Client) pinOutput(config SurfaceConfig) (*output, error) {
+	if config.Output == "" {
+		return nil, nil
+	}
+	if c.layerShell == nil {
+		log.Printf("Compositor lacks zwlr_layer_shell_v1, ignoring output %s", config.Output)
+		return nil, nil
+	}
+	pinned, err := c.outputNamed(config.Output)
+	if err != nil {
+		return nil, err
+	}
+	c.pinnedOutput = config.Output
+	return pinned, nil
+}
+
+// preferredScale returns the scale the surface should be drawn at: the
+// fractional scale the compositor prefers, or else the largest scale of
+// the outputs the surface is shown on. Until it is shown, the surface is
+// expected on the output it is pinned to, or on the only output there is.
+func (c *Client) preferredScale() float64 {
+	if c.fractionalScale > 0 {
+		return c.fractionalScale
+	}
+	scale := int32(0)
+	for _, o := range c.outputs {
+		if o.entered {
+			scale = max(scale, o.info.Scale)
+		}
+	}
+	if scale == 0 {
+		if c.pinnedOutput != "" {
+			if o, err := c.outputNamed(c.pinnedOutput); err == nil {
+				scale = o.info.Scale
+			}
+		} else if len(c.outputs) == 1 {
+			for _, o := range c.outputs {
+				scale = o.info.Scale
+			}
+		}
+	}
+	return float64(max(scale, 1))
+}
+
+// updateScale reports the preferred scale when it changed
+func (c *Client) updateScale() {
+	if scale := c.preferredScale(); scale != c.reportedScale {
+		c.reportedScale = scale
+		c.sendEvent(&ScaleEvent{Scale: scale})
+	}
+}
+
+// checkScale tells whether frames drawn at scale can be presented: a
+// viewport shows any scale, the buffer scale only whole ones
+func checkScale(scale float64, viewport bool) error {
+	if scale <= 0 || math.IsInf(scale, 0) || math.IsNaN(scale) {
+		return fmt.Errorf("invalid scale %g", scale)
+	}
+	if !viewport && scale != math.Trunc(scale) {
+		return fmt.Errorf("fractional scale %g needs wp_viewporter, which the compositor lacks", scale)
+	}
+	return nil
+}
+
+// bufferRect converts a rectangle in surface pixels to the pixels of a
+// frame drawn at scale, rounding outwards
+func bufferRect(r image.Rectangle, scale float64) image.Rectangle {
+	return image.Rect(
+		int(math.Floor(float64(r.Min.X)*scale)), int(math.Floor(float64(r.Min.Y)*scale)),
+		int(math.Ceil(float64(r.Max.X)*scale)), int(math.Ceil(float64(r.Max.Y)*scale)))
+}
+
+// viewportSize returns the surface size a frame of width×height drawn at
+// scale is shown at
+func viewportSize(width, height int, scale float64) (int, int) {
+	return int(math.Round(float64(width) / scale)), int(math.Round(float64(height) / scale))
+}
@@ -0,0 +1,120 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#cgo pkg-config: wayland-client
+#include <wayland-client.h>
+#include <stdint.h>
+
+extern void oskRegistryGlobal(uintptr_t handle, uint32_t name, char *interface, uint32_t version);
+extern void oskRegistryGlobalRemove(uintptr_t handle, uint32_t name);
+
+static void osk_registry_global(void *data, struct wl_registry *registry,
+		uint32_t name, const char *interface, uint32_t version) {
+	oskRegistryGlobal((uintptr_t)data, name, (char *)interface, version);
+}
+
+static void osk_registry_global_remove(void *data, struct wl_registry *registry, uint32_t name) {
+	oskRegistryGlobalRemove((uintptr_t)data, name);
+}
+
+static const struct wl_registry_listener osk_registry_listener = {
+	.global = osk_registry_global,
+	.global_remove = osk_registry_global_remove,
+};
+
+static int osk_registry_add_listener(struct wl_registry *registry, uintptr_t handle) {
+	return wl_registry_add_listener(registry, &osk_registry_listener, (void *)handle);
+}
+
+static void *osk_registry_bind(struct wl_registry *registry, uint32_t name,
+		const struct wl_interface *interface, uint32_t version) {
+	return wl_registry_bind(registry, name, interface, version);
+}
+*/
+import "C"
+
+import (
+	"log"
+	"unsafe"
+)
+
+// global binds the globals the keyboard uses as the compositor announces
+// them and reports every global as a registry event
+func (c *Client) global(name uint32, iface string, version uint32) {
+	c.globals[name] = iface
+
+	switch iface {
+	case "wl_compositor":
+		if c.compositor == nil {
+			c.compositor = (*C.struct_wl_compositor)(c.bind(name, &C.wl_compositor_interface, negotiate(version, compositorVersion)))
+		}
+	case "wl_shm":
+		if c.shm == nil {
+			c.shm = (*C.struct_wl_shm)(c.bind(name, &C.wl_shm_interface, negotiate(version, shmVersion)))
+		}
+	case "wl_seat":
+		// Only the first seat drives the keyboard
+		if c.seat == nil {
+			c.seatName = name
+			c.seatVersion = negotiate(version, seatVersion)
+			c.seat = (*C.struct_wl_seat)(c.bind(name, &C.wl_seat_interface, c.seatVersion))
+			c.addSeatListener()
+		}
+	case "wl_output":
+		c.bindOutput(name, version)
+	case "zwlr_layer_shell_v1":
+		c.bindLayerShell(name, version)
+	case "xdg_wm_base":
+		c.bindWmBase(name, version)
+	case "wp_viewporter":
+		c.bindViewporter(name, version)
+	case "wp_fractional_scale_manager_v1":
+		c.bindFractionalScaleManager(name, version)
+	case "zwp_virtual_keyboard_manager_v1":
+		c.bindVirtualKeyboardManager(name, version)
+	case "zwp_input_method_manager_v2":
+		c.bindInputMethodManager(name, version)
+	}
+
+	c.sendEvent(&RegistryEvent{Name: name, Interface: iface, Version: version})
+}
+
+// globalRemove releases objects bound to a global that went away. Seats
+// and outputs come and go with hardware; the compositor, shm and shell
+// globals live as long as the compositor.
+func (c *Client) globalRemove(name uint32) {
+	iface, exists := c.globals[name]
+	if !exists {
+		return
+	}
+	delete(c.globals, name)
+
+	switch iface {
+	case "wl_seat":
+		if name == c.seatName && c.seat != nil {
+			c.releaseSeat()
+		}
+	case "wl_output":
+		if output, exists := c.outputs[name]; exists {
+			c.releaseOutput(output)
+			delete(c.outputs, name)
+			c.outputRemoved(name)
+		}
+	case "wl_compositor", "wl_shm", "zwlr_layer_shell_v1", "xdg_wm_base", "wp_viewporter",
+		"wp_fractional_scale_manager_v1", "zwp_virtual_keyboard_manager_v1", "zwp_input_method_manager_v2":
+		log.Printf("Wayland global %s (%d) removed by the compositor", iface, name)
+	}
+}
+
+// bind binds a global through the registry
+func (c *Client) bind(name uint32, iface *C.struct_wl_interface, version uint32) unsafe.Pointer {
+	return unsafe.Pointer(C.osk_registry_bind(c.registry, C.uint32_t(name), iface, C.uint32_t(version)))
+}
+
+// addRegistryListener starts receiving globals
+func (c *Client) addRegistryListener() {
+	C.osk_registry_add_listener(c.registry, C.uintptr_t(c.handle))
+}
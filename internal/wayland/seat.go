@@ -0,0 +1,240 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#cgo pkg-config: wayland-client
+#include <wayland-client.h>
+#include <stdint.h>
+
+extern void oskSeatCapabilities(uintptr_t handle, uint32_t capabilities);
+extern void oskPointerMotion(uintptr_t handle, uint32_t time, int32_t x, int32_t y);
+extern void oskPointerButton(uintptr_t handle, uint32_t serial, uint32_t time, uint32_t button, uint32_t state);
+extern void oskPointerAxis(uintptr_t handle, uint32_t time, uint32_t axis, int32_t value);
+extern void oskPointerFrame(uintptr_t handle);
+extern void oskKeyboardKeymap(uintptr_t handle, uint32_t format, int32_t fd, uint32_t size);
+extern void oskKeyboardKey(uintptr_t handle, uint32_t serial, uint32_t time, uint32_t key, uint32_t state);
+extern void oskTouchDown(uintptr_t handle, uint32_t serial, uint32_t time, int32_t id, int32_t x, int32_t y);
+extern void oskTouchUp(uintptr_t handle, uint32_t serial, uint32_t time, int32_t id);
+extern void oskTouchMotion(uintptr_t handle, uint32_t time, int32_t id, int32_t x, int32_t y);
+extern void oskTouchCancel(uintptr_t handle);
+
+static void osk_seat_capabilities(void *data, struct wl_seat *seat, uint32_t capabilities) {
+	oskSeatCapabilities((uintptr_t)data, capabilities);
+}
+
+static void osk_seat_name(void *data, struct wl_seat *seat, const char *name) {}
+
+static const struct wl_seat_listener osk_seat_listener = {
+	.capabilities = osk_seat_capabilities,
+	.name = osk_seat_name,
+};
+
+static void osk_pointer_enter(void *data, struct wl_pointer *pointer, uint32_t serial,
+		struct wl_surface *surface, wl_fixed_t x, wl_fixed_t y) {
+	oskPointerMotion((uintptr_t)data, 0, x, y);
+}
+
+static void osk_pointer_leave(void *data, struct wl_pointer *pointer, uint32_t serial,
+		struct wl_surface *surface) {}
+
+static void osk_pointer_motion(void *data, struct wl_pointer *pointer, uint32_t time,
+		wl_fixed_t x, wl_fixed_t y) {
+	oskPointerMotion((uintptr_t)data, time, x, y);
+}
+
+static void osk_pointer_button(void *data, struct wl_pointer *pointer, uint32_t serial,
+		uint32_t time, uint32_t button, uint32_t state) {
+	oskPointerButton((uintptr_t)data, serial, time, button, state);
+}
+
+static void osk_pointer_axis(void *data, struct wl_pointer *pointer, uint32_t time,
+		uint32_t axis, wl_fixed_t value) {
+	oskPointerAxis((uintptr_t)data, time, axis, value);
+}
+
+static void osk_pointer_frame(void *data, struct wl_pointer *pointer) {
+	oskPointerFrame((uintptr_t)data);
+}
+
+static void osk_pointer_axis_source(void *data, struct wl_pointer *pointer, uint32_t source) {}
+static void osk_pointer_axis_stop(void *data, struct wl_pointer *pointer, uint32_t time, uint32_t axis) {}
+static void osk_pointer_axis_discrete(void *data, struct wl_pointer *pointer, uint32_t axis, int32_t discrete) {}
+
+static const struct wl_pointer_listener osk_pointer_listener = {
+	.enter = osk_pointer_enter,
+	.leave = osk_pointer_leave,
+	.motion = osk_pointer_motion,
+	.button = osk_pointer_button,
+	.axis = osk_pointer_axis,
+	.frame = osk_pointer_frame,
+	.axis_source = osk_pointer_axis_source,
+	.axis_stop = osk_pointer_axis_stop,
+	.axis_discrete = osk_pointer_axis_discrete,
+};
+
+static void osk_keyboard_keymap(void *data, struct wl_keyboard *keyboard, uint32_t format,
+		int32_t fd, uint32_t size) {
+	oskKeyboardKeymap((uintptr_t)data, format, fd, size);
+}
+
+static void osk_keyboard_enter(void *data, struct wl_keyboard *keyboard, uint32_t serial,
+		struct wl_surface *surface, struct wl_array *keys) {}
+static void osk_keyboard_leave(void *data, struct wl_keyboard *keyboard, uint32_t serial,
+		struct wl_surface *surface) {}
+
+static void osk_keyboard_key(void *data, struct wl_keyboard *keyboard, uint32_t serial,
+		uint32_t time, uint32_t key, uint32_t state) {
+	oskKeyboardKey((uintptr_t)data, serial, time, key, state);
+}
+
+static void osk_keyboard_modifiers(void *data, struct wl_keyboard *keyboard, uint32_t serial,
+		uint32_t depressed, uint32_t latched, uint32_t locked, uint32_t group) {}
+static void osk_keyboard_repeat_info(void *data, struct wl_keyboard *keyboard, int32_t rate, int32_t delay) {}
+
+static const struct wl_keyboard_listener osk_keyboard_listener = {
+	.keymap = osk_keyboard_keymap,
+	.enter = osk_keyboard_enter,
+	.leave = osk_keyboard_leave,
+	.key = osk_keyboard_key,
+	.modifiers = osk_keyboard_modifiers,
+	.repeat_info = osk_keyboard_repeat_info,
+};
+
+static void osk_touch_down(void *data, struct wl_touch *touch, uint32_t serial, uint32_t time,
+		struct wl_surface *surface, int32_t id, wl_fixed_t x, wl_fixed_t y) {
+	oskTouchDown((uintptr_t)data, serial, time, id, x, y);
+}
+
+static void osk_touch_up(void *data, struct wl_touch *touch, uint32_t serial, uint32_t time, int32_t id) {
+	oskTouchUp((uintptr_t)data, serial, time, id);
+}
+
+static void osk_touch_motion(void *data, struct wl_touch *touch, uint32_t time, int32_t id,
+		wl_fixed_t x, wl_fixed_t y) {
+	oskTouchMotion((uintptr_t)data, time, id, x, y);
+}
+
+static void osk_touch_frame(void *data, struct wl_touch *touch) {}
+
+static void osk_touch_cancel(void *data, struct wl_touch *touch) {
+	oskTouchCancel((uintptr_t)data);
+}
+
+static const struct wl_touch_listener osk_touch_listener = {
+	.down = osk_touch_down,
+	.up = osk_touch_up,
+	.motion = osk_touch_motion,
+	.frame = osk_touch_frame,
+	.cancel = osk_touch_cancel,
+};
+
+static void osk_seat_add_listener(struct wl_seat *seat, uintptr_t handle) {
+	wl_seat_add_listener(seat, &osk_seat_listener, (void *)handle);
+}
+
+static struct wl_pointer *osk_seat_get_pointer(struct wl_seat *seat, uintptr_t handle) {
+	struct wl_pointer *pointer = wl_seat_get_pointer(seat);
+	wl_pointer_add_listener(pointer, &osk_pointer_listener, (void *)handle);
+	return pointer;
+}
+
+static struct wl_keyboard *osk_seat_get_keyboard(struct wl_seat *seat, uintptr_t handle) {
+	struct wl_keyboard *keyboard = wl_seat_get_keyboard(seat);
+	wl_keyboard_add_listener(keyboard, &osk_keyboard_listener, (void *)handle);
+	return keyboard;
+}
+
+static struct wl_touch *osk_seat_get_touch(struct wl_seat *seat, uintptr_t handle) {
+	struct wl_touch *touch = wl_seat_get_touch(seat);
+	wl_touch_add_listener(touch, &osk_touch_listener, (void *)handle);
+	return touch;
+}
+*/
+import "C"
+
+// addSeatListener starts receiving seat capabilities
+func (c *Client) addSeatListener() {
+	C.osk_seat_add_listener(c.seat, C.uintptr_t(c.handle))
+}
+
+// capabilities creates or releases input devices as the seat gains or loses
+// pointer, keyboard and touch capabilities
+func (c *Client) capabilities(caps uint32) {
+	hasPointer := caps&C.WL_SEAT_CAPABILITY_POINTER != 0
+	hasKeyboard := caps&C.WL_SEAT_CAPABILITY_KEYBOARD != 0
+	hasTouch := caps&C.WL_SEAT_CAPABILITY_TOUCH != 0
+
+	switch {
+	case hasPointer && c.pointer == nil:
+		c.pointer = C.osk_seat_get_pointer(c.seat, C.uintptr_t(c.handle))
+	case !hasPointer && c.pointer != nil:
+		c.releasePointer()
+	}
+
+	switch {
+	case hasKeyboard && c.keyboard == nil:
+		c.keyboard = C.osk_seat_get_keyboard(c.seat, C.uintptr_t(c.handle))
+	case !hasKeyboard && c.keyboard != nil:
+		c.releaseKeyboard()
+	}
+
+	switch {
+	case hasTouch && c.touch == nil:
+		c.touch = C.osk_seat_get_touch(c.seat, C.uintptr_t(c.handle))
+	case !hasTouch && c.touch != nil:
+		c.releaseTouch()
+	}
+}
+
+// releasePointer releases the pointer, using the release request when the
+// seat version has it
+func (c *Client) releasePointer() {
+	if c.seatVersion >= 3 {
+		C.wl_pointer_release(c.pointer)
+	} else {
+		C.wl_pointer_destroy(c.pointer)
+	}
+	c.pointer = nil
+}
+
+// releaseKeyboard releases the keyboard
+func (c *Client) releaseKeyboard() {
+	if c.seatVersion >= 3 {
+		C.wl_keyboard_release(c.keyboard)
+	} else {
+		C.wl_keyboard_destroy(c.keyboard)
+	}
+	c.keyboard = nil
+}
+
+// releaseTouch releases the touch device
+func (c *Client) releaseTouch() {
+	if c.seatVersion >= 3 {
+		C.wl_touch_release(c.touch)
+	} else {
+		C.wl_touch_destroy(c.touch)
+	}
+	c.touch = nil
+}
+
+// releaseSeat releases all input devices and the seat
+func (c *Client) releaseSeat() {
+	if c.pointer != nil {
+		c.releasePointer()
+	}
+	if c.keyboard != nil {
+		c.releaseKeyboard()
+	}
+	if c.touch != nil {
+		c.releaseTouch()
+	}
+	if c.seatVersion >= 5 {
+		C.wl_seat_release(c.seat)
+	} else {
+		C.wl_seat_destroy(c.seat)
+	}
+	c.seat = nil
+}
+
@@ -0,0 +1,314 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#cgo pkg-config: wayland-client
+#cgo CFLAGS: -I${SRCDIR}/../../generated
+#cgo LDFLAGS: -L${SRCDIR}/../../generated -lwlr-layer-shell-protocol -lxdg-shell-protocol
+#include <wayland-client.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include "xdg-shell-client-protocol.h"
+#include "wlr-layer-shell-unstable-v1-client-protocol.h"
+
+extern void oskShellConfigure(uintptr_t handle, uint32_t serial, uint32_t width, uint32_t height);
+extern void oskToplevelConfigure(uintptr_t handle, int32_t width, int32_t height);
+extern void oskShellClosed(uintptr_t handle);
+
+static void osk_layer_surface_configure(void *data, struct zwlr_layer_surface_v1 *surface,
+		uint32_t serial, uint32_t width, uint32_t height) {
+	oskShellConfigure((uintptr_t)data, serial, width, height);
+}
+
+static void osk_layer_surface_closed(void *data, struct zwlr_layer_surface_v1 *surface) {
+	oskShellClosed((uintptr_t)data);
+}
+
+static const struct zwlr_layer_surface_v1_listener osk_layer_surface_listener = {
+	.configure = osk_layer_surface_configure,
+	.closed = osk_layer_surface_closed,
+};
+
+static struct zwlr_layer_surface_v1 *osk_get_layer_surface(struct zwlr_layer_shell_v1 *shell,
+		struct wl_surface *surface, struct wl_output *output, uint32_t layer, const char *namespace,
+		uintptr_t handle) {
+	struct zwlr_layer_surface_v1 *layer_surface =
+		zwlr_layer_shell_v1_get_layer_surface(shell, surface, output, layer, namespace);
+	if (layer_surface != NULL) {
+		zwlr_layer_surface_v1_add_listener(layer_surface, &osk_layer_surface_listener, (void *)handle);
+	}
+	return layer_surface;
+}
+
+// osk_layer_shell_destroy sends the destroy request only on versions that
+// have it
+static void osk_layer_shell_destroy(struct zwlr_layer_shell_v1 *shell) {
+	if (zwlr_layer_shell_v1_get_version(shell) >= ZWLR_LAYER_SHELL_V1_DESTROY_SINCE_VERSION) {
+		zwlr_layer_shell_v1_destroy(shell);
+	} else {
+		wl_proxy_destroy((struct wl_proxy *)shell);
+	}
+}
+
+static void osk_wm_base_ping(void *data, struct xdg_wm_base *wm_base, uint32_t serial) {
+	xdg_wm_base_pong(wm_base, serial);
+}
+
+static const struct xdg_wm_base_listener osk_wm_base_listener = {
+	.ping = osk_wm_base_ping,
+};
+
+static void osk_wm_base_add_listener(struct xdg_wm_base *wm_base) {
+	xdg_wm_base_add_listener(wm_base, &osk_wm_base_listener, NULL);
+}
+
+static void osk_xdg_surface_configure(void *data, struct xdg_surface *surface, uint32_t serial) {
+	oskShellConfigure((uintptr_t)data, serial, 0, 0);
+}
+
+static const struct xdg_surface_listener osk_xdg_surface_listener = {
+	.configure = osk_xdg_surface_configure,
+};
+
+static void osk_toplevel_configure(void *data, struct xdg_toplevel *toplevel,
+		int32_t width, int32_t height, struct wl_array *states) {
+	oskToplevelConfigure((uintptr_t)data, width, height);
+}
+
+static void osk_toplevel_close(void *data, struct xdg_toplevel *toplevel) {
+	oskShellClosed((uintptr_t)data);
+}
+
+static void osk_toplevel_configure_bounds(void *data, struct xdg_toplevel *toplevel,
+		int32_t width, int32_t height) {}
+
+static const struct xdg_toplevel_listener osk_toplevel_listener = {
+	.configure = osk_toplevel_configure,
+	.close = osk_toplevel_close,
+	.configure_bounds = osk_toplevel_configure_bounds,
+};
+
+static struct xdg_surface *osk_get_xdg_surface(struct xdg_wm_base *wm_base,
+		struct wl_surface *surface, uintptr_t handle) {
+	struct xdg_surface *xdg_surface = xdg_wm_base_get_xdg_surface(wm_base, surface);
+	if (xdg_surface != NULL) {
+		xdg_surface_add_listener(xdg_surface, &osk_xdg_surface_listener, (void *)handle);
+	}
+	return xdg_surface;
+}
+
+static struct xdg_toplevel *osk_get_toplevel(struct xdg_surface *xdg_surface, uintptr_t handle) {
+	struct xdg_toplevel *toplevel = xdg_surface_get_toplevel(xdg_surface);
+	if (toplevel != NULL) {
+		xdg_toplevel_add_listener(toplevel, &osk_toplevel_listener, (void *)handle);
+	}
+	return toplevel;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"log"
+	"unsafe"
+)
+
+// bindLayerShell binds zwlr_layer_shell_v1
+func (c *Client) bindLayerShell(name, version uint32) {
+	if c.layerShell != nil {
+		return
+	}
+	c.layerShell = (*C.struct_zwlr_layer_shell_v1)(c.bind(name, &C.zwlr_layer_shell_v1_interface, negotiate(version, layerShellVersion)))
+}
+
+// bindWmBase binds xdg_wm_base, used when layer-shell is missing
+func (c *Client) bindWmBase(name, version uint32) {
+	if c.wmBase != nil {
+		return
+	}
+	c.wmBase = (*C.struct_xdg_wm_base)(c.bind(name, &C.xdg_wm_base_interface, negotiate(version, wmBaseVersion)))
+	C.osk_wm_base_add_listener(c.wmBase)
+}
+
+// CreateSurface creates the keyboard surface and gives it a role: a
+// layer-shell panel placed as config describes, or an xdg_toplevel window
+// when the compositor has no layer-shell. It returns once the compositor
+// has sent the first configure, after which buffers may be attached.
+func (c *Client) CreateSurface(config SurfaceConfig) error {
+	if c.compositor == nil {
+		return fmt.Errorf("compositor not available")
+	}
+	if c.surface != nil {
+		return fmt.Errorf("surface already created")
+	}
+
+	pinned, err := c.pinOutput(config)
+	if err != nil {
+		return err
+	}
+
+	c.surface = C.wl_compositor_create_surface(c.compositor)
+	if c.surface == nil {
+		return fmt.Errorf("failed to create surface")
+	}
+	c.trackScale()
+
+	switch {
+	case c.layerShell != nil:
+		err = c.createLayerSurface(config, pinned)
+	case c.wmBase != nil:
+		log.Printf("Compositor lacks zwlr_layer_shell_v1, falling back to an xdg_toplevel window")
+		err = c.createToplevel(config)
+	default:
+		err = fmt.Errorf("compositor provides neither zwlr_layer_shell_v1 nor xdg_wm_base")
+	}
+	if err != nil {
+		return err
+	}
+
+	// The initial commit carries no buffer; the compositor answers with a
+	// configure that must be acked before the first attach
+	C.wl_surface_commit(c.surface)
+	if err := c.waitConfigured(); err != nil {
+		return err
+	}
+	c.updateScale()
+	return nil
+}
+
+// SetVisible maps or unmaps the keyboard surface. It is unmapped by
+// attaching no buffer, after which both shells require a new initial
+// commit and configure before it can be shown; SetVisible(true) waits for
+// that configure, and the next Attach maps the surface.
+func (c *Client) SetVisible(visible bool) error {
+	if c.surface == nil {
+		return fmt.Errorf("no surface to show")
+	}
+	if !visible {
+		if c.mapped {
+			C.wl_surface_attach(c.surface, nil, 0, 0)
+			C.wl_surface_commit(c.surface)
+			c.mapped = false
+			c.configured = false
+		}
+		return nil
+	}
+	if c.configured {
+		return nil
+	}
+	C.wl_surface_commit(c.surface)
+	return c.waitConfigured()
+}
+
+// waitConfigured dispatches events until the surface has been configured
+func (c *Client) waitConfigured() error {
+	for !c.configured {
+		if C.wl_display_roundtrip(c.display) == -1 {
+			return fmt.Errorf("failed to receive surface configuration")
+		}
+		if c.closed {
+			return fmt.Errorf("surface closed by the compositor before it was shown")
+		}
+	}
+	return nil
+}
+
+// createLayerSurface assigns the layer-shell role and sends the placement.
+// Without a pinned output the compositor picks one.
+func (c *Client) createLayerSurface(config SurfaceConfig, pinned *output) error {
+	namespace := C.CString(config.Namespace)
+	defer C.free(unsafe.Pointer(namespace))
+
+	var proxy *C.struct_wl_output
+	if pinned != nil {
+		proxy = pinned.proxy
+	}
+	c.layerSurface = C.osk_get_layer_surface(c.layerShell, c.surface, proxy, C.uint32_t(config.Layer), namespace,
+		C.uintptr_t(c.handle))
+	if c.layerSurface == nil {
+		return fmt.Errorf("failed to create layer surface")
+	}
+
+	interactivity := config.KeyboardInteractivity
+	if interactivity == KeyboardInteractivityOnDemand && C.zwlr_layer_shell_v1_get_version(c.layerShell) < 4 {
+		log.Printf("Layer shell version %d has no on-demand keyboard interactivity, using none",
+			C.zwlr_layer_shell_v1_get_version(c.layerShell))
+		interactivity = KeyboardInteractivityNone
+	}
+
+	width, height := config.requestSize()
+	margins := config.Margins
+	C.zwlr_layer_surface_v1_set_size(c.layerSurface, C.uint32_t(width), C.uint32_t(height))
+	C.zwlr_layer_surface_v1_set_anchor(c.layerSurface, C.uint32_t(config.Anchor))
+	C.zwlr_layer_surface_v1_set_margin(c.layerSurface, C.int32_t(margins.Top), C.int32_t(margins.Right),
+		C.int32_t(margins.Bottom), C.int32_t(margins.Left))
+	C.zwlr_layer_surface_v1_set_exclusive_zone(c.layerSurface, C.int32_t(config.ExclusiveZone))
+	C.zwlr_layer_surface_v1_set_keyboard_interactivity(c.layerSurface, C.uint32_t(interactivity))
+	return nil
+}
+
+// createToplevel assigns the xdg_toplevel role with a fixed size
+func (c *Client) createToplevel(config SurfaceConfig) error {
+	c.xdgSurface = C.osk_get_xdg_surface(c.wmBase, c.surface, C.uintptr_t(c.handle))
+	if c.xdgSurface == nil {
+		return fmt.Errorf("failed to create xdg surface")
+	}
+	c.toplevel = C.osk_get_toplevel(c.xdgSurface, C.uintptr_t(c.handle))
+	if c.toplevel == nil {
+		return fmt.Errorf("failed to create xdg toplevel")
+	}
+
+	title := C.CString(config.Title)
+	defer C.free(unsafe.Pointer(title))
+	appID := C.CString(config.Namespace)
+	defer C.free(unsafe.Pointer(appID))
+
+	C.xdg_toplevel_set_title(c.toplevel, title)
+	C.xdg_toplevel_set_app_id(c.toplevel, appID)
+	C.xdg_toplevel_set_min_size(c.toplevel, C.int32_t(config.Width), C.int32_t(config.Height))
+	C.xdg_toplevel_set_max_size(c.toplevel, C.int32_t(config.Width), C.int32_t(config.Height))
+	return nil
+}
+
+// shellConfigure acks a configure from either shell and reports the size
+// the compositor chose
+func (c *Client) shellConfigure(serial, width, height uint32) {
+	switch {
+	case c.layerSurface != nil:
+		C.zwlr_layer_surface_v1_ack_configure(c.layerSurface, C.uint32_t(serial))
+	case c.xdgSurface != nil:
+		C.xdg_surface_ack_configure(c.xdgSurface, C.uint32_t(serial))
+		width, height = c.pendingWidth, c.pendingHeight
+	default:
+		return
+	}
+	c.configured = true
+
+	c.sendEvent(&ShellEvent{Width: width, Height: height})
+}
+
+// destroyShell destroys the surface role objects and the shell globals
+func (c *Client) destroyShell() {
+	if c.layerSurface != nil {
+		C.zwlr_layer_surface_v1_destroy(c.layerSurface)
+		c.layerSurface = nil
+	}
+	if c.toplevel != nil {
+		C.xdg_toplevel_destroy(c.toplevel)
+		c.toplevel = nil
+	}
+	if c.xdgSurface != nil {
+		C.xdg_surface_destroy(c.xdgSurface)
+		c.xdgSurface = nil
+	}
+	if c.layerShell != nil {
+		C.osk_layer_shell_destroy(c.layerShell)
+		c.layerShell = nil
+	}
+	if c.wmBase != nil {
+		C.xdg_wm_base_destroy(c.wmBase)
+		c.wmBase = nil
+	}
+}
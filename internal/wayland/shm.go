@@ -0,0 +1,258 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#define _GNU_SOURCE
+#cgo pkg-config: wayland-client
+#include <wayland-client.h>
+#include <stdint.h>
+#include <sys/mman.h>
+
+extern void oskBufferRelease(uintptr_t handle);
+
+static void osk_buffer_release(void *data, struct wl_buffer *buffer) {
+	oskBufferRelease((uintptr_t)data);
+}
+
+static const struct wl_buffer_listener osk_buffer_listener = {
+	.release = osk_buffer_release,
+};
+
+static struct wl_buffer *osk_create_buffer(struct wl_shm_pool *pool, int32_t offset,
+		int32_t width, int32_t height, int32_t stride, uintptr_t handle) {
+	struct wl_buffer *buffer = wl_shm_pool_create_buffer(pool, offset, width, height,
+		stride, WL_SHM_FORMAT_ARGB8888);
+	if (buffer != NULL) {
+		wl_buffer_add_listener(buffer, &osk_buffer_listener, (void *)handle);
+	}
+	return buffer;
+}
+
+static int osk_memfd(void) {
+	return memfd_create("oskway-shm", MFD_CLOEXEC);
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"runtime/cgo"
+	"syscall"
+)
+
+// shmPool is a memfd-backed wl_shm_pool holding the buffers of one
+// swapchain. A new pool is created whenever the surface changes size.
+type shmPool struct {
+	client  *Client
+	chain   *swapchain
+	fd      int
+	pool    *C.struct_wl_shm_pool
+	data    []byte
+	buffers []*shmBuffer
+}
+
+// shmBuffer is the wl_buffer for one swapchain slot. A buffer still held
+// by the compositor when its pool is destroyed is orphaned and destroyed
+// once it is released.
+type shmBuffer struct {
+	pool     *shmPool
+	slot     *bufferSlot
+	buffer   *C.struct_wl_buffer
+	handle   cgo.Handle
+	orphaned bool
+}
+
+// newShmPool allocates a pool large enough for minBuffers buffers
+func newShmPool(client *Client, width, height int) (*shmPool, error) {
+	p := &shmPool{client: client, chain: newSwapchain(width, height), fd: -1}
+
+	fd, err := memfd()
+	if err != nil {
+		return nil, err
+	}
+	p.fd = fd
+
+	size := p.chain.bufferSize() * minBuffers
+	if err := p.allocate(size); err != nil {
+		p.destroy()
+		return nil, err
+	}
+
+	p.pool = C.wl_shm_create_pool(client.shm, C.int32_t(p.fd), C.int32_t(size))
+	if p.pool == nil {
+		p.destroy()
+		return nil, fmt.Errorf("failed to create shm pool")
+	}
+	return p, nil
+}
+
+// memfd creates an anonymous shared memory file to pass to the compositor
+func memfd() (int, error) {
+	fd, err := C.osk_memfd()
+	if fd < 0 {
+		return -1, fmt.Errorf("failed to create shared memory file: %w", err)
+	}
+	return int(fd), nil
+}
+
+// allocate resizes the backing file to size bytes and maps it
+func (p *shmPool) allocate(size int) error {
+	if err := syscall.Ftruncate(p.fd, int64(size)); err != nil {
+		return fmt.Errorf("failed to resize shared memory file: %w", err)
+	}
+	if p.data != nil {
+		syscall.Munmap(p.data)
+		p.data = nil
+	}
+	data, err := syscall.Mmap(p.fd, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("failed to map shared memory: %w", err)
+	}
+	p.data = data
+	return nil
+}
+
+// acquire returns a buffer the compositor is not reading, growing the pool
+// when a new slot is needed
+func (p *shmPool) acquire() (*shmBuffer, error) {
+	slot, ok := p.chain.acquire()
+	if !ok {
+		return nil, fmt.Errorf("all %d buffers are in use by the compositor", maxBuffers)
+	}
+	if slot.index < len(p.buffers) {
+		return p.buffers[slot.index], nil
+	}
+
+	// Pools can only grow, so the compositor's mapping stays valid
+	if size := slot.offset + p.chain.bufferSize(); size > len(p.data) {
+		if err := p.allocate(size); err != nil {
+			return nil, err
+		}
+		C.wl_shm_pool_resize(p.pool, C.int32_t(size))
+	}
+
+	buffer := &shmBuffer{pool: p, slot: slot}
+	buffer.handle = cgo.NewHandle(buffer)
+	buffer.buffer = C.osk_create_buffer(p.pool, C.int32_t(slot.offset), C.int32_t(p.chain.width),
+		C.int32_t(p.chain.height), C.int32_t(p.chain.stride), C.uintptr_t(buffer.handle))
+	if buffer.buffer == nil {
+		buffer.handle.Delete()
+		return nil, fmt.Errorf("failed to create shm buffer")
+	}
+	p.buffers = append(p.buffers, buffer)
+	return buffer, nil
+}
+
+// destroy releases the pool. Buffers the compositor still holds are
+// orphaned and freed on release.
+func (p *shmPool) destroy() {
+	for _, buffer := range p.buffers {
+		if buffer.slot.busy {
+			buffer.orphaned = true
+			p.client.retired[buffer] = struct{}{}
+		} else {
+			buffer.destroy()
+		}
+	}
+	p.buffers = nil
+
+	if p.pool != nil {
+		C.wl_shm_pool_destroy(p.pool)
+		p.pool = nil
+	}
+	if p.data != nil {
+		syscall.Munmap(p.data)
+		p.data = nil
+	}
+	if p.fd >= 0 {
+		syscall.Close(p.fd)
+		p.fd = -1
+	}
+}
+
+// pixels returns the mapped memory of the buffer
+func (b *shmBuffer) pixels() []byte {
+	return b.pool.data[b.slot.offset : b.slot.offset+b.pool.chain.bufferSize()]
+}
+
+// release is called when the compositor stops reading the buffer
+func (b *shmBuffer) release() {
+	b.slot.busy = false
+	if b.orphaned {
+		delete(b.pool.client.retired, b)
+		b.destroy()
+	}
+}
+
+// destroy destroys the wl_buffer
+func (b *shmBuffer) destroy() {
+	C.wl_buffer_destroy(b.buffer)
+	b.handle.Delete()
+}
+
+// Resize prepares buffers for a surface of the given size. Buffers of the
+// previous size are freed once the compositor releases them.
+func (c *Client) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid buffer size %dx%d", width, height)
+	}
+	if c.pool != nil && c.pool.chain.width == width && c.pool.chain.height == height {
+		return nil
+	}
+	if c.shm == nil {
+		return fmt.Errorf("compositor does not provide wl_shm")
+	}
+
+	if c.pool != nil {
+		c.pool.destroy()
+		c.pool = nil
+	}
+	pool, err := newShmPool(c, width, height)
+	if err != nil {
+		return err
+	}
+	c.pool = pool
+	return nil
+}
+
+// Attach copies img into a free buffer, attaches it to the surface and
+// commits along with the damage reported since the last commit. Only the
+// regions that changed since the buffer was last used are copied. The
+// buffers are resized to match img.
+func (c *Client) Attach(img *image.RGBA) error {
+	if c.surface == nil {
+		return fmt.Errorf("no surface to attach to")
+	}
+	size := img.Rect.Size()
+	if err := c.Resize(size.X, size.Y); err != nil {
+		return err
+	}
+
+	buffer, err := c.pool.acquire()
+	if err != nil {
+		return err
+	}
+	buffer.slot.copyPixels(buffer.pixels(), c.pool.chain.stride, img)
+
+	c.applyScale(size.X, size.Y)
+	C.wl_surface_attach(c.surface, buffer.buffer, 0, 0)
+	C.wl_surface_commit(c.surface)
+	buffer.slot.busy = true
+	c.mapped = true
+	return nil
+}
+
+// destroyBuffers frees the current pool and every orphaned buffer
+func (c *Client) destroyBuffers() {
+	if c.pool != nil {
+		c.pool.destroy()
+		c.pool = nil
+	}
+	for buffer := range c.retired {
+		buffer.destroy()
+		delete(c.retired, buffer)
+	}
+}
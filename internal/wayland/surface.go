@@ -0,0 +1,180 @@
+package wayland
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Layer is the zwlr_layer_shell_v1 layer the keyboard is placed in,
+// ordered from the bottom of the stack
+type Layer uint32
+
+const (
+	LayerBackground Layer = iota
+	LayerBottom
+	LayerTop
+	LayerOverlay
+)
+
+// Anchor is a set of output edges the keyboard is anchored to
+type Anchor uint32
+
+const (
+	AnchorTop Anchor = 1 << iota
+	AnchorBottom
+	AnchorLeft
+	AnchorRight
+)
+
+// KeyboardInteractivity controls whether the keyboard surface can take
+// keyboard focus
+type KeyboardInteractivity uint32
+
+const (
+	KeyboardInteractivityNone KeyboardInteractivity = iota
+	KeyboardInteractivityExclusive
+	KeyboardInteractivityOnDemand
+)
+
+// Margins are distances kept from the anchored edges, in surface pixels
+type Margins struct {
+	Top    int
+	Right  int
+	Bottom int
+	Left   int
+}
+
+// SurfaceConfig describes how the keyboard surface is placed. With
+// layer-shell the keyboard is docked as a panel; compositors without it
+// get an xdg_toplevel window of the requested size instead, and the
+// placement fields are ignored.
+type SurfaceConfig struct {
+	// Width and Height are the size of the keyboard. When anchored to
+	// both the left and right edges the compositor chooses the width.
+	Width  int
+	Height int
+
+	Layer   Layer
+	Anchor  Anchor
+	Margins Margins
+	// ExclusiveZone is the distance from the anchored edge other surfaces
+	// should avoid; 0 asks to be moved clear of other panels and -1 to
+	// ignore them
+	ExclusiveZone         int
+	KeyboardInteractivity KeyboardInteractivity
+	// Output is the connector name of the output to show the keyboard on,
+	// such as "HDMI-A-1"; when empty the compositor chooses
+	Output string
+
+	// Namespace identifies the panel to the compositor; Title is used for
+	// the xdg_toplevel fallback
+	Namespace string
+	Title     string
+}
+
+// DefaultSurfaceConfig returns a keyboard docked along the bottom edge of
+// the output across its full width, above applications, reserving its own
+// height and never taking keyboard focus
+func DefaultSurfaceConfig(width, height int) SurfaceConfig {
+	return SurfaceConfig{
+		Width:         width,
+		Height:        height,
+		Layer:         LayerTop,
+		Anchor:        AnchorBottom | AnchorLeft | AnchorRight,
+		ExclusiveZone: height,
+		Namespace:     "oskway",
+		Title:         "oskway",
+	}
+}
+
+// requestSize returns the size to ask the compositor for. A dimension
+// stretched between opposite anchors is left to the compositor.
+func (c SurfaceConfig) requestSize() (width, height int) {
+	width, height = c.Width, c.Height
+	if c.Anchor&(AnchorLeft|AnchorRight) == AnchorLeft|AnchorRight {
+		width = 0
+	}
+	if c.Anchor&(AnchorTop|AnchorBottom) == AnchorTop|AnchorBottom {
+		height = 0
+	}
+	return width, height
+}
+
+var layerNames = map[string]Layer{
+	"background": LayerBackground,
+	"bottom":     LayerBottom,
+	"top":        LayerTop,
+	"overlay":    LayerOverlay,
+}
+
+// ParseLayer parses a layer name: background, bottom, top or overlay
+func ParseLayer(s string) (Layer, error) {
+	layer, ok := layerNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown layer %q (want background, bottom, top or overlay)", s)
+	}
+	return layer, nil
+}
+
+var anchorNames = map[string]Anchor{
+	"top":    AnchorTop,
+	"bottom": AnchorBottom,
+	"left":   AnchorLeft,
+	"right":  AnchorRight,
+}
+
+// ParseAnchor parses a comma-separated list of edges, such as
+// "bottom,left,right". An empty string centres the surface.
+func ParseAnchor(s string) (Anchor, error) {
+	var anchor Anchor
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		edge, ok := anchorNames[name]
+		if !ok {
+			return 0, fmt.Errorf("unknown anchor edge %q (want top, bottom, left or right)", name)
+		}
+		anchor |= edge
+	}
+	return anchor, nil
+}
+
+// ParseMargins parses margins given as one value for every edge or as
+// "top,right,bottom,left"
+func ParseMargins(s string) (Margins, error) {
+	fields := strings.Split(s, ",")
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		v, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return Margins{}, fmt.Errorf("invalid margin %q", field)
+		}
+		values[i] = v
+	}
+
+	switch len(values) {
+	case 1:
+		return Margins{values[0], values[0], values[0], values[0]}, nil
+	case 4:
+		return Margins{values[0], values[1], values[2], values[3]}, nil
+	}
+	return Margins{}, fmt.Errorf("invalid margins %q (want one value or top,right,bottom,left)", s)
+}
+
+var interactivityNames = map[string]KeyboardInteractivity{
+	"none":      KeyboardInteractivityNone,
+	"exclusive": KeyboardInteractivityExclusive,
+	"on-demand": KeyboardInteractivityOnDemand,
+}
+
+// ParseKeyboardInteractivity parses none, exclusive or on-demand
+func ParseKeyboardInteractivity(s string) (KeyboardInteractivity, error) {
+	ki, ok := interactivityNames[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown keyboard interactivity %q (want none, exclusive or on-demand)", s)
+	}
+	return ki, nil
+}
@@ -0,0 +1,50 @@
+package wayland
+
+import (
+	"testing"
+)
+
+func TestParseSurfacePlacement(t *testing.T) {
+	anchor, err := ParseAnchor(" Bottom, left,right ")
+	if err != nil || anchor != AnchorBottom|AnchorLeft|AnchorRight {
+		t.Errorf("ParseAnchor = %v, %v", anchor, err)
+	}
+	if anchor, err := ParseAnchor(""); err != nil || anchor != 0 {
+		t.Errorf("ParseAnchor(\"\") = %v, %v, want centred", anchor, err)
+	}
+	if _, err := ParseAnchor("bottom,middle"); err == nil {
+		t.Error("expected an error for an unknown edge")
+	}
+
+	if m, err := ParseMargins("8"); err != nil || m != (Margins{8, 8, 8, 8}) {
+		t.Errorf("ParseMargins(8) = %v, %v", m, err)
+	}
+	if m, err := ParseMargins("1,2,3,4"); err != nil || m != (Margins{Top: 1, Right: 2, Bottom: 3, Left: 4}) {
+		t.Errorf("ParseMargins(1,2,3,4) = %v, %v", m, err)
+	}
+	if _, err := ParseMargins("1,2"); err == nil {
+		t.Error("expected an error for two margins")
+	}
+
+	if layer, err := ParseLayer("Overlay"); err != nil || layer != LayerOverlay {
+		t.Errorf("ParseLayer = %v, %v", layer, err)
+	}
+	if ki, err := ParseKeyboardInteractivity("on-demand"); err != nil || ki != KeyboardInteractivityOnDemand {
+		t.Errorf("ParseKeyboardInteractivity = %v, %v", ki, err)
+	}
+}
+
+func TestSurfaceConfigLeavesStretchedSizeToCompositor(t *testing.T) {
+	config := DefaultSurfaceConfig(800, 300)
+	if w, h := config.requestSize(); w != 0 || h != 300 {
+		t.Errorf("docked size = %dx%d, want 0x300", w, h)
+	}
+	if config.ExclusiveZone != 300 {
+		t.Errorf("ExclusiveZone = %d, want the keyboard height", config.ExclusiveZone)
+	}
+
+	config.Anchor = AnchorBottom
+	if w, h := config.requestSize(); w != 800 || h != 300 {
+		t.Errorf("centred size = %dx%d, want 800x300", w, h)
+	}
+}
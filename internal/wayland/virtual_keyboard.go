@@ -0,0 +1,346 @@
+package wayland
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// keymapFormatXKB is WL_KEYBOARD_KEYMAP_FORMAT_XKB_V1
+const keymapFormatXKB = 1
+
+// Key states sent with zwp_virtual_keyboard_v1.key
+const (
+	keyStateReleased = 0
+	keyStatePressed  = 1
+)
+
+// Modifier masks of the real modifiers in the default keymap
+const (
+	modShift   = 1 << 0
+	modLock    = 1 << 1
+	modControl = 1 << 2
+	modMod1    = 1 << 3 // Alt
+	modMod4    = 1 << 6 // Super
+	modMod5    = 1 << 7 // AltGr
+)
+
+// modifierMasks maps evdev codes of modifier keys to the modifier they
+// set. Caps Lock locks its modifier instead of holding it.
+var modifierMasks = map[uint32]uint32{
+	42:  modShift,   // KEY_LEFTSHIFT
+	54:  modShift,   // KEY_RIGHTSHIFT
+	58:  modLock,    // KEY_CAPSLOCK
+	29:  modControl, // KEY_LEFTCTRL
+	97:  modControl, // KEY_RIGHTCTRL
+	56:  modMod1,    // KEY_LEFTALT
+	100: modMod5,    // KEY_RIGHTALT
+	125: modMod4,    // KEY_LEFTMETA
+	126: modMod4,    // KEY_RIGHTMETA
+}
+
+// virtualKeyboardProtocol is a zwp_virtual_keyboard_v1 object. The client
+// implements it with libwayland; tests use a fake compositor that records
+// the requests.
+type virtualKeyboardProtocol interface {
+	keymap(format uint32, keymap []byte) error
+	key(time, key, state uint32)
+	modifiers(depressed, latched, locked, group uint32)
+	destroy()
+}
+
+// VirtualKeyboard types into the focused application by sending key events
+// through zwp_virtual_keyboard_v1.
+//
+// Modifier keys behave as on other on-screen keyboards: holding one while
+// pressing another key applies it to that key, while tapping it on its own
+// latches it for the next key. Tapping it again before then unlatches it.
+// Caps Lock toggles its lock.
+//
+// Keys with an Output type their text through key codes the keymap
+// generated for the layout maps to each character. The level is chosen from
+// Shift, Caps Lock and AltGr, which are cleared while the text is typed.
+// While an input method has a text input focused, text is committed through
+// it instead.
+type VirtualKeyboard struct {
+	protocol virtualKeyboardProtocol
+	start    time.Time
+	keymap   *keymap
+
+	mutex     sync.Mutex
+	depressed uint32
+	latched   uint32
+	locked    uint32
+	// chorded records that a key was typed while a modifier was held, so
+	// releasing the modifier does not latch it
+	chorded bool
+	pressed map[uint32]bool
+
+	inputMethod *InputMethod
+}
+
+// newVirtualKeyboard uploads a keymap of the US keys, as a keymap must
+// precede any key event
+func newVirtualKeyboard(protocol virtualKeyboardProtocol) (*VirtualKeyboard, error) {
+	km := newKeymap(nil)
+	if err := protocol.keymap(keymapFormatXKB, km.text); err != nil {
+		protocol.destroy()
+		return nil, fmt.Errorf("failed to upload keymap: %w", err)
+	}
+	return &VirtualKeyboard{
+		protocol: protocol,
+		start:    time.Now(),
+		keymap:   km,
+		pressed:  make(map[uint32]bool),
+	}, nil
+}
+
+// SetLayout uploads a keymap generated for layout when it differs from the
+// current one. Keys still held are released first, as their codes may mean
+// something else in the new keymap.
+func (vk *VirtualKeyboard) SetLayout(layout *keyboard.Layout) error {
+	km := newKeymap(layout)
+
+	vk.mutex.Lock()
+	defer vk.mutex.Unlock()
+
+	if bytes.Equal(km.text, vk.keymap.text) {
+		vk.keymap = km
+		return nil
+	}
+	vk.releaseAll()
+	if err := vk.protocol.keymap(keymapFormatXKB, km.text); err != nil {
+		return fmt.Errorf("failed to upload keymap: %w", err)
+	}
+	vk.keymap = km
+	vk.sendModifiers()
+	return nil
+}
+
+// HandleKey types a key pressed or released on the keyboard
+func (vk *VirtualKeyboard) HandleKey(event keyboard.KeyEvent) {
+	if event.State == keyboard.KeyStatePressed && vk.commitText(event.Key) {
+		return
+	}
+	switch {
+	case len(event.Key.Output) > 0:
+		if event.State == keyboard.KeyStatePressed {
+			vk.typeOutput(event.Key)
+		}
+	case event.Key.Code <= 0:
+		// Negative codes are layout actions, not evdev keys
+	case event.State == keyboard.KeyStatePressed:
+		vk.Press(uint32(event.Key.Code))
+	default:
+		vk.Release(uint32(event.Key.Code))
+	}
+}
+
+// SetInputMethod makes keys that type text commit it through im while a
+// text input is focused, instead of sending key codes
+func (vk *VirtualKeyboard) SetInputMethod(im *InputMethod) {
+	vk.mutex.Lock()
+	defer vk.mutex.Unlock()
+	vk.inputMethod = im
+}
+
+// commitText sends the text of key through the input method, reporting
+// whether it did. Backspace deletes through the input method when the text
+// before the cursor is known. Keys pressed with Ctrl, Alt or Super held are
+// shortcuts and are left to be sent as keys.
+func (vk *VirtualKeyboard) commitText(key *keyboard.Key) bool {
+	vk.mutex.Lock()
+	defer vk.mutex.Unlock()
+
+	im := vk.inputMethod
+	if im == nil || !im.Active() || (vk.depressed|vk.latched)&(modControl|modMod1|modMod4) != 0 {
+		return false
+	}
+	if len(key.Output) == 0 && key.Code == keyBackspace {
+		return im.DeleteBackward()
+	}
+
+	levels := key.Output
+	if len(levels) == 0 && key.Code > 0 {
+		levels = usText[uint32(key.Code)]
+	}
+	if len(levels) == 0 {
+		return false
+	}
+	if err := im.CommitString(levels[vk.level(levels)]); err != nil {
+		return false
+	}
+	if vk.typed() {
+		vk.sendModifiers()
+	}
+	return true
+}
+
+// typeOutput taps the codes of the output of key at the current level
+func (vk *VirtualKeyboard) typeOutput(key *keyboard.Key) {
+	vk.mutex.Lock()
+	defer vk.mutex.Unlock()
+
+	levels := vk.keymap.outputs[key.ID]
+	level := vk.level(key.Output)
+	if level >= len(levels) {
+		return
+	}
+
+	// The level modifiers were used to choose the level, and must not
+	// change what the applications see
+	levelMods := uint32(modShift | modLock | modMod5)
+	consumed := (vk.depressed|vk.latched|vk.locked)&levelMods != 0
+	if consumed {
+		vk.protocol.modifiers(vk.depressed&^levelMods, vk.latched&^levelMods, vk.locked&^levelMods, 0)
+	}
+	for _, code := range levels[level] {
+		vk.protocol.key(vk.now(), code, keyStatePressed)
+		vk.protocol.key(vk.now(), code, keyStateReleased)
+	}
+
+	if vk.typed() || consumed {
+		vk.sendModifiers()
+	}
+}
+
+// level returns the level of levels to type for the Shift, Caps Lock and
+// AltGr state, falling back to the first level of the plain or shifted
+// pair when a key has fewer levels. Caps Lock only shifts keys whose first
+// two levels differ in case. Callers must hold the mutex.
+func (vk *VirtualKeyboard) level(levels []string) int {
+	active := vk.depressed | vk.latched
+	shift := active&modShift != 0
+	if vk.locked&modLock != 0 && len(levels) > 1 && strings.ToUpper(levels[0]) == levels[1] && levels[0] != levels[1] {
+		shift = !shift
+	}
+
+	level := 0
+	if shift {
+		level |= 1
+	}
+	if active&modMod5 != 0 {
+		level |= 2
+	}
+	if level >= len(levels) {
+		level &^= 2
+	}
+	if level >= len(levels) {
+		level = 0
+	}
+	return level
+}
+
+// typed applies a key that typed text to the modifiers: held modifiers no
+// longer latch on release, and latched ones are used up. It reports
+// whether the modifiers sent to the compositor changed. Callers must hold
+// the mutex.
+func (vk *VirtualKeyboard) typed() bool {
+	if vk.depressed != 0 {
+		vk.chorded = true
+	}
+	if vk.latched == 0 {
+		return false
+	}
+	vk.latched = 0
+	return true
+}
+
+// Press sends a press of the key with the given evdev code
+func (vk *VirtualKeyboard) Press(code uint32) {
+	vk.mutex.Lock()
+	defer vk.mutex.Unlock()
+
+	if vk.pressed[code] {
+		return
+	}
+	vk.pressed[code] = true
+	vk.protocol.key(vk.now(), code, keyStatePressed)
+
+	mask, isModifier := modifierMasks[code]
+	switch {
+	case mask == modLock:
+		vk.locked ^= modLock
+	case isModifier:
+		if vk.depressed == 0 {
+			vk.chorded = false
+		}
+		vk.depressed |= mask
+	default:
+		if vk.depressed != 0 {
+			vk.chorded = true
+		}
+		return
+	}
+	vk.sendModifiers()
+}
+
+// Release sends a release of the key with the given evdev code
+func (vk *VirtualKeyboard) Release(code uint32) {
+	vk.mutex.Lock()
+	defer vk.mutex.Unlock()
+
+	if !vk.pressed[code] {
+		return
+	}
+	delete(vk.pressed, code)
+	vk.protocol.key(vk.now(), code, keyStateReleased)
+
+	mask, isModifier := modifierMasks[code]
+	switch {
+	case mask == modLock:
+		return
+	case isModifier:
+		vk.depressed &^= mask
+		if !vk.chorded {
+			vk.latched ^= mask
+		}
+	case vk.latched != 0:
+		// A latched modifier applies to one key only
+		vk.latched = 0
+	default:
+		return
+	}
+	vk.sendModifiers()
+}
+
+// Close releases any keys still held, so none stay stuck in the focused
+// application, and destroys the virtual keyboard
+func (vk *VirtualKeyboard) Close() {
+	vk.mutex.Lock()
+	defer vk.mutex.Unlock()
+
+	if vk.releaseAll() {
+		vk.sendModifiers()
+	}
+	vk.protocol.destroy()
+}
+
+// releaseAll releases every held key and clears held and latched
+// modifiers, reporting whether modifiers changed. Callers must hold the
+// mutex.
+func (vk *VirtualKeyboard) releaseAll() bool {
+	for code := range vk.pressed {
+		vk.protocol.key(vk.now(), code, keyStateReleased)
+		delete(vk.pressed, code)
+	}
+	if vk.depressed == 0 && vk.latched == 0 {
+		return false
+	}
+	vk.depressed, vk.latched = 0, 0
+	return true
+}
+
+// sendModifiers sends the modifier state. Callers must hold the mutex.
+func (vk *VirtualKeyboard) sendModifiers() {
+	vk.protocol.modifiers(vk.depressed, vk.latched, vk.locked, 0)
+}
+
+// now returns the event timestamp in milliseconds
+func (vk *VirtualKeyboard) now() uint32 {
+	return uint32(time.Since(vk.start).Milliseconds())
+}
@@ -0,0 +1,133 @@
+package wayland
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// fakeCompositor records the zwp_virtual_keyboard_v1 requests it receives
+type fakeCompositor struct {
+	format   uint32
+	xkb      []byte
+	messages []string
+	times    []uint32
+}
+
+func (f *fakeCompositor) keymap(format uint32, keymap []byte) error {
+	f.format = format
+	f.xkb = keymap
+	f.messages = append(f.messages, "keymap")
+	return nil
+}
+
+func (f *fakeCompositor) key(time, key, state uint32) {
+	f.times = append(f.times, time)
+	f.messages = append(f.messages, fmt.Sprintf("key %d %d", key, state))
+}
+
+func (f *fakeCompositor) modifiers(depressed, latched, locked, group uint32) {
+	f.messages = append(f.messages, fmt.Sprintf("modifiers %d %d %d %d", depressed, latched, locked, group))
+}
+
+func (f *fakeCompositor) destroy() {
+	f.messages = append(f.messages, "destroy")
+}
+
+// take returns the messages received since the last call
+func (f *fakeCompositor) take() []string {
+	messages := f.messages
+	f.messages = nil
+	return messages
+}
+
+func newTestVirtualKeyboard(t *testing.T) (*VirtualKeyboard, *fakeCompositor) {
+	t.Helper()
+	compositor := &fakeCompositor{}
+	vk, err := newVirtualKeyboard(compositor)
+	if err != nil {
+		t.Fatalf("newVirtualKeyboard: %v", err)
+	}
+	return vk, compositor
+}
+
+func expectMessages(t *testing.T, compositor *fakeCompositor, want ...string) {
+	t.Helper()
+	if got := compositor.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("messages = %q, want %q", got, want)
+	}
+}
+
+func TestVirtualKeyboardUploadsKeymapFirst(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+
+	expectMessages(t, compositor, "keymap")
+	if compositor.format != keymapFormatXKB {
+		t.Errorf("keymap format = %d, want %d", compositor.format, keymapFormatXKB)
+	}
+	if !bytes.HasPrefix(compositor.xkb, []byte("xkb_keymap")) || compositor.xkb[len(compositor.xkb)-1] != 0 {
+		t.Errorf("keymap is not a NUL-terminated XKB keymap: %q", compositor.xkb)
+	}
+
+	vk.Press(30)
+	vk.Release(30)
+	expectMessages(t, compositor, "key 30 1", "key 30 0")
+	if compositor.times[1] < compositor.times[0] {
+		t.Errorf("timestamps went backwards: %v", compositor.times)
+	}
+}
+
+func TestVirtualKeyboardLatchesTappedShift(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+	compositor.take()
+
+	vk.Press(42)
+	vk.Release(42)
+	expectMessages(t, compositor, "key 42 1", "modifiers 1 0 0 0", "key 42 0", "modifiers 0 1 0 0")
+
+	// The latch applies to the next key only
+	vk.Press(30)
+	vk.Release(30)
+	expectMessages(t, compositor, "key 30 1", "key 30 0", "modifiers 0 0 0 0")
+
+	vk.Press(31)
+	vk.Release(31)
+	expectMessages(t, compositor, "key 31 1", "key 31 0")
+}
+
+func TestVirtualKeyboardHeldShiftDoesNotLatch(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+	compositor.take()
+
+	vk.Press(42)
+	vk.Press(30)
+	vk.Release(30)
+	vk.Release(42)
+	expectMessages(t, compositor,
+		"key 42 1", "modifiers 1 0 0 0",
+		"key 30 1", "key 30 0",
+		"key 42 0", "modifiers 0 0 0 0")
+}
+
+func TestVirtualKeyboardCapsLockToggles(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+	compositor.take()
+
+	vk.Press(58)
+	vk.Release(58)
+	vk.Press(58)
+	vk.Release(58)
+	expectMessages(t, compositor,
+		"key 58 1", "modifiers 0 0 2 0", "key 58 0",
+		"key 58 1", "modifiers 0 0 0 0", "key 58 0")
+}
+
+func TestVirtualKeyboardCloseReleasesHeldKeys(t *testing.T) {
+	vk, compositor := newTestVirtualKeyboard(t)
+	compositor.take()
+
+	vk.Press(29)
+	vk.Close()
+	expectMessages(t, compositor, "key 29 1", "modifiers 4 0 0 0", "key 29 0", "modifiers 0 0 0 0", "destroy")
+}
@@ -0,0 +1,93 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#cgo pkg-config: wayland-client
+#cgo CFLAGS: -I${SRCDIR}/../../generated
+#cgo LDFLAGS: -L${SRCDIR}/../../generated -lvirtual-keyboard-protocol
+#include <wayland-client.h>
+#include <stdint.h>
+#include "virtual-keyboard-unstable-v1-client-protocol.h"
+*/
+import "C"
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// wlVirtualKeyboard sends virtual keyboard requests through libwayland
+type wlVirtualKeyboard struct {
+	keyboard *C.struct_zwp_virtual_keyboard_v1
+}
+
+// keymap copies the keymap into a memfd and sends it. libwayland duplicates
+// the fd when marshalling, so it is closed straight away.
+func (w *wlVirtualKeyboard) keymap(format uint32, keymap []byte) error {
+	fd, err := memfd()
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	for data := keymap; len(data) > 0; {
+		n, err := syscall.Write(fd, data)
+		if err != nil {
+			return fmt.Errorf("failed to write keymap: %w", err)
+		}
+		data = data[n:]
+	}
+
+	C.zwp_virtual_keyboard_v1_keymap(w.keyboard, C.uint32_t(format), C.int32_t(fd), C.uint32_t(len(keymap)))
+	return nil
+}
+
+func (w *wlVirtualKeyboard) key(time, key, state uint32) {
+	C.zwp_virtual_keyboard_v1_key(w.keyboard, C.uint32_t(time), C.uint32_t(key), C.uint32_t(state))
+}
+
+func (w *wlVirtualKeyboard) modifiers(depressed, latched, locked, group uint32) {
+	C.zwp_virtual_keyboard_v1_modifiers(w.keyboard, C.uint32_t(depressed), C.uint32_t(latched),
+		C.uint32_t(locked), C.uint32_t(group))
+}
+
+func (w *wlVirtualKeyboard) destroy() {
+	C.zwp_virtual_keyboard_v1_destroy(w.keyboard)
+}
+
+// bindVirtualKeyboardManager binds zwp_virtual_keyboard_manager_v1
+func (c *Client) bindVirtualKeyboardManager(name, version uint32) {
+	if c.vkManager != nil {
+		return
+	}
+	c.vkManager = (*C.struct_zwp_virtual_keyboard_manager_v1)(c.bind(name,
+		&C.zwp_virtual_keyboard_manager_v1_interface, negotiate(version, virtualKeyboardManagerVersion)))
+}
+
+// VirtualKeyboard creates a virtual keyboard on the seat that types into
+// the focused application
+func (c *Client) VirtualKeyboard() (*VirtualKeyboard, error) {
+	if c.vkManager == nil {
+		return nil, fmt.Errorf("compositor does not provide zwp_virtual_keyboard_manager_v1")
+	}
+	if c.seat == nil {
+		return nil, fmt.Errorf("no seat to type on")
+	}
+
+	keyboard := C.zwp_virtual_keyboard_manager_v1_create_virtual_keyboard(c.vkManager, c.seat)
+	if keyboard == nil {
+		return nil, fmt.Errorf("failed to create virtual keyboard")
+	}
+	return newVirtualKeyboard(&wlVirtualKeyboard{keyboard: keyboard})
+}
+
+// destroyVirtualKeyboardManager destroys the manager; virtual keyboards
+// created from it are destroyed by their owners
+func (c *Client) destroyVirtualKeyboardManager() {
+	if c.vkManager != nil {
+		C.zwp_virtual_keyboard_manager_v1_destroy(c.vkManager)
+		c.vkManager = nil
+	}
+}
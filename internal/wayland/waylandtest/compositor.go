@@ -0,0 +1,375 @@
+// Package waylandtest provides an in-process Wayland compositor for tests.
+// It speaks enough of the protocol for the keyboard over a socketpair:
+// the registry, wl_compositor, wl_shm, wl_seat, wl_output,
+// zwlr_layer_shell_v1, zwp_virtual_keyboard_manager_v1,
+// zwp_input_method_manager_v2 and, when enabled, wp_viewporter and
+// wp_fractional_scale_manager_v1. Tests inject pointer, touch and text
+// input focus, add and remove outputs, and assert on the buffers the client
+// commits and the keys and text it sends.
+package waylandtest
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// Size of the output the compositor starts with, used for layer surfaces
+// stretched between opposite edges
+const (
+	OutputWidth  = 1280
+	OutputHeight = 720
+)
+
+// waitTimeout is how long Wait waits for the client
+const waitTimeout = 5 * time.Second
+
+// global is a global announced on the registry. Outputs carry their
+// state; removed globals keep their place so names stay stable.
+type global struct {
+	iface   *wire.Interface
+	version uint32
+	output  *output
+	removed bool
+}
+
+// defaultGlobals are announced in this order, named from 1, followed by
+// those the test adds
+func defaultGlobals() []global {
+	return []global{
+		{iface: wire.WlCompositorInterface, version: 4},
+		{iface: wire.WlShmInterface, version: 1},
+		{iface: wire.WlSeatInterface, version: 7},
+		{iface: wire.WlOutputInterface, version: 4, output: &output{Output: Output{
+			Name: "WL-1", Width: OutputWidth, Height: OutputHeight, Scale: 1}}},
+		{iface: wire.ZwlrLayerShellV1Interface, version: 4},
+		{iface: wire.ZwpVirtualKeyboardManagerV1Interface, version: 1},
+		{iface: wire.ZwpInputMethodManagerV2Interface, version: 1},
+	}
+}
+
+// Compositor is a Wayland compositor serving one client. Requests are
+// handled on a goroutine of its own as they arrive; the methods injecting
+// input and reading state may be called from the test at any time.
+type Compositor struct {
+	t      testing.TB
+	conn   *wire.ServerConn
+	start  time.Time
+	done   chan struct{}
+	client int
+
+	// mu guards the protocol state. changed is closed and replaced
+	// whenever a request was handled, waking Wait.
+	mu      sync.Mutex
+	changed chan struct{}
+	serial  uint32
+	objects map[uint32]any
+	// globals are those announced on registry, once the client has one
+	globals  []global
+	registry uint32
+	// preferredScale is sent to wp_fractional_scale_v1 objects, when set
+	preferredScale float64
+
+	frames  []Frame
+	keys    []Key
+	keymap  string
+	commits []TextCommit
+}
+
+// NewCompositor starts a compositor. The client end of the connection is
+// taken with ClientConn or Setenv. The compositor is stopped when the
+// test finishes.
+func NewCompositor(t testing.TB) *Compositor {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+	file := os.NewFile(uintptr(fds[0]), "waylandtest-compositor")
+	sock, err := net.FileConn(file)
+	file.Close()
+	if err != nil {
+		syscall.Close(fds[1])
+		t.Fatalf("FileConn: %v", err)
+	}
+
+	c := &Compositor{
+		t:       t,
+		conn:    wire.NewServerConn(sock.(*net.UnixConn)),
+		start:   time.Now(),
+		done:    make(chan struct{}),
+		client:  fds[1],
+		changed: make(chan struct{}),
+		objects: make(map[uint32]any),
+		globals: defaultGlobals(),
+	}
+	go c.serve()
+
+	t.Cleanup(c.close)
+	return c
+}
+
+// ClientConn returns the client end of the connection
+func (c *Compositor) ClientConn() *net.UnixConn {
+	c.t.Helper()
+	file := os.NewFile(uintptr(c.takeClient()), "waylandtest-client")
+	defer file.Close()
+	conn, err := net.FileConn(file)
+	if err != nil {
+		c.t.Fatalf("FileConn: %v", err)
+	}
+	return conn.(*net.UnixConn)
+}
+
+// Setenv passes the client end of the connection in WAYLAND_SOCKET for
+// the rest of the test, so the next client to connect uses it
+func (c *Compositor) Setenv() {
+	c.t.Helper()
+	c.t.Setenv("WAYLAND_SOCKET", strconv.Itoa(c.takeClient()))
+}
+
+// takeClient hands out the client end once
+func (c *Compositor) takeClient() int {
+	c.t.Helper()
+	if c.client < 0 {
+		c.t.Fatal("waylandtest: client end already taken")
+	}
+	fd := c.client
+	c.client = -1
+	return fd
+}
+
+// close disconnects the client and releases the shared memory it sent
+func (c *Compositor) close() {
+	if c.client >= 0 {
+		syscall.Close(c.client)
+	}
+	c.conn.Close()
+	<-c.done
+
+	for _, obj := range c.objects {
+		switch obj := obj.(type) {
+		case *pool:
+			obj.unmap()
+		case *buffer:
+			obj.pool.unmap()
+		}
+	}
+}
+
+// serve handles requests until the connection closes
+func (c *Compositor) serve() {
+	defer close(c.done)
+	for {
+		r, err := c.conn.ReadRequest()
+		if err != nil {
+			if !errors.Is(err, wire.ErrClosed) && !errors.Is(err, net.ErrClosed) &&
+				!errors.Is(err, syscall.ECONNRESET) {
+				c.t.Errorf("waylandtest: %v", err)
+			}
+			return
+		}
+
+		c.mu.Lock()
+		err = c.handle(r)
+		close(c.changed)
+		c.changed = make(chan struct{})
+		c.mu.Unlock()
+		if err != nil {
+			c.t.Errorf("waylandtest: %s: %v", r.Name(), err)
+			return
+		}
+	}
+}
+
+// handle handles one request. Requests the keyboard never makes, or whose
+// effect tests do not observe, are accepted and ignored.
+func (c *Compositor) handle(r *wire.Request) error {
+	switch r.Name() {
+	case "wl_display.sync":
+		callback := r.NewID(0).ID
+		if err := c.conn.SendEvent(callback, 0, c.nextSerial()); err != nil {
+			return err
+		}
+		return c.conn.Destroy(callback)
+	case "wl_display.get_registry":
+		c.registry = r.NewID(0).ID
+		for i, g := range c.globals {
+			if g.removed {
+				continue
+			}
+			if err := c.conn.SendEvent(c.registry, 0, uint32(i+1), g.iface.Name, g.version); err != nil {
+				return err
+			}
+		}
+	case "wl_registry.bind":
+		return c.bind(r.Uint(0), r.NewID(3))
+
+	case "wl_compositor.create_surface":
+		c.objects[r.NewID(0).ID] = &surface{id: r.NewID(0).ID, bufferScale: 1}
+	case "wl_shm.create_pool":
+		return c.createPool(r.NewID(0).ID, r.FD(1), r.Int(2))
+	case "wl_shm_pool.create_buffer":
+		return c.createBuffer(r)
+	case "wl_shm_pool.resize":
+		return c.objects[r.Object].(*pool).resize(int(r.Int(0)))
+	case "wl_shm_pool.destroy":
+		c.objects[r.Object].(*pool).destroy()
+		delete(c.objects, r.Object)
+	case "wl_buffer.destroy":
+		c.objects[r.Object].(*buffer).pool.release()
+		delete(c.objects, r.Object)
+	case "wl_surface.attach":
+		return c.attach(r.Object, r.Uint(0))
+	case "wl_surface.damage", "wl_surface.damage_buffer":
+		c.objects[r.Object].(*surface).damage(r.Int(0), r.Int(1), r.Int(2), r.Int(3))
+	case "wl_surface.set_buffer_scale":
+		if r.Int(0) < 1 {
+			return fmt.Errorf("invalid buffer scale %d", r.Int(0))
+		}
+		c.objects[r.Object].(*surface).bufferScale = r.Int(0)
+	case "wl_surface.frame":
+		s := c.objects[r.Object].(*surface)
+		s.callbacks = append(s.callbacks, r.NewID(0).ID)
+	case "wl_surface.commit":
+		return c.commit(c.objects[r.Object].(*surface))
+
+	case "wl_seat.get_pointer", "wl_seat.get_touch", "wl_seat.get_keyboard":
+		id := r.NewID(0)
+		c.objects[id.ID] = &device{kind: id.Interface, version: id.Version}
+
+	case "zwlr_layer_shell_v1.get_layer_surface":
+		return c.getLayerSurface(r)
+	case "zwlr_layer_surface_v1.set_size", "zwlr_layer_surface_v1.set_anchor",
+		"zwlr_layer_surface_v1.set_exclusive_zone", "zwlr_layer_surface_v1.set_margin",
+		"zwlr_layer_surface_v1.set_keyboard_interactivity", "zwlr_layer_surface_v1.set_layer",
+		"zwlr_layer_surface_v1.ack_configure":
+		c.objects[r.Object].(*layerSurface).set(r)
+
+	case "wp_viewporter.get_viewport":
+		return c.getViewport(r)
+	case "wp_viewport.set_destination":
+		c.objects[r.Object].(*viewport).surface.destination = image.Pt(int(r.Int(0)), int(r.Int(1)))
+	case "wp_fractional_scale_manager_v1.get_fractional_scale":
+		return c.getFractionalScale(r)
+
+	case "zwp_virtual_keyboard_manager_v1.create_virtual_keyboard":
+		c.objects[r.NewID(1).ID] = &virtualKeyboard{}
+	case "zwp_virtual_keyboard_v1.keymap":
+		return c.setKeymap(r.FD(1), r.Uint(2))
+	case "zwp_virtual_keyboard_v1.key":
+		vk := c.objects[r.Object].(*virtualKeyboard)
+		c.keys = append(c.keys, Key{Code: r.Uint(1), Pressed: r.Uint(2) == 1, Modifiers: vk.modifiers})
+	case "zwp_virtual_keyboard_v1.modifiers":
+		c.objects[r.Object].(*virtualKeyboard).modifiers = r.Uint(0) | r.Uint(1) | r.Uint(2)
+
+	case "zwp_input_method_manager_v2.get_input_method":
+		c.objects[r.NewID(1).ID] = &inputMethod{}
+	case "zwp_input_method_v2.commit_string", "zwp_input_method_v2.set_preedit_string",
+		"zwp_input_method_v2.delete_surrounding_text", "zwp_input_method_v2.commit":
+		c.inputMethodRequest(c.objects[r.Object].(*inputMethod), r)
+	}
+
+	// The connection has already told the client the object is gone
+	if r.Message.Destructor {
+		delete(c.objects, r.Object)
+	}
+	return nil
+}
+
+// bind creates the object for a global and sends its initial events
+func (c *Compositor) bind(name uint32, id wire.NewID) error {
+	if name == 0 || int(name) > len(c.globals) || c.globals[name-1].iface != id.Interface {
+		return fmt.Errorf("no global %d of interface %s", name, id.Interface.Name)
+	}
+	g := c.globals[name-1]
+	if id.Version > g.version {
+		return fmt.Errorf("%s version %d is above the advertised %d", id.Interface.Name, id.Version, g.version)
+	}
+
+	switch id.Interface {
+	case wire.WlShmInterface:
+		for _, format := range []uint32{wire.WlShmFormatArgb8888, wire.WlShmFormatXrgb8888} {
+			if err := c.conn.SendEvent(id.ID, 0, format); err != nil {
+				return err
+			}
+		}
+	case wire.WlSeatInterface:
+		c.objects[id.ID] = &seat{}
+		return c.bindSeat(id)
+	case wire.WlOutputInterface:
+		bound := &boundOutput{id: id.ID, version: id.Version, output: g.output}
+		c.objects[id.ID] = bound
+		return c.describeOutput(bound)
+	}
+	return nil
+}
+
+// send sends the event called name to object id
+func (c *Compositor) send(id uint32, name string, args ...any) error {
+	iface := c.conn.Interface(id)
+	if iface == nil {
+		return fmt.Errorf("event %s to unknown object %d", name, id)
+	}
+	for opcode, event := range iface.Events {
+		if event.Name == name {
+			return c.conn.SendEvent(id, uint16(opcode), args...)
+		}
+	}
+	return fmt.Errorf("%s has no event %s", iface.Name, name)
+}
+
+// nextSerial returns a new event serial
+func (c *Compositor) nextSerial() uint32 {
+	c.serial++
+	return c.serial
+}
+
+// now returns the event timestamp in milliseconds
+func (c *Compositor) now() uint32 {
+	return uint32(time.Since(c.start).Milliseconds())
+}
+
+// Wait waits until cond holds, re-checking it after every request the
+// client sends. It fails the test if that takes too long.
+func (c *Compositor) Wait(cond func() bool) {
+	c.t.Helper()
+	deadline := time.After(waitTimeout)
+	for {
+		c.mu.Lock()
+		changed := c.changed
+		c.mu.Unlock()
+		if cond() {
+			return
+		}
+		select {
+		case <-changed:
+		case <-c.done:
+			if !cond() {
+				c.t.Fatal("waylandtest: client disconnected while waiting")
+			}
+			return
+		case <-deadline:
+			c.t.Fatal("waylandtest: timed out waiting for the client")
+		}
+	}
+}
+
+// inject sends events from the test, failing it if they cannot be sent
+func (c *Compositor) inject(fn func() error) {
+	c.t.Helper()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := fn(); err != nil {
+		c.t.Fatalf("waylandtest: %v", err)
+	}
+}
@@ -0,0 +1,188 @@
+package waylandtest
+
+import (
+	"fmt"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// Linux input event codes of pointer buttons
+const (
+	ButtonLeft   = 0x110
+	ButtonRight  = 0x111
+	ButtonMiddle = 0x112
+)
+
+// seat is the wl_seat; it has a pointer and a touch screen
+type seat struct{}
+
+// device is a wl_pointer, wl_touch or wl_keyboard. entered is set once a
+// pointer was sent enter.
+type device struct {
+	kind    *wire.Interface
+	version uint32
+	entered bool
+}
+
+func (c *Compositor) bindSeat(id wire.NewID) error {
+	if err := c.send(id.ID, "capabilities", uint32(wire.WlSeatCapabilityPointer|wire.WlSeatCapabilityTouch)); err != nil {
+		return err
+	}
+	if id.Version >= 2 {
+		return c.send(id.ID, "name", "seat0")
+	}
+	return nil
+}
+
+// devices calls fn for every device of kind the client created
+func (c *Compositor) devices(kind *wire.Interface, fn func(id uint32, d *device) error) error {
+	found := false
+	for id, obj := range c.objects {
+		if d, ok := obj.(*device); ok && d.kind == kind {
+			found = true
+			if err := fn(id, d); err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("client has no %s", kind.Name)
+	}
+	return nil
+}
+
+// target returns the surface input goes to: the layer surface, once
+// mapped
+func (c *Compositor) target() (uint32, error) {
+	l := c.layerSurface()
+	if l == nil || !l.surface.mapped {
+		return 0, fmt.Errorf("no mapped surface to send input to")
+	}
+	return l.surface.id, nil
+}
+
+// pointerFrame ends a group of pointer events on pointers that have frames
+func (c *Compositor) pointerFrame(id uint32, d *device) error {
+	if d.version < 5 {
+		return nil
+	}
+	return c.send(id, "frame")
+}
+
+// PointerMove moves the pointer to x, y on the keyboard surface, entering
+// it first if needed
+func (c *Compositor) PointerMove(x, y float64) {
+	c.t.Helper()
+	c.inject(func() error {
+		surface, err := c.target()
+		if err != nil {
+			return err
+		}
+		return c.devices(wire.WlPointerInterface, func(id uint32, d *device) error {
+			if d.entered {
+				err = c.send(id, "motion", c.now(), wire.FixedFromFloat(x), wire.FixedFromFloat(y))
+			} else {
+				d.entered = true
+				err = c.send(id, "enter", c.nextSerial(), surface, wire.FixedFromFloat(x), wire.FixedFromFloat(y))
+			}
+			if err != nil {
+				return err
+			}
+			return c.pointerFrame(id, d)
+		})
+	})
+}
+
+// PointerButton presses or releases a pointer button
+func (c *Compositor) PointerButton(button uint32, pressed bool) {
+	c.t.Helper()
+	state := uint32(wire.WlPointerButtonStateReleased)
+	if pressed {
+		state = wire.WlPointerButtonStatePressed
+	}
+	c.inject(func() error {
+		return c.devices(wire.WlPointerInterface, func(id uint32, d *device) error {
+			if !d.entered {
+				return fmt.Errorf("button pressed before the pointer entered the surface")
+			}
+			if err := c.send(id, "button", c.nextSerial(), c.now(), button, state); err != nil {
+				return err
+			}
+			return c.pointerFrame(id, d)
+		})
+	})
+}
+
+// Scroll scrolls along axis, a wire.WlPointerAxis value, by value surface
+// pixels at the pointer
+func (c *Compositor) Scroll(axis uint32, value float64) {
+	c.t.Helper()
+	c.inject(func() error {
+		return c.devices(wire.WlPointerInterface, func(id uint32, d *device) error {
+			if !d.entered {
+				return fmt.Errorf("scrolled before the pointer entered the surface")
+			}
+			if err := c.send(id, "axis", c.now(), axis, wire.FixedFromFloat(value)); err != nil {
+				return err
+			}
+			return c.pointerFrame(id, d)
+		})
+	})
+}
+
+// Click moves the pointer to x, y and clicks the left button
+func (c *Compositor) Click(x, y float64) {
+	c.t.Helper()
+	c.PointerMove(x, y)
+	c.PointerButton(ButtonLeft, true)
+	c.PointerButton(ButtonLeft, false)
+}
+
+// touch sends a touch event followed by a frame
+func (c *Compositor) touch(event func(id uint32, surface uint32) error) {
+	c.t.Helper()
+	c.inject(func() error {
+		surface, err := c.target()
+		if err != nil {
+			return err
+		}
+		return c.devices(wire.WlTouchInterface, func(id uint32, d *device) error {
+			if err := event(id, surface); err != nil {
+				return err
+			}
+			return c.send(id, "frame")
+		})
+	})
+}
+
+// TouchDown puts touch point id down at x, y on the keyboard surface
+func (c *Compositor) TouchDown(id int32, x, y float64) {
+	c.t.Helper()
+	c.touch(func(touch, surface uint32) error {
+		return c.send(touch, "down", c.nextSerial(), c.now(), surface, id,
+			wire.FixedFromFloat(x), wire.FixedFromFloat(y))
+	})
+}
+
+// TouchMove moves touch point id to x, y
+func (c *Compositor) TouchMove(id int32, x, y float64) {
+	c.t.Helper()
+	c.touch(func(touch, surface uint32) error {
+		return c.send(touch, "motion", c.now(), id, wire.FixedFromFloat(x), wire.FixedFromFloat(y))
+	})
+}
+
+// TouchUp lifts touch point id
+func (c *Compositor) TouchUp(id int32) {
+	c.t.Helper()
+	c.touch(func(touch, surface uint32) error {
+		return c.send(touch, "up", c.nextSerial(), c.now(), id)
+	})
+}
+
+// Tap touches x, y with one finger and lifts it
+func (c *Compositor) Tap(x, y float64) {
+	c.t.Helper()
+	c.TouchDown(0, x, y)
+	c.TouchUp(0)
+}
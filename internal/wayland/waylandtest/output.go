@@ -0,0 +1,184 @@
+package waylandtest
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// Output describes an output of the compositor. Scale is the integer
+// buffer scale the output suggests, and Transform its wl_output transform.
+type Output struct {
+	Name          string
+	Width, Height int32
+	Scale         int32
+	Transform     int32
+}
+
+// output is the state of an output global
+type output struct {
+	Output
+}
+
+// boundOutput is a wl_output object of the client
+type boundOutput struct {
+	id      uint32
+	version uint32
+	output  *output
+}
+
+// viewport is a wp_viewport, setting the destination size of its surface
+type viewport struct {
+	surface *surface
+}
+
+// fractionalScale is a wp_fractional_scale_v1 of a surface
+type fractionalScale struct {
+	id uint32
+}
+
+// describeOutput sends the state of an output to a wl_output of the
+// client, as far as its version has events for
+func (c *Compositor) describeOutput(bound *boundOutput) error {
+	o := bound.output
+	events := [][]any{
+		{"geometry", int32(0), int32(0), int32(0), int32(0), int32(0), "waylandtest", "virtual", o.Transform},
+		{"mode", uint32(wire.WlOutputModeCurrent | wire.WlOutputModePreferred), o.Width, o.Height, int32(60000)},
+	}
+	if bound.version >= 2 {
+		events = append(events, []any{"scale", o.Scale})
+	}
+	if bound.version >= 4 {
+		events = append(events, []any{"name", o.Name}, []any{"description", "waylandtest output " + o.Name})
+	}
+	if bound.version >= 2 {
+		events = append(events, []any{"done"})
+	}
+	for _, event := range events {
+		if err := c.send(bound.id, event[0].(string), event[1:]...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddOutput adds an output. Outputs added before the client connects are
+// announced with the other globals; later ones are announced at once.
+func (c *Compositor) AddOutput(o Output) {
+	c.t.Helper()
+	if o.Scale == 0 {
+		o.Scale = 1
+	}
+	c.addGlobal(global{iface: wire.WlOutputInterface, version: 4, output: &output{Output: o}})
+}
+
+// RemoveOutput removes the output called name, as when it is unplugged
+func (c *Compositor) RemoveOutput(name string) {
+	c.t.Helper()
+	c.inject(func() error {
+		for i := range c.globals {
+			g := &c.globals[i]
+			if g.output == nil || g.removed || g.output.Name != name {
+				continue
+			}
+			g.removed = true
+			if c.registry == 0 {
+				return nil
+			}
+			return c.send(c.registry, "global_remove", uint32(i+1))
+		}
+		return fmt.Errorf("no output named %s", name)
+	})
+}
+
+// EnableFractionalScale offers wp_viewporter and
+// wp_fractional_scale_manager_v1, with scale as the preferred scale of
+// every surface. It announces them at once if the client has connected.
+func (c *Compositor) EnableFractionalScale(scale float64) {
+	c.t.Helper()
+	c.mu.Lock()
+	c.preferredScale = scale
+	c.mu.Unlock()
+	c.addGlobal(global{iface: wire.WpViewporterInterface, version: 1})
+	c.addGlobal(global{iface: wire.WpFractionalScaleManagerV1Interface, version: 1})
+}
+
+// SetPreferredScale changes the fractional scale preferred for every
+// surface
+func (c *Compositor) SetPreferredScale(scale float64) {
+	c.t.Helper()
+	c.inject(func() error {
+		c.preferredScale = scale
+		for _, obj := range c.objects {
+			if f, ok := obj.(*fractionalScale); ok {
+				if err := c.sendPreferredScale(f); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// addGlobal adds a global, announcing it if the client has a registry
+func (c *Compositor) addGlobal(g global) {
+	c.t.Helper()
+	c.inject(func() error {
+		c.globals = append(c.globals, g)
+		if c.registry == 0 {
+			return nil
+		}
+		return c.send(c.registry, "global", uint32(len(c.globals)), g.iface.Name, g.version)
+	})
+}
+
+// outputNamed returns the output called name, or the first output for an
+// empty name
+func (c *Compositor) outputNamed(name string) *output {
+	for _, g := range c.globals {
+		if g.output != nil && !g.removed && (name == "" || g.output.Name == name) {
+			return g.output
+		}
+	}
+	return nil
+}
+
+// enter tells the client its surface is shown on an output, through each
+// wl_output it bound for it
+func (c *Compositor) enter(s *surface, o *output) error {
+	for _, obj := range c.objects {
+		if bound, ok := obj.(*boundOutput); ok && bound.output == o {
+			if err := c.send(s.id, "enter", bound.id); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Compositor) getViewport(r *wire.Request) error {
+	s, ok := c.objects[r.Uint(1)].(*surface)
+	if !ok {
+		return fmt.Errorf("object %d is not a surface", r.Uint(1))
+	}
+	c.objects[r.NewID(0).ID] = &viewport{surface: s}
+	return nil
+}
+
+func (c *Compositor) getFractionalScale(r *wire.Request) error {
+	if _, ok := c.objects[r.Uint(1)].(*surface); !ok {
+		return fmt.Errorf("object %d is not a surface", r.Uint(1))
+	}
+	f := &fractionalScale{id: r.NewID(0).ID}
+	c.objects[f.id] = f
+	return c.sendPreferredScale(f)
+}
+
+// sendPreferredScale sends the preferred scale, in 120ths
+func (c *Compositor) sendPreferredScale(f *fractionalScale) error {
+	if c.preferredScale <= 0 {
+		return nil
+	}
+	return c.send(f.id, "preferred_scale", uint32(math.Round(c.preferredScale*120)))
+}
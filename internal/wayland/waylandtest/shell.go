@@ -0,0 +1,160 @@
+package waylandtest
+
+import (
+	"fmt"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// LayerSurface is the state of the client's layer surface
+type LayerSurface struct {
+	Namespace             string
+	Layer                 uint32
+	Anchor                uint32
+	Width, Height         uint32
+	ExclusiveZone         int32
+	Margins               [4]int32 // top, right, bottom, left
+	KeyboardInteractivity uint32
+	// Output is the name of the output the client asked for, or empty
+	// when it left the choice to the compositor
+	Output string
+
+	// ConfiguredWidth and ConfiguredHeight are the size last sent in a
+	// configure; Acked is set once the client acked it
+	ConfiguredWidth  uint32
+	ConfiguredHeight uint32
+	Acked            bool
+	// Mapped is set while a buffer is attached
+	Mapped bool
+}
+
+// layerSurface is a zwlr_layer_surface_v1. The pending state the client
+// sets is applied on the surface's next commit in a real compositor; here
+// it takes effect at once, which tests cannot tell apart.
+type layerSurface struct {
+	id         uint32
+	surface    *surface
+	state      LayerSurface
+	configured bool
+	serial     uint32
+}
+
+func (c *Compositor) getLayerSurface(r *wire.Request) error {
+	s, ok := c.objects[r.Uint(1)].(*surface)
+	if !ok {
+		return fmt.Errorf("object %d is not a surface", r.Uint(1))
+	}
+	if s.role != nil {
+		return fmt.Errorf("surface %d already has a role", s.id)
+	}
+	l := &layerSurface{
+		id:      r.NewID(0).ID,
+		surface: s,
+		state:   LayerSurface{Layer: r.Uint(3), Namespace: r.String(4)},
+	}
+	if id := r.Uint(2); id != 0 {
+		bound, ok := c.objects[id].(*boundOutput)
+		if !ok {
+			return fmt.Errorf("object %d is not an output", id)
+		}
+		l.state.Output = bound.output.Name
+	}
+	s.role = l
+	c.objects[l.id] = l
+	return nil
+}
+
+// set applies a request setting the layer surface state
+func (l *layerSurface) set(r *wire.Request) {
+	switch r.Message.Name {
+	case "set_size":
+		l.state.Width, l.state.Height = r.Uint(0), r.Uint(1)
+	case "set_anchor":
+		l.state.Anchor = r.Uint(0)
+	case "set_exclusive_zone":
+		l.state.ExclusiveZone = r.Int(0)
+	case "set_margin":
+		l.state.Margins = [4]int32{r.Int(0), r.Int(1), r.Int(2), r.Int(3)}
+	case "set_keyboard_interactivity":
+		l.state.KeyboardInteractivity = r.Uint(0)
+	case "set_layer":
+		l.state.Layer = r.Uint(0)
+	case "ack_configure":
+		if r.Uint(0) == l.serial {
+			l.state.Acked = true
+		}
+	}
+}
+
+// configure sends the size the compositor chose: the requested size, or
+// the size of its output along an axis the surface is stretched on
+func (l *layerSurface) configure(c *Compositor) error {
+	o := c.outputNamed(l.state.Output)
+	if o == nil {
+		return fmt.Errorf("no output to show the layer surface on")
+	}
+	width, height := l.state.Width, l.state.Height
+	horizontal := uint32(wire.ZwlrLayerSurfaceV1AnchorLeft | wire.ZwlrLayerSurfaceV1AnchorRight)
+	vertical := uint32(wire.ZwlrLayerSurfaceV1AnchorTop | wire.ZwlrLayerSurfaceV1AnchorBottom)
+	if width == 0 {
+		if l.state.Anchor&horizontal != horizontal {
+			return fmt.Errorf("width 0 without anchoring to the left and right edges")
+		}
+		width = uint32(o.Width)
+	}
+	if height == 0 {
+		if l.state.Anchor&vertical != vertical {
+			return fmt.Errorf("height 0 without anchoring to the top and bottom edges")
+		}
+		height = uint32(o.Height)
+	}
+
+	l.configured = true
+	l.serial = c.nextSerial()
+	l.state.ConfiguredWidth, l.state.ConfiguredHeight = width, height
+	l.state.Acked = false
+	return c.send(l.id, "configure", l.serial, width, height)
+}
+
+// reset forgets the configure after the surface was unmapped
+func (l *layerSurface) reset() {
+	l.configured = false
+	l.state.Acked = false
+}
+
+// LayerSurface returns the state of the client's layer surface, and false
+// if it has none
+func (c *Compositor) LayerSurface() (LayerSurface, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	l := c.layerSurface()
+	if l == nil {
+		return LayerSurface{}, false
+	}
+	state := l.state
+	state.Mapped = l.surface.mapped
+	return state, true
+}
+
+// layerSurface returns the client's layer surface, or nil
+func (c *Compositor) layerSurface() *layerSurface {
+	for _, obj := range c.objects {
+		if l, ok := obj.(*layerSurface); ok {
+			return l
+		}
+	}
+	return nil
+}
+
+// CloseLayerSurface tells the client its layer surface was closed, as when
+// its output goes away
+func (c *Compositor) CloseLayerSurface() {
+	c.t.Helper()
+	c.inject(func() error {
+		l := c.layerSurface()
+		if l == nil {
+			return fmt.Errorf("no layer surface to close")
+		}
+		return c.send(l.id, "closed")
+	})
+}
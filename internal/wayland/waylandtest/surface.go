@@ -0,0 +1,240 @@
+package waylandtest
+
+import (
+	"fmt"
+	"image"
+	"syscall"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// Frame is a buffer the client committed, copied when it was committed
+type Frame struct {
+	Image *image.RGBA
+	// Damage is the damage committed with the buffer, in surface
+	// coordinates
+	Damage []image.Rectangle
+	// BufferScale is the surface buffer scale, and Destination the
+	// viewport destination size, if the client set one
+	BufferScale int32
+	Destination image.Point
+}
+
+// surface is a wl_surface with its pending and committed state
+type surface struct {
+	id        uint32
+	role      *layerSurface
+	attached  bool
+	buffer    *buffer
+	damaged   []image.Rectangle
+	callbacks []uint32
+	mapped    bool
+	// bufferScale and destination apply at once rather than on commit,
+	// as the layer surface state does
+	bufferScale int32
+	destination image.Point
+}
+
+// damage adds a damaged rectangle to the pending state
+func (s *surface) damage(x, y, width, height int32) {
+	s.damaged = append(s.damaged, image.Rect(int(x), int(y), int(x+width), int(y+height)))
+}
+
+// pool is a wl_shm_pool, mapped read-only. It stays mapped until it and
+// every buffer created from it are destroyed.
+type pool struct {
+	fd        int
+	data      []byte
+	buffers   int
+	destroyed bool
+}
+
+// buffer is a wl_buffer in a pool
+type buffer struct {
+	id                            uint32
+	pool                          *pool
+	offset, width, height, stride int
+	format                        uint32
+}
+
+func (c *Compositor) createPool(id uint32, fd int, size int32) error {
+	p := &pool{fd: fd}
+	c.objects[id] = p
+	return p.resize(int(size))
+}
+
+// resize maps the pool at its new size
+func (p *pool) resize(size int) error {
+	if size <= 0 || size < len(p.data) {
+		return fmt.Errorf("invalid pool size %d", size)
+	}
+	if p.data != nil {
+		syscall.Munmap(p.data)
+	}
+	data, err := syscall.Mmap(p.fd, 0, size, syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("mapping the pool: %w", err)
+	}
+	p.data = data
+	return nil
+}
+
+func (p *pool) destroy() {
+	p.destroyed = true
+	if p.buffers == 0 {
+		p.unmap()
+	}
+}
+
+// release drops the reference of a destroyed buffer
+func (p *pool) release() {
+	p.buffers--
+	if p.destroyed && p.buffers == 0 {
+		p.unmap()
+	}
+}
+
+func (p *pool) unmap() {
+	if p.data != nil {
+		syscall.Munmap(p.data)
+		p.data = nil
+	}
+	if p.fd >= 0 {
+		syscall.Close(p.fd)
+		p.fd = -1
+	}
+}
+
+func (c *Compositor) createBuffer(r *wire.Request) error {
+	p := c.objects[r.Object].(*pool)
+	b := &buffer{
+		id:     r.NewID(0).ID,
+		pool:   p,
+		offset: int(r.Int(1)),
+		width:  int(r.Int(2)),
+		height: int(r.Int(3)),
+		stride: int(r.Int(4)),
+		format: r.Uint(5),
+	}
+	if b.format != wire.WlShmFormatArgb8888 && b.format != wire.WlShmFormatXrgb8888 {
+		return fmt.Errorf("unsupported format %#x", b.format)
+	}
+	if b.width <= 0 || b.height <= 0 || b.stride < 4*b.width || b.offset < 0 ||
+		b.offset+b.stride*b.height > len(p.data) {
+		return fmt.Errorf("buffer %dx%d with stride %d at %d does not fit a pool of %d bytes",
+			b.width, b.height, b.stride, b.offset, len(p.data))
+	}
+	p.buffers++
+	c.objects[b.id] = b
+	return nil
+}
+
+// image copies the buffer contents
+func (b *buffer) image() *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, b.width, b.height))
+	for y := 0; y < b.height; y++ {
+		src := b.pool.data[b.offset+y*b.stride:]
+		dst := img.Pix[y*img.Stride:]
+		for x := 0; x < b.width; x++ {
+			// Little-endian ARGB8888 is stored as B, G, R, A
+			dst[4*x], dst[4*x+1], dst[4*x+2], dst[4*x+3] = src[4*x+2], src[4*x+1], src[4*x], src[4*x+3]
+			if b.format == wire.WlShmFormatXrgb8888 {
+				dst[4*x+3] = 0xff
+			}
+		}
+	}
+	return img
+}
+
+func (c *Compositor) attach(id, bufferID uint32) error {
+	s := c.objects[id].(*surface)
+	s.attached = true
+	s.buffer = nil
+	if bufferID != 0 {
+		b, ok := c.objects[bufferID].(*buffer)
+		if !ok {
+			return fmt.Errorf("object %d is not a buffer", bufferID)
+		}
+		s.buffer = b
+	}
+	return nil
+}
+
+// commit applies the pending state. A committed buffer is copied and
+// released straight away, and frame callbacks are done at once, so the
+// client may draw as fast as it likes. As on real compositors, callbacks
+// of an unmapped surface are held until a buffer maps it.
+func (c *Compositor) commit(s *surface) error {
+	unmapped := false
+	if s.attached && s.buffer == nil && s.mapped {
+		// Unmapping resets the surface; it has to be configured again
+		// before it is mapped
+		s.mapped = false
+		unmapped = true
+		if s.role != nil {
+			s.role.reset()
+		}
+	}
+	if s.attached && s.buffer != nil {
+		if s.role != nil && !s.role.configured {
+			return fmt.Errorf("buffer attached before the first configure")
+		}
+		if s.destination == (image.Point{}) &&
+			(s.buffer.width%int(s.bufferScale) != 0 || s.buffer.height%int(s.bufferScale) != 0) {
+			return fmt.Errorf("buffer of %dx%d is not a multiple of the buffer scale %d",
+				s.buffer.width, s.buffer.height, s.bufferScale)
+		}
+		c.frames = append(c.frames, Frame{Image: s.buffer.image(), Damage: s.damaged,
+			BufferScale: s.bufferScale, Destination: s.destination})
+		if !s.mapped && s.role != nil {
+			if o := c.outputNamed(s.role.state.Output); o != nil {
+				if err := c.enter(s, o); err != nil {
+					return err
+				}
+			}
+		}
+		s.mapped = true
+		if err := c.send(s.buffer.id, "release"); err != nil {
+			return err
+		}
+	}
+	if s.role != nil && !s.role.configured && !unmapped {
+		if err := s.role.configure(c); err != nil {
+			return err
+		}
+	}
+	s.attached = false
+	s.damaged = nil
+
+	if !s.mapped {
+		return nil
+	}
+	for _, callback := range s.callbacks {
+		if err := c.conn.SendEvent(callback, 0, c.now()); err != nil {
+			return err
+		}
+		if err := c.conn.Destroy(callback); err != nil {
+			return err
+		}
+	}
+	s.callbacks = nil
+	return nil
+}
+
+// Frames returns the buffers committed so far, oldest first
+func (c *Compositor) Frames() []Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Frame(nil), c.frames...)
+}
+
+// LastFrame returns the buffer committed last, or nil if there is none
+func (c *Compositor) LastFrame() *Frame {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.frames) == 0 {
+		return nil
+	}
+	frame := c.frames[len(c.frames)-1]
+	return &frame
+}
@@ -0,0 +1,145 @@
+package waylandtest
+
+import (
+	"bytes"
+	"fmt"
+	"syscall"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// Key is a key pressed or released on a virtual keyboard
+type Key struct {
+	// Code is the key code in the client's keymap; the keyboard uses
+	// evdev codes for US keys
+	Code    uint32
+	Pressed bool
+	// Modifiers are the depressed, latched and locked modifiers the
+	// client had set when it sent the key
+	Modifiers uint32
+}
+
+// TextCommit is the state an input method applied with one commit
+type TextCommit struct {
+	Text         string
+	Preedit      string
+	DeleteBefore uint32
+	DeleteAfter  uint32
+}
+
+// virtualKeyboard is a zwp_virtual_keyboard_v1
+type virtualKeyboard struct {
+	modifiers uint32
+}
+
+// inputMethod is a zwp_input_method_v2 and its pending state
+type inputMethod struct {
+	pending TextCommit
+}
+
+// setKeymap reads the keymap the client uploaded
+func (c *Compositor) setKeymap(fd int, size uint32) error {
+	defer syscall.Close(fd)
+	data := make([]byte, size)
+	if _, err := syscall.Pread(fd, data, 0); err != nil {
+		return fmt.Errorf("reading the keymap: %w", err)
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		data = data[:i]
+	}
+	c.keymap = string(data)
+	return nil
+}
+
+// inputMethodRequest applies a request of an input method. Its state is
+// recorded when it is committed.
+func (c *Compositor) inputMethodRequest(im *inputMethod, r *wire.Request) {
+	switch r.Message.Name {
+	case "commit_string":
+		im.pending.Text = r.String(0)
+	case "set_preedit_string":
+		im.pending.Preedit = r.String(0)
+	case "delete_surrounding_text":
+		im.pending.DeleteBefore, im.pending.DeleteAfter = r.Uint(0), r.Uint(1)
+	case "commit":
+		c.commits = append(c.commits, im.pending)
+		im.pending = TextCommit{}
+	}
+}
+
+// Keys returns the keys the client sent on its virtual keyboards, oldest
+// first
+func (c *Compositor) Keys() []Key {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Key(nil), c.keys...)
+}
+
+// Keymap returns the keymap last uploaded to a virtual keyboard
+func (c *Compositor) Keymap() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.keymap
+}
+
+// Commits returns the state the client's input method committed, oldest
+// first
+func (c *Compositor) Commits() []TextCommit {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]TextCommit(nil), c.commits...)
+}
+
+// inputMethods calls fn for every input method of the client
+func (c *Compositor) inputMethods(fn func(id uint32, im *inputMethod) error) error {
+	found := false
+	for id, obj := range c.objects {
+		if im, ok := obj.(*inputMethod); ok {
+			found = true
+			if err := fn(id, im); err != nil {
+				return err
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("client has no input method")
+	}
+	return nil
+}
+
+// FocusTextInput focuses a text input holding text, with the cursor at
+// its end, and the given zwp_text_input_v3 content purpose
+func (c *Compositor) FocusTextInput(text string, purpose uint32) {
+	c.t.Helper()
+	cursor := uint32(len(text))
+	c.inject(func() error {
+		return c.inputMethods(func(id uint32, im *inputMethod) error {
+			events := [][]any{
+				{"activate"},
+				{"surrounding_text", text, cursor, cursor},
+				{"text_change_cause", uint32(0)},
+				{"content_type", uint32(0), purpose},
+				{"done"},
+			}
+			for _, event := range events {
+				if err := c.send(id, event[0].(string), event[1:]...); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
+
+// UnfocusTextInput moves the focus away from the text input
+func (c *Compositor) UnfocusTextInput() {
+	c.t.Helper()
+	c.inject(func() error {
+		return c.inputMethods(func(id uint32, im *inputMethod) error {
+			if err := c.send(id, "deactivate"); err != nil {
+				return err
+			}
+			return c.send(id, "done")
+		})
+	})
+}
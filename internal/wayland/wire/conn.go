@@ -0,0 +1,449 @@
+package wire
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+const (
+	// bufferSize is how many bytes of requests are buffered before they
+	// are sent without waiting for Flush
+	bufferSize = 4096
+	// maxFDs is the number of file descriptors sent or received with one
+	// sendmsg or recvmsg, as in libwayland
+	maxFDs = 28
+	// serverIDs is the first id of objects created by the compositor
+	serverIDs = 0xff000000
+)
+
+// ErrClosed is returned for a connection that has been closed
+var ErrClosed = errors.New("wayland connection closed")
+
+// Conn is a client connection to a Wayland compositor. Requests are
+// buffered until Flush. Events are read by a goroutine and queued, and
+// their handlers run on the goroutine that calls Dispatch or Roundtrip.
+type Conn struct {
+	sock    *net.UnixConn
+	display *WlDisplay
+
+	// mu guards the object table and the outgoing requests. err is the
+	// first protocol or connection error; nothing is sent after it.
+	mu      sync.Mutex
+	objects map[uint32]Object
+	nextID  uint32
+	freeIDs []uint32
+	out     []byte
+	outFDs  []int
+	err     error
+
+	// inMu guards what the reader received and has not been dispatched
+	inMu     sync.Mutex
+	in       []byte
+	inFDs    []int
+	readErr  error
+	readable chan struct{}
+	done     chan struct{}
+}
+
+// Dial connects to the compositor of the session: the socket passed in
+// WAYLAND_SOCKET, or WAYLAND_DISPLAY (wayland-0 by default) in
+// XDG_RUNTIME_DIR. WAYLAND_DISPLAY may also be an absolute path.
+func Dial() (*Conn, error) {
+	if value := os.Getenv("WAYLAND_SOCKET"); value != "" {
+		// The socket belongs to this process alone; children must not
+		// try to use it too
+		os.Unsetenv("WAYLAND_SOCKET")
+		fd, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WAYLAND_SOCKET %q", value)
+		}
+		file := os.NewFile(uintptr(fd), "wayland-socket")
+		defer file.Close()
+		conn, err := net.FileConn(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to use WAYLAND_SOCKET: %w", err)
+		}
+		sock, ok := conn.(*net.UnixConn)
+		if !ok {
+			conn.Close()
+			return nil, fmt.Errorf("WAYLAND_SOCKET is not a Unix socket")
+		}
+		return NewConn(sock), nil
+	}
+
+	name := os.Getenv("WAYLAND_DISPLAY")
+	if name == "" {
+		name = "wayland-0"
+	}
+	if !filepath.IsAbs(name) {
+		dir := os.Getenv("XDG_RUNTIME_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("XDG_RUNTIME_DIR is not set")
+		}
+		name = filepath.Join(dir, name)
+	}
+	sock, err := net.DialUnix("unix", nil, &net.UnixAddr{Name: name, Net: "unix"})
+	if err != nil {
+		return nil, err
+	}
+	return NewConn(sock), nil
+}
+
+// NewConn starts a connection over sock, which must be connected to a
+// compositor. The connection owns the socket.
+func NewConn(sock *net.UnixConn) *Conn {
+	c := &Conn{
+		sock:     sock,
+		objects:  make(map[uint32]Object),
+		nextID:   1,
+		readable: make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+	c.display = &WlDisplay{}
+	c.register(c.display, 1)
+	c.display.OnError = c.protocolError
+	c.display.OnDeleteID = c.deleteID
+
+	go c.read()
+	return c
+}
+
+// Display returns the wl_display singleton
+func (c *Conn) Display() *WlDisplay {
+	return c.display
+}
+
+// Readable returns a channel that receives a value when events have been
+// received. Dispatch runs their handlers without blocking.
+func (c *Conn) Readable() <-chan struct{} {
+	return c.readable
+}
+
+// Err returns the error that broke the connection, if any
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// fail records err as the error that broke the connection
+func (c *Conn) fail(err error) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.failLocked(err)
+}
+
+func (c *Conn) failLocked(err error) error {
+	if c.err == nil {
+		c.err = err
+	}
+	return c.err
+}
+
+// register gives obj the next free id and adds it to the object table
+func (c *Conn) register(obj Object, version uint32) uint32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var id uint32
+	if n := len(c.freeIDs); n > 0 {
+		id = c.freeIDs[n-1]
+		c.freeIDs = c.freeIDs[:n-1]
+	} else {
+		id = c.nextID
+		c.nextID++
+	}
+
+	p := obj.proxy()
+	p.conn, p.id, p.version = c, id, version
+	c.objects[id] = obj
+	return id
+}
+
+// lookup returns the object with id, or nil if there is none
+func (c *Conn) lookup(id uint32) Object {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.objects[id]
+}
+
+// deleteID forgets an object the compositor has destroyed, freeing its id
+func (c *Conn) deleteID(id uint32) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, exists := c.objects[id]; !exists || id >= serverIDs {
+		return
+	}
+	delete(c.objects, id)
+	c.freeIDs = append(c.freeIDs, id)
+}
+
+// protocolError handles wl_display.error, after which the compositor
+// closes the connection
+func (c *Conn) protocolError(obj Object, code uint32, message string) {
+	if obj == nil {
+		c.fail(fmt.Errorf("wayland protocol error %d: %s", code, message))
+		return
+	}
+	c.fail(fmt.Errorf("wayland protocol error %d on %s@%d: %s", code, obj.Interface().Name, obj.ID(), message))
+}
+
+// send queues a request, writing the buffer out when it is full
+func (c *Conn) send(e *encoder) {
+	msg, err := e.finish()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err == nil {
+		err = c.err
+	}
+	if err != nil {
+		e.close()
+		c.failLocked(err)
+		return
+	}
+
+	if len(c.out)+len(msg) > bufferSize || len(c.outFDs)+len(e.fds) > maxFDs {
+		if c.flushLocked() != nil {
+			e.close()
+			return
+		}
+	}
+	c.out = append(c.out, msg...)
+	c.outFDs = append(c.outFDs, e.fds...)
+}
+
+// Flush sends the buffered requests
+func (c *Conn) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	return c.flushLocked()
+}
+
+// flushLocked writes out the buffered requests and the file descriptors
+// they carry, which are closed once sent
+func (c *Conn) flushLocked() error {
+	if len(c.out) == 0 {
+		return nil
+	}
+
+	var oob []byte
+	if len(c.outFDs) > 0 {
+		oob = syscall.UnixRights(c.outFDs...)
+	}
+	n, _, err := c.sock.WriteMsgUnix(c.out, oob, nil)
+	closeFDs(c.outFDs...)
+	c.outFDs = c.outFDs[:0]
+	if err == nil && n < len(c.out) {
+		_, err = c.sock.Write(c.out[n:])
+	}
+	c.out = c.out[:0]
+
+	if err != nil {
+		return c.failLocked(fmt.Errorf("failed to send Wayland requests: %w", err))
+	}
+	return nil
+}
+
+// read receives data and file descriptors until the connection fails
+func (c *Conn) read() {
+	defer close(c.done)
+
+	buf := make([]byte, bufferSize)
+	oob := make([]byte, syscall.CmsgSpace(maxFDs*4))
+	for {
+		n, oobn, flags, _, err := c.sock.ReadMsgUnix(buf, oob)
+		n, oobn = max(n, 0), max(oobn, 0)
+		fds, rightsErr := parseRights(oob[:oobn])
+		switch {
+		case err != nil:
+		case rightsErr != nil:
+			err = rightsErr
+		case flags&syscall.MSG_CTRUNC != 0:
+			err = fmt.Errorf("file descriptors received from the compositor were truncated")
+		case n == 0:
+			err = fmt.Errorf("compositor closed the connection")
+		}
+
+		c.inMu.Lock()
+		c.in = append(c.in, buf[:n]...)
+		c.inFDs = append(c.inFDs, fds...)
+		if err != nil {
+			c.readErr = err
+		}
+		c.inMu.Unlock()
+
+		select {
+		case c.readable <- struct{}{}:
+		default:
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// parseRights returns the file descriptors in the control messages oob
+func parseRights(oob []byte) ([]int, error) {
+	if len(oob) == 0 {
+		return nil, nil
+	}
+	messages, err := syscall.ParseSocketControlMessage(oob)
+	if err != nil {
+		return nil, err
+	}
+	var fds []int
+	for _, message := range messages {
+		rights, err := syscall.ParseUnixRights(&message)
+		if err != nil {
+			continue
+		}
+		fds = append(fds, rights...)
+	}
+	return fds, nil
+}
+
+// next takes the next complete message off the input queue
+func (c *Conn) next() (sender uint32, opcode uint16, data []byte, ok bool, err error) {
+	c.inMu.Lock()
+	defer c.inMu.Unlock()
+
+	if len(c.in) < headerSize {
+		return 0, 0, nil, false, nil
+	}
+	sender = order.Uint32(c.in)
+	word := order.Uint32(c.in[4:])
+	size := int(word >> 16)
+	if size < headerSize || size%4 != 0 {
+		return 0, 0, nil, false, fmt.Errorf("invalid message size %d from the compositor", size)
+	}
+	if len(c.in) < size {
+		return 0, 0, nil, false, nil
+	}
+
+	data = append([]byte(nil), c.in[headerSize:size]...)
+	c.in = c.in[:copy(c.in, c.in[size:])]
+	return sender, uint16(word), data, true, nil
+}
+
+// popFD takes the oldest file descriptor received
+func (c *Conn) popFD() (int, bool) {
+	c.inMu.Lock()
+	defer c.inMu.Unlock()
+	if len(c.inFDs) == 0 {
+		return -1, false
+	}
+	fd := c.inFDs[0]
+	c.inFDs = c.inFDs[1:]
+	return fd, true
+}
+
+// Dispatch runs the handlers of the events received so far. It never
+// waits for more to arrive.
+func (c *Conn) Dispatch() error {
+	for {
+		if err := c.Err(); err != nil {
+			return err
+		}
+		sender, opcode, data, ok, err := c.next()
+		if err != nil {
+			return c.fail(err)
+		}
+		if !ok {
+			break
+		}
+		if err := c.dispatch(sender, opcode, data); err != nil {
+			return c.fail(err)
+		}
+	}
+
+	// A read error is reported once everything received before it ran
+	c.inMu.Lock()
+	err := c.readErr
+	c.inMu.Unlock()
+	if err != nil {
+		return c.fail(err)
+	}
+	return nil
+}
+
+// dispatch delivers one event to its object
+func (c *Conn) dispatch(sender uint32, opcode uint16, data []byte) error {
+	obj := c.lookup(sender)
+	if obj == nil {
+		// libwayland drops events for objects it does not know as well
+		return nil
+	}
+	events := obj.Interface().Events
+	if int(opcode) >= len(events) {
+		return fmt.Errorf("invalid event %d for %s@%d", opcode, obj.Interface().Name, sender)
+	}
+	if obj.proxy().zombie.Load() {
+		for i := events[opcode].fds(); i > 0; i-- {
+			if fd, ok := c.popFD(); ok {
+				closeFDs(fd)
+			}
+		}
+		return nil
+	}
+	return obj.dispatch(opcode, &decoder{conn: c, data: data})
+}
+
+// Roundtrip sends the buffered requests and dispatches events until the
+// compositor has processed them all
+func (c *Conn) Roundtrip() error {
+	done := false
+	callback := c.display.Sync()
+	callback.OnDone = func(uint32) {
+		done = true
+	}
+	if err := c.Flush(); err != nil {
+		return err
+	}
+
+	for {
+		if err := c.Dispatch(); err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		select {
+		case <-c.readable:
+		case <-c.done:
+			// The reader stopped; the next Dispatch reports why
+		}
+	}
+}
+
+// Close closes the connection without sending buffered requests. File
+// descriptors received and not yet dispatched are closed.
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	if c.err == ErrClosed {
+		c.mu.Unlock()
+		return nil
+	}
+	c.err = ErrClosed
+	closeFDs(c.outFDs...)
+	c.out, c.outFDs = nil, nil
+	c.mu.Unlock()
+
+	err := c.sock.Close()
+	<-c.done
+
+	c.inMu.Lock()
+	closeFDs(c.inFDs...)
+	c.in, c.inFDs = nil, nil
+	c.inMu.Unlock()
+	return err
+}
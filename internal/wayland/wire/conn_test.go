@@ -0,0 +1,397 @@
+package wire
+
+import (
+	"net"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// socketPair returns two connected Unix sockets
+func socketPair(t *testing.T) (*net.UnixConn, *net.UnixConn) {
+	t.Helper()
+	fds, err := syscall.Socketpair(syscall.AF_UNIX, syscall.SOCK_STREAM|syscall.SOCK_CLOEXEC, 0)
+	if err != nil {
+		t.Fatalf("socketpair: %v", err)
+	}
+	var socks [2]*net.UnixConn
+	for i, fd := range fds {
+		file := os.NewFile(uintptr(fd), "socketpair")
+		conn, err := net.FileConn(file)
+		file.Close()
+		if err != nil {
+			t.Fatalf("FileConn: %v", err)
+		}
+		socks[i] = conn.(*net.UnixConn)
+	}
+	return socks[0], socks[1]
+}
+
+// testServer plays the compositor end of a connection, reading raw
+// requests and writing raw events
+type testServer struct {
+	t    *testing.T
+	sock *net.UnixConn
+	in   []byte
+	fds  []int
+}
+
+func newTestConn(t *testing.T) (*Conn, *testServer) {
+	t.Helper()
+	client, server := socketPair(t)
+	conn := NewConn(client)
+	s := &testServer{t: t, sock: server}
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		closeFDs(s.fds...)
+	})
+	return conn, s
+}
+
+// request reads the next request, returning its sender, opcode and
+// arguments
+func (s *testServer) request() (uint32, uint16, *decoder) {
+	s.t.Helper()
+	s.sock.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if len(s.in) >= headerSize {
+			size := int(order.Uint32(s.in[4:]) >> 16)
+			if len(s.in) >= size {
+				sender, opcode := order.Uint32(s.in), uint16(order.Uint32(s.in[4:]))
+				d := &decoder{data: append([]byte(nil), s.in[headerSize:size]...)}
+				s.in = s.in[size:]
+				return sender, opcode, d
+			}
+		}
+		buf := make([]byte, 4096)
+		oob := make([]byte, syscall.CmsgSpace(maxFDs*4))
+		n, oobn, _, _, err := s.sock.ReadMsgUnix(buf, oob)
+		if err != nil {
+			s.t.Fatalf("reading requests: %v", err)
+		}
+		fds, err := parseRights(oob[:oobn])
+		if err != nil {
+			s.t.Fatalf("parsing rights: %v", err)
+		}
+		s.in = append(s.in, buf[:n]...)
+		s.fds = append(s.fds, fds...)
+	}
+}
+
+// event sends an event built by args, which appends the arguments
+func (s *testServer) event(sender uint32, opcode uint16, args func(e *encoder)) {
+	s.t.Helper()
+	e := newEncoder(nil, sender, opcode)
+	if args != nil {
+		args(e)
+	}
+	msg, err := e.finish()
+	if err != nil {
+		s.t.Fatalf("encoding event: %v", err)
+	}
+	var oob []byte
+	if len(e.fds) > 0 {
+		oob = syscall.UnixRights(e.fds...)
+	}
+	if _, _, err := s.sock.WriteMsgUnix(msg, oob, nil); err != nil {
+		s.t.Fatalf("sending event: %v", err)
+	}
+	e.close()
+}
+
+// waitFor dispatches events until cond holds
+func waitFor(t *testing.T, conn *Conn, cond func() bool) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for !cond() {
+		select {
+		case <-conn.Readable():
+			if err := conn.Dispatch(); err != nil {
+				t.Fatalf("Dispatch: %v", err)
+			}
+		case <-deadline:
+			t.Fatal("timed out waiting for events")
+		}
+	}
+}
+
+func TestRequestEncoding(t *testing.T) {
+	conn, server := newTestConn(t)
+
+	registry := conn.Display().GetRegistry()
+	compositor := &WlCompositor{}
+	registry.Bind(7, compositor, 4)
+	surface := compositor.CreateSurface()
+	surface.Attach(nil, 1, -2)
+	toplevel := &XdgToplevel{}
+	conn.register(toplevel, 1)
+	toplevel.SetTitle("abc")
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	sender, opcode, d := server.request()
+	if sender != 1 || opcode != 1 || d.getUint() != registry.ID() {
+		t.Fatalf("get_registry: got %d.%d", sender, opcode)
+	}
+
+	sender, opcode, d = server.request()
+	if sender != registry.ID() || opcode != 0 {
+		t.Fatalf("bind: got %d.%d", sender, opcode)
+	}
+	if name, iface, version, id := d.getUint(), d.getString(), d.getUint(), d.getUint(); name != 7 ||
+		iface != "wl_compositor" || version != 4 || id != compositor.ID() {
+		t.Errorf("bind(%d, %q, %d, %d), want (7, wl_compositor, 4, %d)", name, iface, version, id, compositor.ID())
+	}
+	if compositor.Version() != 4 {
+		t.Errorf("compositor version %d, want 4", compositor.Version())
+	}
+
+	sender, opcode, d = server.request()
+	if sender != compositor.ID() || opcode != 0 || d.getUint() != surface.ID() {
+		t.Errorf("create_surface: got %d.%d", sender, opcode)
+	}
+	if surface.Version() != 4 {
+		t.Errorf("surface version %d, want the compositor's 4", surface.Version())
+	}
+
+	sender, opcode, d = server.request()
+	if buffer, x, y := d.getUint(), d.getInt(), d.getInt(); sender != surface.ID() || opcode != 1 ||
+		buffer != 0 || x != 1 || y != -2 {
+		t.Errorf("attach: got %d.%d(%d, %d, %d)", sender, opcode, buffer, x, y)
+	}
+
+	// "abc" is sent with its length including the NUL, padded to 4 bytes
+	_, opcode, d = server.request()
+	if opcode != 2 || len(d.data) != 8 || order.Uint32(d.data) != 4 || string(d.data[4:]) != "abc\x00" {
+		t.Errorf("set_title: got opcode %d, arguments %q", opcode, d.data)
+	}
+}
+
+func TestEventDispatch(t *testing.T) {
+	conn, server := newTestConn(t)
+
+	type global struct {
+		name    uint32
+		iface   string
+		version uint32
+	}
+	var globals []global
+	registry := conn.Display().GetRegistry()
+	registry.OnGlobal = func(name uint32, iface string, version uint32) {
+		globals = append(globals, global{name, iface, version})
+	}
+	conn.Flush()
+	server.request()
+
+	server.event(registry.ID(), 0, func(e *encoder) {
+		e.putUint(1)
+		e.putString("wl_compositor")
+		e.putUint(6)
+	})
+	server.event(registry.ID(), 0, func(e *encoder) {
+		e.putUint(2)
+		e.putString("zwp_input_method_manager_v2")
+		e.putUint(1)
+	})
+	waitFor(t, conn, func() bool { return len(globals) == 2 })
+
+	want := []global{{1, "wl_compositor", 6}, {2, "zwp_input_method_manager_v2", 1}}
+	for i := range want {
+		if globals[i] != want[i] {
+			t.Errorf("global %d = %+v, want %+v", i, globals[i], want[i])
+		}
+	}
+}
+
+func TestFixed(t *testing.T) {
+	if got := FixedFromFloat(12.5); got != 3200 || got.Float() != 12.5 || got.Int() != 12 {
+		t.Errorf("FixedFromFloat(12.5) = %d", got)
+	}
+	if got := FixedFromInt(-3).Int(); got != -3 {
+		t.Errorf("FixedFromInt(-3).Int() = %d", got)
+	}
+}
+
+// tempFile returns a file holding content
+func tempFile(t *testing.T, content string) *os.File {
+	t.Helper()
+	file, err := os.CreateTemp(t.TempDir(), "wire")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	return file
+}
+
+// readFD reads what a received file descriptor holds from the start
+func readFD(t *testing.T, fd int) string {
+	t.Helper()
+	buf := make([]byte, 64)
+	n, err := syscall.Pread(fd, buf, 0)
+	if err != nil {
+		t.Fatalf("reading fd: %v", err)
+	}
+	return string(buf[:n])
+}
+
+func TestFDPassing(t *testing.T) {
+	conn, server := newTestConn(t)
+
+	// The client duplicates the fd it sends, so it may close its own
+	shm := &WlShm{}
+	conn.register(shm, 1)
+	file := tempFile(t, "pool")
+	pool := shm.CreatePool(int(file.Fd()), 4)
+	file.Close()
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	_, opcode, d := server.request()
+	if id := d.getUint(); opcode != 0 || id != pool.ID() {
+		t.Fatalf("create_pool: got opcode %d, id %d", opcode, id)
+	}
+	if size := d.getInt(); size != 4 {
+		t.Errorf("pool size %d, want 4", size)
+	}
+	if len(server.fds) != 1 {
+		t.Fatalf("compositor received %d fds, want 1", len(server.fds))
+	}
+	if got := readFD(t, server.fds[0]); got != "pool" {
+		t.Errorf("pool fd holds %q, want %q", got, "pool")
+	}
+
+	// A keymap fd is handed to the handler, which owns it
+	keyboard := &WlKeyboard{}
+	conn.register(keyboard, 1)
+	var keymap string
+	keyboard.OnKeymap = func(format uint32, fd int, size uint32) {
+		keymap = readFD(t, fd)[:size]
+		syscall.Close(fd)
+	}
+	server.event(keyboard.ID(), 0, func(e *encoder) {
+		e.putUint(WlKeyboardKeymapFormatXkbV1)
+		e.putFD(int(tempFile(t, "xkb_keymap {}").Fd()))
+		e.putUint(13)
+	})
+	waitFor(t, conn, func() bool { return keymap != "" })
+	if keymap != "xkb_keymap {}" {
+		t.Errorf("keymap %q", keymap)
+	}
+}
+
+func TestRoundtripAndDeleteID(t *testing.T) {
+	conn, server := newTestConn(t)
+
+	// The compositor answers the sync, then confirms its deletion
+	go func() {
+		sender, opcode, d := server.request()
+		if sender != 1 || opcode != 0 {
+			return
+		}
+		callback := d.getUint()
+		server.event(callback, 0, func(e *encoder) { e.putUint(42) })
+		server.event(1, 1, func(e *encoder) { e.putUint(callback) })
+	}()
+	if err := conn.Roundtrip(); err != nil {
+		t.Fatalf("Roundtrip: %v", err)
+	}
+	// delete_id may arrive after the done event that ended the roundtrip
+	waitFor(t, conn, func() bool { return conn.lookup(2) == nil })
+
+	// The id of the deleted callback is reused
+	if id := conn.register(&WlCallback{}, 1); id != 2 {
+		t.Errorf("new object got id %d, want the freed id 2", id)
+	}
+}
+
+func TestZombieEventsAreDropped(t *testing.T) {
+	conn, server := newTestConn(t)
+
+	keyboard := &WlKeyboard{}
+	conn.register(keyboard, 3)
+	called := false
+	keyboard.OnKeymap = func(format uint32, fd int, size uint32) {
+		called = true
+	}
+	keyboard.Release()
+	conn.Flush()
+	server.request()
+
+	// The fd of an event sent before the compositor saw the release is
+	// consumed, so the next object's fd is not mistaken for it
+	server.event(keyboard.ID(), 0, func(e *encoder) {
+		e.putUint(1)
+		e.putFD(int(tempFile(t, "stale").Fd()))
+		e.putUint(5)
+	})
+	other := &WlKeyboard{}
+	conn.register(other, 3)
+	var got string
+	other.OnKeymap = func(format uint32, fd int, size uint32) {
+		got = readFD(t, fd)
+		syscall.Close(fd)
+	}
+	server.event(other.ID(), 0, func(e *encoder) {
+		e.putUint(1)
+		e.putFD(int(tempFile(t, "fresh").Fd()))
+		e.putUint(5)
+	})
+	waitFor(t, conn, func() bool { return got != "" })
+
+	if called {
+		t.Error("event delivered to a released object")
+	}
+	if got != "fresh" {
+		t.Errorf("second keymap read %q, want %q", got, "fresh")
+	}
+}
+
+func TestProtocolError(t *testing.T) {
+	conn, server := newTestConn(t)
+
+	server.event(1, 0, func(e *encoder) {
+		e.putUint(1)
+		e.putUint(WlDisplayErrorInvalidMethod)
+		e.putString("bad request")
+	})
+	deadline := time.After(5 * time.Second)
+	for {
+		select {
+		case <-conn.Readable():
+		case <-deadline:
+			t.Fatal("timed out waiting for the error")
+		}
+		if err := conn.Dispatch(); err != nil {
+			if !strings.Contains(err.Error(), "wl_display@1: bad request") {
+				t.Errorf("error %q does not name the object and message", err)
+			}
+			break
+		}
+	}
+
+	// Nothing is sent after a protocol error
+	if err := conn.Flush(); err == nil {
+		t.Error("Flush succeeded on a broken connection")
+	}
+}
+
+func TestClosedByCompositor(t *testing.T) {
+	conn, server := newTestConn(t)
+	server.sock.Close()
+
+	<-conn.Readable()
+	if err := conn.Dispatch(); err == nil {
+		t.Error("Dispatch succeeded after the compositor hung up")
+	}
+	if err := conn.Roundtrip(); err == nil {
+		t.Error("Roundtrip succeeded after the compositor hung up")
+	}
+}
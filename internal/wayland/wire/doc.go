@@ -0,0 +1,11 @@
+// Package wire is a client of the Wayland wire protocol written in Go. It
+// speaks the protocol over the compositor's Unix socket, passing file
+// descriptors for shared memory and keymaps with SCM_RIGHTS, so the
+// keyboard can be built without cgo and libwayland-client.
+//
+// The proxy types in protocol.go are generated from the XML in the
+// protocols directory. Each has a method per request, and an On field per
+// event that is called from Conn.Dispatch.
+package wire
+
+//go:generate go run ./gen -o protocol.go ../../../protocols/wayland.xml ../../../protocols/xdg-shell.xml ../../../protocols/wlr-layer-shell-unstable-v1.xml ../../../protocols/virtual-keyboard-unstable-v1.xml ../../../protocols/input-method-unstable-v2.xml ../../../protocols/viewporter.xml ../../../protocols/fractional-scale-v1.xml
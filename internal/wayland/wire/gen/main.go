@@ -0,0 +1,468 @@
+// Command gen generates the Go bindings of the wire package from Wayland
+// protocol XML files. For each interface it writes a descriptor, the
+// enum values, and a proxy type with a method per request and a handler
+// field per event.
+//
+// Usage:
+//
+//	go run ./gen -o protocol.go wayland.xml xdg-shell.xml ...
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+type protocol struct {
+	Name       string      `xml:"name,attr"`
+	Interfaces []Interface `xml:"interface"`
+}
+
+// Interface, Message, Arg and Enum mirror the elements of the protocol
+// XML
+type Interface struct {
+	Name        string      `xml:"name,attr"`
+	Version     int         `xml:"version,attr"`
+	Description Description `xml:"description"`
+	Requests    []Message   `xml:"request"`
+	Events      []Message   `xml:"event"`
+	Enums       []Enum      `xml:"enum"`
+}
+
+type Description struct {
+	Summary string `xml:"summary,attr"`
+}
+
+type Message struct {
+	Name  string `xml:"name,attr"`
+	Type  string `xml:"type,attr"`
+	Since int    `xml:"since,attr"`
+	Args  []Arg  `xml:"arg"`
+}
+
+type Arg struct {
+	Name      string `xml:"name,attr"`
+	Type      string `xml:"type,attr"`
+	Interface string `xml:"interface,attr"`
+	AllowNull bool   `xml:"allow-null,attr"`
+}
+
+type Enum struct {
+	Name    string  `xml:"name,attr"`
+	Entries []Entry `xml:"entry"`
+}
+
+type Entry struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+func main() {
+	output := flag.String("o", "protocol.go", "file to write")
+	pkg := flag.String("package", "wire", "package of the generated file")
+	flag.Parse()
+	if flag.NArg() == 0 {
+		log.Fatal("usage: gen [-o file] [-package name] protocol.xml...")
+	}
+
+	var interfaces []Interface
+	var sources []string
+	for _, path := range flag.Args() {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var p protocol
+		if err := xml.Unmarshal(data, &p); err != nil {
+			log.Fatalf("%s: %v", path, err)
+		}
+		interfaces = append(interfaces, p.Interfaces...)
+		sources = append(sources, filepath.Base(path))
+	}
+
+	g := &generator{known: make(map[string]bool)}
+	for _, iface := range interfaces {
+		g.known[iface.Name] = true
+	}
+
+	g.printf("// Code generated by gen from %s. DO NOT EDIT.\n\n", strings.Join(sources, ", "))
+	g.printf("package %s\n\nimport \"fmt\"\n\n", *pkg)
+	for _, iface := range interfaces {
+		if err := g.generate(iface); err != nil {
+			log.Fatalf("%s: %v", iface.Name, err)
+		}
+	}
+	g.printf("// interfaces maps interface names to their descriptors\n")
+	g.printf("var interfaces = map[string]*Interface{\n")
+	for _, iface := range interfaces {
+		g.printf("\t%q: %sInterface,\n", iface.Name, exportedName(iface.Name))
+	}
+	g.printf("}\n")
+
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		log.Fatalf("generated code does not parse: %v", err)
+	}
+	if err := os.WriteFile(*output, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generator accumulates the generated file
+type generator struct {
+	buf   bytes.Buffer
+	known map[string]bool
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// generate writes the descriptor, enums and proxy type of one interface
+func (g *generator) generate(iface Interface) error {
+	for _, messages := range [][]Message{iface.Requests, iface.Events} {
+		for _, m := range messages {
+			for _, arg := range m.Args {
+				if arg.Interface != "" && !g.known[arg.Interface] {
+					return fmt.Errorf("%s refers to unknown interface %s", m.Name, arg.Interface)
+				}
+				switch paramName(arg.Name) {
+				case "p", "m", "d":
+					return fmt.Errorf("%s: argument %s clashes with generated names", m.Name, arg.Name)
+				}
+			}
+		}
+	}
+
+	typeName := exportedName(iface.Name)
+	g.descriptor(iface, typeName)
+	g.enums(iface, typeName)
+
+	summary := iface.Description.Summary
+	if summary == "" {
+		summary = "the " + iface.Name + " interface"
+	}
+	g.printf("// %s is %s: %s\n", typeName, iface.Name, summary)
+	g.printf("type %s struct {\n\tProxy\n", typeName)
+	for _, event := range iface.Events {
+		params, err := g.eventParams(event)
+		if err != nil {
+			return err
+		}
+		g.printf("\t// On%s handles the %s event\n", exportedName(event.Name), event.Name)
+		g.printf("\tOn%s func(%s)\n", exportedName(event.Name), strings.Join(params, ", "))
+	}
+	g.printf("}\n\n")
+
+	g.printf("// Interface returns the descriptor of %s\n", iface.Name)
+	g.printf("func (p *%s) Interface() *Interface {\n\treturn %sInterface\n}\n\n", typeName, typeName)
+
+	for opcode, request := range iface.Requests {
+		if err := g.request(iface, typeName, opcode, request); err != nil {
+			return err
+		}
+	}
+	return g.dispatch(iface, typeName)
+}
+
+// descriptor writes the Interface value of iface
+func (g *generator) descriptor(iface Interface, typeName string) {
+	g.printf("// %sInterface describes %s\n", typeName, iface.Name)
+	g.printf("var %sInterface = &Interface{\n", typeName)
+	g.printf("\tName: %q,\n\tVersion: %d,\n", iface.Name, iface.Version)
+	for _, list := range []struct {
+		field    string
+		messages []Message
+	}{{"Requests", iface.Requests}, {"Events", iface.Events}} {
+		if len(list.messages) == 0 {
+			continue
+		}
+		g.printf("\t%s: []Message{\n", list.field)
+		for _, m := range list.messages {
+			signature, types := wireSignature(m)
+			g.printf("\t\t{Name: %q, Signature: %q", m.Name, signature)
+			if hasTypes(types) {
+				quoted := make([]string, len(types))
+				for i, t := range types {
+					quoted[i] = strconv.Quote(t)
+				}
+				g.printf(", Types: []string{%s}", strings.Join(quoted, ", "))
+			}
+			if m.Since > 1 {
+				g.printf(", Since: %d", m.Since)
+			}
+			if m.Type == "destructor" {
+				g.printf(", Destructor: true")
+			}
+			g.printf("},\n")
+		}
+		g.printf("\t},\n")
+	}
+	g.printf("}\n\n")
+}
+
+// wireSignature returns the libwayland signature of m and the interface
+// of each argument. An untyped new_id is sent as the interface name, the
+// version and the id.
+func wireSignature(m Message) (string, []string) {
+	var signature strings.Builder
+	var types []string
+	for _, arg := range m.Args {
+		if arg.AllowNull {
+			signature.WriteByte('?')
+		}
+		if arg.Type == "new_id" && arg.Interface == "" {
+			signature.WriteString("sun")
+			types = append(types, "", "", "")
+			continue
+		}
+		signature.WriteByte(typeCodes[arg.Type])
+		types = append(types, arg.Interface)
+	}
+	return signature.String(), types
+}
+
+var typeCodes = map[string]byte{
+	"int":    'i',
+	"uint":   'u',
+	"fixed":  'f',
+	"string": 's',
+	"object": 'o',
+	"new_id": 'n',
+	"array":  'a',
+	"fd":     'h',
+}
+
+func hasTypes(types []string) bool {
+	for _, t := range types {
+		if t != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// enums writes the values of the enums of iface as untyped constants
+func (g *generator) enums(iface Interface, typeName string) {
+	for _, enum := range iface.Enums {
+		g.printf("// Values of %s.%s\n", iface.Name, enum.Name)
+		g.printf("const (\n")
+		for _, entry := range enum.Entries {
+			g.printf("\t%s%s%s = %s\n", typeName, exportedName(enum.Name), exportedName(entry.Name), entry.Value)
+		}
+		g.printf(")\n\n")
+	}
+}
+
+// request writes the method sending a request
+func (g *generator) request(iface Interface, typeName string, opcode int, m Message) error {
+	var params, body []string
+	result := ""
+	for _, arg := range m.Args {
+		name := paramName(arg.Name)
+		switch arg.Type {
+		case "new_id":
+			if arg.Interface == "" {
+				params = append(params, name+" Object", "version uint32")
+				body = append(body,
+					fmt.Sprintf("m.putString(%s.Interface().Name)", name),
+					"m.putUint(version)",
+					fmt.Sprintf("m.putNewID(%s, version)", name))
+				continue
+			}
+			if result != "" {
+				return fmt.Errorf("%s creates more than one object", m.Name)
+			}
+			result = name
+			body = append(body,
+				fmt.Sprintf("%s := &%s{}", name, exportedName(arg.Interface)),
+				fmt.Sprintf("m.putNewID(%s, p.version)", name))
+		case "object":
+			if arg.Interface == "" {
+				return fmt.Errorf("%s has an untyped object argument", m.Name)
+			}
+			params = append(params, name+" *"+exportedName(arg.Interface))
+			body = append(body, fmt.Sprintf("m.putUint(idOf(%s))", name))
+		default:
+			goType, put := goTypes[arg.Type].goType, goTypes[arg.Type].put
+			if goType == "" {
+				return fmt.Errorf("%s: unknown argument type %s", m.Name, arg.Type)
+			}
+			if arg.Type == "string" && arg.AllowNull {
+				put = "putNullableString"
+			}
+			params = append(params, name+" "+goType)
+			body = append(body, fmt.Sprintf("m.%s(%s)", put, name))
+		}
+	}
+
+	method := exportedName(m.Name)
+	returns := ""
+	if result != "" {
+		for _, arg := range m.Args {
+			if paramName(arg.Name) == result {
+				returns = " *" + exportedName(arg.Interface)
+			}
+		}
+	}
+
+	if m.Type == "destructor" {
+		g.printf("// %s sends the %s.%s destructor; the object receives no more events\n", method, iface.Name, m.Name)
+	} else {
+		g.printf("// %s sends the %s.%s request\n", method, iface.Name, m.Name)
+	}
+	g.printf("func (p *%s) %s(%s)%s {\n", typeName, method, strings.Join(params, ", "), returns)
+	g.printf("\tm := p.request(%d)\n", opcode)
+	for _, line := range body {
+		g.printf("\t%s\n", line)
+	}
+	g.printf("\tp.send(m, %t)\n", m.Type == "destructor")
+	if result != "" {
+		g.printf("\treturn %s\n", result)
+	}
+	g.printf("}\n\n")
+	return nil
+}
+
+// goTypes maps the scalar argument types to Go types and the encoder
+// method writing them
+var goTypes = map[string]struct {
+	goType string
+	put    string
+	get    string
+}{
+	"int":    {"int32", "putInt", "getInt"},
+	"uint":   {"uint32", "putUint", "getUint"},
+	"fixed":  {"Fixed", "putFixed", "getFixed"},
+	"string": {"string", "putString", "getString"},
+	"array":  {"[]byte", "putArray", "getArray"},
+	"fd":     {"int", "putFD", "getFD"},
+}
+
+// eventParams returns the parameters of the handler of an event
+func (g *generator) eventParams(m Message) ([]string, error) {
+	var params []string
+	for _, arg := range m.Args {
+		name := paramName(arg.Name)
+		switch arg.Type {
+		case "new_id":
+			return nil, fmt.Errorf("event %s creates an object, which is not supported", m.Name)
+		case "object":
+			if arg.Interface == "" {
+				params = append(params, name+" Object")
+			} else {
+				params = append(params, name+" *"+exportedName(arg.Interface))
+			}
+		default:
+			goType := goTypes[arg.Type].goType
+			if goType == "" {
+				return nil, fmt.Errorf("%s: unknown argument type %s", m.Name, arg.Type)
+			}
+			params = append(params, name+" "+goType)
+		}
+	}
+	return params, nil
+}
+
+// dispatch writes the method decoding events and calling their handlers.
+// File descriptors are closed when the event has no handler.
+func (g *generator) dispatch(iface Interface, typeName string) error {
+	g.printf("func (p *%s) dispatch(opcode uint16, d *decoder) error {\n", typeName)
+	if len(iface.Events) > 0 {
+		g.printf("\tswitch opcode {\n")
+	}
+	for opcode, event := range iface.Events {
+		handler := "On" + exportedName(event.Name)
+		g.printf("\tcase %d:\n", opcode)
+
+		var names, fds []string
+		for _, arg := range event.Args {
+			name := paramName(arg.Name)
+			names = append(names, name)
+			switch {
+			case arg.Type == "object" && arg.Interface == "":
+				g.printf("\t\t%s := d.getObject()\n", name)
+			case arg.Type == "object":
+				g.printf("\t\t%s, _ := d.getObject().(*%s)\n", name, exportedName(arg.Interface))
+			default:
+				g.printf("\t\t%s := d.%s()\n", name, goTypes[arg.Type].get)
+			}
+			if arg.Type == "fd" {
+				fds = append(fds, name)
+			}
+		}
+		if len(event.Args) > 0 {
+			g.printf("\t\tif d.err != nil {\n")
+			if len(fds) > 0 {
+				g.printf("\t\t\tcloseFDs(%s)\n", strings.Join(fds, ", "))
+			}
+			g.printf("\t\t\treturn fmt.Errorf(\"%s.%s: %%w\", d.err)\n", iface.Name, event.Name)
+			g.printf("\t\t}\n")
+		}
+		g.printf("\t\tif p.%s != nil {\n", handler)
+		g.printf("\t\t\tp.%s(%s)\n", handler, strings.Join(names, ", "))
+		if len(fds) > 0 {
+			g.printf("\t\t} else {\n\t\t\tcloseFDs(%s)\n", strings.Join(fds, ", "))
+		}
+		g.printf("\t\t}\n")
+	}
+	if len(iface.Events) > 0 {
+		g.printf("\t}\n")
+	}
+	g.printf("\treturn nil\n}\n\n")
+	return nil
+}
+
+// initialisms are words written in capitals in Go names
+var initialisms = map[string]string{
+	"id":  "ID",
+	"fd":  "FD",
+	"xdg": "Xdg",
+}
+
+// exportedName converts a snake_case protocol name to CamelCase
+func exportedName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Split(name, "_") {
+		if word == "" {
+			continue
+		}
+		if initialism, ok := initialisms[word]; ok {
+			b.WriteString(initialism)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	return b.String()
+}
+
+// keywords are the Go keywords that appear as argument names, and what
+// they are renamed to
+var keywords = map[string]string{
+	"interface": "iface",
+	"type":      "kind",
+	"func":      "fn",
+	"range":     "span",
+	"select":    "selection",
+	"default":   "dflt",
+}
+
+// paramName converts a snake_case argument name to a Go parameter name
+func paramName(name string) string {
+	if renamed, ok := keywords[name]; ok {
+		return renamed
+	}
+	exported := exportedName(name)
+	if exported == strings.ToUpper(exported) {
+		return strings.ToLower(exported)
+	}
+	return strings.ToLower(exported[:1]) + exported[1:]
+}
@@ -0,0 +1,240 @@
+package wire
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"syscall"
+)
+
+const (
+	// headerSize is the size of a message header: the object id, then the
+	// message size in the upper and the opcode in the lower 16 bits
+	headerSize = 8
+	// maxMessageSize is the largest message libwayland accepts
+	maxMessageSize = 4096
+)
+
+// order is the byte order of the wire protocol, which is that of the host
+var order = binary.NativeEndian
+
+// Fixed is a wl_fixed_t: a signed 24.8 fixed-point number
+type Fixed int32
+
+// FixedFromFloat converts v to the nearest fixed-point value
+func FixedFromFloat(v float64) Fixed {
+	return Fixed(math.Round(v * 256))
+}
+
+// FixedFromInt converts a whole number to fixed point
+func FixedFromInt(v int) Fixed {
+	return Fixed(v * 256)
+}
+
+// Float returns f as a floating-point number
+func (f Fixed) Float() float64 {
+	return float64(f) / 256
+}
+
+// Int returns the whole part of f, rounded towards zero
+func (f Fixed) Int() int32 {
+	return int32(f) / 256
+}
+
+// padded returns n rounded up to the 32-bit alignment of arguments
+func padded(n int) int {
+	return (n + 3) &^ 3
+}
+
+// encoder builds a request. Arguments are appended in signature order and
+// file descriptors are duplicated, so callers keep ownership of theirs.
+type encoder struct {
+	conn   *Conn
+	opcode uint16
+	buf    []byte
+	fds    []int
+	err    error
+}
+
+// newEncoder starts a message from object id
+func newEncoder(conn *Conn, id uint32, opcode uint16) *encoder {
+	e := &encoder{conn: conn, opcode: opcode, buf: make([]byte, headerSize, 64)}
+	order.PutUint32(e.buf, id)
+	return e
+}
+
+func (e *encoder) putUint(v uint32) {
+	e.buf = order.AppendUint32(e.buf, v)
+}
+
+func (e *encoder) putInt(v int32) {
+	e.putUint(uint32(v))
+}
+
+func (e *encoder) putFixed(v Fixed) {
+	e.putUint(uint32(v))
+}
+
+// putString appends a NUL-terminated string
+func (e *encoder) putString(s string) {
+	e.putUint(uint32(len(s) + 1))
+	e.buf = append(e.buf, s...)
+	e.buf = append(e.buf, make([]byte, padded(len(s)+1)-len(s))...)
+}
+
+// putNullableString appends a string argument that may be null. Go has no
+// null string, so an empty one is sent as null.
+func (e *encoder) putNullableString(s string) {
+	if s == "" {
+		e.putUint(0)
+		return
+	}
+	e.putString(s)
+}
+
+func (e *encoder) putArray(a []byte) {
+	e.putUint(uint32(len(a)))
+	e.buf = append(e.buf, a...)
+	e.buf = append(e.buf, make([]byte, padded(len(a))-len(a))...)
+}
+
+// idOf returns the id of obj, or 0 for a null object
+func idOf[P interface {
+	*T
+	Object
+}, T any](obj P) uint32 {
+	if obj == nil {
+		return 0
+	}
+	return obj.ID()
+}
+
+// putNewID creates obj on the connection and appends its id
+func (e *encoder) putNewID(obj Object, version uint32) {
+	e.putUint(e.conn.register(obj, version))
+}
+
+// putFD duplicates fd to send it with the message
+func (e *encoder) putFD(fd int) {
+	dup, _, errno := syscall.Syscall(syscall.SYS_FCNTL, uintptr(fd), syscall.F_DUPFD_CLOEXEC, 0)
+	if errno != 0 {
+		if e.err == nil {
+			e.err = fmt.Errorf("failed to duplicate fd %d: %w", fd, errno)
+		}
+		return
+	}
+	e.fds = append(e.fds, int(dup))
+}
+
+// finish fills in the header and returns the message
+func (e *encoder) finish() ([]byte, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	if len(e.buf) > maxMessageSize {
+		return nil, fmt.Errorf("message of %d bytes exceeds the maximum of %d", len(e.buf), maxMessageSize)
+	}
+	order.PutUint32(e.buf[4:], uint32(len(e.buf))<<16|uint32(e.opcode))
+	return e.buf, nil
+}
+
+// close releases the duplicated file descriptors of a message that will
+// not be sent
+func (e *encoder) close() {
+	for _, fd := range e.fds {
+		syscall.Close(fd)
+	}
+	e.fds = nil
+}
+
+// decoder reads the arguments of an event. Errors are sticky: once the
+// message is found to be short, every further argument is zero.
+type decoder struct {
+	conn *Conn
+	data []byte
+	err  error
+}
+
+// fail records the first decoding error
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (d *decoder) getUint() uint32 {
+	if len(d.data) < 4 {
+		d.fail("message too short")
+		return 0
+	}
+	v := order.Uint32(d.data)
+	d.data = d.data[4:]
+	return v
+}
+
+func (d *decoder) getInt() int32 {
+	return int32(d.getUint())
+}
+
+func (d *decoder) getFixed() Fixed {
+	return Fixed(d.getUint())
+}
+
+// getBytes returns the next n bytes, skipping the padding after them
+func (d *decoder) getBytes(n uint32) []byte {
+	size := padded(int(n))
+	if n > maxMessageSize || len(d.data) < size {
+		d.fail("argument of %d bytes overruns the message", n)
+		return nil
+	}
+	b := d.data[:n]
+	d.data = d.data[size:]
+	return b
+}
+
+func (d *decoder) getString() string {
+	n := d.getUint()
+	if n == 0 {
+		return ""
+	}
+	b := d.getBytes(n)
+	if len(b) == 0 || b[len(b)-1] != 0 {
+		d.fail("string is not NUL-terminated")
+		return ""
+	}
+	return string(b[:len(b)-1])
+}
+
+// getArray returns a copy of an array argument
+func (d *decoder) getArray() []byte {
+	return append([]byte(nil), d.getBytes(d.getUint())...)
+}
+
+// getObject returns the object with the next id, or nil for a null or
+// unknown object
+func (d *decoder) getObject() Object {
+	id := d.getUint()
+	if id == 0 {
+		return nil
+	}
+	return d.conn.lookup(id)
+}
+
+// getFD takes the next file descriptor received on the connection
+func (d *decoder) getFD() int {
+	fd, ok := d.conn.popFD()
+	if !ok {
+		d.fail("missing file descriptor")
+		return -1
+	}
+	return fd
+}
+
+// closeFDs closes file descriptors decoded for an event nobody handles
+func closeFDs(fds ...int) {
+	for _, fd := range fds {
+		if fd >= 0 {
+			syscall.Close(fd)
+		}
+	}
+}
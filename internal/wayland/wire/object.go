@@ -0,0 +1,101 @@
+package wire
+
+import (
+	"sync/atomic"
+)
+
+// Interface describes a protocol interface as declared in its XML. The
+// generated descriptors are used to discard events sent to destroyed
+// objects, and give tests the wire signature of every message.
+type Interface struct {
+	Name     string
+	Version  uint32
+	Requests []Message
+	Events   []Message
+}
+
+// Message describes a request or event. Signature has one character per
+// argument, as in libwayland: i int, u uint, f fixed, s string, o object,
+// n new_id, a array and h fd, with ? before nullable strings and objects.
+// Types holds the interface of each object and new_id argument, or "" for
+// other arguments and untyped ids.
+type Message struct {
+	Name       string
+	Signature  string
+	Types      []string
+	Since      uint32
+	Destructor bool
+}
+
+// fds returns the number of file descriptors the message carries
+func (m Message) fds() int {
+	n := 0
+	for _, c := range m.Signature {
+		if c == 'h' {
+			n++
+		}
+	}
+	return n
+}
+
+// Object is a protocol object. It is implemented by the generated proxy
+// types, which embed Proxy.
+type Object interface {
+	ID() uint32
+	Version() uint32
+	Interface() *Interface
+	proxy() *Proxy
+	dispatch(opcode uint16, d *decoder) error
+}
+
+// Proxy is the client side of a protocol object. The generated types embed
+// it and add a method per request and a handler field per event.
+type Proxy struct {
+	conn    *Conn
+	id      uint32
+	version uint32
+	// zombie is set once a destructor request is sent. The compositor may
+	// still send events until it acknowledges with delete_id; they are
+	// decoded, so their file descriptors are closed, but not delivered.
+	zombie atomic.Bool
+}
+
+// ID returns the object id, or 0 for an object not yet created
+func (p *Proxy) ID() uint32 {
+	return p.id
+}
+
+// Version returns the interface version the object was created with
+func (p *Proxy) Version() uint32 {
+	return p.version
+}
+
+// Conn returns the connection the object belongs to
+func (p *Proxy) Conn() *Conn {
+	return p.conn
+}
+
+// Forget stops delivering events to the object without telling the
+// compositor, as wl_proxy_destroy does for interfaces or versions that
+// have no destructor request
+func (p *Proxy) Forget() {
+	p.zombie.Store(true)
+}
+
+func (p *Proxy) proxy() *Proxy {
+	return p
+}
+
+// request starts a request on the object
+func (p *Proxy) request(opcode uint16) *encoder {
+	return newEncoder(p.conn, p.id, opcode)
+}
+
+// send queues a request built with request. After a destructor the object
+// no longer receives events.
+func (p *Proxy) send(e *encoder, destructor bool) {
+	if destructor {
+		p.zombie.Store(true)
+	}
+	p.conn.send(e)
+}
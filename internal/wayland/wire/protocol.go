@@ -0,0 +1,2677 @@
+// Code generated by gen from wayland.xml, xdg-shell.xml, wlr-layer-shell-unstable-v1.xml, virtual-keyboard-unstable-v1.xml, input-method-unstable-v2.xml, viewporter.xml, fractional-scale-v1.xml. DO NOT EDIT.
+
+package wire
+
+import "fmt"
+
+// WlDisplayInterface describes wl_display
+var WlDisplayInterface = &Interface{
+	Name:    "wl_display",
+	Version: 1,
+	Requests: []Message{
+		{Name: "sync", Signature: "n", Types: []string{"wl_callback"}},
+		{Name: "get_registry", Signature: "n", Types: []string{"wl_registry"}},
+	},
+	Events: []Message{
+		{Name: "error", Signature: "ous"},
+		{Name: "delete_id", Signature: "u"},
+	},
+}
+
+// Values of wl_display.error
+const (
+	WlDisplayErrorInvalidObject  = 0
+	WlDisplayErrorInvalidMethod  = 1
+	WlDisplayErrorNoMemory       = 2
+	WlDisplayErrorImplementation = 3
+)
+
+// WlDisplay is wl_display: core global object
+type WlDisplay struct {
+	Proxy
+	// OnError handles the error event
+	OnError func(objectID Object, code uint32, message string)
+	// OnDeleteID handles the delete_id event
+	OnDeleteID func(id uint32)
+}
+
+// Interface returns the descriptor of wl_display
+func (p *WlDisplay) Interface() *Interface {
+	return WlDisplayInterface
+}
+
+// Sync sends the wl_display.sync request
+func (p *WlDisplay) Sync() *WlCallback {
+	m := p.request(0)
+	callback := &WlCallback{}
+	m.putNewID(callback, p.version)
+	p.send(m, false)
+	return callback
+}
+
+// GetRegistry sends the wl_display.get_registry request
+func (p *WlDisplay) GetRegistry() *WlRegistry {
+	m := p.request(1)
+	registry := &WlRegistry{}
+	m.putNewID(registry, p.version)
+	p.send(m, false)
+	return registry
+}
+
+func (p *WlDisplay) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		objectID := d.getObject()
+		code := d.getUint()
+		message := d.getString()
+		if d.err != nil {
+			return fmt.Errorf("wl_display.error: %w", d.err)
+		}
+		if p.OnError != nil {
+			p.OnError(objectID, code, message)
+		}
+	case 1:
+		id := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_display.delete_id: %w", d.err)
+		}
+		if p.OnDeleteID != nil {
+			p.OnDeleteID(id)
+		}
+	}
+	return nil
+}
+
+// WlRegistryInterface describes wl_registry
+var WlRegistryInterface = &Interface{
+	Name:    "wl_registry",
+	Version: 1,
+	Requests: []Message{
+		{Name: "bind", Signature: "usun"},
+	},
+	Events: []Message{
+		{Name: "global", Signature: "usu"},
+		{Name: "global_remove", Signature: "u"},
+	},
+}
+
+// WlRegistry is wl_registry: global registry object
+type WlRegistry struct {
+	Proxy
+	// OnGlobal handles the global event
+	OnGlobal func(name uint32, iface string, version uint32)
+	// OnGlobalRemove handles the global_remove event
+	OnGlobalRemove func(name uint32)
+}
+
+// Interface returns the descriptor of wl_registry
+func (p *WlRegistry) Interface() *Interface {
+	return WlRegistryInterface
+}
+
+// Bind sends the wl_registry.bind request
+func (p *WlRegistry) Bind(name uint32, id Object, version uint32) {
+	m := p.request(0)
+	m.putUint(name)
+	m.putString(id.Interface().Name)
+	m.putUint(version)
+	m.putNewID(id, version)
+	p.send(m, false)
+}
+
+func (p *WlRegistry) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		name := d.getUint()
+		iface := d.getString()
+		version := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_registry.global: %w", d.err)
+		}
+		if p.OnGlobal != nil {
+			p.OnGlobal(name, iface, version)
+		}
+	case 1:
+		name := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_registry.global_remove: %w", d.err)
+		}
+		if p.OnGlobalRemove != nil {
+			p.OnGlobalRemove(name)
+		}
+	}
+	return nil
+}
+
+// WlCallbackInterface describes wl_callback
+var WlCallbackInterface = &Interface{
+	Name:    "wl_callback",
+	Version: 1,
+	Events: []Message{
+		{Name: "done", Signature: "u", Destructor: true},
+	},
+}
+
+// WlCallback is wl_callback: callback object
+type WlCallback struct {
+	Proxy
+	// OnDone handles the done event
+	OnDone func(callbackData uint32)
+}
+
+// Interface returns the descriptor of wl_callback
+func (p *WlCallback) Interface() *Interface {
+	return WlCallbackInterface
+}
+
+func (p *WlCallback) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		callbackData := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_callback.done: %w", d.err)
+		}
+		if p.OnDone != nil {
+			p.OnDone(callbackData)
+		}
+	}
+	return nil
+}
+
+// WlCompositorInterface describes wl_compositor
+var WlCompositorInterface = &Interface{
+	Name:    "wl_compositor",
+	Version: 6,
+	Requests: []Message{
+		{Name: "create_surface", Signature: "n", Types: []string{"wl_surface"}},
+		{Name: "create_region", Signature: "n", Types: []string{"wl_region"}},
+	},
+}
+
+// WlCompositor is wl_compositor: the compositor singleton
+type WlCompositor struct {
+	Proxy
+}
+
+// Interface returns the descriptor of wl_compositor
+func (p *WlCompositor) Interface() *Interface {
+	return WlCompositorInterface
+}
+
+// CreateSurface sends the wl_compositor.create_surface request
+func (p *WlCompositor) CreateSurface() *WlSurface {
+	m := p.request(0)
+	id := &WlSurface{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+// CreateRegion sends the wl_compositor.create_region request
+func (p *WlCompositor) CreateRegion() *WlRegion {
+	m := p.request(1)
+	id := &WlRegion{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+func (p *WlCompositor) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// WlShmPoolInterface describes wl_shm_pool
+var WlShmPoolInterface = &Interface{
+	Name:    "wl_shm_pool",
+	Version: 1,
+	Requests: []Message{
+		{Name: "create_buffer", Signature: "niiiiu", Types: []string{"wl_buffer", "", "", "", "", ""}},
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "resize", Signature: "i"},
+	},
+}
+
+// WlShmPool is wl_shm_pool: a shared memory pool
+type WlShmPool struct {
+	Proxy
+}
+
+// Interface returns the descriptor of wl_shm_pool
+func (p *WlShmPool) Interface() *Interface {
+	return WlShmPoolInterface
+}
+
+// CreateBuffer sends the wl_shm_pool.create_buffer request
+func (p *WlShmPool) CreateBuffer(offset int32, width int32, height int32, stride int32, format uint32) *WlBuffer {
+	m := p.request(0)
+	id := &WlBuffer{}
+	m.putNewID(id, p.version)
+	m.putInt(offset)
+	m.putInt(width)
+	m.putInt(height)
+	m.putInt(stride)
+	m.putUint(format)
+	p.send(m, false)
+	return id
+}
+
+// Destroy sends the wl_shm_pool.destroy destructor; the object receives no more events
+func (p *WlShmPool) Destroy() {
+	m := p.request(1)
+	p.send(m, true)
+}
+
+// Resize sends the wl_shm_pool.resize request
+func (p *WlShmPool) Resize(size int32) {
+	m := p.request(2)
+	m.putInt(size)
+	p.send(m, false)
+}
+
+func (p *WlShmPool) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// WlShmInterface describes wl_shm
+var WlShmInterface = &Interface{
+	Name:    "wl_shm",
+	Version: 2,
+	Requests: []Message{
+		{Name: "create_pool", Signature: "nhi", Types: []string{"wl_shm_pool", "", ""}},
+		{Name: "release", Signature: "", Since: 2, Destructor: true},
+	},
+	Events: []Message{
+		{Name: "format", Signature: "u"},
+	},
+}
+
+// Values of wl_shm.error
+const (
+	WlShmErrorInvalidFormat = 0
+	WlShmErrorInvalidStride = 1
+	WlShmErrorInvalidFD     = 2
+)
+
+// Values of wl_shm.format
+const (
+	WlShmFormatArgb8888 = 0
+	WlShmFormatXrgb8888 = 1
+)
+
+// WlShm is wl_shm: shared memory support
+type WlShm struct {
+	Proxy
+	// OnFormat handles the format event
+	OnFormat func(format uint32)
+}
+
+// Interface returns the descriptor of wl_shm
+func (p *WlShm) Interface() *Interface {
+	return WlShmInterface
+}
+
+// CreatePool sends the wl_shm.create_pool request
+func (p *WlShm) CreatePool(fd int, size int32) *WlShmPool {
+	m := p.request(0)
+	id := &WlShmPool{}
+	m.putNewID(id, p.version)
+	m.putFD(fd)
+	m.putInt(size)
+	p.send(m, false)
+	return id
+}
+
+// Release sends the wl_shm.release destructor; the object receives no more events
+func (p *WlShm) Release() {
+	m := p.request(1)
+	p.send(m, true)
+}
+
+func (p *WlShm) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		format := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_shm.format: %w", d.err)
+		}
+		if p.OnFormat != nil {
+			p.OnFormat(format)
+		}
+	}
+	return nil
+}
+
+// WlBufferInterface describes wl_buffer
+var WlBufferInterface = &Interface{
+	Name:    "wl_buffer",
+	Version: 1,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+	},
+	Events: []Message{
+		{Name: "release", Signature: ""},
+	},
+}
+
+// WlBuffer is wl_buffer: content for a wl_surface
+type WlBuffer struct {
+	Proxy
+	// OnRelease handles the release event
+	OnRelease func()
+}
+
+// Interface returns the descriptor of wl_buffer
+func (p *WlBuffer) Interface() *Interface {
+	return WlBufferInterface
+}
+
+// Destroy sends the wl_buffer.destroy destructor; the object receives no more events
+func (p *WlBuffer) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+func (p *WlBuffer) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		if p.OnRelease != nil {
+			p.OnRelease()
+		}
+	}
+	return nil
+}
+
+// WlSurfaceInterface describes wl_surface
+var WlSurfaceInterface = &Interface{
+	Name:    "wl_surface",
+	Version: 6,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "attach", Signature: "?oii", Types: []string{"wl_buffer", "", ""}},
+		{Name: "damage", Signature: "iiii"},
+		{Name: "frame", Signature: "n", Types: []string{"wl_callback"}},
+		{Name: "set_opaque_region", Signature: "?o", Types: []string{"wl_region"}},
+		{Name: "set_input_region", Signature: "?o", Types: []string{"wl_region"}},
+		{Name: "commit", Signature: ""},
+		{Name: "set_buffer_transform", Signature: "i", Since: 2},
+		{Name: "set_buffer_scale", Signature: "i", Since: 3},
+		{Name: "damage_buffer", Signature: "iiii", Since: 4},
+		{Name: "offset", Signature: "ii", Since: 5},
+	},
+	Events: []Message{
+		{Name: "enter", Signature: "o", Types: []string{"wl_output"}},
+		{Name: "leave", Signature: "o", Types: []string{"wl_output"}},
+		{Name: "preferred_buffer_scale", Signature: "i", Since: 6},
+		{Name: "preferred_buffer_transform", Signature: "u", Since: 6},
+	},
+}
+
+// Values of wl_surface.error
+const (
+	WlSurfaceErrorInvalidScale      = 0
+	WlSurfaceErrorInvalidTransform  = 1
+	WlSurfaceErrorInvalidSize       = 2
+	WlSurfaceErrorInvalidOffset     = 3
+	WlSurfaceErrorDefunctRoleObject = 4
+)
+
+// WlSurface is wl_surface: an onscreen surface
+type WlSurface struct {
+	Proxy
+	// OnEnter handles the enter event
+	OnEnter func(output *WlOutput)
+	// OnLeave handles the leave event
+	OnLeave func(output *WlOutput)
+	// OnPreferredBufferScale handles the preferred_buffer_scale event
+	OnPreferredBufferScale func(factor int32)
+	// OnPreferredBufferTransform handles the preferred_buffer_transform event
+	OnPreferredBufferTransform func(transform uint32)
+}
+
+// Interface returns the descriptor of wl_surface
+func (p *WlSurface) Interface() *Interface {
+	return WlSurfaceInterface
+}
+
+// Destroy sends the wl_surface.destroy destructor; the object receives no more events
+func (p *WlSurface) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// Attach sends the wl_surface.attach request
+func (p *WlSurface) Attach(buffer *WlBuffer, x int32, y int32) {
+	m := p.request(1)
+	m.putUint(idOf(buffer))
+	m.putInt(x)
+	m.putInt(y)
+	p.send(m, false)
+}
+
+// Damage sends the wl_surface.damage request
+func (p *WlSurface) Damage(x int32, y int32, width int32, height int32) {
+	m := p.request(2)
+	m.putInt(x)
+	m.putInt(y)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// Frame sends the wl_surface.frame request
+func (p *WlSurface) Frame() *WlCallback {
+	m := p.request(3)
+	callback := &WlCallback{}
+	m.putNewID(callback, p.version)
+	p.send(m, false)
+	return callback
+}
+
+// SetOpaqueRegion sends the wl_surface.set_opaque_region request
+func (p *WlSurface) SetOpaqueRegion(region *WlRegion) {
+	m := p.request(4)
+	m.putUint(idOf(region))
+	p.send(m, false)
+}
+
+// SetInputRegion sends the wl_surface.set_input_region request
+func (p *WlSurface) SetInputRegion(region *WlRegion) {
+	m := p.request(5)
+	m.putUint(idOf(region))
+	p.send(m, false)
+}
+
+// Commit sends the wl_surface.commit request
+func (p *WlSurface) Commit() {
+	m := p.request(6)
+	p.send(m, false)
+}
+
+// SetBufferTransform sends the wl_surface.set_buffer_transform request
+func (p *WlSurface) SetBufferTransform(transform int32) {
+	m := p.request(7)
+	m.putInt(transform)
+	p.send(m, false)
+}
+
+// SetBufferScale sends the wl_surface.set_buffer_scale request
+func (p *WlSurface) SetBufferScale(scale int32) {
+	m := p.request(8)
+	m.putInt(scale)
+	p.send(m, false)
+}
+
+// DamageBuffer sends the wl_surface.damage_buffer request
+func (p *WlSurface) DamageBuffer(x int32, y int32, width int32, height int32) {
+	m := p.request(9)
+	m.putInt(x)
+	m.putInt(y)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// Offset sends the wl_surface.offset request
+func (p *WlSurface) Offset(x int32, y int32) {
+	m := p.request(10)
+	m.putInt(x)
+	m.putInt(y)
+	p.send(m, false)
+}
+
+func (p *WlSurface) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		output, _ := d.getObject().(*WlOutput)
+		if d.err != nil {
+			return fmt.Errorf("wl_surface.enter: %w", d.err)
+		}
+		if p.OnEnter != nil {
+			p.OnEnter(output)
+		}
+	case 1:
+		output, _ := d.getObject().(*WlOutput)
+		if d.err != nil {
+			return fmt.Errorf("wl_surface.leave: %w", d.err)
+		}
+		if p.OnLeave != nil {
+			p.OnLeave(output)
+		}
+	case 2:
+		factor := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_surface.preferred_buffer_scale: %w", d.err)
+		}
+		if p.OnPreferredBufferScale != nil {
+			p.OnPreferredBufferScale(factor)
+		}
+	case 3:
+		transform := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_surface.preferred_buffer_transform: %w", d.err)
+		}
+		if p.OnPreferredBufferTransform != nil {
+			p.OnPreferredBufferTransform(transform)
+		}
+	}
+	return nil
+}
+
+// WlSeatInterface describes wl_seat
+var WlSeatInterface = &Interface{
+	Name:    "wl_seat",
+	Version: 9,
+	Requests: []Message{
+		{Name: "get_pointer", Signature: "n", Types: []string{"wl_pointer"}},
+		{Name: "get_keyboard", Signature: "n", Types: []string{"wl_keyboard"}},
+		{Name: "get_touch", Signature: "n", Types: []string{"wl_touch"}},
+		{Name: "release", Signature: "", Since: 5, Destructor: true},
+	},
+	Events: []Message{
+		{Name: "capabilities", Signature: "u"},
+		{Name: "name", Signature: "s", Since: 2},
+	},
+}
+
+// Values of wl_seat.capability
+const (
+	WlSeatCapabilityPointer  = 1
+	WlSeatCapabilityKeyboard = 2
+	WlSeatCapabilityTouch    = 4
+)
+
+// Values of wl_seat.error
+const (
+	WlSeatErrorMissingCapability = 0
+)
+
+// WlSeat is wl_seat: group of input devices
+type WlSeat struct {
+	Proxy
+	// OnCapabilities handles the capabilities event
+	OnCapabilities func(capabilities uint32)
+	// OnName handles the name event
+	OnName func(name string)
+}
+
+// Interface returns the descriptor of wl_seat
+func (p *WlSeat) Interface() *Interface {
+	return WlSeatInterface
+}
+
+// GetPointer sends the wl_seat.get_pointer request
+func (p *WlSeat) GetPointer() *WlPointer {
+	m := p.request(0)
+	id := &WlPointer{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+// GetKeyboard sends the wl_seat.get_keyboard request
+func (p *WlSeat) GetKeyboard() *WlKeyboard {
+	m := p.request(1)
+	id := &WlKeyboard{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+// GetTouch sends the wl_seat.get_touch request
+func (p *WlSeat) GetTouch() *WlTouch {
+	m := p.request(2)
+	id := &WlTouch{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+// Release sends the wl_seat.release destructor; the object receives no more events
+func (p *WlSeat) Release() {
+	m := p.request(3)
+	p.send(m, true)
+}
+
+func (p *WlSeat) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		capabilities := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_seat.capabilities: %w", d.err)
+		}
+		if p.OnCapabilities != nil {
+			p.OnCapabilities(capabilities)
+		}
+	case 1:
+		name := d.getString()
+		if d.err != nil {
+			return fmt.Errorf("wl_seat.name: %w", d.err)
+		}
+		if p.OnName != nil {
+			p.OnName(name)
+		}
+	}
+	return nil
+}
+
+// WlPointerInterface describes wl_pointer
+var WlPointerInterface = &Interface{
+	Name:    "wl_pointer",
+	Version: 9,
+	Requests: []Message{
+		{Name: "set_cursor", Signature: "u?oii", Types: []string{"", "wl_surface", "", ""}},
+		{Name: "release", Signature: "", Since: 3, Destructor: true},
+	},
+	Events: []Message{
+		{Name: "enter", Signature: "uoff", Types: []string{"", "wl_surface", "", ""}},
+		{Name: "leave", Signature: "uo", Types: []string{"", "wl_surface"}},
+		{Name: "motion", Signature: "uff"},
+		{Name: "button", Signature: "uuuu"},
+		{Name: "axis", Signature: "uuf"},
+		{Name: "frame", Signature: "", Since: 5},
+		{Name: "axis_source", Signature: "u", Since: 5},
+		{Name: "axis_stop", Signature: "uu", Since: 5},
+		{Name: "axis_discrete", Signature: "ui", Since: 5},
+		{Name: "axis_value120", Signature: "ui", Since: 8},
+		{Name: "axis_relative_direction", Signature: "uu", Since: 9},
+	},
+}
+
+// Values of wl_pointer.error
+const (
+	WlPointerErrorRole = 0
+)
+
+// Values of wl_pointer.button_state
+const (
+	WlPointerButtonStateReleased = 0
+	WlPointerButtonStatePressed  = 1
+)
+
+// Values of wl_pointer.axis
+const (
+	WlPointerAxisVerticalScroll   = 0
+	WlPointerAxisHorizontalScroll = 1
+)
+
+// WlPointer is wl_pointer: pointer input device
+type WlPointer struct {
+	Proxy
+	// OnEnter handles the enter event
+	OnEnter func(serial uint32, surface *WlSurface, surfaceX Fixed, surfaceY Fixed)
+	// OnLeave handles the leave event
+	OnLeave func(serial uint32, surface *WlSurface)
+	// OnMotion handles the motion event
+	OnMotion func(time uint32, surfaceX Fixed, surfaceY Fixed)
+	// OnButton handles the button event
+	OnButton func(serial uint32, time uint32, button uint32, state uint32)
+	// OnAxis handles the axis event
+	OnAxis func(time uint32, axis uint32, value Fixed)
+	// OnFrame handles the frame event
+	OnFrame func()
+	// OnAxisSource handles the axis_source event
+	OnAxisSource func(axisSource uint32)
+	// OnAxisStop handles the axis_stop event
+	OnAxisStop func(time uint32, axis uint32)
+	// OnAxisDiscrete handles the axis_discrete event
+	OnAxisDiscrete func(axis uint32, discrete int32)
+	// OnAxisValue120 handles the axis_value120 event
+	OnAxisValue120 func(axis uint32, value120 int32)
+	// OnAxisRelativeDirection handles the axis_relative_direction event
+	OnAxisRelativeDirection func(axis uint32, direction uint32)
+}
+
+// Interface returns the descriptor of wl_pointer
+func (p *WlPointer) Interface() *Interface {
+	return WlPointerInterface
+}
+
+// SetCursor sends the wl_pointer.set_cursor request
+func (p *WlPointer) SetCursor(serial uint32, surface *WlSurface, hotspotX int32, hotspotY int32) {
+	m := p.request(0)
+	m.putUint(serial)
+	m.putUint(idOf(surface))
+	m.putInt(hotspotX)
+	m.putInt(hotspotY)
+	p.send(m, false)
+}
+
+// Release sends the wl_pointer.release destructor; the object receives no more events
+func (p *WlPointer) Release() {
+	m := p.request(1)
+	p.send(m, true)
+}
+
+func (p *WlPointer) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		serial := d.getUint()
+		surface, _ := d.getObject().(*WlSurface)
+		surfaceX := d.getFixed()
+		surfaceY := d.getFixed()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.enter: %w", d.err)
+		}
+		if p.OnEnter != nil {
+			p.OnEnter(serial, surface, surfaceX, surfaceY)
+		}
+	case 1:
+		serial := d.getUint()
+		surface, _ := d.getObject().(*WlSurface)
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.leave: %w", d.err)
+		}
+		if p.OnLeave != nil {
+			p.OnLeave(serial, surface)
+		}
+	case 2:
+		time := d.getUint()
+		surfaceX := d.getFixed()
+		surfaceY := d.getFixed()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.motion: %w", d.err)
+		}
+		if p.OnMotion != nil {
+			p.OnMotion(time, surfaceX, surfaceY)
+		}
+	case 3:
+		serial := d.getUint()
+		time := d.getUint()
+		button := d.getUint()
+		state := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.button: %w", d.err)
+		}
+		if p.OnButton != nil {
+			p.OnButton(serial, time, button, state)
+		}
+	case 4:
+		time := d.getUint()
+		axis := d.getUint()
+		value := d.getFixed()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.axis: %w", d.err)
+		}
+		if p.OnAxis != nil {
+			p.OnAxis(time, axis, value)
+		}
+	case 5:
+		if p.OnFrame != nil {
+			p.OnFrame()
+		}
+	case 6:
+		axisSource := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.axis_source: %w", d.err)
+		}
+		if p.OnAxisSource != nil {
+			p.OnAxisSource(axisSource)
+		}
+	case 7:
+		time := d.getUint()
+		axis := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.axis_stop: %w", d.err)
+		}
+		if p.OnAxisStop != nil {
+			p.OnAxisStop(time, axis)
+		}
+	case 8:
+		axis := d.getUint()
+		discrete := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.axis_discrete: %w", d.err)
+		}
+		if p.OnAxisDiscrete != nil {
+			p.OnAxisDiscrete(axis, discrete)
+		}
+	case 9:
+		axis := d.getUint()
+		value120 := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.axis_value120: %w", d.err)
+		}
+		if p.OnAxisValue120 != nil {
+			p.OnAxisValue120(axis, value120)
+		}
+	case 10:
+		axis := d.getUint()
+		direction := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_pointer.axis_relative_direction: %w", d.err)
+		}
+		if p.OnAxisRelativeDirection != nil {
+			p.OnAxisRelativeDirection(axis, direction)
+		}
+	}
+	return nil
+}
+
+// WlKeyboardInterface describes wl_keyboard
+var WlKeyboardInterface = &Interface{
+	Name:    "wl_keyboard",
+	Version: 9,
+	Requests: []Message{
+		{Name: "release", Signature: "", Since: 3, Destructor: true},
+	},
+	Events: []Message{
+		{Name: "keymap", Signature: "uhu"},
+		{Name: "enter", Signature: "uoa", Types: []string{"", "wl_surface", ""}},
+		{Name: "leave", Signature: "uo", Types: []string{"", "wl_surface"}},
+		{Name: "key", Signature: "uuuu"},
+		{Name: "modifiers", Signature: "uuuuu"},
+		{Name: "repeat_info", Signature: "ii", Since: 4},
+	},
+}
+
+// Values of wl_keyboard.keymap_format
+const (
+	WlKeyboardKeymapFormatNoKeymap = 0
+	WlKeyboardKeymapFormatXkbV1    = 1
+)
+
+// Values of wl_keyboard.key_state
+const (
+	WlKeyboardKeyStateReleased = 0
+	WlKeyboardKeyStatePressed  = 1
+)
+
+// WlKeyboard is wl_keyboard: keyboard input device
+type WlKeyboard struct {
+	Proxy
+	// OnKeymap handles the keymap event
+	OnKeymap func(format uint32, fd int, size uint32)
+	// OnEnter handles the enter event
+	OnEnter func(serial uint32, surface *WlSurface, keys []byte)
+	// OnLeave handles the leave event
+	OnLeave func(serial uint32, surface *WlSurface)
+	// OnKey handles the key event
+	OnKey func(serial uint32, time uint32, key uint32, state uint32)
+	// OnModifiers handles the modifiers event
+	OnModifiers func(serial uint32, modsDepressed uint32, modsLatched uint32, modsLocked uint32, group uint32)
+	// OnRepeatInfo handles the repeat_info event
+	OnRepeatInfo func(rate int32, delay int32)
+}
+
+// Interface returns the descriptor of wl_keyboard
+func (p *WlKeyboard) Interface() *Interface {
+	return WlKeyboardInterface
+}
+
+// Release sends the wl_keyboard.release destructor; the object receives no more events
+func (p *WlKeyboard) Release() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+func (p *WlKeyboard) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		format := d.getUint()
+		fd := d.getFD()
+		size := d.getUint()
+		if d.err != nil {
+			closeFDs(fd)
+			return fmt.Errorf("wl_keyboard.keymap: %w", d.err)
+		}
+		if p.OnKeymap != nil {
+			p.OnKeymap(format, fd, size)
+		} else {
+			closeFDs(fd)
+		}
+	case 1:
+		serial := d.getUint()
+		surface, _ := d.getObject().(*WlSurface)
+		keys := d.getArray()
+		if d.err != nil {
+			return fmt.Errorf("wl_keyboard.enter: %w", d.err)
+		}
+		if p.OnEnter != nil {
+			p.OnEnter(serial, surface, keys)
+		}
+	case 2:
+		serial := d.getUint()
+		surface, _ := d.getObject().(*WlSurface)
+		if d.err != nil {
+			return fmt.Errorf("wl_keyboard.leave: %w", d.err)
+		}
+		if p.OnLeave != nil {
+			p.OnLeave(serial, surface)
+		}
+	case 3:
+		serial := d.getUint()
+		time := d.getUint()
+		key := d.getUint()
+		state := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_keyboard.key: %w", d.err)
+		}
+		if p.OnKey != nil {
+			p.OnKey(serial, time, key, state)
+		}
+	case 4:
+		serial := d.getUint()
+		modsDepressed := d.getUint()
+		modsLatched := d.getUint()
+		modsLocked := d.getUint()
+		group := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wl_keyboard.modifiers: %w", d.err)
+		}
+		if p.OnModifiers != nil {
+			p.OnModifiers(serial, modsDepressed, modsLatched, modsLocked, group)
+		}
+	case 5:
+		rate := d.getInt()
+		delay := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_keyboard.repeat_info: %w", d.err)
+		}
+		if p.OnRepeatInfo != nil {
+			p.OnRepeatInfo(rate, delay)
+		}
+	}
+	return nil
+}
+
+// WlTouchInterface describes wl_touch
+var WlTouchInterface = &Interface{
+	Name:    "wl_touch",
+	Version: 9,
+	Requests: []Message{
+		{Name: "release", Signature: "", Since: 3, Destructor: true},
+	},
+	Events: []Message{
+		{Name: "down", Signature: "uuoiff", Types: []string{"", "", "wl_surface", "", "", ""}},
+		{Name: "up", Signature: "uui"},
+		{Name: "motion", Signature: "uiff"},
+		{Name: "frame", Signature: ""},
+		{Name: "cancel", Signature: ""},
+		{Name: "shape", Signature: "iff", Since: 6},
+		{Name: "orientation", Signature: "if", Since: 6},
+	},
+}
+
+// WlTouch is wl_touch: touchscreen input device
+type WlTouch struct {
+	Proxy
+	// OnDown handles the down event
+	OnDown func(serial uint32, time uint32, surface *WlSurface, id int32, x Fixed, y Fixed)
+	// OnUp handles the up event
+	OnUp func(serial uint32, time uint32, id int32)
+	// OnMotion handles the motion event
+	OnMotion func(time uint32, id int32, x Fixed, y Fixed)
+	// OnFrame handles the frame event
+	OnFrame func()
+	// OnCancel handles the cancel event
+	OnCancel func()
+	// OnShape handles the shape event
+	OnShape func(id int32, major Fixed, minor Fixed)
+	// OnOrientation handles the orientation event
+	OnOrientation func(id int32, orientation Fixed)
+}
+
+// Interface returns the descriptor of wl_touch
+func (p *WlTouch) Interface() *Interface {
+	return WlTouchInterface
+}
+
+// Release sends the wl_touch.release destructor; the object receives no more events
+func (p *WlTouch) Release() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+func (p *WlTouch) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		serial := d.getUint()
+		time := d.getUint()
+		surface, _ := d.getObject().(*WlSurface)
+		id := d.getInt()
+		x := d.getFixed()
+		y := d.getFixed()
+		if d.err != nil {
+			return fmt.Errorf("wl_touch.down: %w", d.err)
+		}
+		if p.OnDown != nil {
+			p.OnDown(serial, time, surface, id, x, y)
+		}
+	case 1:
+		serial := d.getUint()
+		time := d.getUint()
+		id := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_touch.up: %w", d.err)
+		}
+		if p.OnUp != nil {
+			p.OnUp(serial, time, id)
+		}
+	case 2:
+		time := d.getUint()
+		id := d.getInt()
+		x := d.getFixed()
+		y := d.getFixed()
+		if d.err != nil {
+			return fmt.Errorf("wl_touch.motion: %w", d.err)
+		}
+		if p.OnMotion != nil {
+			p.OnMotion(time, id, x, y)
+		}
+	case 3:
+		if p.OnFrame != nil {
+			p.OnFrame()
+		}
+	case 4:
+		if p.OnCancel != nil {
+			p.OnCancel()
+		}
+	case 5:
+		id := d.getInt()
+		major := d.getFixed()
+		minor := d.getFixed()
+		if d.err != nil {
+			return fmt.Errorf("wl_touch.shape: %w", d.err)
+		}
+		if p.OnShape != nil {
+			p.OnShape(id, major, minor)
+		}
+	case 6:
+		id := d.getInt()
+		orientation := d.getFixed()
+		if d.err != nil {
+			return fmt.Errorf("wl_touch.orientation: %w", d.err)
+		}
+		if p.OnOrientation != nil {
+			p.OnOrientation(id, orientation)
+		}
+	}
+	return nil
+}
+
+// WlOutputInterface describes wl_output
+var WlOutputInterface = &Interface{
+	Name:    "wl_output",
+	Version: 4,
+	Requests: []Message{
+		{Name: "release", Signature: "", Since: 3, Destructor: true},
+	},
+	Events: []Message{
+		{Name: "geometry", Signature: "iiiiissi"},
+		{Name: "mode", Signature: "uiii"},
+		{Name: "done", Signature: "", Since: 2},
+		{Name: "scale", Signature: "i", Since: 2},
+		{Name: "name", Signature: "s", Since: 4},
+		{Name: "description", Signature: "s", Since: 4},
+	},
+}
+
+// Values of wl_output.subpixel
+const (
+	WlOutputSubpixelUnknown       = 0
+	WlOutputSubpixelNone          = 1
+	WlOutputSubpixelHorizontalRgb = 2
+	WlOutputSubpixelHorizontalBgr = 3
+	WlOutputSubpixelVerticalRgb   = 4
+	WlOutputSubpixelVerticalBgr   = 5
+)
+
+// Values of wl_output.transform
+const (
+	WlOutputTransformNormal     = 0
+	WlOutputTransform90         = 1
+	WlOutputTransform180        = 2
+	WlOutputTransform270        = 3
+	WlOutputTransformFlipped    = 4
+	WlOutputTransformFlipped90  = 5
+	WlOutputTransformFlipped180 = 6
+	WlOutputTransformFlipped270 = 7
+)
+
+// Values of wl_output.mode
+const (
+	WlOutputModeCurrent   = 0x1
+	WlOutputModePreferred = 0x2
+)
+
+// WlOutput is wl_output: compositor output region
+type WlOutput struct {
+	Proxy
+	// OnGeometry handles the geometry event
+	OnGeometry func(x int32, y int32, physicalWidth int32, physicalHeight int32, subpixel int32, make string, model string, transform int32)
+	// OnMode handles the mode event
+	OnMode func(flags uint32, width int32, height int32, refresh int32)
+	// OnDone handles the done event
+	OnDone func()
+	// OnScale handles the scale event
+	OnScale func(factor int32)
+	// OnName handles the name event
+	OnName func(name string)
+	// OnDescription handles the description event
+	OnDescription func(description string)
+}
+
+// Interface returns the descriptor of wl_output
+func (p *WlOutput) Interface() *Interface {
+	return WlOutputInterface
+}
+
+// Release sends the wl_output.release destructor; the object receives no more events
+func (p *WlOutput) Release() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+func (p *WlOutput) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		x := d.getInt()
+		y := d.getInt()
+		physicalWidth := d.getInt()
+		physicalHeight := d.getInt()
+		subpixel := d.getInt()
+		make := d.getString()
+		model := d.getString()
+		transform := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_output.geometry: %w", d.err)
+		}
+		if p.OnGeometry != nil {
+			p.OnGeometry(x, y, physicalWidth, physicalHeight, subpixel, make, model, transform)
+		}
+	case 1:
+		flags := d.getUint()
+		width := d.getInt()
+		height := d.getInt()
+		refresh := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_output.mode: %w", d.err)
+		}
+		if p.OnMode != nil {
+			p.OnMode(flags, width, height, refresh)
+		}
+	case 2:
+		if p.OnDone != nil {
+			p.OnDone()
+		}
+	case 3:
+		factor := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("wl_output.scale: %w", d.err)
+		}
+		if p.OnScale != nil {
+			p.OnScale(factor)
+		}
+	case 4:
+		name := d.getString()
+		if d.err != nil {
+			return fmt.Errorf("wl_output.name: %w", d.err)
+		}
+		if p.OnName != nil {
+			p.OnName(name)
+		}
+	case 5:
+		description := d.getString()
+		if d.err != nil {
+			return fmt.Errorf("wl_output.description: %w", d.err)
+		}
+		if p.OnDescription != nil {
+			p.OnDescription(description)
+		}
+	}
+	return nil
+}
+
+// WlRegionInterface describes wl_region
+var WlRegionInterface = &Interface{
+	Name:    "wl_region",
+	Version: 1,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "add", Signature: "iiii"},
+		{Name: "subtract", Signature: "iiii"},
+	},
+}
+
+// WlRegion is wl_region: region interface
+type WlRegion struct {
+	Proxy
+}
+
+// Interface returns the descriptor of wl_region
+func (p *WlRegion) Interface() *Interface {
+	return WlRegionInterface
+}
+
+// Destroy sends the wl_region.destroy destructor; the object receives no more events
+func (p *WlRegion) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// Add sends the wl_region.add request
+func (p *WlRegion) Add(x int32, y int32, width int32, height int32) {
+	m := p.request(1)
+	m.putInt(x)
+	m.putInt(y)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// Subtract sends the wl_region.subtract request
+func (p *WlRegion) Subtract(x int32, y int32, width int32, height int32) {
+	m := p.request(2)
+	m.putInt(x)
+	m.putInt(y)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+func (p *WlRegion) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// XdgWmBaseInterface describes xdg_wm_base
+var XdgWmBaseInterface = &Interface{
+	Name:    "xdg_wm_base",
+	Version: 6,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "create_positioner", Signature: "n", Types: []string{"xdg_positioner"}},
+		{Name: "get_xdg_surface", Signature: "no", Types: []string{"xdg_surface", "wl_surface"}},
+		{Name: "pong", Signature: "u"},
+	},
+	Events: []Message{
+		{Name: "ping", Signature: "u"},
+	},
+}
+
+// Values of xdg_wm_base.error
+const (
+	XdgWmBaseErrorRole                = 0
+	XdgWmBaseErrorDefunctSurfaces     = 1
+	XdgWmBaseErrorNotTheTopmostPopup  = 2
+	XdgWmBaseErrorInvalidPopupParent  = 3
+	XdgWmBaseErrorInvalidSurfaceState = 4
+	XdgWmBaseErrorInvalidPositioner   = 5
+	XdgWmBaseErrorUnresponsive        = 6
+)
+
+// XdgWmBase is xdg_wm_base: create desktop-style surfaces
+type XdgWmBase struct {
+	Proxy
+	// OnPing handles the ping event
+	OnPing func(serial uint32)
+}
+
+// Interface returns the descriptor of xdg_wm_base
+func (p *XdgWmBase) Interface() *Interface {
+	return XdgWmBaseInterface
+}
+
+// Destroy sends the xdg_wm_base.destroy destructor; the object receives no more events
+func (p *XdgWmBase) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// CreatePositioner sends the xdg_wm_base.create_positioner request
+func (p *XdgWmBase) CreatePositioner() *XdgPositioner {
+	m := p.request(1)
+	id := &XdgPositioner{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+// GetXdgSurface sends the xdg_wm_base.get_xdg_surface request
+func (p *XdgWmBase) GetXdgSurface(surface *WlSurface) *XdgSurface {
+	m := p.request(2)
+	id := &XdgSurface{}
+	m.putNewID(id, p.version)
+	m.putUint(idOf(surface))
+	p.send(m, false)
+	return id
+}
+
+// Pong sends the xdg_wm_base.pong request
+func (p *XdgWmBase) Pong(serial uint32) {
+	m := p.request(3)
+	m.putUint(serial)
+	p.send(m, false)
+}
+
+func (p *XdgWmBase) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		serial := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("xdg_wm_base.ping: %w", d.err)
+		}
+		if p.OnPing != nil {
+			p.OnPing(serial)
+		}
+	}
+	return nil
+}
+
+// XdgPositionerInterface describes xdg_positioner
+var XdgPositionerInterface = &Interface{
+	Name:    "xdg_positioner",
+	Version: 6,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "set_size", Signature: "ii"},
+		{Name: "set_anchor_rect", Signature: "iiii"},
+		{Name: "set_anchor", Signature: "u"},
+		{Name: "set_gravity", Signature: "u"},
+		{Name: "set_constraint_adjustment", Signature: "u"},
+		{Name: "set_offset", Signature: "ii"},
+		{Name: "set_reactive", Signature: "", Since: 3},
+		{Name: "set_parent_size", Signature: "ii", Since: 3},
+		{Name: "set_parent_configure", Signature: "u", Since: 3},
+	},
+}
+
+// XdgPositioner is xdg_positioner: child surface positioner
+type XdgPositioner struct {
+	Proxy
+}
+
+// Interface returns the descriptor of xdg_positioner
+func (p *XdgPositioner) Interface() *Interface {
+	return XdgPositionerInterface
+}
+
+// Destroy sends the xdg_positioner.destroy destructor; the object receives no more events
+func (p *XdgPositioner) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// SetSize sends the xdg_positioner.set_size request
+func (p *XdgPositioner) SetSize(width int32, height int32) {
+	m := p.request(1)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// SetAnchorRect sends the xdg_positioner.set_anchor_rect request
+func (p *XdgPositioner) SetAnchorRect(x int32, y int32, width int32, height int32) {
+	m := p.request(2)
+	m.putInt(x)
+	m.putInt(y)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// SetAnchor sends the xdg_positioner.set_anchor request
+func (p *XdgPositioner) SetAnchor(anchor uint32) {
+	m := p.request(3)
+	m.putUint(anchor)
+	p.send(m, false)
+}
+
+// SetGravity sends the xdg_positioner.set_gravity request
+func (p *XdgPositioner) SetGravity(gravity uint32) {
+	m := p.request(4)
+	m.putUint(gravity)
+	p.send(m, false)
+}
+
+// SetConstraintAdjustment sends the xdg_positioner.set_constraint_adjustment request
+func (p *XdgPositioner) SetConstraintAdjustment(constraintAdjustment uint32) {
+	m := p.request(5)
+	m.putUint(constraintAdjustment)
+	p.send(m, false)
+}
+
+// SetOffset sends the xdg_positioner.set_offset request
+func (p *XdgPositioner) SetOffset(x int32, y int32) {
+	m := p.request(6)
+	m.putInt(x)
+	m.putInt(y)
+	p.send(m, false)
+}
+
+// SetReactive sends the xdg_positioner.set_reactive request
+func (p *XdgPositioner) SetReactive() {
+	m := p.request(7)
+	p.send(m, false)
+}
+
+// SetParentSize sends the xdg_positioner.set_parent_size request
+func (p *XdgPositioner) SetParentSize(parentWidth int32, parentHeight int32) {
+	m := p.request(8)
+	m.putInt(parentWidth)
+	m.putInt(parentHeight)
+	p.send(m, false)
+}
+
+// SetParentConfigure sends the xdg_positioner.set_parent_configure request
+func (p *XdgPositioner) SetParentConfigure(serial uint32) {
+	m := p.request(9)
+	m.putUint(serial)
+	p.send(m, false)
+}
+
+func (p *XdgPositioner) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// XdgSurfaceInterface describes xdg_surface
+var XdgSurfaceInterface = &Interface{
+	Name:    "xdg_surface",
+	Version: 6,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "get_toplevel", Signature: "n", Types: []string{"xdg_toplevel"}},
+		{Name: "get_popup", Signature: "n?oo", Types: []string{"xdg_popup", "xdg_surface", "xdg_positioner"}},
+		{Name: "set_window_geometry", Signature: "iiii"},
+		{Name: "ack_configure", Signature: "u"},
+	},
+	Events: []Message{
+		{Name: "configure", Signature: "u"},
+	},
+}
+
+// XdgSurface is xdg_surface: desktop user interface surface base interface
+type XdgSurface struct {
+	Proxy
+	// OnConfigure handles the configure event
+	OnConfigure func(serial uint32)
+}
+
+// Interface returns the descriptor of xdg_surface
+func (p *XdgSurface) Interface() *Interface {
+	return XdgSurfaceInterface
+}
+
+// Destroy sends the xdg_surface.destroy destructor; the object receives no more events
+func (p *XdgSurface) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// GetToplevel sends the xdg_surface.get_toplevel request
+func (p *XdgSurface) GetToplevel() *XdgToplevel {
+	m := p.request(1)
+	id := &XdgToplevel{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+// GetPopup sends the xdg_surface.get_popup request
+func (p *XdgSurface) GetPopup(parent *XdgSurface, positioner *XdgPositioner) *XdgPopup {
+	m := p.request(2)
+	id := &XdgPopup{}
+	m.putNewID(id, p.version)
+	m.putUint(idOf(parent))
+	m.putUint(idOf(positioner))
+	p.send(m, false)
+	return id
+}
+
+// SetWindowGeometry sends the xdg_surface.set_window_geometry request
+func (p *XdgSurface) SetWindowGeometry(x int32, y int32, width int32, height int32) {
+	m := p.request(3)
+	m.putInt(x)
+	m.putInt(y)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// AckConfigure sends the xdg_surface.ack_configure request
+func (p *XdgSurface) AckConfigure(serial uint32) {
+	m := p.request(4)
+	m.putUint(serial)
+	p.send(m, false)
+}
+
+func (p *XdgSurface) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		serial := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("xdg_surface.configure: %w", d.err)
+		}
+		if p.OnConfigure != nil {
+			p.OnConfigure(serial)
+		}
+	}
+	return nil
+}
+
+// XdgToplevelInterface describes xdg_toplevel
+var XdgToplevelInterface = &Interface{
+	Name:    "xdg_toplevel",
+	Version: 6,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "set_parent", Signature: "?o", Types: []string{"xdg_toplevel"}},
+		{Name: "set_title", Signature: "s"},
+		{Name: "set_app_id", Signature: "s"},
+		{Name: "show_window_menu", Signature: "ouii", Types: []string{"wl_seat", "", "", ""}},
+		{Name: "move", Signature: "ou", Types: []string{"wl_seat", ""}},
+		{Name: "resize", Signature: "ouu", Types: []string{"wl_seat", "", ""}},
+		{Name: "set_max_size", Signature: "ii"},
+		{Name: "set_min_size", Signature: "ii"},
+		{Name: "set_maximized", Signature: ""},
+		{Name: "unset_maximized", Signature: ""},
+		{Name: "set_fullscreen", Signature: "?o", Types: []string{"wl_output"}},
+		{Name: "unset_fullscreen", Signature: ""},
+		{Name: "set_minimized", Signature: ""},
+	},
+	Events: []Message{
+		{Name: "configure", Signature: "iia"},
+		{Name: "close", Signature: ""},
+		{Name: "configure_bounds", Signature: "ii", Since: 4},
+		{Name: "wm_capabilities", Signature: "a", Since: 5},
+	},
+}
+
+// XdgToplevel is xdg_toplevel: toplevel surface
+type XdgToplevel struct {
+	Proxy
+	// OnConfigure handles the configure event
+	OnConfigure func(width int32, height int32, states []byte)
+	// OnClose handles the close event
+	OnClose func()
+	// OnConfigureBounds handles the configure_bounds event
+	OnConfigureBounds func(width int32, height int32)
+	// OnWmCapabilities handles the wm_capabilities event
+	OnWmCapabilities func(capabilities []byte)
+}
+
+// Interface returns the descriptor of xdg_toplevel
+func (p *XdgToplevel) Interface() *Interface {
+	return XdgToplevelInterface
+}
+
+// Destroy sends the xdg_toplevel.destroy destructor; the object receives no more events
+func (p *XdgToplevel) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// SetParent sends the xdg_toplevel.set_parent request
+func (p *XdgToplevel) SetParent(parent *XdgToplevel) {
+	m := p.request(1)
+	m.putUint(idOf(parent))
+	p.send(m, false)
+}
+
+// SetTitle sends the xdg_toplevel.set_title request
+func (p *XdgToplevel) SetTitle(title string) {
+	m := p.request(2)
+	m.putString(title)
+	p.send(m, false)
+}
+
+// SetAppID sends the xdg_toplevel.set_app_id request
+func (p *XdgToplevel) SetAppID(appID string) {
+	m := p.request(3)
+	m.putString(appID)
+	p.send(m, false)
+}
+
+// ShowWindowMenu sends the xdg_toplevel.show_window_menu request
+func (p *XdgToplevel) ShowWindowMenu(seat *WlSeat, serial uint32, x int32, y int32) {
+	m := p.request(4)
+	m.putUint(idOf(seat))
+	m.putUint(serial)
+	m.putInt(x)
+	m.putInt(y)
+	p.send(m, false)
+}
+
+// Move sends the xdg_toplevel.move request
+func (p *XdgToplevel) Move(seat *WlSeat, serial uint32) {
+	m := p.request(5)
+	m.putUint(idOf(seat))
+	m.putUint(serial)
+	p.send(m, false)
+}
+
+// Resize sends the xdg_toplevel.resize request
+func (p *XdgToplevel) Resize(seat *WlSeat, serial uint32, edges uint32) {
+	m := p.request(6)
+	m.putUint(idOf(seat))
+	m.putUint(serial)
+	m.putUint(edges)
+	p.send(m, false)
+}
+
+// SetMaxSize sends the xdg_toplevel.set_max_size request
+func (p *XdgToplevel) SetMaxSize(width int32, height int32) {
+	m := p.request(7)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// SetMinSize sends the xdg_toplevel.set_min_size request
+func (p *XdgToplevel) SetMinSize(width int32, height int32) {
+	m := p.request(8)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+// SetMaximized sends the xdg_toplevel.set_maximized request
+func (p *XdgToplevel) SetMaximized() {
+	m := p.request(9)
+	p.send(m, false)
+}
+
+// UnsetMaximized sends the xdg_toplevel.unset_maximized request
+func (p *XdgToplevel) UnsetMaximized() {
+	m := p.request(10)
+	p.send(m, false)
+}
+
+// SetFullscreen sends the xdg_toplevel.set_fullscreen request
+func (p *XdgToplevel) SetFullscreen(output *WlOutput) {
+	m := p.request(11)
+	m.putUint(idOf(output))
+	p.send(m, false)
+}
+
+// UnsetFullscreen sends the xdg_toplevel.unset_fullscreen request
+func (p *XdgToplevel) UnsetFullscreen() {
+	m := p.request(12)
+	p.send(m, false)
+}
+
+// SetMinimized sends the xdg_toplevel.set_minimized request
+func (p *XdgToplevel) SetMinimized() {
+	m := p.request(13)
+	p.send(m, false)
+}
+
+func (p *XdgToplevel) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		width := d.getInt()
+		height := d.getInt()
+		states := d.getArray()
+		if d.err != nil {
+			return fmt.Errorf("xdg_toplevel.configure: %w", d.err)
+		}
+		if p.OnConfigure != nil {
+			p.OnConfigure(width, height, states)
+		}
+	case 1:
+		if p.OnClose != nil {
+			p.OnClose()
+		}
+	case 2:
+		width := d.getInt()
+		height := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("xdg_toplevel.configure_bounds: %w", d.err)
+		}
+		if p.OnConfigureBounds != nil {
+			p.OnConfigureBounds(width, height)
+		}
+	case 3:
+		capabilities := d.getArray()
+		if d.err != nil {
+			return fmt.Errorf("xdg_toplevel.wm_capabilities: %w", d.err)
+		}
+		if p.OnWmCapabilities != nil {
+			p.OnWmCapabilities(capabilities)
+		}
+	}
+	return nil
+}
+
+// XdgPopupInterface describes xdg_popup
+var XdgPopupInterface = &Interface{
+	Name:    "xdg_popup",
+	Version: 6,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "grab", Signature: "ou", Types: []string{"wl_seat", ""}},
+		{Name: "reposition", Signature: "ou", Types: []string{"xdg_positioner", ""}, Since: 3},
+	},
+	Events: []Message{
+		{Name: "configure", Signature: "iiii"},
+		{Name: "popup_done", Signature: ""},
+		{Name: "repositioned", Signature: "u", Since: 3},
+	},
+}
+
+// XdgPopup is xdg_popup: short-lived, popup surfaces for menus
+type XdgPopup struct {
+	Proxy
+	// OnConfigure handles the configure event
+	OnConfigure func(x int32, y int32, width int32, height int32)
+	// OnPopupDone handles the popup_done event
+	OnPopupDone func()
+	// OnRepositioned handles the repositioned event
+	OnRepositioned func(token uint32)
+}
+
+// Interface returns the descriptor of xdg_popup
+func (p *XdgPopup) Interface() *Interface {
+	return XdgPopupInterface
+}
+
+// Destroy sends the xdg_popup.destroy destructor; the object receives no more events
+func (p *XdgPopup) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// Grab sends the xdg_popup.grab request
+func (p *XdgPopup) Grab(seat *WlSeat, serial uint32) {
+	m := p.request(1)
+	m.putUint(idOf(seat))
+	m.putUint(serial)
+	p.send(m, false)
+}
+
+// Reposition sends the xdg_popup.reposition request
+func (p *XdgPopup) Reposition(positioner *XdgPositioner, token uint32) {
+	m := p.request(2)
+	m.putUint(idOf(positioner))
+	m.putUint(token)
+	p.send(m, false)
+}
+
+func (p *XdgPopup) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		x := d.getInt()
+		y := d.getInt()
+		width := d.getInt()
+		height := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("xdg_popup.configure: %w", d.err)
+		}
+		if p.OnConfigure != nil {
+			p.OnConfigure(x, y, width, height)
+		}
+	case 1:
+		if p.OnPopupDone != nil {
+			p.OnPopupDone()
+		}
+	case 2:
+		token := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("xdg_popup.repositioned: %w", d.err)
+		}
+		if p.OnRepositioned != nil {
+			p.OnRepositioned(token)
+		}
+	}
+	return nil
+}
+
+// ZwlrLayerShellV1Interface describes zwlr_layer_shell_v1
+var ZwlrLayerShellV1Interface = &Interface{
+	Name:    "zwlr_layer_shell_v1",
+	Version: 4,
+	Requests: []Message{
+		{Name: "get_layer_surface", Signature: "no?ous", Types: []string{"zwlr_layer_surface_v1", "wl_surface", "wl_output", "", ""}},
+		{Name: "destroy", Signature: "", Since: 3, Destructor: true},
+	},
+}
+
+// Values of zwlr_layer_shell_v1.error
+const (
+	ZwlrLayerShellV1ErrorRole               = 0
+	ZwlrLayerShellV1ErrorInvalidLayer       = 1
+	ZwlrLayerShellV1ErrorAlreadyConstructed = 2
+)
+
+// Values of zwlr_layer_shell_v1.layer
+const (
+	ZwlrLayerShellV1LayerBackground = 0
+	ZwlrLayerShellV1LayerBottom     = 1
+	ZwlrLayerShellV1LayerTop        = 2
+	ZwlrLayerShellV1LayerOverlay    = 3
+)
+
+// ZwlrLayerShellV1 is zwlr_layer_shell_v1: create surfaces that are layers of the desktop
+type ZwlrLayerShellV1 struct {
+	Proxy
+}
+
+// Interface returns the descriptor of zwlr_layer_shell_v1
+func (p *ZwlrLayerShellV1) Interface() *Interface {
+	return ZwlrLayerShellV1Interface
+}
+
+// GetLayerSurface sends the zwlr_layer_shell_v1.get_layer_surface request
+func (p *ZwlrLayerShellV1) GetLayerSurface(surface *WlSurface, output *WlOutput, layer uint32, namespace string) *ZwlrLayerSurfaceV1 {
+	m := p.request(0)
+	id := &ZwlrLayerSurfaceV1{}
+	m.putNewID(id, p.version)
+	m.putUint(idOf(surface))
+	m.putUint(idOf(output))
+	m.putUint(layer)
+	m.putString(namespace)
+	p.send(m, false)
+	return id
+}
+
+// Destroy sends the zwlr_layer_shell_v1.destroy destructor; the object receives no more events
+func (p *ZwlrLayerShellV1) Destroy() {
+	m := p.request(1)
+	p.send(m, true)
+}
+
+func (p *ZwlrLayerShellV1) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// ZwlrLayerSurfaceV1Interface describes zwlr_layer_surface_v1
+var ZwlrLayerSurfaceV1Interface = &Interface{
+	Name:    "zwlr_layer_surface_v1",
+	Version: 4,
+	Requests: []Message{
+		{Name: "set_size", Signature: "uu"},
+		{Name: "set_anchor", Signature: "u"},
+		{Name: "set_exclusive_zone", Signature: "i"},
+		{Name: "set_margin", Signature: "iiii"},
+		{Name: "set_keyboard_interactivity", Signature: "u"},
+		{Name: "get_popup", Signature: "o", Types: []string{"xdg_popup"}},
+		{Name: "ack_configure", Signature: "u"},
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "set_layer", Signature: "u", Since: 2},
+	},
+	Events: []Message{
+		{Name: "configure", Signature: "uuu"},
+		{Name: "closed", Signature: ""},
+	},
+}
+
+// Values of zwlr_layer_surface_v1.keyboard_interactivity
+const (
+	ZwlrLayerSurfaceV1KeyboardInteractivityNone      = 0
+	ZwlrLayerSurfaceV1KeyboardInteractivityExclusive = 1
+	ZwlrLayerSurfaceV1KeyboardInteractivityOnDemand  = 2
+)
+
+// Values of zwlr_layer_surface_v1.error
+const (
+	ZwlrLayerSurfaceV1ErrorInvalidSurfaceState          = 0
+	ZwlrLayerSurfaceV1ErrorInvalidSize                  = 1
+	ZwlrLayerSurfaceV1ErrorInvalidAnchor                = 2
+	ZwlrLayerSurfaceV1ErrorInvalidKeyboardInteractivity = 3
+)
+
+// Values of zwlr_layer_surface_v1.anchor
+const (
+	ZwlrLayerSurfaceV1AnchorTop    = 1
+	ZwlrLayerSurfaceV1AnchorBottom = 2
+	ZwlrLayerSurfaceV1AnchorLeft   = 4
+	ZwlrLayerSurfaceV1AnchorRight  = 8
+)
+
+// ZwlrLayerSurfaceV1 is zwlr_layer_surface_v1: layer metadata interface
+type ZwlrLayerSurfaceV1 struct {
+	Proxy
+	// OnConfigure handles the configure event
+	OnConfigure func(serial uint32, width uint32, height uint32)
+	// OnClosed handles the closed event
+	OnClosed func()
+}
+
+// Interface returns the descriptor of zwlr_layer_surface_v1
+func (p *ZwlrLayerSurfaceV1) Interface() *Interface {
+	return ZwlrLayerSurfaceV1Interface
+}
+
+// SetSize sends the zwlr_layer_surface_v1.set_size request
+func (p *ZwlrLayerSurfaceV1) SetSize(width uint32, height uint32) {
+	m := p.request(0)
+	m.putUint(width)
+	m.putUint(height)
+	p.send(m, false)
+}
+
+// SetAnchor sends the zwlr_layer_surface_v1.set_anchor request
+func (p *ZwlrLayerSurfaceV1) SetAnchor(anchor uint32) {
+	m := p.request(1)
+	m.putUint(anchor)
+	p.send(m, false)
+}
+
+// SetExclusiveZone sends the zwlr_layer_surface_v1.set_exclusive_zone request
+func (p *ZwlrLayerSurfaceV1) SetExclusiveZone(zone int32) {
+	m := p.request(2)
+	m.putInt(zone)
+	p.send(m, false)
+}
+
+// SetMargin sends the zwlr_layer_surface_v1.set_margin request
+func (p *ZwlrLayerSurfaceV1) SetMargin(top int32, right int32, bottom int32, left int32) {
+	m := p.request(3)
+	m.putInt(top)
+	m.putInt(right)
+	m.putInt(bottom)
+	m.putInt(left)
+	p.send(m, false)
+}
+
+// SetKeyboardInteractivity sends the zwlr_layer_surface_v1.set_keyboard_interactivity request
+func (p *ZwlrLayerSurfaceV1) SetKeyboardInteractivity(keyboardInteractivity uint32) {
+	m := p.request(4)
+	m.putUint(keyboardInteractivity)
+	p.send(m, false)
+}
+
+// GetPopup sends the zwlr_layer_surface_v1.get_popup request
+func (p *ZwlrLayerSurfaceV1) GetPopup(popup *XdgPopup) {
+	m := p.request(5)
+	m.putUint(idOf(popup))
+	p.send(m, false)
+}
+
+// AckConfigure sends the zwlr_layer_surface_v1.ack_configure request
+func (p *ZwlrLayerSurfaceV1) AckConfigure(serial uint32) {
+	m := p.request(6)
+	m.putUint(serial)
+	p.send(m, false)
+}
+
+// Destroy sends the zwlr_layer_surface_v1.destroy destructor; the object receives no more events
+func (p *ZwlrLayerSurfaceV1) Destroy() {
+	m := p.request(7)
+	p.send(m, true)
+}
+
+// SetLayer sends the zwlr_layer_surface_v1.set_layer request
+func (p *ZwlrLayerSurfaceV1) SetLayer(layer uint32) {
+	m := p.request(8)
+	m.putUint(layer)
+	p.send(m, false)
+}
+
+func (p *ZwlrLayerSurfaceV1) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		serial := d.getUint()
+		width := d.getUint()
+		height := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("zwlr_layer_surface_v1.configure: %w", d.err)
+		}
+		if p.OnConfigure != nil {
+			p.OnConfigure(serial, width, height)
+		}
+	case 1:
+		if p.OnClosed != nil {
+			p.OnClosed()
+		}
+	}
+	return nil
+}
+
+// ZwpVirtualKeyboardV1Interface describes zwp_virtual_keyboard_v1
+var ZwpVirtualKeyboardV1Interface = &Interface{
+	Name:    "zwp_virtual_keyboard_v1",
+	Version: 1,
+	Requests: []Message{
+		{Name: "keymap", Signature: "uhu"},
+		{Name: "key", Signature: "uuu"},
+		{Name: "modifiers", Signature: "uuuu"},
+		{Name: "destroy", Signature: "", Destructor: true},
+	},
+}
+
+// Values of zwp_virtual_keyboard_v1.error
+const (
+	ZwpVirtualKeyboardV1ErrorNoKeymap = 0
+)
+
+// ZwpVirtualKeyboardV1 is zwp_virtual_keyboard_v1: virtual keyboard
+type ZwpVirtualKeyboardV1 struct {
+	Proxy
+}
+
+// Interface returns the descriptor of zwp_virtual_keyboard_v1
+func (p *ZwpVirtualKeyboardV1) Interface() *Interface {
+	return ZwpVirtualKeyboardV1Interface
+}
+
+// Keymap sends the zwp_virtual_keyboard_v1.keymap request
+func (p *ZwpVirtualKeyboardV1) Keymap(format uint32, fd int, size uint32) {
+	m := p.request(0)
+	m.putUint(format)
+	m.putFD(fd)
+	m.putUint(size)
+	p.send(m, false)
+}
+
+// Key sends the zwp_virtual_keyboard_v1.key request
+func (p *ZwpVirtualKeyboardV1) Key(time uint32, key uint32, state uint32) {
+	m := p.request(1)
+	m.putUint(time)
+	m.putUint(key)
+	m.putUint(state)
+	p.send(m, false)
+}
+
+// Modifiers sends the zwp_virtual_keyboard_v1.modifiers request
+func (p *ZwpVirtualKeyboardV1) Modifiers(modsDepressed uint32, modsLatched uint32, modsLocked uint32, group uint32) {
+	m := p.request(2)
+	m.putUint(modsDepressed)
+	m.putUint(modsLatched)
+	m.putUint(modsLocked)
+	m.putUint(group)
+	p.send(m, false)
+}
+
+// Destroy sends the zwp_virtual_keyboard_v1.destroy destructor; the object receives no more events
+func (p *ZwpVirtualKeyboardV1) Destroy() {
+	m := p.request(3)
+	p.send(m, true)
+}
+
+func (p *ZwpVirtualKeyboardV1) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// ZwpVirtualKeyboardManagerV1Interface describes zwp_virtual_keyboard_manager_v1
+var ZwpVirtualKeyboardManagerV1Interface = &Interface{
+	Name:    "zwp_virtual_keyboard_manager_v1",
+	Version: 1,
+	Requests: []Message{
+		{Name: "create_virtual_keyboard", Signature: "on", Types: []string{"wl_seat", "zwp_virtual_keyboard_v1"}},
+	},
+}
+
+// Values of zwp_virtual_keyboard_manager_v1.error
+const (
+	ZwpVirtualKeyboardManagerV1ErrorUnauthorized = 0
+)
+
+// ZwpVirtualKeyboardManagerV1 is zwp_virtual_keyboard_manager_v1: virtual keyboard manager
+type ZwpVirtualKeyboardManagerV1 struct {
+	Proxy
+}
+
+// Interface returns the descriptor of zwp_virtual_keyboard_manager_v1
+func (p *ZwpVirtualKeyboardManagerV1) Interface() *Interface {
+	return ZwpVirtualKeyboardManagerV1Interface
+}
+
+// CreateVirtualKeyboard sends the zwp_virtual_keyboard_manager_v1.create_virtual_keyboard request
+func (p *ZwpVirtualKeyboardManagerV1) CreateVirtualKeyboard(seat *WlSeat) *ZwpVirtualKeyboardV1 {
+	m := p.request(0)
+	m.putUint(idOf(seat))
+	id := &ZwpVirtualKeyboardV1{}
+	m.putNewID(id, p.version)
+	p.send(m, false)
+	return id
+}
+
+func (p *ZwpVirtualKeyboardManagerV1) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// ZwpInputMethodV2Interface describes zwp_input_method_v2
+var ZwpInputMethodV2Interface = &Interface{
+	Name:    "zwp_input_method_v2",
+	Version: 1,
+	Requests: []Message{
+		{Name: "commit_string", Signature: "s"},
+		{Name: "set_preedit_string", Signature: "sii"},
+		{Name: "delete_surrounding_text", Signature: "uu"},
+		{Name: "commit", Signature: "u"},
+		{Name: "get_input_popup_surface", Signature: "no", Types: []string{"zwp_input_popup_surface_v2", "wl_surface"}},
+		{Name: "grab_keyboard", Signature: "n", Types: []string{"zwp_input_method_keyboard_grab_v2"}},
+		{Name: "destroy", Signature: "", Destructor: true},
+	},
+	Events: []Message{
+		{Name: "activate", Signature: ""},
+		{Name: "deactivate", Signature: ""},
+		{Name: "surrounding_text", Signature: "suu"},
+		{Name: "text_change_cause", Signature: "u"},
+		{Name: "content_type", Signature: "uu"},
+		{Name: "done", Signature: ""},
+		{Name: "unavailable", Signature: ""},
+	},
+}
+
+// ZwpInputMethodV2 is zwp_input_method_v2: input method
+type ZwpInputMethodV2 struct {
+	Proxy
+	// OnActivate handles the activate event
+	OnActivate func()
+	// OnDeactivate handles the deactivate event
+	OnDeactivate func()
+	// OnSurroundingText handles the surrounding_text event
+	OnSurroundingText func(text string, cursor uint32, anchor uint32)
+	// OnTextChangeCause handles the text_change_cause event
+	OnTextChangeCause func(cause uint32)
+	// OnContentType handles the content_type event
+	OnContentType func(hint uint32, purpose uint32)
+	// OnDone handles the done event
+	OnDone func()
+	// OnUnavailable handles the unavailable event
+	OnUnavailable func()
+}
+
+// Interface returns the descriptor of zwp_input_method_v2
+func (p *ZwpInputMethodV2) Interface() *Interface {
+	return ZwpInputMethodV2Interface
+}
+
+// CommitString sends the zwp_input_method_v2.commit_string request
+func (p *ZwpInputMethodV2) CommitString(text string) {
+	m := p.request(0)
+	m.putString(text)
+	p.send(m, false)
+}
+
+// SetPreeditString sends the zwp_input_method_v2.set_preedit_string request
+func (p *ZwpInputMethodV2) SetPreeditString(text string, cursorBegin int32, cursorEnd int32) {
+	m := p.request(1)
+	m.putString(text)
+	m.putInt(cursorBegin)
+	m.putInt(cursorEnd)
+	p.send(m, false)
+}
+
+// DeleteSurroundingText sends the zwp_input_method_v2.delete_surrounding_text request
+func (p *ZwpInputMethodV2) DeleteSurroundingText(beforeLength uint32, afterLength uint32) {
+	m := p.request(2)
+	m.putUint(beforeLength)
+	m.putUint(afterLength)
+	p.send(m, false)
+}
+
+// Commit sends the zwp_input_method_v2.commit request
+func (p *ZwpInputMethodV2) Commit(serial uint32) {
+	m := p.request(3)
+	m.putUint(serial)
+	p.send(m, false)
+}
+
+// GetInputPopupSurface sends the zwp_input_method_v2.get_input_popup_surface request
+func (p *ZwpInputMethodV2) GetInputPopupSurface(surface *WlSurface) *ZwpInputPopupSurfaceV2 {
+	m := p.request(4)
+	id := &ZwpInputPopupSurfaceV2{}
+	m.putNewID(id, p.version)
+	m.putUint(idOf(surface))
+	p.send(m, false)
+	return id
+}
+
+// GrabKeyboard sends the zwp_input_method_v2.grab_keyboard request
+func (p *ZwpInputMethodV2) GrabKeyboard() *ZwpInputMethodKeyboardGrabV2 {
+	m := p.request(5)
+	keyboard := &ZwpInputMethodKeyboardGrabV2{}
+	m.putNewID(keyboard, p.version)
+	p.send(m, false)
+	return keyboard
+}
+
+// Destroy sends the zwp_input_method_v2.destroy destructor; the object receives no more events
+func (p *ZwpInputMethodV2) Destroy() {
+	m := p.request(6)
+	p.send(m, true)
+}
+
+func (p *ZwpInputMethodV2) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		if p.OnActivate != nil {
+			p.OnActivate()
+		}
+	case 1:
+		if p.OnDeactivate != nil {
+			p.OnDeactivate()
+		}
+	case 2:
+		text := d.getString()
+		cursor := d.getUint()
+		anchor := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("zwp_input_method_v2.surrounding_text: %w", d.err)
+		}
+		if p.OnSurroundingText != nil {
+			p.OnSurroundingText(text, cursor, anchor)
+		}
+	case 3:
+		cause := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("zwp_input_method_v2.text_change_cause: %w", d.err)
+		}
+		if p.OnTextChangeCause != nil {
+			p.OnTextChangeCause(cause)
+		}
+	case 4:
+		hint := d.getUint()
+		purpose := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("zwp_input_method_v2.content_type: %w", d.err)
+		}
+		if p.OnContentType != nil {
+			p.OnContentType(hint, purpose)
+		}
+	case 5:
+		if p.OnDone != nil {
+			p.OnDone()
+		}
+	case 6:
+		if p.OnUnavailable != nil {
+			p.OnUnavailable()
+		}
+	}
+	return nil
+}
+
+// ZwpInputPopupSurfaceV2Interface describes zwp_input_popup_surface_v2
+var ZwpInputPopupSurfaceV2Interface = &Interface{
+	Name:    "zwp_input_popup_surface_v2",
+	Version: 1,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+	},
+	Events: []Message{
+		{Name: "text_input_rectangle", Signature: "iiii"},
+	},
+}
+
+// ZwpInputPopupSurfaceV2 is zwp_input_popup_surface_v2: popup surface
+type ZwpInputPopupSurfaceV2 struct {
+	Proxy
+	// OnTextInputRectangle handles the text_input_rectangle event
+	OnTextInputRectangle func(x int32, y int32, width int32, height int32)
+}
+
+// Interface returns the descriptor of zwp_input_popup_surface_v2
+func (p *ZwpInputPopupSurfaceV2) Interface() *Interface {
+	return ZwpInputPopupSurfaceV2Interface
+}
+
+// Destroy sends the zwp_input_popup_surface_v2.destroy destructor; the object receives no more events
+func (p *ZwpInputPopupSurfaceV2) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+func (p *ZwpInputPopupSurfaceV2) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		x := d.getInt()
+		y := d.getInt()
+		width := d.getInt()
+		height := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("zwp_input_popup_surface_v2.text_input_rectangle: %w", d.err)
+		}
+		if p.OnTextInputRectangle != nil {
+			p.OnTextInputRectangle(x, y, width, height)
+		}
+	}
+	return nil
+}
+
+// ZwpInputMethodKeyboardGrabV2Interface describes zwp_input_method_keyboard_grab_v2
+var ZwpInputMethodKeyboardGrabV2Interface = &Interface{
+	Name:    "zwp_input_method_keyboard_grab_v2",
+	Version: 1,
+	Requests: []Message{
+		{Name: "release", Signature: "", Destructor: true},
+	},
+	Events: []Message{
+		{Name: "keymap", Signature: "uhu"},
+		{Name: "key", Signature: "uuuu"},
+		{Name: "modifiers", Signature: "uuuuu"},
+		{Name: "repeat_info", Signature: "ii"},
+	},
+}
+
+// ZwpInputMethodKeyboardGrabV2 is zwp_input_method_keyboard_grab_v2: keyboard grab
+type ZwpInputMethodKeyboardGrabV2 struct {
+	Proxy
+	// OnKeymap handles the keymap event
+	OnKeymap func(format uint32, fd int, size uint32)
+	// OnKey handles the key event
+	OnKey func(serial uint32, time uint32, key uint32, state uint32)
+	// OnModifiers handles the modifiers event
+	OnModifiers func(serial uint32, modsDepressed uint32, modsLatched uint32, modsLocked uint32, group uint32)
+	// OnRepeatInfo handles the repeat_info event
+	OnRepeatInfo func(rate int32, delay int32)
+}
+
+// Interface returns the descriptor of zwp_input_method_keyboard_grab_v2
+func (p *ZwpInputMethodKeyboardGrabV2) Interface() *Interface {
+	return ZwpInputMethodKeyboardGrabV2Interface
+}
+
+// Release sends the zwp_input_method_keyboard_grab_v2.release destructor; the object receives no more events
+func (p *ZwpInputMethodKeyboardGrabV2) Release() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+func (p *ZwpInputMethodKeyboardGrabV2) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		format := d.getUint()
+		fd := d.getFD()
+		size := d.getUint()
+		if d.err != nil {
+			closeFDs(fd)
+			return fmt.Errorf("zwp_input_method_keyboard_grab_v2.keymap: %w", d.err)
+		}
+		if p.OnKeymap != nil {
+			p.OnKeymap(format, fd, size)
+		} else {
+			closeFDs(fd)
+		}
+	case 1:
+		serial := d.getUint()
+		time := d.getUint()
+		key := d.getUint()
+		state := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("zwp_input_method_keyboard_grab_v2.key: %w", d.err)
+		}
+		if p.OnKey != nil {
+			p.OnKey(serial, time, key, state)
+		}
+	case 2:
+		serial := d.getUint()
+		modsDepressed := d.getUint()
+		modsLatched := d.getUint()
+		modsLocked := d.getUint()
+		group := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("zwp_input_method_keyboard_grab_v2.modifiers: %w", d.err)
+		}
+		if p.OnModifiers != nil {
+			p.OnModifiers(serial, modsDepressed, modsLatched, modsLocked, group)
+		}
+	case 3:
+		rate := d.getInt()
+		delay := d.getInt()
+		if d.err != nil {
+			return fmt.Errorf("zwp_input_method_keyboard_grab_v2.repeat_info: %w", d.err)
+		}
+		if p.OnRepeatInfo != nil {
+			p.OnRepeatInfo(rate, delay)
+		}
+	}
+	return nil
+}
+
+// ZwpInputMethodManagerV2Interface describes zwp_input_method_manager_v2
+var ZwpInputMethodManagerV2Interface = &Interface{
+	Name:    "zwp_input_method_manager_v2",
+	Version: 1,
+	Requests: []Message{
+		{Name: "get_input_method", Signature: "on", Types: []string{"wl_seat", "zwp_input_method_v2"}},
+		{Name: "destroy", Signature: "", Destructor: true},
+	},
+}
+
+// ZwpInputMethodManagerV2 is zwp_input_method_manager_v2: input method manager
+type ZwpInputMethodManagerV2 struct {
+	Proxy
+}
+
+// Interface returns the descriptor of zwp_input_method_manager_v2
+func (p *ZwpInputMethodManagerV2) Interface() *Interface {
+	return ZwpInputMethodManagerV2Interface
+}
+
+// GetInputMethod sends the zwp_input_method_manager_v2.get_input_method request
+func (p *ZwpInputMethodManagerV2) GetInputMethod(seat *WlSeat) *ZwpInputMethodV2 {
+	m := p.request(0)
+	m.putUint(idOf(seat))
+	inputMethod := &ZwpInputMethodV2{}
+	m.putNewID(inputMethod, p.version)
+	p.send(m, false)
+	return inputMethod
+}
+
+// Destroy sends the zwp_input_method_manager_v2.destroy destructor; the object receives no more events
+func (p *ZwpInputMethodManagerV2) Destroy() {
+	m := p.request(1)
+	p.send(m, true)
+}
+
+func (p *ZwpInputMethodManagerV2) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// WpViewporterInterface describes wp_viewporter
+var WpViewporterInterface = &Interface{
+	Name:    "wp_viewporter",
+	Version: 1,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "get_viewport", Signature: "no", Types: []string{"wp_viewport", "wl_surface"}},
+	},
+}
+
+// Values of wp_viewporter.error
+const (
+	WpViewporterErrorViewportExists = 0
+)
+
+// WpViewporter is wp_viewporter: surface cropping and scaling
+type WpViewporter struct {
+	Proxy
+}
+
+// Interface returns the descriptor of wp_viewporter
+func (p *WpViewporter) Interface() *Interface {
+	return WpViewporterInterface
+}
+
+// Destroy sends the wp_viewporter.destroy destructor; the object receives no more events
+func (p *WpViewporter) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// GetViewport sends the wp_viewporter.get_viewport request
+func (p *WpViewporter) GetViewport(surface *WlSurface) *WpViewport {
+	m := p.request(1)
+	id := &WpViewport{}
+	m.putNewID(id, p.version)
+	m.putUint(idOf(surface))
+	p.send(m, false)
+	return id
+}
+
+func (p *WpViewporter) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// WpViewportInterface describes wp_viewport
+var WpViewportInterface = &Interface{
+	Name:    "wp_viewport",
+	Version: 1,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "set_source", Signature: "ffff"},
+		{Name: "set_destination", Signature: "ii"},
+	},
+}
+
+// Values of wp_viewport.error
+const (
+	WpViewportErrorBadValue    = 0
+	WpViewportErrorBadSize     = 1
+	WpViewportErrorOutOfBuffer = 2
+	WpViewportErrorNoSurface   = 3
+)
+
+// WpViewport is wp_viewport: crop and scale interface to a wl_surface
+type WpViewport struct {
+	Proxy
+}
+
+// Interface returns the descriptor of wp_viewport
+func (p *WpViewport) Interface() *Interface {
+	return WpViewportInterface
+}
+
+// Destroy sends the wp_viewport.destroy destructor; the object receives no more events
+func (p *WpViewport) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// SetSource sends the wp_viewport.set_source request
+func (p *WpViewport) SetSource(x Fixed, y Fixed, width Fixed, height Fixed) {
+	m := p.request(1)
+	m.putFixed(x)
+	m.putFixed(y)
+	m.putFixed(width)
+	m.putFixed(height)
+	p.send(m, false)
+}
+
+// SetDestination sends the wp_viewport.set_destination request
+func (p *WpViewport) SetDestination(width int32, height int32) {
+	m := p.request(2)
+	m.putInt(width)
+	m.putInt(height)
+	p.send(m, false)
+}
+
+func (p *WpViewport) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// WpFractionalScaleManagerV1Interface describes wp_fractional_scale_manager_v1
+var WpFractionalScaleManagerV1Interface = &Interface{
+	Name:    "wp_fractional_scale_manager_v1",
+	Version: 1,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+		{Name: "get_fractional_scale", Signature: "no", Types: []string{"wp_fractional_scale_v1", "wl_surface"}},
+	},
+}
+
+// Values of wp_fractional_scale_manager_v1.error
+const (
+	WpFractionalScaleManagerV1ErrorFractionalScaleExists = 0
+)
+
+// WpFractionalScaleManagerV1 is wp_fractional_scale_manager_v1: fractional surface scale information
+type WpFractionalScaleManagerV1 struct {
+	Proxy
+}
+
+// Interface returns the descriptor of wp_fractional_scale_manager_v1
+func (p *WpFractionalScaleManagerV1) Interface() *Interface {
+	return WpFractionalScaleManagerV1Interface
+}
+
+// Destroy sends the wp_fractional_scale_manager_v1.destroy destructor; the object receives no more events
+func (p *WpFractionalScaleManagerV1) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+// GetFractionalScale sends the wp_fractional_scale_manager_v1.get_fractional_scale request
+func (p *WpFractionalScaleManagerV1) GetFractionalScale(surface *WlSurface) *WpFractionalScaleV1 {
+	m := p.request(1)
+	id := &WpFractionalScaleV1{}
+	m.putNewID(id, p.version)
+	m.putUint(idOf(surface))
+	p.send(m, false)
+	return id
+}
+
+func (p *WpFractionalScaleManagerV1) dispatch(opcode uint16, d *decoder) error {
+	return nil
+}
+
+// WpFractionalScaleV1Interface describes wp_fractional_scale_v1
+var WpFractionalScaleV1Interface = &Interface{
+	Name:    "wp_fractional_scale_v1",
+	Version: 1,
+	Requests: []Message{
+		{Name: "destroy", Signature: "", Destructor: true},
+	},
+	Events: []Message{
+		{Name: "preferred_scale", Signature: "u"},
+	},
+}
+
+// WpFractionalScaleV1 is wp_fractional_scale_v1: fractional scale interface to a wl_surface
+type WpFractionalScaleV1 struct {
+	Proxy
+	// OnPreferredScale handles the preferred_scale event
+	OnPreferredScale func(scale uint32)
+}
+
+// Interface returns the descriptor of wp_fractional_scale_v1
+func (p *WpFractionalScaleV1) Interface() *Interface {
+	return WpFractionalScaleV1Interface
+}
+
+// Destroy sends the wp_fractional_scale_v1.destroy destructor; the object receives no more events
+func (p *WpFractionalScaleV1) Destroy() {
+	m := p.request(0)
+	p.send(m, true)
+}
+
+func (p *WpFractionalScaleV1) dispatch(opcode uint16, d *decoder) error {
+	switch opcode {
+	case 0:
+		scale := d.getUint()
+		if d.err != nil {
+			return fmt.Errorf("wp_fractional_scale_v1.preferred_scale: %w", d.err)
+		}
+		if p.OnPreferredScale != nil {
+			p.OnPreferredScale(scale)
+		}
+	}
+	return nil
+}
+
+// interfaces maps interface names to their descriptors
+var interfaces = map[string]*Interface{
+	"wl_display":                        WlDisplayInterface,
+	"wl_registry":                       WlRegistryInterface,
+	"wl_callback":                       WlCallbackInterface,
+	"wl_compositor":                     WlCompositorInterface,
+	"wl_shm_pool":                       WlShmPoolInterface,
+	"wl_shm":                            WlShmInterface,
+	"wl_buffer":                         WlBufferInterface,
+	"wl_surface":                        WlSurfaceInterface,
+	"wl_seat":                           WlSeatInterface,
+	"wl_pointer":                        WlPointerInterface,
+	"wl_keyboard":                       WlKeyboardInterface,
+	"wl_touch":                          WlTouchInterface,
+	"wl_output":                         WlOutputInterface,
+	"wl_region":                         WlRegionInterface,
+	"xdg_wm_base":                       XdgWmBaseInterface,
+	"xdg_positioner":                    XdgPositionerInterface,
+	"xdg_surface":                       XdgSurfaceInterface,
+	"xdg_toplevel":                      XdgToplevelInterface,
+	"xdg_popup":                         XdgPopupInterface,
+	"zwlr_layer_shell_v1":               ZwlrLayerShellV1Interface,
+	"zwlr_layer_surface_v1":             ZwlrLayerSurfaceV1Interface,
+	"zwp_virtual_keyboard_v1":           ZwpVirtualKeyboardV1Interface,
+	"zwp_virtual_keyboard_manager_v1":   ZwpVirtualKeyboardManagerV1Interface,
+	"zwp_input_method_v2":               ZwpInputMethodV2Interface,
+	"zwp_input_popup_surface_v2":        ZwpInputPopupSurfaceV2Interface,
+	"zwp_input_method_keyboard_grab_v2": ZwpInputMethodKeyboardGrabV2Interface,
+	"zwp_input_method_manager_v2":       ZwpInputMethodManagerV2Interface,
+	"wp_viewporter":                     WpViewporterInterface,
+	"wp_viewport":                       WpViewportInterface,
+	"wp_fractional_scale_manager_v1":    WpFractionalScaleManagerV1Interface,
+	"wp_fractional_scale_v1":            WpFractionalScaleV1Interface,
+}
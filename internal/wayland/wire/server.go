@@ -0,0 +1,319 @@
+package wire
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+)
+
+// ServerConn is the compositor end of a connection, for compositors used
+// in tests. Requests are read one at a time and decoded with the
+// descriptors of the objects the client created, and events are encoded
+// from plain Go values and sent straight away.
+type ServerConn struct {
+	sock *net.UnixConn
+
+	// mu guards the object table and serialises writes, so events may be
+	// sent from any goroutine
+	mu      sync.Mutex
+	objects map[uint32]serverObject
+
+	// in and fds hold what was received and not yet decoded; they are
+	// only used by the goroutine calling ReadRequest
+	in  []byte
+	fds []int
+}
+
+// serverObject is an object the client created
+type serverObject struct {
+	iface   *Interface
+	version uint32
+}
+
+// NewID is the decoded value of a new_id argument
+type NewID struct {
+	ID        uint32
+	Interface *Interface
+	Version   uint32
+}
+
+// Request is a request decoded by a ServerConn. Args holds one value per
+// argument of the signature: int32 for i, uint32 for u and o (0 for a
+// null object), Fixed for f, string for s, []byte for a, int for an fd
+// owned by the receiver, and NewID for n. An untyped new_id is decoded as
+// its interface name, version and NewID.
+type Request struct {
+	Object    uint32
+	Interface *Interface
+	Version   uint32
+	Opcode    uint16
+	Message   *Message
+	Args      []any
+}
+
+// Name returns the request name as interface.request
+func (r *Request) Name() string {
+	return r.Interface.Name + "." + r.Message.Name
+}
+
+// Uint returns argument i as a uint or object id
+func (r *Request) Uint(i int) uint32 {
+	v, _ := r.Args[i].(uint32)
+	return v
+}
+
+// Int returns argument i as an int
+func (r *Request) Int(i int) int32 {
+	v, _ := r.Args[i].(int32)
+	return v
+}
+
+// String returns argument i as a string
+func (r *Request) String(i int) string {
+	v, _ := r.Args[i].(string)
+	return v
+}
+
+// FD returns argument i as a file descriptor
+func (r *Request) FD(i int) int {
+	v, ok := r.Args[i].(int)
+	if !ok {
+		return -1
+	}
+	return v
+}
+
+// NewID returns argument i as a new object
+func (r *Request) NewID(i int) NewID {
+	v, _ := r.Args[i].(NewID)
+	return v
+}
+
+// NewServerConn serves a client on sock. The client's wl_display is
+// object 1.
+func NewServerConn(sock *net.UnixConn) *ServerConn {
+	return &ServerConn{
+		sock:    sock,
+		objects: map[uint32]serverObject{1: {WlDisplayInterface, 1}},
+	}
+}
+
+// ReadRequest waits for the next request and decodes it. Objects created
+// by the request are added to the object table. Objects destroyed by a
+// destructor request are removed and the client is sent delete_id, so
+// the caller only has to forget its own state. Once it fails, fds not yet
+// decoded are closed.
+func (s *ServerConn) ReadRequest() (*Request, error) {
+	r, err := s.readRequest()
+	if err != nil {
+		closeFDs(s.fds...)
+		s.fds = nil
+	}
+	return r, err
+}
+
+func (s *ServerConn) readRequest() (*Request, error) {
+	for {
+		if len(s.in) >= headerSize {
+			size := int(order.Uint32(s.in[4:]) >> 16)
+			if size < headerSize || size%4 != 0 {
+				return nil, fmt.Errorf("invalid message size %d from the client", size)
+			}
+			if len(s.in) >= size {
+				sender, opcode := order.Uint32(s.in), uint16(order.Uint32(s.in[4:]))
+				data := append([]byte(nil), s.in[headerSize:size]...)
+				s.in = s.in[:copy(s.in, s.in[size:])]
+				return s.decode(sender, opcode, data)
+			}
+		}
+
+		buf := make([]byte, bufferSize)
+		oob := make([]byte, syscall.CmsgSpace(maxFDs*4))
+		n, oobn, _, _, err := s.sock.ReadMsgUnix(buf, oob)
+		n, oobn = max(n, 0), max(oobn, 0)
+		fds, rightsErr := parseRights(oob[:oobn])
+		s.in = append(s.in, buf[:n]...)
+		s.fds = append(s.fds, fds...)
+		switch {
+		case err != nil:
+			return nil, err
+		case rightsErr != nil:
+			return nil, rightsErr
+		case n == 0:
+			return nil, ErrClosed
+		}
+	}
+}
+
+// decode decodes the arguments of a request
+func (s *ServerConn) decode(sender uint32, opcode uint16, data []byte) (*Request, error) {
+	s.mu.Lock()
+	obj, ok := s.objects[sender]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("request %d to unknown object %d", opcode, sender)
+	}
+	if int(opcode) >= len(obj.iface.Requests) {
+		return nil, fmt.Errorf("%s has no request %d", obj.iface.Name, opcode)
+	}
+	message := &obj.iface.Requests[opcode]
+	r := &Request{Object: sender, Interface: obj.iface, Version: obj.version, Opcode: opcode, Message: message}
+
+	d := &decoder{data: data}
+	arg := 0
+	for _, code := range message.Signature {
+		switch code {
+		case '?':
+			continue
+		case 'i':
+			r.Args = append(r.Args, d.getInt())
+		case 'u', 'o':
+			r.Args = append(r.Args, d.getUint())
+		case 'f':
+			r.Args = append(r.Args, d.getFixed())
+		case 's':
+			r.Args = append(r.Args, d.getString())
+		case 'a':
+			r.Args = append(r.Args, d.getArray())
+		case 'h':
+			if len(s.fds) == 0 {
+				d.fail("missing file descriptor")
+				r.Args = append(r.Args, -1)
+				break
+			}
+			r.Args = append(r.Args, s.fds[0])
+			s.fds = s.fds[1:]
+		case 'n':
+			id := NewID{ID: d.getUint(), Version: obj.version}
+			if arg < len(message.Types) && message.Types[arg] != "" {
+				id.Interface = interfaces[message.Types[arg]]
+			} else if arg >= 2 {
+				name, _ := r.Args[arg-2].(string)
+				id.Interface = interfaces[name]
+				id.Version, _ = r.Args[arg-1].(uint32)
+			}
+			if id.Interface == nil {
+				d.fail("new object of unknown interface")
+			}
+			r.Args = append(r.Args, id)
+		}
+		arg++
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("%s: %w", r.Name(), d.err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, a := range r.Args {
+		if id, ok := a.(NewID); ok {
+			s.objects[id.ID] = serverObject{id.Interface, id.Version}
+		}
+	}
+	if message.Destructor {
+		delete(s.objects, sender)
+		if err := s.sendLocked(1, 1, sender); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Interface returns the interface of object id, or nil if the client has
+// not created it
+func (s *ServerConn) Interface(id uint32) *Interface {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.objects[id].iface
+}
+
+// Destroy forgets an object the compositor destroyed on its own, as with
+// wl_callback after done, and sends delete_id
+func (s *ServerConn) Destroy(id uint32) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, id)
+	return s.sendLocked(1, 1, id)
+}
+
+// SendEvent sends event opcode of object id. Args holds one value per
+// argument of the event signature, of the types Request uses; fds are
+// sent as duplicates, so the caller keeps its own.
+func (s *ServerConn) SendEvent(id uint32, opcode uint16, args ...any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.sendLocked(id, opcode, args...)
+}
+
+func (s *ServerConn) sendLocked(id uint32, opcode uint16, args ...any) error {
+	obj, ok := s.objects[id]
+	if !ok {
+		return fmt.Errorf("event %d to unknown object %d", opcode, id)
+	}
+	if int(opcode) >= len(obj.iface.Events) {
+		return fmt.Errorf("%s has no event %d", obj.iface.Name, opcode)
+	}
+	message := obj.iface.Events[opcode]
+
+	e := newEncoder(nil, id, opcode)
+	defer e.close()
+	arg := 0
+	for _, code := range message.Signature {
+		if code == '?' {
+			continue
+		}
+		if arg >= len(args) {
+			return fmt.Errorf("%s.%s: too few arguments", obj.iface.Name, message.Name)
+		}
+		ok := true
+		switch v := args[arg]; code {
+		case 'i':
+			var n int32
+			n, ok = v.(int32)
+			e.putInt(n)
+		case 'u', 'o':
+			var n uint32
+			n, ok = v.(uint32)
+			e.putUint(n)
+		case 'f':
+			var f Fixed
+			f, ok = v.(Fixed)
+			e.putFixed(f)
+		case 's':
+			var str string
+			str, ok = v.(string)
+			e.putString(str)
+		case 'a':
+			var a []byte
+			a, ok = v.([]byte)
+			e.putArray(a)
+		case 'h':
+			var fd int
+			fd, ok = v.(int)
+			e.putFD(fd)
+		case 'n':
+			return fmt.Errorf("%s.%s: events creating objects are not supported", obj.iface.Name, message.Name)
+		}
+		if !ok {
+			return fmt.Errorf("%s.%s: argument %d is %T, not of type %c", obj.iface.Name, message.Name, arg, args[arg], code)
+		}
+		arg++
+	}
+
+	msg, err := e.finish()
+	if err != nil {
+		return err
+	}
+	var oob []byte
+	if len(e.fds) > 0 {
+		oob = syscall.UnixRights(e.fds...)
+	}
+	_, _, err = s.sock.WriteMsgUnix(msg, oob, nil)
+	return err
+}
+
+// Close closes the connection, making ReadRequest fail
+func (s *ServerConn) Close() error {
+	return s.sock.Close()
+}
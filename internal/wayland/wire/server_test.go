@@ -0,0 +1,89 @@
+package wire
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestServerConn connects a client and a server end
+func newTestServerConn(t *testing.T) (*Conn, *ServerConn) {
+	t.Helper()
+	client, server := socketPair(t)
+	conn, s := NewConn(client), NewServerConn(server)
+	t.Cleanup(func() {
+		conn.Close()
+		s.Close()
+	})
+	return conn, s
+}
+
+// readRequest reads the next request, failing the test if it does not
+// arrive
+func readRequest(t *testing.T, s *ServerConn) *Request {
+	t.Helper()
+	s.sock.SetReadDeadline(time.Now().Add(5 * time.Second))
+	r, err := s.ReadRequest()
+	if err != nil {
+		t.Fatalf("ReadRequest: %v", err)
+	}
+	return r
+}
+
+func TestServerDecodesRequestsAndTracksObjects(t *testing.T) {
+	conn, s := newTestServerConn(t)
+
+	registry := conn.Display().GetRegistry()
+	compositor := &WlCompositor{}
+	registry.Bind(7, compositor, 4)
+	surface := compositor.CreateSurface()
+	if err := conn.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	if r := readRequest(t, s); r.Name() != "wl_display.get_registry" || s.Interface(r.NewID(0).ID) != WlRegistryInterface {
+		t.Fatalf("first request %s, want get_registry creating a registry", r.Name())
+	}
+	// The interface and version of an untyped new_id come with it
+	r := readRequest(t, s)
+	if id := r.NewID(3); r.Uint(0) != 7 || id.Interface != WlCompositorInterface || id.Version != 4 {
+		t.Errorf("bind of %d to %+v", r.Uint(0), id)
+	}
+	// A typed new_id takes the version of its parent
+	r = readRequest(t, s)
+	if id := r.NewID(0); r.Interface != WlCompositorInterface || id.ID != surface.ID() || id.Version != 4 {
+		t.Errorf("create_surface on %s made %+v", r.Interface.Name, id)
+	}
+
+	surface.Destroy()
+	conn.Flush()
+	if r := readRequest(t, s); r.Name() != "wl_surface.destroy" || s.Interface(surface.ID()) != nil {
+		t.Errorf("%s left the surface registered", r.Name())
+	}
+	// The client may reuse the id once the server confirmed the deletion
+	waitFor(t, conn, func() bool { return conn.lookup(surface.ID()) == nil })
+}
+
+func TestServerEventsReachTheClient(t *testing.T) {
+	conn, s := newTestServerConn(t)
+
+	registry := conn.Display().GetRegistry()
+	var globals []string
+	registry.OnGlobal = func(name uint32, iface string, version uint32) {
+		globals = append(globals, iface)
+	}
+	conn.Flush()
+	r := readRequest(t, s)
+
+	if err := s.SendEvent(r.NewID(0).ID, 0, uint32(1), "wl_shm", uint32(1)); err != nil {
+		t.Fatalf("SendEvent: %v", err)
+	}
+	waitFor(t, conn, func() bool { return len(globals) == 1 })
+	if globals[0] != "wl_shm" {
+		t.Errorf("globals = %v", globals)
+	}
+
+	// Arguments must match the message exactly
+	if err := s.SendEvent(r.NewID(0).ID, 0, 1, "wl_shm", 1); err == nil {
+		t.Error("SendEvent accepted int arguments for uint ones")
+	}
+}
@@ -0,0 +1,209 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// Client represents a Wayland client connection. This implementation
+// speaks the wire protocol itself instead of going through
+// libwayland-client, so it builds without cgo; it is used with the purego
+// build tag or when cgo is disabled.
+type Client struct {
+	conn       *wire.Conn
+	registry   *wire.WlRegistry
+	compositor *wire.WlCompositor
+	surface    *wire.WlSurface
+	shm        *wire.WlShm
+	outputs    map[uint32]*output
+
+	// pool holds the buffers attached to the surface; retired holds
+	// buffers of earlier sizes the compositor has not released yet
+	pool    *shmPool
+	retired map[*shmBuffer]struct{}
+
+	// seat is the first seat announced; its devices exist while the seat
+	// has the matching capability
+	seat     *wire.WlSeat
+	seatName uint32
+	pointer  *wire.WlPointer
+	keyboard *wire.WlKeyboard
+	touch    *wire.WlTouch
+
+	// globals maps registry names to interfaces; pointer and touch
+	// positions are kept for events that carry no coordinates
+	globals  map[uint32]string
+	pointerX int32
+	pointerY int32
+	touches  map[int32][2]int32
+
+	// The surface role is a layer-shell panel, or an xdg_toplevel when
+	// the compositor has no layer-shell; pending* hold the size from the
+	// last xdg_toplevel configure
+	layerShell    *wire.ZwlrLayerShellV1
+	layerSurface  *wire.ZwlrLayerSurfaceV1
+	wmBase        *wire.XdgWmBase
+	xdgSurface    *wire.XdgSurface
+	toplevel      *wire.XdgToplevel
+	pendingWidth  uint32
+	pendingHeight uint32
+	configured    bool
+	closed        bool
+	// mapped is set once a buffer is attached, until SetVisible(false)
+	mapped bool
+
+	// Frames are drawn at drawScale times the surface size and shown with
+	// bufferScale, or through viewport when the compositor offers
+	// fractional scaling. reportedScale is the scale last reported to be
+	// preferred, fractionalScale the one wp_fractional_scale_v1 sent, and
+	// pinnedOutput the name of the output the surface was placed on.
+	viewporter             *wire.WpViewporter
+	viewport               *wire.WpViewport
+	fractionalScaleManager *wire.WpFractionalScaleManagerV1
+	fractionalScaleObject  *wire.WpFractionalScaleV1
+	fractionalScale        float64
+	drawScale              float64
+	bufferScale            int32
+	reportedScale          float64
+	pinnedOutput           string
+
+	vkManager *wire.ZwpVirtualKeyboardManagerV1
+	imManager *wire.ZwpInputMethodManagerV2
+
+	dispatcher *EventDispatcher
+	frames     chan uint32
+}
+
+// NewClient creates a new Wayland client connection
+func NewClient() (*Client, error) {
+	conn, err := wire.Dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to Wayland display: %w", err)
+	}
+	return newClient(conn)
+}
+
+// newClient binds the globals of an established connection. The client
+// owns conn and closes it on failure.
+func newClient(conn *wire.Conn) (*Client, error) {
+	client := &Client{
+		conn:          conn,
+		outputs:       make(map[uint32]*output),
+		globals:       make(map[uint32]string),
+		touches:       make(map[int32][2]int32),
+		retired:       make(map[*shmBuffer]struct{}),
+		frames:        make(chan uint32, 1),
+		drawScale:     1,
+		bufferScale:   1,
+		reportedScale: 1,
+	}
+
+	// Bind the globals announced in the initial burst
+	client.registry = conn.Display().GetRegistry()
+	client.registry.OnGlobal = client.global
+	client.registry.OnGlobalRemove = client.globalRemove
+	if err := conn.Roundtrip(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to receive Wayland globals: %w", err)
+	}
+	if client.compositor == nil {
+		client.Close()
+		return nil, fmt.Errorf("compositor does not provide wl_compositor")
+	}
+	// Outputs send their state once bound; wait for it, so that the
+	// keyboard can be pinned to an output by name
+	if err := conn.Roundtrip(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to receive Wayland outputs: %w", err)
+	}
+
+	return client, nil
+}
+
+// Close cleans up the Wayland client connection
+func (c *Client) Close() {
+	c.destroyShell()
+	c.destroyScale()
+	c.destroyVirtualKeyboardManager()
+	c.destroyInputMethodManager()
+	if c.surface != nil {
+		c.surface.Destroy()
+		c.surface = nil
+	}
+	c.destroyBuffers()
+	if c.seat != nil {
+		c.releaseSeat()
+	}
+	for name, output := range c.outputs {
+		c.releaseOutput(output)
+		delete(c.outputs, name)
+	}
+	// Send the destroy requests, and key releases from a closed virtual
+	// keyboard, before the connection goes away
+	c.conn.Flush()
+	c.conn.Close()
+}
+
+// Damage marks a region of the surface as changed. It is ignored until a
+// surface has been created.
+func (c *Client) Damage(x, y, width, height int) error {
+	if c.surface == nil {
+		return nil
+	}
+
+	if c.pool != nil {
+		c.pool.chain.damage(bufferRect(image.Rect(x, y, x+width, y+height), c.drawScale))
+	}
+	c.surface.Damage(int32(x), int32(y), int32(width), int32(height))
+	return nil
+}
+
+// Dispatch processes the events received on the connection. Events are
+// read by the connection as they arrive, so it never blocks.
+func (c *Client) Dispatch() error {
+	if err := c.conn.Dispatch(); err != nil {
+		return fmt.Errorf("failed to dispatch Wayland events: %w", err)
+	}
+	return nil
+}
+
+// DispatchPending processes events already read from the connection
+func (c *Client) DispatchPending() error {
+	if err := c.conn.Dispatch(); err != nil {
+		return fmt.Errorf("failed to dispatch pending Wayland events: %w", err)
+	}
+	return nil
+}
+
+// Events returns a channel signalled when events have been received
+func (c *Client) Events() <-chan struct{} {
+	return c.conn.Readable()
+}
+
+// RequestFrame requests a frame callback and commits the surface. Without
+// a mapped surface there is nothing to throttle against, and compositors
+// send no frame callbacks for one, so a frame is delivered straight away;
+// drawing it attaches the buffer that maps the surface.
+func (c *Client) RequestFrame() error {
+	if c.surface == nil || !c.mapped {
+		c.frameDone(0)
+		return nil
+	}
+	callback := c.surface.Frame()
+	callback.OnDone = c.frameDone
+	c.surface.Commit()
+	return nil
+}
+
+// Flush sends buffered requests to the Wayland server
+func (c *Client) Flush() error {
+	if err := c.conn.Flush(); err != nil {
+		return fmt.Errorf("failed to flush Wayland display: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,29 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/waylandtest"
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// connectTestClient connects a client to a compositor the test set up
+func connectTestClient(t *testing.T, compositor *waylandtest.Compositor) *Client {
+	t.Helper()
+	client, err := newClient(wire.NewConn(compositor.ClientConn()))
+	if err != nil {
+		t.Fatalf("newClient: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client
+}
+
+func TestWireClientNegotiatesVersions(t *testing.T) {
+	client, _ := newTestClient(t)
+	if v := client.seat.Version(); v != seatVersion {
+		t.Errorf("seat version = %d, want %d", v, seatVersion)
+	}
+}
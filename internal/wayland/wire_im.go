@@ -0,0 +1,75 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"fmt"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// wireInputMethod sends input method requests over the wire connection
+type wireInputMethod struct {
+	im *wire.ZwpInputMethodV2
+}
+
+func (w *wireInputMethod) commitString(text string) {
+	w.im.CommitString(text)
+}
+
+func (w *wireInputMethod) setPreeditString(text string, cursorBegin, cursorEnd int32) {
+	w.im.SetPreeditString(text, cursorBegin, cursorEnd)
+}
+
+func (w *wireInputMethod) deleteSurroundingText(beforeLength, afterLength uint32) {
+	w.im.DeleteSurroundingText(beforeLength, afterLength)
+}
+
+func (w *wireInputMethod) commit(serial uint32) {
+	w.im.Commit(serial)
+}
+
+func (w *wireInputMethod) destroy() {
+	w.im.Destroy()
+}
+
+// bindInputMethodManager binds zwp_input_method_manager_v2
+func (c *Client) bindInputMethodManager(name, version uint32) {
+	if c.imManager != nil {
+		return
+	}
+	c.imManager = &wire.ZwpInputMethodManagerV2{}
+	c.registry.Bind(name, c.imManager, negotiate(version, inputMethodManagerVersion))
+}
+
+// InputMethod makes the client the input method of the seat. Focus changes
+// of text inputs are reported as InputMethodEvents.
+func (c *Client) InputMethod() (*InputMethod, error) {
+	if c.imManager == nil {
+		return nil, fmt.Errorf("compositor does not provide zwp_input_method_manager_v2")
+	}
+	if c.seat == nil {
+		return nil, fmt.Errorf("no seat to serve")
+	}
+
+	protocol := &wireInputMethod{im: c.imManager.GetInputMethod(c.seat)}
+	im := newInputMethod(protocol, c.sendEvent)
+	protocol.im.OnActivate = im.activate
+	protocol.im.OnDeactivate = im.deactivate
+	protocol.im.OnSurroundingText = im.surroundingText
+	protocol.im.OnTextChangeCause = im.textChangeCause
+	protocol.im.OnContentType = im.contentType
+	protocol.im.OnDone = im.done
+	protocol.im.OnUnavailable = im.unavailable
+	return im, nil
+}
+
+// destroyInputMethodManager destroys the manager; input methods created
+// from it are destroyed by their owners
+func (c *Client) destroyInputMethodManager() {
+	if c.imManager != nil {
+		c.imManager.Destroy()
+		c.imManager = nil
+	}
+}
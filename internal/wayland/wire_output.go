@@ -0,0 +1,134 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import "github.com/iotcore/osk-iotcore/internal/wayland/wire"
+
+// output is a bound wl_output and what it reported
+type output struct {
+	proxy *wire.WlOutput
+	outputState
+}
+
+// bindOutput binds an output and tracks its state
+func (c *Client) bindOutput(name, version uint32) {
+	version = negotiate(version, outputVersion)
+	o := &output{outputState: newOutputState(name, version)}
+	state := &o.outputState
+	o.proxy = &wire.WlOutput{
+		OnGeometry: func(x, y, physicalWidth, physicalHeight, subpixel int32, manufacturer, model string, transform int32) {
+			c.outputGeometry(state, x, y, physicalWidth, physicalHeight, manufacturer, model, transform)
+		},
+		OnMode: func(flags uint32, width, height, refresh int32) {
+			c.outputMode(state, flags, width, height, refresh)
+		},
+		OnScale:       func(factor int32) { c.outputScale(state, factor) },
+		OnName:        func(name string) { c.outputName(state, name) },
+		OnDescription: func(description string) { c.outputDescription(state, description) },
+		OnDone:        func() { c.outputDone(state) },
+	}
+	c.registry.Bind(name, o.proxy, version)
+	c.outputs[name] = o
+}
+
+// releaseOutput releases an output, using the release request when the
+// output version has it
+func (c *Client) releaseOutput(o *output) {
+	if o.proxy.Version() >= 3 {
+		o.proxy.Release()
+	} else {
+		o.proxy.Forget()
+	}
+}
+
+// surfaceEnter records the surface entering or leaving an output
+func (c *Client) surfaceEnter(proxy *wire.WlOutput, entered bool) {
+	for _, o := range c.outputs {
+		if o.proxy == proxy {
+			c.outputEntered(&o.outputState, entered)
+			return
+		}
+	}
+}
+
+// bindViewporter binds wp_viewporter, which lets buffers of fractionally
+// scaled frames be shown at the surface size
+func (c *Client) bindViewporter(name, version uint32) {
+	if c.viewporter != nil {
+		return
+	}
+	c.viewporter = &wire.WpViewporter{}
+	c.registry.Bind(name, c.viewporter, negotiate(version, viewporterVersion))
+}
+
+// bindFractionalScaleManager binds wp_fractional_scale_manager_v1
+func (c *Client) bindFractionalScaleManager(name, version uint32) {
+	if c.fractionalScaleManager != nil {
+		return
+	}
+	c.fractionalScaleManager = &wire.WpFractionalScaleManagerV1{}
+	c.registry.Bind(name, c.fractionalScaleManager, negotiate(version, fractionalScaleManagerVersion))
+}
+
+// trackScale follows the outputs the new surface is shown on and, when the
+// compositor offers both fractional scaling and viewports, the fractional
+// scale it prefers for the surface
+func (c *Client) trackScale() {
+	c.surface.OnEnter = func(output *wire.WlOutput) { c.surfaceEnter(output, true) }
+	c.surface.OnLeave = func(output *wire.WlOutput) { c.surfaceEnter(output, false) }
+	if c.viewporter == nil || c.fractionalScaleManager == nil {
+		return
+	}
+	c.viewport = c.viewporter.GetViewport(c.surface)
+	c.fractionalScaleObject = c.fractionalScaleManager.GetFractionalScale(c.surface)
+	c.fractionalScaleObject.OnPreferredScale = c.fractionalScaleChanged
+}
+
+// SetScale sets the scale the frames attached from now on are drawn at.
+// Frames are scale times the surface size; whole scales are presented with
+// the surface buffer scale, fractional ones through a viewport, which is
+// only there when the compositor offers fractional scaling.
+func (c *Client) SetScale(scale float64) error {
+	if err := checkScale(scale, c.viewport != nil); err != nil {
+		return err
+	}
+	c.drawScale = scale
+	return nil
+}
+
+// applyScale tells the compositor the scale of a frame of width×height
+// about to be attached. The buffer scale is only changed along with a
+// buffer, since the attached one must be a multiple of it.
+func (c *Client) applyScale(width, height int) {
+	if c.viewport != nil {
+		w, h := viewportSize(width, height, c.drawScale)
+		c.viewport.SetDestination(int32(w), int32(h))
+		return
+	}
+	if scale := int32(c.drawScale); scale != c.bufferScale && c.surface.Version() >= 3 {
+		c.surface.SetBufferScale(scale)
+		c.bufferScale = scale
+	}
+}
+
+// destroyScale destroys the fractional scale and viewport objects and
+// their globals
+func (c *Client) destroyScale() {
+	if c.fractionalScaleObject != nil {
+		c.fractionalScaleObject.Destroy()
+		c.fractionalScaleObject = nil
+	}
+	if c.viewport != nil {
+		c.viewport.Destroy()
+		c.viewport = nil
+	}
+	if c.fractionalScaleManager != nil {
+		c.fractionalScaleManager.Destroy()
+		c.fractionalScaleManager = nil
+	}
+	if c.viewporter != nil {
+		c.viewporter.Destroy()
+		c.viewporter = nil
+	}
+}
@@ -0,0 +1,79 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"log"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// global binds the globals the keyboard uses as the compositor announces
+// them and reports every global as a registry event
+func (c *Client) global(name uint32, iface string, version uint32) {
+	c.globals[name] = iface
+
+	switch iface {
+	case "wl_compositor":
+		if c.compositor == nil {
+			c.compositor = &wire.WlCompositor{}
+			c.registry.Bind(name, c.compositor, negotiate(version, compositorVersion))
+		}
+	case "wl_shm":
+		if c.shm == nil {
+			c.shm = &wire.WlShm{}
+			c.registry.Bind(name, c.shm, negotiate(version, shmVersion))
+		}
+	case "wl_seat":
+		// Only the first seat drives the keyboard
+		if c.seat == nil {
+			c.seatName = name
+			c.seat = &wire.WlSeat{OnCapabilities: c.capabilities}
+			c.registry.Bind(name, c.seat, negotiate(version, seatVersion))
+		}
+	case "wl_output":
+		c.bindOutput(name, version)
+	case "zwlr_layer_shell_v1":
+		c.bindLayerShell(name, version)
+	case "xdg_wm_base":
+		c.bindWmBase(name, version)
+	case "wp_viewporter":
+		c.bindViewporter(name, version)
+	case "wp_fractional_scale_manager_v1":
+		c.bindFractionalScaleManager(name, version)
+	case "zwp_virtual_keyboard_manager_v1":
+		c.bindVirtualKeyboardManager(name, version)
+	case "zwp_input_method_manager_v2":
+		c.bindInputMethodManager(name, version)
+	}
+
+	c.sendEvent(&RegistryEvent{Name: name, Interface: iface, Version: version})
+}
+
+// globalRemove releases objects bound to a global that went away. Seats
+// and outputs come and go with hardware; the compositor, shm and shell
+// globals live as long as the compositor.
+func (c *Client) globalRemove(name uint32) {
+	iface, exists := c.globals[name]
+	if !exists {
+		return
+	}
+	delete(c.globals, name)
+
+	switch iface {
+	case "wl_seat":
+		if name == c.seatName && c.seat != nil {
+			c.releaseSeat()
+		}
+	case "wl_output":
+		if output, exists := c.outputs[name]; exists {
+			c.releaseOutput(output)
+			delete(c.outputs, name)
+			c.outputRemoved(name)
+		}
+	case "wl_compositor", "wl_shm", "zwlr_layer_shell_v1", "xdg_wm_base", "wp_viewporter",
+		"wp_fractional_scale_manager_v1", "zwp_virtual_keyboard_manager_v1", "zwp_input_method_manager_v2":
+		log.Printf("Wayland global %s (%d) removed by the compositor", iface, name)
+	}
+}
@@ -0,0 +1,110 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// capabilities creates or releases input devices as the seat gains or loses
+// pointer, keyboard and touch capabilities
+func (c *Client) capabilities(caps uint32) {
+	hasPointer := caps&wire.WlSeatCapabilityPointer != 0
+	hasKeyboard := caps&wire.WlSeatCapabilityKeyboard != 0
+	hasTouch := caps&wire.WlSeatCapabilityTouch != 0
+
+	switch {
+	case hasPointer && c.pointer == nil:
+		c.pointer = c.seat.GetPointer()
+		c.pointer.OnEnter = func(serial uint32, surface *wire.WlSurface, x, y wire.Fixed) {
+			c.pointerMotion(0, int32(x), int32(y))
+		}
+		c.pointer.OnMotion = func(time uint32, x, y wire.Fixed) {
+			c.pointerMotion(time, int32(x), int32(y))
+		}
+		c.pointer.OnButton = c.pointerButton
+		c.pointer.OnAxis = func(time, axis uint32, value wire.Fixed) {
+			c.pointerAxis(time, axis, int32(value))
+		}
+		c.pointer.OnFrame = c.pointerFrame
+	case !hasPointer && c.pointer != nil:
+		c.releasePointer()
+	}
+
+	switch {
+	case hasKeyboard && c.keyboard == nil:
+		c.keyboard = c.seat.GetKeyboard()
+		c.keyboard.OnKeymap = c.keyboardKeymap
+		c.keyboard.OnKey = c.keyboardKey
+	case !hasKeyboard && c.keyboard != nil:
+		c.releaseKeyboard()
+	}
+
+	switch {
+	case hasTouch && c.touch == nil:
+		c.touch = c.seat.GetTouch()
+		c.touch.OnDown = func(serial, time uint32, surface *wire.WlSurface, id int32, x, y wire.Fixed) {
+			c.touchPoint(TouchStateDown, serial, time, id, int32(x), int32(y), true)
+		}
+		c.touch.OnUp = func(serial, time uint32, id int32) {
+			c.touchPoint(TouchStateUp, serial, time, id, 0, 0, false)
+		}
+		c.touch.OnMotion = func(time uint32, id int32, x, y wire.Fixed) {
+			c.touchPoint(TouchStateMotion, 0, time, id, int32(x), int32(y), true)
+		}
+		c.touch.OnCancel = c.touchCancel
+	case !hasTouch && c.touch != nil:
+		c.releaseTouch()
+	}
+}
+
+// releasePointer releases the pointer, using the release request when the
+// seat version has it
+func (c *Client) releasePointer() {
+	if c.seat.Version() >= 3 {
+		c.pointer.Release()
+	} else {
+		c.pointer.Forget()
+	}
+	c.pointer = nil
+}
+
+// releaseKeyboard releases the keyboard
+func (c *Client) releaseKeyboard() {
+	if c.seat.Version() >= 3 {
+		c.keyboard.Release()
+	} else {
+		c.keyboard.Forget()
+	}
+	c.keyboard = nil
+}
+
+// releaseTouch releases the touch device
+func (c *Client) releaseTouch() {
+	if c.seat.Version() >= 3 {
+		c.touch.Release()
+	} else {
+		c.touch.Forget()
+	}
+	c.touch = nil
+}
+
+// releaseSeat releases all input devices and the seat
+func (c *Client) releaseSeat() {
+	if c.pointer != nil {
+		c.releasePointer()
+	}
+	if c.keyboard != nil {
+		c.releaseKeyboard()
+	}
+	if c.touch != nil {
+		c.releaseTouch()
+	}
+	if c.seat.Version() >= 5 {
+		c.seat.Release()
+	} else {
+		c.seat.Forget()
+	}
+	c.seat = nil
+}
@@ -0,0 +1,198 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// bindLayerShell binds zwlr_layer_shell_v1
+func (c *Client) bindLayerShell(name, version uint32) {
+	if c.layerShell != nil {
+		return
+	}
+	c.layerShell = &wire.ZwlrLayerShellV1{}
+	c.registry.Bind(name, c.layerShell, negotiate(version, layerShellVersion))
+}
+
+// bindWmBase binds xdg_wm_base, used when layer-shell is missing
+func (c *Client) bindWmBase(name, version uint32) {
+	if c.wmBase != nil {
+		return
+	}
+	c.wmBase = &wire.XdgWmBase{}
+	c.wmBase.OnPing = c.wmBase.Pong
+	c.registry.Bind(name, c.wmBase, negotiate(version, wmBaseVersion))
+}
+
+// CreateSurface creates the keyboard surface and gives it a role: a
+// layer-shell panel placed as config describes, or an xdg_toplevel window
+// when the compositor has no layer-shell. It returns once the compositor
+// has sent the first configure, after which buffers may be attached.
+func (c *Client) CreateSurface(config SurfaceConfig) error {
+	if c.compositor == nil {
+		return fmt.Errorf("compositor not available")
+	}
+	if c.surface != nil {
+		return fmt.Errorf("surface already created")
+	}
+
+	pinned, err := c.pinOutput(config)
+	if err != nil {
+		return err
+	}
+
+	c.surface = c.compositor.CreateSurface()
+	c.trackScale()
+
+	switch {
+	case c.layerShell != nil:
+		c.createLayerSurface(config, pinned)
+	case c.wmBase != nil:
+		log.Printf("Compositor lacks zwlr_layer_shell_v1, falling back to an xdg_toplevel window")
+		c.createToplevel(config)
+	default:
+		return fmt.Errorf("compositor provides neither zwlr_layer_shell_v1 nor xdg_wm_base")
+	}
+
+	// The initial commit carries no buffer; the compositor answers with a
+	// configure that must be acked before the first attach
+	c.surface.Commit()
+	if err := c.waitConfigured(); err != nil {
+		return err
+	}
+	c.updateScale()
+	return nil
+}
+
+// SetVisible maps or unmaps the keyboard surface. It is unmapped by
+// attaching no buffer, after which both shells require a new initial
+// commit and configure before it can be shown; SetVisible(true) waits for
+// that configure, and the next Attach maps the surface.
+func (c *Client) SetVisible(visible bool) error {
+	if c.surface == nil {
+		return fmt.Errorf("no surface to show")
+	}
+	if !visible {
+		if c.mapped {
+			c.surface.Attach(nil, 0, 0)
+			c.surface.Commit()
+			c.mapped = false
+			c.configured = false
+		}
+		return nil
+	}
+	if c.configured {
+		return nil
+	}
+	c.surface.Commit()
+	return c.waitConfigured()
+}
+
+// waitConfigured dispatches events until the surface has been configured
+func (c *Client) waitConfigured() error {
+	for !c.configured {
+		if err := c.conn.Roundtrip(); err != nil {
+			return fmt.Errorf("failed to receive surface configuration: %w", err)
+		}
+		if c.closed {
+			return fmt.Errorf("surface closed by the compositor before it was shown")
+		}
+	}
+	return nil
+}
+
+// createLayerSurface assigns the layer-shell role and sends the placement.
+// Without a pinned output the compositor picks one.
+func (c *Client) createLayerSurface(config SurfaceConfig, pinned *output) {
+	var proxy *wire.WlOutput
+	if pinned != nil {
+		proxy = pinned.proxy
+	}
+	c.layerSurface = c.layerShell.GetLayerSurface(c.surface, proxy, uint32(config.Layer), config.Namespace)
+	c.layerSurface.OnConfigure = c.shellConfigure
+	c.layerSurface.OnClosed = c.shellClosed
+
+	interactivity := config.KeyboardInteractivity
+	if interactivity == KeyboardInteractivityOnDemand && c.layerShell.Version() < 4 {
+		log.Printf("Layer shell version %d has no on-demand keyboard interactivity, using none",
+			c.layerShell.Version())
+		interactivity = KeyboardInteractivityNone
+	}
+
+	width, height := config.requestSize()
+	margins := config.Margins
+	c.layerSurface.SetSize(uint32(width), uint32(height))
+	c.layerSurface.SetAnchor(uint32(config.Anchor))
+	c.layerSurface.SetMargin(int32(margins.Top), int32(margins.Right), int32(margins.Bottom), int32(margins.Left))
+	c.layerSurface.SetExclusiveZone(int32(config.ExclusiveZone))
+	c.layerSurface.SetKeyboardInteractivity(uint32(interactivity))
+}
+
+// createToplevel assigns the xdg_toplevel role with a fixed size
+func (c *Client) createToplevel(config SurfaceConfig) {
+	c.xdgSurface = c.wmBase.GetXdgSurface(c.surface)
+	c.xdgSurface.OnConfigure = func(serial uint32) {
+		c.shellConfigure(serial, 0, 0)
+	}
+	c.toplevel = c.xdgSurface.GetToplevel()
+	c.toplevel.OnConfigure = func(width, height int32, states []byte) {
+		c.toplevelConfigure(width, height)
+	}
+	c.toplevel.OnClose = c.shellClosed
+
+	c.toplevel.SetTitle(config.Title)
+	c.toplevel.SetAppID(config.Namespace)
+	c.toplevel.SetMinSize(int32(config.Width), int32(config.Height))
+	c.toplevel.SetMaxSize(int32(config.Width), int32(config.Height))
+}
+
+// shellConfigure acks a configure from either shell and reports the size
+// the compositor chose
+func (c *Client) shellConfigure(serial, width, height uint32) {
+	switch {
+	case c.layerSurface != nil:
+		c.layerSurface.AckConfigure(serial)
+	case c.xdgSurface != nil:
+		c.xdgSurface.AckConfigure(serial)
+		width, height = c.pendingWidth, c.pendingHeight
+	default:
+		return
+	}
+	c.configured = true
+
+	c.sendEvent(&ShellEvent{Width: width, Height: height})
+}
+
+// destroyShell destroys the surface role objects and the shell globals
+func (c *Client) destroyShell() {
+	if c.layerSurface != nil {
+		c.layerSurface.Destroy()
+		c.layerSurface = nil
+	}
+	if c.toplevel != nil {
+		c.toplevel.Destroy()
+		c.toplevel = nil
+	}
+	if c.xdgSurface != nil {
+		c.xdgSurface.Destroy()
+		c.xdgSurface = nil
+	}
+	if c.layerShell != nil {
+		// The destroy request only exists from version 3
+		if c.layerShell.Version() >= 3 {
+			c.layerShell.Destroy()
+		} else {
+			c.layerShell.Forget()
+		}
+		c.layerShell = nil
+	}
+	if c.wmBase != nil {
+		c.wmBase.Destroy()
+		c.wmBase = nil
+	}
+}
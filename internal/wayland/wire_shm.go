@@ -0,0 +1,236 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"fmt"
+	"image"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// shmPool is a wl_shm_pool backed by an anonymous file, holding the
+// buffers of one swapchain. A new pool is created whenever the surface
+// changes size.
+type shmPool struct {
+	client  *Client
+	chain   *swapchain
+	fd      int
+	pool    *wire.WlShmPool
+	data    []byte
+	buffers []*shmBuffer
+}
+
+// shmBuffer is the wl_buffer for one swapchain slot. A buffer still held
+// by the compositor when its pool is destroyed is orphaned and destroyed
+// once it is released.
+type shmBuffer struct {
+	pool     *shmPool
+	slot     *bufferSlot
+	buffer   *wire.WlBuffer
+	orphaned bool
+}
+
+// newShmPool allocates a pool large enough for minBuffers buffers
+func newShmPool(client *Client, width, height int) (*shmPool, error) {
+	p := &shmPool{client: client, chain: newSwapchain(width, height), fd: -1}
+
+	fd, err := memfd()
+	if err != nil {
+		return nil, err
+	}
+	p.fd = fd
+
+	size := p.chain.bufferSize() * minBuffers
+	if err := p.allocate(size); err != nil {
+		p.destroy()
+		return nil, err
+	}
+
+	// The connection sends a duplicate of the fd, so the pool keeps its own
+	p.pool = client.shm.CreatePool(p.fd, int32(size))
+	return p, nil
+}
+
+// memfd creates an anonymous shared memory file to pass to the compositor.
+// memfd_create has no wrapper in the syscall package, so this does what
+// libwayland does without it: create a file in XDG_RUNTIME_DIR, which is
+// normally a tmpfs, and unlink it straight away.
+func memfd() (int, error) {
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		dir = os.TempDir()
+	}
+
+	for attempt := 0; ; attempt++ {
+		path := filepath.Join(dir, fmt.Sprintf("oskway-shm-%08x", rand.Uint32()))
+		fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CREAT|syscall.O_EXCL|syscall.O_CLOEXEC, 0o600)
+		if err == syscall.EEXIST && attempt < 100 {
+			continue
+		}
+		if err != nil {
+			return -1, fmt.Errorf("failed to create shared memory file: %w", err)
+		}
+		syscall.Unlink(path)
+		return fd, nil
+	}
+}
+
+// allocate resizes the backing file to size bytes and maps it
+func (p *shmPool) allocate(size int) error {
+	if err := syscall.Ftruncate(p.fd, int64(size)); err != nil {
+		return fmt.Errorf("failed to resize shared memory file: %w", err)
+	}
+	if p.data != nil {
+		syscall.Munmap(p.data)
+		p.data = nil
+	}
+	data, err := syscall.Mmap(p.fd, 0, size, syscall.PROT_READ|syscall.PROT_WRITE, syscall.MAP_SHARED)
+	if err != nil {
+		return fmt.Errorf("failed to map shared memory: %w", err)
+	}
+	p.data = data
+	return nil
+}
+
+// acquire returns a buffer the compositor is not reading, growing the pool
+// when a new slot is needed
+func (p *shmPool) acquire() (*shmBuffer, error) {
+	slot, ok := p.chain.acquire()
+	if !ok {
+		return nil, fmt.Errorf("all %d buffers are in use by the compositor", maxBuffers)
+	}
+	if slot.index < len(p.buffers) {
+		return p.buffers[slot.index], nil
+	}
+
+	// Pools can only grow, so the compositor's mapping stays valid
+	if size := slot.offset + p.chain.bufferSize(); size > len(p.data) {
+		if err := p.allocate(size); err != nil {
+			return nil, err
+		}
+		p.pool.Resize(int32(size))
+	}
+
+	buffer := &shmBuffer{pool: p, slot: slot}
+	buffer.buffer = p.pool.CreateBuffer(int32(slot.offset), int32(p.chain.width), int32(p.chain.height),
+		int32(p.chain.stride), wire.WlShmFormatArgb8888)
+	buffer.buffer.OnRelease = buffer.release
+	p.buffers = append(p.buffers, buffer)
+	return buffer, nil
+}
+
+// destroy releases the pool. Buffers the compositor still holds are
+// orphaned and freed on release.
+func (p *shmPool) destroy() {
+	for _, buffer := range p.buffers {
+		if buffer.slot.busy {
+			buffer.orphaned = true
+			p.client.retired[buffer] = struct{}{}
+		} else {
+			buffer.destroy()
+		}
+	}
+	p.buffers = nil
+
+	if p.pool != nil {
+		p.pool.Destroy()
+		p.pool = nil
+	}
+	if p.data != nil {
+		syscall.Munmap(p.data)
+		p.data = nil
+	}
+	if p.fd >= 0 {
+		syscall.Close(p.fd)
+		p.fd = -1
+	}
+}
+
+// pixels returns the mapped memory of the buffer
+func (b *shmBuffer) pixels() []byte {
+	return b.pool.data[b.slot.offset : b.slot.offset+b.pool.chain.bufferSize()]
+}
+
+// release is called when the compositor stops reading the buffer
+func (b *shmBuffer) release() {
+	b.slot.busy = false
+	if b.orphaned {
+		delete(b.pool.client.retired, b)
+		b.destroy()
+	}
+}
+
+// destroy destroys the wl_buffer
+func (b *shmBuffer) destroy() {
+	b.buffer.Destroy()
+}
+
+// Resize prepares buffers for a surface of the given size. Buffers of the
+// previous size are freed once the compositor releases them.
+func (c *Client) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid buffer size %dx%d", width, height)
+	}
+	if c.pool != nil && c.pool.chain.width == width && c.pool.chain.height == height {
+		return nil
+	}
+	if c.shm == nil {
+		return fmt.Errorf("compositor does not provide wl_shm")
+	}
+
+	if c.pool != nil {
+		c.pool.destroy()
+		c.pool = nil
+	}
+	pool, err := newShmPool(c, width, height)
+	if err != nil {
+		return err
+	}
+	c.pool = pool
+	return nil
+}
+
+// Attach copies img into a free buffer, attaches it to the surface and
+// commits along with the damage reported since the last commit. Only the
+// regions that changed since the buffer was last used are copied. The
+// buffers are resized to match img.
+func (c *Client) Attach(img *image.RGBA) error {
+	if c.surface == nil {
+		return fmt.Errorf("no surface to attach to")
+	}
+	size := img.Rect.Size()
+	if err := c.Resize(size.X, size.Y); err != nil {
+		return err
+	}
+
+	buffer, err := c.pool.acquire()
+	if err != nil {
+		return err
+	}
+	buffer.slot.copyPixels(buffer.pixels(), c.pool.chain.stride, img)
+
+	c.applyScale(size.X, size.Y)
+	c.surface.Attach(buffer.buffer, 0, 0)
+	c.surface.Commit()
+	buffer.slot.busy = true
+	c.mapped = true
+	return nil
+}
+
+// destroyBuffers frees the current pool and every orphaned buffer
+func (c *Client) destroyBuffers() {
+	if c.pool != nil {
+		c.pool.destroy()
+		c.pool = nil
+	}
+	for buffer := range c.retired {
+		buffer.destroy()
+		delete(c.retired, buffer)
+	}
+}
@@ -0,0 +1,84 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import (
+	"fmt"
+	"syscall"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland/wire"
+)
+
+// wireVirtualKeyboard sends virtual keyboard requests over the wire
+// connection
+type wireVirtualKeyboard struct {
+	keyboard *wire.ZwpVirtualKeyboardV1
+}
+
+// keymap copies the keymap into an anonymous file and sends it. The
+// connection duplicates the fd when marshalling, so it is closed straight
+// away.
+func (w *wireVirtualKeyboard) keymap(format uint32, keymap []byte) error {
+	fd, err := memfd()
+	if err != nil {
+		return err
+	}
+	defer syscall.Close(fd)
+
+	for data := keymap; len(data) > 0; {
+		n, err := syscall.Write(fd, data)
+		if err != nil {
+			return fmt.Errorf("failed to write keymap: %w", err)
+		}
+		data = data[n:]
+	}
+
+	w.keyboard.Keymap(format, fd, uint32(len(keymap)))
+	return nil
+}
+
+func (w *wireVirtualKeyboard) key(time, key, state uint32) {
+	w.keyboard.Key(time, key, state)
+}
+
+func (w *wireVirtualKeyboard) modifiers(depressed, latched, locked, group uint32) {
+	w.keyboard.Modifiers(depressed, latched, locked, group)
+}
+
+func (w *wireVirtualKeyboard) destroy() {
+	w.keyboard.Destroy()
+}
+
+// bindVirtualKeyboardManager binds zwp_virtual_keyboard_manager_v1
+func (c *Client) bindVirtualKeyboardManager(name, version uint32) {
+	if c.vkManager != nil {
+		return
+	}
+	c.vkManager = &wire.ZwpVirtualKeyboardManagerV1{}
+	c.registry.Bind(name, c.vkManager, negotiate(version, virtualKeyboardManagerVersion))
+}
+
+// VirtualKeyboard creates a virtual keyboard on the seat that types into
+// the focused application
+func (c *Client) VirtualKeyboard() (*VirtualKeyboard, error) {
+	if c.vkManager == nil {
+		return nil, fmt.Errorf("compositor does not provide zwp_virtual_keyboard_manager_v1")
+	}
+	if c.seat == nil {
+		return nil, fmt.Errorf("no seat to type on")
+	}
+
+	keyboard := c.vkManager.CreateVirtualKeyboard(c.seat)
+	return newVirtualKeyboard(&wireVirtualKeyboard{keyboard: keyboard})
+}
+
+// destroyVirtualKeyboardManager forgets the manager, which has no
+// destructor request; virtual keyboards created from it are destroyed by
+// their owners
+func (c *Client) destroyVirtualKeyboardManager() {
+	if c.vkManager != nil {
+		c.vkManager.Forget()
+		c.vkManager = nil
+	}
+}
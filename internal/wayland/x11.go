@@ -0,0 +1,738 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+/*
+#cgo pkg-config: x11 xtst
+#include <X11/Xlib.h>
+#include <X11/Xutil.h>
+#include <X11/Xatom.h>
+#include <X11/XKBlib.h>
+#include <X11/extensions/XTest.h>
+#include <poll.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include <string.h>
+
+// osk_x11_error_code holds the code of the last protocol error. Xlib's
+// default handler exits the process, so errors are recorded instead and
+// checked after a sync.
+static int osk_x11_error_code;
+
+static int osk_x11_error(Display *display, XErrorEvent *event) {
+	osk_x11_error_code = event->error_code;
+	return 0;
+}
+
+static Display *osk_x11_open(void) {
+	XInitThreads();
+	XSetErrorHandler(osk_x11_error);
+	return XOpenDisplay(NULL);
+}
+
+// osk_x11_sync waits for the server to process every request, returning
+// the code of the first error among them or 0
+static int osk_x11_sync(Display *display) {
+	osk_x11_error_code = 0;
+	XSync(display, False);
+	return osk_x11_error_code;
+}
+
+// osk_x11_wait_readable polls fd for input, returning 1 when readable or
+// on error or hang-up, and 0 on timeout
+static int osk_x11_wait_readable(int fd, int timeout) {
+	struct pollfd pfd = { .fd = fd, .events = POLLIN };
+	return poll(&pfd, 1, timeout) != 0;
+}
+
+typedef struct {
+	int type;
+	int x, y, width, height;
+	unsigned int button, keycode;
+	unsigned long time;
+	unsigned long message;
+} osk_x11_event;
+
+// osk_x11_next_event takes the next event off the queue, reading the
+// connection first when mode allows it, and copies the fields the client
+// uses out of the XEvent union. It returns 0 when no event is queued.
+static int osk_x11_next_event(Display *display, int mode, osk_x11_event *out) {
+	if (XEventsQueued(display, mode) == 0) {
+		return 0;
+	}
+	XEvent event;
+	XNextEvent(display, &event);
+	memset(out, 0, sizeof(*out));
+	out->type = event.type;
+	switch (event.type) {
+	case ButtonPress:
+	case ButtonRelease:
+		out->x = event.xbutton.x;
+		out->y = event.xbutton.y;
+		out->button = event.xbutton.button;
+		out->time = event.xbutton.time;
+		break;
+	case MotionNotify:
+		out->x = event.xmotion.x;
+		out->y = event.xmotion.y;
+		out->time = event.xmotion.time;
+		break;
+	case KeyPress:
+	case KeyRelease:
+		out->keycode = event.xkey.keycode;
+		out->time = event.xkey.time;
+		break;
+	case Expose:
+		out->x = event.xexpose.x;
+		out->y = event.xexpose.y;
+		out->width = event.xexpose.width;
+		out->height = event.xexpose.height;
+		break;
+	case ConfigureNotify:
+		out->x = event.xconfigure.x;
+		out->y = event.xconfigure.y;
+		out->width = event.xconfigure.width;
+		out->height = event.xconfigure.height;
+		break;
+	case ClientMessage:
+		if (event.xclient.format == 32) {
+			out->message = event.xclient.data.l[0];
+		}
+		break;
+	}
+	return 1;
+}
+
+// osk_x11_create_window creates the keyboard window. It never takes the
+// input focus unless input is set, so typing goes to the focused window.
+static Window osk_x11_create_window(Display *display, int x, int y, int width, int height,
+		int override_redirect, int input) {
+	int screen = DefaultScreen(display);
+	XSetWindowAttributes attributes;
+	attributes.override_redirect = override_redirect ? True : False;
+	attributes.background_pixmap = None;
+	attributes.event_mask = ExposureMask | StructureNotifyMask | ButtonPressMask |
+		ButtonReleaseMask | PointerMotionMask | KeyPressMask | KeyReleaseMask;
+	Window window = XCreateWindow(display, RootWindow(display, screen), x, y, width, height, 0,
+		CopyFromParent, InputOutput, CopyFromParent,
+		CWOverrideRedirect | CWBackPixmap | CWEventMask, &attributes);
+
+	XWMHints *hints = XAllocWMHints();
+	if (hints != NULL) {
+		hints->flags = InputHint;
+		hints->input = input ? True : False;
+		XSetWMHints(display, window, hints);
+		XFree(hints);
+	}
+
+	// The position is the user's choice, so the window manager keeps it,
+	// and the size is fixed
+	XSizeHints *size = XAllocSizeHints();
+	if (size != NULL) {
+		size->flags = USPosition | USSize | PMinSize | PMaxSize;
+		size->x = x;
+		size->y = y;
+		size->width = size->min_width = size->max_width = width;
+		size->height = size->min_height = size->max_height = height;
+		XSetWMNormalHints(display, window, size);
+		XFree(size);
+	}
+	return window;
+}
+
+static void osk_x11_set_names(Display *display, Window window, char *title, char *class) {
+	XStoreName(display, window, title);
+	XChangeProperty(display, window, XInternAtom(display, "_NET_WM_NAME", False),
+		XInternAtom(display, "UTF8_STRING", False), 8, PropModeReplace,
+		(unsigned char *)title, strlen(title));
+
+	XClassHint *hint = XAllocClassHint();
+	if (hint != NULL) {
+		hint->res_name = class;
+		hint->res_class = class;
+		XSetClassHint(display, window, hint);
+		XFree(hint);
+	}
+}
+
+// osk_x11_set_property sets a property of format 32, whose values Xlib
+// takes as longs
+static void osk_x11_set_property(Display *display, Window window, char *name, Atom type,
+		long *values, int count) {
+	XChangeProperty(display, window, XInternAtom(display, name, False), type, 32,
+		PropModeReplace, (unsigned char *)values, count);
+}
+
+// osk_x11_has_wm reports whether an EWMH window manager is running, which
+// honours dock windows and struts
+static int osk_x11_has_wm(Display *display) {
+	Atom type;
+	int format;
+	unsigned long count, after;
+	unsigned char *data = NULL;
+	Atom check = XInternAtom(display, "_NET_SUPPORTING_WM_CHECK", False);
+	if (XGetWindowProperty(display, DefaultRootWindow(display), check, 0, 1, False, XA_WINDOW,
+			&type, &format, &count, &after, &data) != Success) {
+		return 0;
+	}
+	int found = data != NULL && count == 1;
+	if (data != NULL) {
+		XFree(data);
+	}
+	return found;
+}
+
+// osk_x11_create_image allocates an image in the screen's pixel format.
+// Its data is freed with the image.
+static XImage *osk_x11_create_image(Display *display, int width, int height) {
+	int screen = DefaultScreen(display);
+	char *data = malloc((size_t)width * height * 4);
+	if (data == NULL) {
+		return NULL;
+	}
+	XImage *image = XCreateImage(display, DefaultVisual(display, screen), DefaultDepth(display, screen),
+		ZPixmap, 0, data, width, height, 32, width * 4);
+	if (image == NULL) {
+		free(data);
+	}
+	return image;
+}
+
+static void osk_x11_destroy_image(XImage *image) {
+	XDestroyImage(image);
+}
+
+static int osk_x11_has_xtest(Display *display) {
+	int event, error, major, minor;
+	return XTestQueryExtension(display, &event, &error, &major, &minor);
+}
+
+// osk_x11_keysym_level returns the shift level of keycode producing sym in
+// the first group, or -1 if neither of the first two does
+static int osk_x11_keysym_level(Display *display, KeyCode keycode, KeySym sym) {
+	for (int level = 0; level < 2; level++) {
+		if (XkbKeycodeToKeysym(display, keycode, 0, level) == sym) {
+			return level;
+		}
+	}
+	return -1;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"slices"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+// x11FrameInterval paces frames on X11, which has no frame callbacks
+const x11FrameInterval = 16 * time.Millisecond // ~60 FPS
+
+// X11Client presents the keyboard in a window on an X server. With an
+// EWMH window manager the window is a dock that stays above applications
+// and reserves its exclusive zone as a strut; without one it is an
+// override-redirect window placed directly. Pointer and key events are
+// translated into the events the Wayland client sends.
+//
+// X11 has no virtual keyboard or input method protocols; keys are typed
+// through the XTEST extension instead.
+type X11Client struct {
+	display *C.Display
+	screen  C.int
+	window  C.Window
+	gc      C.GC
+
+	// image is the window contents in the server's pixel format, kept to
+	// repaint exposed areas; pending holds the regions damaged since the
+	// last Attach
+	image   *C.XImage
+	pixels  []byte
+	pending bufferSlot
+
+	width        int
+	height       int
+	deleteWindow C.Atom
+	// visible is the visibility asked for; mapped is set once the window
+	// is mapped by an Attach
+	visible bool
+	mapped  bool
+	closed  bool
+
+	dispatcher *EventDispatcher
+
+	start   time.Time
+	events  chan struct{}
+	resume  chan struct{}
+	frames  chan uint32
+	done    chan struct{}
+	watcher sync.WaitGroup
+}
+
+// NewX11Client connects to the X server named by DISPLAY
+func NewX11Client() (*X11Client, error) {
+	display := C.osk_x11_open()
+	if display == nil {
+		return nil, fmt.Errorf("failed to connect to X display")
+	}
+
+	client := &X11Client{
+		display: display,
+		screen:  C.XDefaultScreen(display),
+		start:   time.Now(),
+		events:  make(chan struct{}),
+		resume:  make(chan struct{}, 1),
+		frames:  make(chan uint32, 1),
+		done:    make(chan struct{}),
+	}
+
+	client.watcher.Add(1)
+	go client.watch(int(C.XConnectionNumber(display)))
+
+	return client, nil
+}
+
+// connectX11 connects to the X server for NewClientInterface
+func connectX11() (WaylandClient, error) {
+	client, err := NewX11Client()
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// watch signals events whenever the connection becomes readable, waiting
+// for Dispatch to read the data before polling again
+func (c *X11Client) watch(fd int) {
+	defer c.watcher.Done()
+	for {
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		if C.osk_x11_wait_readable(C.int(fd), C.int(pollInterval/time.Millisecond)) == 0 {
+			continue
+		}
+
+		select {
+		case c.events <- struct{}{}:
+		case <-c.done:
+			return
+		}
+		select {
+		case <-c.resume:
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// Close destroys the window and disconnects from the X server
+func (c *X11Client) Close() {
+	select {
+	case <-c.done:
+		return
+	default:
+		close(c.done)
+	}
+	c.watcher.Wait()
+
+	if c.image != nil {
+		C.osk_x11_destroy_image(c.image)
+		c.image = nil
+	}
+	if c.gc != nil {
+		C.XFreeGC(c.display, c.gc)
+	}
+	if c.window != 0 {
+		C.XDestroyWindow(c.display, c.window)
+	}
+	C.XCloseDisplay(c.display)
+}
+
+// CreateSurface creates the keyboard window placed on the screen as config
+// describes and reports its size. The window is mapped by the first
+// Attach.
+func (c *X11Client) CreateSurface(config SurfaceConfig) error {
+	if c.window != 0 {
+		return fmt.Errorf("surface already created")
+	}
+
+	screen := image.Rect(0, 0, int(C.XDisplayWidth(c.display, c.screen)), int(C.XDisplayHeight(c.display, c.screen)))
+	geometry := x11Geometry(config, screen)
+	docked := C.osk_x11_has_wm(c.display) != 0
+	if !docked {
+		log.Printf("No EWMH window manager, placing the keyboard in an override-redirect window")
+	}
+
+	c.window = C.osk_x11_create_window(c.display, C.int(geometry.Min.X), C.int(geometry.Min.Y),
+		C.int(geometry.Dx()), C.int(geometry.Dy()), cBool(!docked),
+		cBool(config.KeyboardInteractivity != KeyboardInteractivityNone))
+
+	title := C.CString(config.Title)
+	defer C.free(unsafe.Pointer(title))
+	class := C.CString(config.Namespace)
+	defer C.free(unsafe.Pointer(class))
+	C.osk_x11_set_names(c.display, c.window, title, class)
+
+	c.deleteWindow = c.atom("WM_DELETE_WINDOW")
+	C.XSetWMProtocols(c.display, c.window, &c.deleteWindow, 1)
+	if docked {
+		c.setDockHints(config, geometry)
+	}
+	c.gc = C.XCreateGC(c.display, C.Drawable(c.window), 0, nil)
+
+	if code := C.osk_x11_sync(c.display); code != 0 {
+		return fmt.Errorf("failed to create window: X error %d", int(code))
+	}
+
+	c.width, c.height = geometry.Dx(), geometry.Dy()
+	c.visible = true
+	c.sendEvent(&ShellEvent{Width: uint32(c.width), Height: uint32(c.height)})
+	return nil
+}
+
+// setDockHints asks the window manager to treat the window as a panel: on
+// every desktop, out of the task bar, stacked by the layer and keeping
+// other windows clear of its exclusive zone
+func (c *X11Client) setDockHints(config SurfaceConfig, geometry image.Rectangle) {
+	c.setAtoms("_NET_WM_WINDOW_TYPE", "_NET_WM_WINDOW_TYPE_DOCK")
+
+	stacking := "_NET_WM_STATE_ABOVE"
+	if config.Layer < LayerTop {
+		stacking = "_NET_WM_STATE_BELOW"
+	}
+	c.setAtoms("_NET_WM_STATE", stacking, "_NET_WM_STATE_STICKY",
+		"_NET_WM_STATE_SKIP_TASKBAR", "_NET_WM_STATE_SKIP_PAGER")
+	c.setCardinals("_NET_WM_DESKTOP", 0xffffffff)
+
+	if strut, ok := x11Strut(config, geometry); ok {
+		c.setCardinals("_NET_WM_STRUT_PARTIAL", strut[:]...)
+		c.setCardinals("_NET_WM_STRUT", strut[:4]...)
+	}
+}
+
+func (c *X11Client) atom(name string) C.Atom {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	return C.XInternAtom(c.display, cname, C.False)
+}
+
+// setAtoms sets the window property name to a list of atoms
+func (c *X11Client) setAtoms(name string, atoms ...string) {
+	values := make([]C.long, len(atoms))
+	for i, atom := range atoms {
+		values[i] = C.long(c.atom(atom))
+	}
+	c.setProperty(name, C.XA_ATOM, values)
+}
+
+// setCardinals sets the window property name to a list of numbers
+func (c *X11Client) setCardinals(name string, numbers ...int) {
+	values := make([]C.long, len(numbers))
+	for i, number := range numbers {
+		values[i] = C.long(number)
+	}
+	c.setProperty(name, C.XA_CARDINAL, values)
+}
+
+func (c *X11Client) setProperty(name string, kind C.Atom, values []C.long) {
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+	C.osk_x11_set_property(c.display, c.window, cname, kind, &values[0], C.int(len(values)))
+}
+
+// SetVisible maps or unmaps the window. A shown window is mapped by the
+// next Attach, so it never appears before its contents.
+func (c *X11Client) SetVisible(visible bool) error {
+	if c.window == 0 {
+		return fmt.Errorf("no surface to show")
+	}
+	c.visible = visible
+	if !visible && c.mapped {
+		C.XUnmapWindow(c.display, c.window)
+		c.mapped = false
+	}
+	return nil
+}
+
+// Dispatch reads the events available on the connection without blocking,
+// handles them and re-arms the connection watcher
+func (c *X11Client) Dispatch() error {
+	c.dispatch(C.QueuedAfterReading)
+	select {
+	case c.resume <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// DispatchPending handles events Xlib already read from the connection,
+// which the connection watcher cannot see
+func (c *X11Client) DispatchPending() error {
+	c.dispatch(C.QueuedAlready)
+	return nil
+}
+
+// dispatch handles queued events, reading more first if mode allows it
+func (c *X11Client) dispatch(mode C.int) {
+	var raw C.osk_x11_event
+	for C.osk_x11_next_event(c.display, mode, &raw) != 0 {
+		c.handleEvent(x11Event{
+			Type:    int(raw._type),
+			X:       int(raw.x),
+			Y:       int(raw.y),
+			Width:   int(raw.width),
+			Height:  int(raw.height),
+			Button:  uint32(raw.button),
+			Keycode: uint32(raw.keycode),
+			Time:    uint32(raw.time),
+			Message: uint32(raw.message),
+		})
+	}
+}
+
+// handleEvent repaints exposed areas, reports size changes and closing,
+// and forwards input
+func (c *X11Client) handleEvent(e x11Event) {
+	switch e.Type {
+	case x11Expose:
+		c.put(image.Rect(e.X, e.Y, e.X+e.Width, e.Y+e.Height))
+
+	case x11ConfigureNotify:
+		if e.Width == c.width && e.Height == c.height {
+			return
+		}
+		c.width, c.height = e.Width, e.Height
+		c.sendEvent(&ShellEvent{Width: uint32(e.Width), Height: uint32(e.Height)})
+
+	case x11ClientMessage:
+		if C.Atom(e.Message) != c.deleteWindow || c.closed {
+			return
+		}
+		c.closed = true
+		c.sendEvent(&ShellEvent{Closed: true})
+
+	default:
+		if event := translateX11Event(e); event != nil {
+			c.sendEvent(event)
+		}
+	}
+}
+
+// Flush sends buffered requests to the X server
+func (c *X11Client) Flush() error {
+	C.XFlush(c.display)
+	return nil
+}
+
+// Events returns a channel signalled when the connection is readable
+func (c *X11Client) Events() <-chan struct{} {
+	return c.events
+}
+
+// RequestFrame delivers a frame after one frame interval. X11 has no frame
+// callbacks, so frames are paced by a timer instead.
+func (c *X11Client) RequestFrame() error {
+	if c.window == 0 {
+		c.frameDone(0)
+		return nil
+	}
+	time.AfterFunc(x11FrameInterval, func() {
+		c.frameDone(uint32(time.Since(c.start).Milliseconds()))
+	})
+	return nil
+}
+
+// Frames returns the channel frames are delivered on
+func (c *X11Client) Frames() <-chan uint32 {
+	return c.frames
+}
+
+// frameDone delivers a frame, replacing one not yet received
+func (c *X11Client) frameDone(time uint32) {
+	for {
+		select {
+		case c.frames <- time:
+			return
+		default:
+			select {
+			case <-c.frames:
+			default:
+			}
+		}
+	}
+}
+
+// SetEventDispatcher sets where translated input and window events are
+// sent
+func (c *X11Client) SetEventDispatcher(dispatcher *EventDispatcher) {
+	c.dispatcher = dispatcher
+}
+
+func (c *X11Client) sendEvent(event Event) {
+	if c.dispatcher != nil {
+		c.dispatcher.SendEvent(event)
+	}
+}
+
+// Damage marks a region of the window as changed for the next Attach
+func (c *X11Client) Damage(x, y, width, height int) error {
+	if c.image == nil {
+		return nil
+	}
+	r := image.Rect(x, y, x+width, y+height).Intersect(c.bounds())
+	if !r.Empty() {
+		c.pending.addStale(r)
+	}
+	return nil
+}
+
+// Resize allocates the window image for a new size. It is drawn in full by
+// the next Attach.
+func (c *X11Client) Resize(width, height int) error {
+	if width <= 0 || height <= 0 {
+		return fmt.Errorf("invalid buffer size %dx%d", width, height)
+	}
+	if c.image != nil && int(c.image.width) == width && int(c.image.height) == height {
+		return nil
+	}
+
+	if c.image != nil {
+		C.osk_x11_destroy_image(c.image)
+		c.image, c.pixels = nil, nil
+	}
+	img := C.osk_x11_create_image(c.display, C.int(width), C.int(height))
+	if img == nil {
+		return fmt.Errorf("failed to allocate a %dx%d window image", width, height)
+	}
+	// Frames are converted to the byte order of ARGB8888 shm buffers,
+	// which is what 24-bit TrueColor screens use
+	if img.bits_per_pixel != 32 || img.byte_order != C.LSBFirst || img.red_mask != 0xff0000 ||
+		img.green_mask != 0xff00 || img.blue_mask != 0xff {
+		C.osk_x11_destroy_image(img)
+		return fmt.Errorf("unsupported screen format: depth %d", int(C.XDefaultDepth(c.display, c.screen)))
+	}
+	c.image = img
+	c.pixels = unsafe.Slice((*byte)(unsafe.Pointer(img.data)), int(img.bytes_per_line)*height)
+	c.pending.stale = append(c.pending.stale[:0], c.bounds())
+	return nil
+}
+
+// bounds returns the rectangle covered by the window image
+func (c *X11Client) bounds() image.Rectangle {
+	if c.image == nil {
+		return image.Rectangle{}
+	}
+	return image.Rect(0, 0, int(c.image.width), int(c.image.height))
+}
+
+// Attach copies the regions of img damaged since the last Attach into the
+// window image and draws them, mapping the window if it is to be shown.
+// The image is resized to match img.
+func (c *X11Client) Attach(img *image.RGBA) error {
+	if c.window == 0 {
+		return fmt.Errorf("no surface to attach to")
+	}
+	size := img.Rect.Size()
+	if err := c.Resize(size.X, size.Y); err != nil {
+		return err
+	}
+
+	regions := slices.Clone(c.pending.stale)
+	c.pending.copyPixels(c.pixels, int(c.image.bytes_per_line), img)
+	for _, region := range regions {
+		c.put(region)
+	}
+
+	// Exposure after mapping draws the whole window from the image
+	if c.visible && !c.mapped {
+		C.XMapRaised(c.display, c.window)
+		c.mapped = true
+	}
+	return nil
+}
+
+// SetScale accepts only a scale of 1: X windows are sized in screen
+// pixels, so no scale is ever reported
+func (c *X11Client) SetScale(scale float64) error {
+	if scale != 1 {
+		return fmt.Errorf("X11 windows cannot be drawn at scale %g", scale)
+	}
+	return nil
+}
+
+// put draws a region of the window image
+func (c *X11Client) put(r image.Rectangle) {
+	r = r.Intersect(c.bounds())
+	if r.Empty() {
+		return
+	}
+	C.XPutImage(c.display, C.Drawable(c.window), c.gc, c.image, C.int(r.Min.X), C.int(r.Min.Y),
+		C.int(r.Min.X), C.int(r.Min.Y), C.uint(r.Dx()), C.uint(r.Dy()))
+}
+
+// VirtualKeyboard is unavailable: X11 types through XTest instead
+func (c *X11Client) VirtualKeyboard() (*VirtualKeyboard, error) {
+	return nil, fmt.Errorf("X11 has no virtual keyboard protocol")
+}
+
+// InputMethod is unavailable: X11 has no input method protocol the
+// keyboard can serve, so it stays shown
+func (c *X11Client) InputMethod() (*InputMethod, error) {
+	return nil, fmt.Errorf("X11 has no input method protocol")
+}
+
+// XTest types keys through the XTEST extension, as if they were pressed
+// on a keyboard attached to the X server
+type XTest struct {
+	client *X11Client
+}
+
+// XTest returns the XTEST keyboard of the X server
+func (c *X11Client) XTest() (*XTest, error) {
+	if C.osk_x11_has_xtest(c.display) == 0 {
+		return nil, fmt.Errorf("X server lacks the XTEST extension")
+	}
+	return &XTest{client: c}, nil
+}
+
+// FakeKey presses or releases the key with evdev code
+func (x *XTest) FakeKey(code uint32, pressed bool) {
+	display := x.client.display
+	C.XTestFakeKeyEvent(display, C.uint(code+x11KeycodeOffset), cBool(pressed), C.CurrentTime)
+	C.XFlush(display)
+}
+
+// Lookup returns the evdev code of the key typing r in the server's
+// keymap, and whether it needs Shift
+func (x *XTest) Lookup(r rune) (code uint32, shift bool, ok bool) {
+	sym, ok := x11Keysym(r)
+	if !ok {
+		return 0, false, false
+	}
+	display := x.client.display
+	keycode := C.XKeysymToKeycode(display, C.KeySym(sym))
+	if keycode < x11KeycodeOffset {
+		return 0, false, false
+	}
+	level := C.osk_x11_keysym_level(display, keycode, C.KeySym(sym))
+	if level < 0 {
+		return 0, false, false
+	}
+	return uint32(keycode) - x11KeycodeOffset, level == 1, true
+}
+
+func cBool(b bool) C.int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,42 @@
+//go:build purego || !cgo || test
+// +build purego !cgo test
+
+package wayland
+
+import "errors"
+
+// errNoX11 is returned when the binary was built without Xlib
+var errNoX11 = errors.New("X11 support requires cgo and Xlib")
+
+// X11Client is unavailable without cgo; NewX11Client always fails. It
+// embeds the interface only so it satisfies WaylandClient as the cgo type
+// does.
+type X11Client struct {
+	WaylandClient
+}
+
+// NewX11Client fails, as X11 support needs Xlib
+func NewX11Client() (*X11Client, error) {
+	return nil, errNoX11
+}
+
+// connectX11 fails, as X11 support needs Xlib
+func connectX11() (WaylandClient, error) {
+	return nil, errNoX11
+}
+
+// XTest is unavailable without cgo
+type XTest struct{}
+
+// XTest fails, as X11 support needs Xlib
+func (c *X11Client) XTest() (*XTest, error) {
+	return nil, errNoX11
+}
+
+// FakeKey does nothing
+func (x *XTest) FakeKey(code uint32, pressed bool) {}
+
+// Lookup finds no keys
+func (x *XTest) Lookup(r rune) (code uint32, shift bool, ok bool) {
+	return 0, false, false
+}
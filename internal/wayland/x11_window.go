@@ -0,0 +1,191 @@
+package wayland
+
+import (
+	"image"
+)
+
+// X11 core event types, as in X11/X.h
+const (
+	x11KeyPress        = 2
+	x11KeyRelease      = 3
+	x11ButtonPress     = 4
+	x11ButtonRelease   = 5
+	x11MotionNotify    = 6
+	x11Expose          = 12
+	x11ConfigureNotify = 22
+	x11ClientMessage   = 33
+)
+
+// x11KeycodeOffset is the difference between X keycodes and evdev codes
+const x11KeycodeOffset = 8
+
+// x11Event holds the fields of an XEvent the client uses. Which are set
+// depends on Type: pointer events have X, Y and Button, key events
+// Keycode, and Expose and ConfigureNotify a rectangle.
+type x11Event struct {
+	Type    int
+	X       int
+	Y       int
+	Width   int
+	Height  int
+	Button  uint32
+	Keycode uint32
+	Time    uint32
+	// Message is the first data item of a ClientMessage
+	Message uint32
+}
+
+// x11ScrollStep is the distance in pixels reported for one scroll wheel
+// click, as Weston does for wheel axis events
+const x11ScrollStep = 10
+
+// translateX11Event converts an X input event to the event widgets
+// receive, or returns nil for events that are not input. Presses of the
+// scroll wheel buttons 4 to 7 become axis events; their releases are
+// dropped.
+func translateX11Event(e x11Event) Event {
+	switch e.Type {
+	case x11ButtonPress, x11ButtonRelease:
+		if e.Button >= 4 && e.Button <= 7 {
+			if e.Type == x11ButtonRelease {
+				return nil
+			}
+			// 4 and 5 scroll up and down, 6 and 7 left and right
+			axis, value := AxisVertical, float64(x11ScrollStep)
+			if e.Button >= 6 {
+				axis = AxisHorizontal
+			}
+			if e.Button%2 == 0 {
+				value = -value
+			}
+			return &PointerAxisEvent{Axis: axis, Value: value, Time: e.Time}
+		}
+		if e.Button < 1 || e.Button > 3 {
+			return nil
+		}
+		state := uint32(0)
+		if e.Type == x11ButtonPress {
+			state = 1
+		}
+		return &PointerButtonEvent{X: int32(e.X), Y: int32(e.Y), Button: e.Button, State: state, Time: e.Time}
+
+	case x11MotionNotify:
+		return &PointerMotionEvent{X: int32(e.X), Y: int32(e.Y), Time: e.Time}
+
+	case x11KeyPress, x11KeyRelease:
+		if e.Keycode < x11KeycodeOffset {
+			return nil
+		}
+		state := uint32(0)
+		if e.Type == x11KeyPress {
+			state = 1
+		}
+		return &KeyboardEvent{Key: e.Keycode - x11KeycodeOffset, State: state, Time: e.Time}
+	}
+	return nil
+}
+
+// x11Geometry returns where a window placed as config describes goes on a
+// screen, following the layer-shell rules: a dimension stretched between
+// opposite anchors fills the screen less the margins, an edge anchor keeps
+// the margin from that edge, and an unanchored dimension is centred.
+func x11Geometry(config SurfaceConfig, screen image.Rectangle) image.Rectangle {
+	width, height := config.requestSize()
+	margins := config.Margins
+	if width == 0 {
+		width = screen.Dx() - margins.Left - margins.Right
+	}
+	if height == 0 {
+		height = screen.Dy() - margins.Top - margins.Bottom
+	}
+	width, height = max(width, 1), max(height, 1)
+
+	x := place(config.Anchor&AnchorLeft != 0, config.Anchor&AnchorRight != 0,
+		screen.Min.X, screen.Max.X, margins.Left, margins.Right, width)
+	y := place(config.Anchor&AnchorTop != 0, config.Anchor&AnchorBottom != 0,
+		screen.Min.Y, screen.Max.Y, margins.Top, margins.Bottom, height)
+	return image.Rect(x, y, x+width, y+height)
+}
+
+// place positions a window of size between the screen edges lo and hi
+// along one axis
+func place(toLo, toHi bool, lo, hi, loMargin, hiMargin, size int) int {
+	switch {
+	case toLo:
+		return lo + loMargin
+	case toHi:
+		return hi - hiMargin - size
+	}
+	return lo + (hi-lo-size)/2
+}
+
+// exclusiveEdge returns the edge an exclusive zone reserves space along.
+// As with layer-shell, that is only defined when the surface is anchored
+// to one edge, or to one edge and both edges beside it.
+func exclusiveEdge(anchor Anchor) (Anchor, bool) {
+	horizontal := AnchorLeft | AnchorRight
+	vertical := AnchorTop | AnchorBottom
+	switch anchor {
+	case AnchorTop, AnchorTop | horizontal:
+		return AnchorTop, true
+	case AnchorBottom, AnchorBottom | horizontal:
+		return AnchorBottom, true
+	case AnchorLeft, AnchorLeft | vertical:
+		return AnchorLeft, true
+	case AnchorRight, AnchorRight | vertical:
+		return AnchorRight, true
+	}
+	return 0, false
+}
+
+// x11Strut returns the _NET_WM_STRUT_PARTIAL values reserving the
+// exclusive zone of config for a window at geometry: the space taken from
+// the left, right, top and bottom screen edges, then the start and end of
+// each along the edge. Struts are measured from the edges of the root
+// window, which geometry is relative to. It returns false when no space is
+// reserved.
+func x11Strut(config SurfaceConfig, geometry image.Rectangle) ([12]int, bool) {
+	var strut [12]int
+	edge, ok := exclusiveEdge(config.Anchor)
+	if !ok || config.ExclusiveZone <= 0 {
+		return strut, false
+	}
+
+	margins := config.Margins
+	switch edge {
+	case AnchorLeft:
+		strut[0] = margins.Left + config.ExclusiveZone
+		strut[4], strut[5] = geometry.Min.Y, geometry.Max.Y-1
+	case AnchorRight:
+		strut[1] = margins.Right + config.ExclusiveZone
+		strut[6], strut[7] = geometry.Min.Y, geometry.Max.Y-1
+	case AnchorTop:
+		strut[2] = margins.Top + config.ExclusiveZone
+		strut[8], strut[9] = geometry.Min.X, geometry.Max.X-1
+	case AnchorBottom:
+		strut[3] = margins.Bottom + config.ExclusiveZone
+		strut[10], strut[11] = geometry.Min.X, geometry.Max.X-1
+	}
+	return strut, true
+}
+
+// x11Keysym returns the keysym of the character r, following the keysym
+// encoding: Latin-1 characters are their own keysym, control characters
+// map to their function keys, and others use the Unicode range
+func x11Keysym(r rune) (uint32, bool) {
+	switch {
+	case r == '\n' || r == '\r':
+		return 0xff0d, true // XK_Return
+	case r == '\t':
+		return 0xff09, true // XK_Tab
+	case r == '\b':
+		return 0xff08, true // XK_BackSpace
+	case r == 0x1b:
+		return 0xff1b, true // XK_Escape
+	case r < 0x20 || r == 0x7f || (r >= 0x80 && r < 0xa0) || r > 0x10ffff:
+		return 0, false
+	case r < 0x100:
+		return uint32(r), true
+	}
+	return 0x01000000 | uint32(r), true
+}
@@ -0,0 +1,82 @@
+package wayland
+
+import (
+	"image"
+	"testing"
+)
+
+func TestX11GeometryFollowsLayerShellPlacement(t *testing.T) {
+	screen := image.Rect(0, 0, 1280, 800)
+
+	config := DefaultSurfaceConfig(800, 300)
+	config.Margins = Margins{Bottom: 10, Left: 20, Right: 40}
+	if got, want := x11Geometry(config, screen), image.Rect(20, 490, 1240, 790); got != want {
+		t.Errorf("docked geometry = %v, want %v", got, want)
+	}
+
+	config.Anchor = AnchorTop | AnchorRight
+	config.Margins = Margins{Top: 5, Right: 5}
+	if got, want := x11Geometry(config, screen), image.Rect(475, 5, 1275, 305); got != want {
+		t.Errorf("corner geometry = %v, want %v", got, want)
+	}
+
+	config.Anchor = 0
+	if got, want := x11Geometry(config, screen), image.Rect(240, 250, 1040, 550); got != want {
+		t.Errorf("centred geometry = %v, want %v", got, want)
+	}
+}
+
+func TestX11StrutReservesExclusiveZone(t *testing.T) {
+	config := DefaultSurfaceConfig(800, 300)
+	config.Margins.Bottom = 10
+	geometry := x11Geometry(config, image.Rect(0, 0, 1280, 800))
+	strut, ok := x11Strut(config, geometry)
+	want := [12]int{0, 0, 0, 310, 0, 0, 0, 0, 0, 0, 0, 1279}
+	if !ok || strut != want {
+		t.Errorf("bottom strut = %v, %v, want %v", strut, ok, want)
+	}
+
+	// A zone needs a single edge to be measured from, and a size
+	config.Anchor = AnchorBottom | AnchorLeft
+	if _, ok := x11Strut(config, geometry); ok {
+		t.Error("corner anchored surface reserved a strut")
+	}
+	config.Anchor = AnchorBottom
+	config.ExclusiveZone = -1
+	if _, ok := x11Strut(config, geometry); ok {
+		t.Error("negative exclusive zone reserved a strut")
+	}
+}
+
+func TestTranslateX11Event(t *testing.T) {
+	event := translateX11Event(x11Event{Type: x11ButtonPress, X: 12, Y: 34, Button: 1, Time: 5})
+	if pointer, ok := event.(*PointerButtonEvent); !ok ||
+		*pointer != (PointerButtonEvent{X: 12, Y: 34, Button: 1, State: 1, Time: 5}) {
+		t.Errorf("button press = %+v", event)
+	}
+
+	event = translateX11Event(x11Event{Type: x11KeyRelease, Keycode: 38})
+	if key, ok := event.(*KeyboardEvent); !ok || *key != (KeyboardEvent{Key: 30}) {
+		t.Errorf("key release = %+v, want evdev code 30 released", event)
+	}
+
+	event = translateX11Event(x11Event{Type: x11ButtonPress, Button: 4, Time: 7})
+	if axis, ok := event.(*PointerAxisEvent); !ok || *axis != (PointerAxisEvent{Axis: AxisVertical, Value: -10, Time: 7}) {
+		t.Errorf("scroll up = %+v", event)
+	}
+	if event := translateX11Event(x11Event{Type: x11ButtonRelease, Button: 4}); event != nil {
+		t.Errorf("scroll button release = %+v, want it dropped", event)
+	}
+}
+
+func TestX11Keysym(t *testing.T) {
+	tests := map[rune]uint32{'a': 0x61, 'é': 0xe9, '€': 0x10020ac, '\n': 0xff0d}
+	for r, want := range tests {
+		if got, ok := x11Keysym(r); !ok || got != want {
+			t.Errorf("x11Keysym(%q) = %#x, %v, want %#x", r, got, ok, want)
+		}
+	}
+	if _, ok := x11Keysym(0x7f); ok {
+		t.Error("x11Keysym(DEL) has a keysym")
+	}
+}
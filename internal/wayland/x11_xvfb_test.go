@@ -0,0 +1,125 @@
+//go:build !test && !purego
+// +build !test,!purego
+
+package wayland
+
+import (
+	"bufio"
+	"image"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startXvfb runs a virtual X server for the test and points DISPLAY at
+// it, skipping the test when Xvfb is not installed
+func startXvfb(t *testing.T) {
+	t.Helper()
+	path, err := exec.LookPath("Xvfb")
+	if err != nil {
+		t.Skip("Xvfb not installed")
+	}
+
+	// Xvfb picks a free display and writes its number to fd 3
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	cmd := exec.Command(path, "-displayfd", "3", "-screen", "0", "640x480x24", "-nolisten", "tcp")
+	cmd.ExtraFiles = []*os.File{w}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("failed to start Xvfb: %v", err)
+	}
+	w.Close()
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+
+	number := make(chan string, 1)
+	go func() {
+		line, _ := bufio.NewReader(r).ReadString('\n')
+		number <- strings.TrimSpace(line)
+	}()
+	select {
+	case n := <-number:
+		if n == "" {
+			t.Fatal("Xvfb exited without a display")
+		}
+		t.Setenv("DISPLAY", ":"+n)
+	case <-time.After(10 * time.Second):
+		t.Fatal("timed out waiting for Xvfb")
+	}
+}
+
+func TestX11ClientOnXvfb(t *testing.T) {
+	startXvfb(t)
+	t.Setenv("WAYLAND_DISPLAY", "")
+	t.Setenv("XDG_SESSION_TYPE", "x11")
+
+	wc, err := NewClientInterface()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wc.Close()
+	client, ok := wc.(*X11Client)
+	if !ok {
+		t.Fatalf("NewClientInterface returned %T, want the X11 client", wc)
+	}
+
+	dispatcher := NewEventDispatcher()
+	client.SetEventDispatcher(dispatcher)
+	if err := client.CreateSurface(DefaultSurfaceConfig(400, 200)); err != nil {
+		t.Fatal(err)
+	}
+	<-dispatcher.Events()
+	event, _ := dispatcher.Next()
+	if shell, ok := event.(*ShellEvent); !ok || shell.Width != 640 || shell.Height != 200 {
+		t.Fatalf("configure = %+v, want the screen width and keyboard height", event)
+	}
+
+	// Drawing maps the window; the exposure it causes is repainted from
+	// the window image
+	frame := image.NewRGBA(image.Rect(0, 0, 640, 200))
+	if err := client.Attach(frame); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Damage(0, 0, 10, 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := client.Attach(frame); err != nil {
+		t.Fatal(err)
+	}
+	client.Flush()
+	select {
+	case <-client.Events():
+		if err := client.Dispatch(); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no events after mapping the window")
+	}
+
+	if err := client.RequestFrame(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-client.Frames():
+	case <-time.After(time.Second):
+		t.Fatal("no frame delivered")
+	}
+
+	xtest, err := client.XTest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code, shift, ok := xtest.Lookup('a'); !ok || code != 30 || shift {
+		t.Errorf("Lookup(a) = %d, %v, %v, want 30 without Shift", code, shift, ok)
+	}
+	if code, shift, ok := xtest.Lookup('A'); !ok || code != 30 || !shift {
+		t.Errorf("Lookup(A) = %d, %v, %v, want 30 with Shift", code, shift, ok)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/internal/render"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// TestLabelsHaveGlyphs shapes the label of every key in every layout with
+// the fonts of every theme, and fails on labels that would be drawn with the
+// missing-glyph box
+func TestLabelsHaveGlyphs(t *testing.T) {
+	kb, err := keyboard.New()
+	if err != nil {
+		t.Fatalf("Failed to initialize keyboard: %v", err)
+	}
+
+	layouts, err := kb.ListAvailableLayouts()
+	if err != nil {
+		t.Fatalf("Failed to list layouts: %v", err)
+	}
+	themes, err := kb.ListAvailableThemes()
+	if err != nil {
+		t.Fatalf("Failed to list themes: %v", err)
+	}
+
+	fonts := render.NewFontManager(render.NewTextureManager())
+	for _, themeName := range themes {
+		t.Run(themeName, func(t *testing.T) {
+			if err := kb.LoadTheme(themeName); err != nil {
+				t.Fatalf("Failed to load theme %s: %v", themeName, err)
+			}
+			for _, layoutName := range layouts {
+				if err := kb.SwitchLayout(layoutName); err != nil {
+					t.Fatalf("Failed to switch to layout %s: %v", layoutName, err)
+				}
+				assertLabelsHaveGlyphs(t, fonts, kb.GetLayout(), kb.GetTheme())
+			}
+		})
+	}
+}
+
+// assertLabelsHaveGlyphs reports every key label of layout that the font
+// chain of theme cannot draw
+func assertLabelsHaveGlyphs(t *testing.T, fonts *render.FontManager, layout *keyboard.Layout, theme *keyboard.Theme) {
+	t.Helper()
+	for _, key := range layout.Keys {
+		text, err := fonts.Layout(theme.FontFamily(), float64(theme.FontSize), key.Label)
+		if err != nil {
+			t.Fatalf("Failed to shape label %q: %v", key.Label, err)
+		}
+		if text.Missing > 0 {
+			t.Errorf("%s with theme %s: label %q of key %s draws %d missing-glyph boxes",
+				layout.Name, theme.Name, key.Label, key.ID, text.Missing)
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package keyboard
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ContentPurpose is the purpose of the focused text field, with the values
+// of zwp_text_input_v3.content_purpose
+type ContentPurpose uint32
+
+const (
+	ContentPurposeNormal ContentPurpose = iota
+	ContentPurposeAlpha
+	ContentPurposeDigits
+	ContentPurposeNumber
+	ContentPurposePhone
+	ContentPurposeURL
+	ContentPurposeEmail
+	ContentPurposeName
+	ContentPurposePassword
+	ContentPurposePIN
+	ContentPurposeDate
+	ContentPurposeTime
+	ContentPurposeDatetime
+	ContentPurposeTerminal
+)
+
+// ContentHint is a set of hints about the focused text field, with the
+// values of zwp_text_input_v3.content_hint
+type ContentHint uint32
+
+const (
+	ContentHintCompletion ContentHint = 1 << iota
+	ContentHintSpellcheck
+	ContentHintAutoCapitalization
+	ContentHintLowercase
+	ContentHintUppercase
+	ContentHintTitlecase
+	ContentHintHiddenText
+	ContentHintSensitiveData
+	ContentHintLatin
+	ContentHintMultiline
+)
+
+// contentPurposeNames are the names of the content purposes in settings
+var contentPurposeNames = []string{
+	"normal", "alpha", "digits", "number", "phone", "url", "email",
+	"name", "password", "pin", "date", "time", "datetime", "terminal",
+}
+
+// contentHintNames are the names of the content hints in settings, by bit
+var contentHintNames = []string{
+	"completion", "spellcheck", "auto-capitalization", "lowercase", "uppercase",
+	"titlecase", "hidden-text", "sensitive-data", "latin", "multiline",
+}
+
+func (p ContentPurpose) String() string {
+	if int(p) < len(contentPurposeNames) {
+		return contentPurposeNames[p]
+	}
+	return fmt.Sprintf("purpose(%d)", uint32(p))
+}
+
+// ParseContentPurpose parses a content purpose name such as "email"
+func ParseContentPurpose(name string) (ContentPurpose, error) {
+	for i, purposeName := range contentPurposeNames {
+		if name == purposeName {
+			return ContentPurpose(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown content purpose %q", name)
+}
+
+// ParseContentHint parses a content hint name such as "multiline"
+func ParseContentHint(name string) (ContentHint, error) {
+	for i, hintName := range contentHintNames {
+		if name == hintName {
+			return ContentHint(1) << i, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown content hint %q", name)
+}
+
+// ContentType describes the focused text field. The zero value is a
+// normal field without hints, used when no field is focused.
+type ContentType struct {
+	Hint    ContentHint
+	Purpose ContentPurpose
+}
+
+// SetContentType records the content type of the focused text field and
+// switches to the layout configured for it. The layout the user chose is
+// restored once a field without one is focused, or focus is lost.
+func (kb *Keyboard) SetContentType(contentType ContentType) error {
+	layout, listeners, err := kb.setContentType(contentType)
+	if err != nil {
+		return err
+	}
+	for _, fn := range listeners {
+		fn(layout)
+	}
+	return nil
+}
+
+// setContentType records contentType and replaces the layout if needed,
+// returning the layout listeners to notify
+func (kb *Keyboard) setContentType(contentType ContentType) (*Layout, []func(*Layout), error) {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+	kb.contentType = contentType
+
+	name := kb.contentLayout(contentType)
+	if name == "" {
+		// Restore the user's layout
+		name, kb.userLayout = kb.userLayout, ""
+		if name == "" {
+			return nil, nil, nil
+		}
+	} else if kb.userLayout == "" {
+		kb.userLayout = kb.layout.Name
+	}
+	if name == kb.layout.Name {
+		return nil, nil, nil
+	}
+
+	listeners, err := kb.replaceLayout(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kb.layout, listeners, nil
+}
+
+// ContentType returns the content type of the focused text field
+func (kb *Keyboard) ContentType() ContentType {
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+	return kb.contentType
+}
+
+// ContentLayout selects the layout shown for text fields with a purpose
+// and at least the given hints. An empty Layout keeps the user's layout.
+type ContentLayout struct {
+	Purpose ContentPurpose
+	Hint    ContentHint
+	Layout  string
+}
+
+// matches reports whether the rule applies to contentType
+func (cl ContentLayout) matches(contentType ContentType) bool {
+	return cl.Purpose == contentType.Purpose && contentType.Hint&cl.Hint == cl.Hint
+}
+
+// DefaultContentLayouts returns the layouts shown for text fields unless
+// configured otherwise: a PIN pad for digits and PINs, a numeric pad for
+// numbers, a dial pad for phone numbers and a page with "@", "/" and
+// ".com" keys for e-mail addresses and URLs
+func DefaultContentLayouts() []ContentLayout {
+	return []ContentLayout{
+		{Purpose: ContentPurposeDigits, Layout: "pin"},
+		{Purpose: ContentPurposePIN, Layout: "pin"},
+		{Purpose: ContentPurposeNumber, Layout: "numeric"},
+		{Purpose: ContentPurposePhone, Layout: "phone"},
+		{Purpose: ContentPurposeEmail, Layout: "web"},
+		{Purpose: ContentPurposeURL, Layout: "web"},
+	}
+}
+
+// ParseContentLayouts parses comma-separated purpose=layout rules, such
+// as "email=web,pin=pin". A purpose may be followed by "+" and the hints a
+// field must have, as in "normal+multiline=qwerty"; an empty layout keeps
+// the user's layout for the matching fields.
+func ParseContentLayouts(s string) ([]ContentLayout, error) {
+	var rules []ContentLayout
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		selector, layout, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid content layout %q: want purpose=layout", entry)
+		}
+
+		names := strings.Split(strings.TrimSpace(selector), "+")
+		purpose, err := ParseContentPurpose(names[0])
+		if err != nil {
+			return nil, err
+		}
+		rule := ContentLayout{Purpose: purpose, Layout: strings.TrimSpace(layout)}
+		for _, name := range names[1:] {
+			hint, err := ParseContentHint(name)
+			if err != nil {
+				return nil, err
+			}
+			rule.Hint |= hint
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// SetContentLayouts replaces the rules choosing the layout for the focused
+// text field. The first rule matching a field applies.
+func (kb *Keyboard) SetContentLayouts(rules []ContentLayout) {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+	kb.contentLayouts = append([]ContentLayout(nil), rules...)
+}
+
+// ContentLayouts returns the rules choosing the layout for the focused
+// text field
+func (kb *Keyboard) ContentLayouts() []ContentLayout {
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+	return append([]ContentLayout(nil), kb.contentLayouts...)
+}
+
+// contentLayout returns the layout for contentType, or "" to show the
+// user's layout. Callers must hold the mutex.
+func (kb *Keyboard) contentLayout(contentType ContentType) string {
+	for _, rule := range kb.contentLayouts {
+		if rule.matches(contentType) {
+			return rule.Layout
+		}
+	}
+	return ""
+}
@@ -0,0 +1,99 @@
+package keyboard
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// chdirRoot runs the test from the repository root, where the layouts
+// other than the built-in QWERTY are found
+func chdirRoot(t *testing.T) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(filepath.Join("..", "..")); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+func TestContentTypeSelectsLayout(t *testing.T) {
+	chdirRoot(t)
+
+	tests := []struct {
+		name        string
+		rules       []ContentLayout
+		contentType ContentType
+		want        string
+	}{
+		{"no field", nil, ContentType{}, "dvorak"},
+		{"normal field", nil, ContentType{Purpose: ContentPurposeNormal}, "dvorak"},
+		{"no rule matches", nil, ContentType{Purpose: ContentPurposeDate}, "dvorak"},
+		{"purpose", nil, ContentType{Purpose: ContentPurposePIN}, "pin"},
+		{"purpose with any hints", nil,
+			ContentType{Purpose: ContentPurposeEmail, Hint: ContentHintLowercase | ContentHintLatin}, "web"},
+		{"override keeps the user's layout", []ContentLayout{{Purpose: ContentPurposeEmail}},
+			ContentType{Purpose: ContentPurposeEmail}, "dvorak"},
+		{"override replaces the defaults", []ContentLayout{{Purpose: ContentPurposeEmail, Layout: "qwerty"}},
+			ContentType{Purpose: ContentPurposePIN}, "dvorak"},
+		{"hint rule matches", []ContentLayout{{Purpose: ContentPurposeNormal, Hint: ContentHintMultiline, Layout: "web"}},
+			ContentType{Purpose: ContentPurposeNormal, Hint: ContentHintMultiline | ContentHintSpellcheck}, "web"},
+		{"hint rule lacks its hint", []ContentLayout{{Purpose: ContentPurposeNormal, Hint: ContentHintMultiline, Layout: "web"}},
+			ContentType{Purpose: ContentPurposeNormal, Hint: ContentHintSpellcheck}, "dvorak"},
+		{"first rule wins", []ContentLayout{{Purpose: ContentPurposeURL, Layout: "web"}, {Purpose: ContentPurposeURL, Layout: "qwerty"}},
+			ContentType{Purpose: ContentPurposeURL}, "web"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := kb.SwitchLayout("dvorak"); err != nil {
+				t.Fatal(err)
+			}
+			if tt.rules != nil {
+				kb.SetContentLayouts(tt.rules)
+			}
+			if err := kb.SetContentType(tt.contentType); err != nil {
+				t.Fatalf("SetContentType: %v", err)
+			}
+			if got := kb.GetCurrentLayoutName(); got != tt.want {
+				t.Errorf("layout = %s, want %s", got, tt.want)
+			}
+
+			// Losing focus restores the user's layout
+			if err := kb.SetContentType(ContentType{}); err != nil {
+				t.Fatalf("SetContentType: %v", err)
+			}
+			if got := kb.GetCurrentLayoutName(); got != "dvorak" {
+				t.Errorf("layout after focus loss = %s, want dvorak", got)
+			}
+		})
+	}
+}
+
+func TestParseContentLayouts(t *testing.T) {
+	rules, err := ParseContentLayouts("email=web, pin=pin,normal+multiline+latin=, ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ContentLayout{
+		{Purpose: ContentPurposeEmail, Layout: "web"},
+		{Purpose: ContentPurposePIN, Layout: "pin"},
+		{Purpose: ContentPurposeNormal, Hint: ContentHintMultiline | ContentHintLatin},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("rules = %+v, want %+v", rules, want)
+	}
+
+	for _, s := range []string{"email", "mail=web", "email+shouty=web"} {
+		if _, err := ParseContentLayouts(s); err == nil {
+			t.Errorf("ParseContentLayouts(%q) succeeded", s)
+		}
+	}
+}
@@ -7,6 +7,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
 )
 
@@ -30,6 +32,29 @@ type Key struct {
 	Height   int      `json:"height"`
 	State    KeyState `json:"-"`       // Not serialized
 	Modifier bool     `json:"modifier,omitempty"`
+	// Output is the text the key types, typed instead of Code when set
+	Output Levels `json:"output,omitempty"`
+}
+
+// Levels is the text a key types at each shift level: plain, Shift, AltGr
+// and AltGr+Shift. Levels may be left out from the end, and each may be a
+// single character or a longer string. In layout files a key with one level
+// may give a string instead of an array.
+type Levels []string
+
+// UnmarshalJSON accepts a string or an array of strings
+func (l *Levels) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		*l = Levels{text}
+		return nil
+	}
+	var levels []string
+	if err := json.Unmarshal(data, &levels); err != nil {
+		return fmt.Errorf("output must be a string or an array of strings")
+	}
+	*l = levels
+	return nil
 }
 
 // Layout represents a keyboard layout
@@ -44,28 +69,63 @@ type Layout struct {
 // Theme represents keyboard visual theme
 type Theme struct {
 	Name            string     `json:"name"`
+	Description     string     `json:"description,omitempty"`
 	BackgroundColor [4]float32 `json:"background_color"`
 	KeyColor        [4]float32 `json:"key_color"`
 	KeyPressedColor [4]float32 `json:"key_pressed_color"`
+	KeyHoverColor   [4]float32 `json:"key_hover_color"`
 	TextColor       [4]float32 `json:"text_color"`
+	BorderColor     [4]float32 `json:"border_color"`
+	Font            string     `json:"font,omitempty"`
+	FontFallbacks   []string   `json:"font_fallbacks,omitempty"`
 	FontSize        int        `json:"font_size"`
 	BorderRadius    int        `json:"border_radius"`
+	BorderWidth     int        `json:"border_width"`
+	KeyPadding      int        `json:"key_padding"`
+	ShadowEnabled   bool       `json:"shadow_enabled"`
+	ShadowColor     [4]float32 `json:"shadow_color"`
+	ShadowOffset    [2]int     `json:"shadow_offset"`
+	ShadowBlur      int        `json:"shadow_blur"`
+}
+
+// KeyEvent reports a key being pressed or released
+type KeyEvent struct {
+	Key   *Key
+	State KeyState
+	// Sensitive is set when the key was typed in sensitive mode; see
+	// Keyboard.Sensitive. Only inject.Recorder reads it: the other key
+	// outputs and the logging on the key and layout paths never name keys,
+	// codes or text, so they have nothing to redact.
+	Sensitive bool
 }
 
 // Keyboard manages keyboard state and layout
 type Keyboard struct {
-	layout     *Layout
-	theme      *Theme
-	keyStates  map[string]KeyState
-	mutex      sync.RWMutex
-	callbacks  map[string]func(*Key)
+	layout       *Layout
+	theme        *Theme
+	keyStates    map[string]KeyState
+	mutex        sync.RWMutex
+	callbacks    map[string]func(*Key)
+	listeners    map[int]func(KeyEvent)
+	nextListener int
+	// layoutListeners share ids with listeners
+	layoutListeners map[int]func(*Layout)
+	contentType     ContentType
+	contentLayouts  []ContentLayout
+	// userLayout is the layout the user chose while a layout for the
+	// focused text field is shown, and empty otherwise
+	userLayout     string
+	forceSensitive bool
 }
 
 // New creates a new keyboard instance
 func New() (*Keyboard, error) {
 	kb := &Keyboard{
-		keyStates: make(map[string]KeyState),
-		callbacks: make(map[string]func(*Key)),
+		keyStates:       make(map[string]KeyState),
+		callbacks:       make(map[string]func(*Key)),
+		listeners:       make(map[int]func(KeyEvent)),
+		layoutListeners: make(map[int]func(*Layout)),
+		contentLayouts:  DefaultContentLayouts(),
 	}
 
 	// Load default layout
@@ -83,9 +143,31 @@ func New() (*Keyboard, error) {
 
 // LoadLayout loads a keyboard layout by name from the layout.d directory
 func (kb *Keyboard) LoadLayout(name string) error {
+	layout, listeners, err := kb.loadLayout(name)
+	if err != nil {
+		return err
+	}
+	for _, fn := range listeners {
+		fn(layout)
+	}
+	return nil
+}
+
+// loadLayout replaces the layout and returns the layout listeners to notify
+func (kb *Keyboard) loadLayout(name string) (*Layout, []func(*Layout), error) {
 	kb.mutex.Lock()
 	defer kb.mutex.Unlock()
 
+	listeners, err := kb.replaceLayout(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return kb.layout, listeners, nil
+}
+
+// replaceLayout loads a layout and returns the layout listeners to notify.
+// Callers must hold the mutex.
+func (kb *Keyboard) replaceLayout(name string) ([]func(*Layout), error) {
 	// Try to load from layout.d directory first
 	parser := NewLayoutParser("assets/layouts")
 	layout, err := parser.ParseLayout(name + ".json")
@@ -99,17 +181,27 @@ func (kb *Keyboard) LoadLayout(name string) error {
 				Keys:   createQWERTYLayout(),
 			}
 		} else {
-			return fmt.Errorf("layout %s not found: %w", name, err)
+			return nil, fmt.Errorf("failed to load layout %s: %w", name, err)
 		}
 	}
 
 	// Validate the loaded layout
 	if err := parser.ValidateLayout(layout); err != nil {
-		return fmt.Errorf("invalid layout %s: %w", name, err)
+		return nil, fmt.Errorf("invalid layout %s: %w", name, err)
 	}
 
 	kb.layout = layout
-	return nil
+
+	ids := make([]int, 0, len(kb.layoutListeners))
+	for id := range kb.layoutListeners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	listeners := make([]func(*Layout), len(ids))
+	for i, id := range ids {
+		listeners[i] = kb.layoutListeners[id]
+	}
+	return listeners, nil
 }
 
 // LoadTheme loads a visual theme by name
@@ -153,14 +245,16 @@ func (kb *Keyboard) GetTheme() *Theme {
 // PressKey sets a key to pressed state
 func (kb *Keyboard) PressKey(keyID string) error {
 	kb.mutex.Lock()
-	defer kb.mutex.Unlock()
 
+	changed := kb.keyStates[keyID] != KeyStatePressed
 	kb.keyStates[keyID] = KeyStatePressed
 	
 	// Find the key and update its state
+	var pressed *Key
 	for _, key := range kb.layout.Keys {
 		if key.ID == keyID {
 			key.State = KeyStatePressed
+			pressed = key
 			// Call callback if registered
 			if callback, exists := kb.callbacks[keyID]; exists {
 				callback(key)
@@ -169,27 +263,103 @@ func (kb *Keyboard) PressKey(keyID string) error {
 		}
 	}
 
+	listeners := kb.keyListeners(pressed, changed)
+	sensitive := kb.sensitive()
+	kb.mutex.Unlock()
+
+	notify(listeners, KeyEvent{Key: pressed, State: KeyStatePressed, Sensitive: sensitive})
 	return nil
 }
 
 // ReleaseKey sets a key to released state
 func (kb *Keyboard) ReleaseKey(keyID string) error {
 	kb.mutex.Lock()
-	defer kb.mutex.Unlock()
 
+	changed := kb.keyStates[keyID] == KeyStatePressed
 	kb.keyStates[keyID] = KeyStateReleased
 	
 	// Find the key and update its state
+	var released *Key
 	for _, key := range kb.layout.Keys {
 		if key.ID == keyID {
 			key.State = KeyStateReleased
+			released = key
 			break
 		}
 	}
 
+	listeners := kb.keyListeners(released, changed)
+	sensitive := kb.sensitive()
+	kb.mutex.Unlock()
+
+	notify(listeners, KeyEvent{Key: released, State: KeyStateReleased, Sensitive: sensitive})
 	return nil
 }
 
+// Subscribe registers fn to be called whenever a key is pressed or
+// released. Repeated presses of a held key are not reported. fn runs on the
+// goroutine that changed the key, without the keyboard lock held. The
+// returned function removes the subscription.
+func (kb *Keyboard) Subscribe(fn func(KeyEvent)) func() {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+
+	id := kb.nextListener
+	kb.nextListener++
+	kb.listeners[id] = fn
+
+	return func() {
+		kb.mutex.Lock()
+		defer kb.mutex.Unlock()
+		delete(kb.listeners, id)
+	}
+}
+
+// SubscribeLayout registers fn to be called with the new layout whenever
+// one is loaded. fn runs on the goroutine that loaded it, without the
+// keyboard lock held. The returned function removes the subscription.
+func (kb *Keyboard) SubscribeLayout(fn func(*Layout)) func() {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+
+	id := kb.nextListener
+	kb.nextListener++
+	kb.layoutListeners[id] = fn
+
+	return func() {
+		kb.mutex.Lock()
+		defer kb.mutex.Unlock()
+		delete(kb.layoutListeners, id)
+	}
+}
+
+// keyListeners returns the listeners to notify of a state change of key.
+// Callers must hold the mutex.
+func (kb *Keyboard) keyListeners(key *Key, changed bool) []func(KeyEvent) {
+	if key == nil || !changed || len(kb.listeners) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(kb.listeners))
+	for id := range kb.listeners {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	listeners := make([]func(KeyEvent), len(ids))
+	for i, id := range ids {
+		listeners[i] = kb.listeners[id]
+	}
+	return listeners
+}
+
+// notify calls listeners in subscription order
+func notify(listeners []func(KeyEvent), event KeyEvent) {
+	for _, fn := range listeners {
+		fn(event)
+	}
+}
+
 // GetKeyState returns the state of a specific key
 func (kb *Keyboard) GetKeyState(keyID string) KeyState {
 	kb.mutex.RLock()
@@ -242,9 +412,40 @@ func (kb *Keyboard) ListAvailableLayouts() ([]string, error) {
 	return layoutNames, nil
 }
 
-// SwitchLayout switches to a different keyboard layout
+// ListAvailableThemes returns a list of available theme names
+func (kb *Keyboard) ListAvailableThemes() ([]string, error) {
+	seen := make(map[string]bool)
+	var themeNames []string
+
+	for _, themeDir := range themeSearchDirs {
+		files, err := filepath.Glob(filepath.Join(themeDir, "*.json"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to list themes in %s: %w", themeDir, err)
+		}
+		for _, file := range files {
+			name := strings.TrimSuffix(filepath.Base(file), ".json")
+			if !seen[name] {
+				seen[name] = true
+				themeNames = append(themeNames, name)
+			}
+		}
+	}
+
+	sort.Strings(themeNames)
+	return themeNames, nil
+}
+
+// SwitchLayout switches to a different keyboard layout chosen by the user.
+// It stays shown after the focused text field loses focus, even if the
+// field has a layout of its own.
 func (kb *Keyboard) SwitchLayout(layoutName string) error {
-	return kb.LoadLayout(layoutName)
+	if err := kb.LoadLayout(layoutName); err != nil {
+		return err
+	}
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+	kb.userLayout = ""
+	return nil
 }
 
 // GetCurrentLayoutName returns the name of the current layout
@@ -311,16 +512,16 @@ func createQWERTYLayout() []*Key {
 	return keys
 }
 
+// themeSearchDirs lists the directories searched for theme files, in order
+var themeSearchDirs = []string{
+	"assets/themes",
+	"themes",
+	"../assets/themes",
+}
+
 // loadThemeFromFile loads a theme from a JSON file
 func loadThemeFromFile(name string) (*Theme, error) {
-	// Try different theme directories
-	themeDirs := []string{
-		"assets/themes",
-		"themes",
-		"../assets/themes",
-	}
-	
-	for _, themeDir := range themeDirs {
+	for _, themeDir := range themeSearchDirs {
 		themePath := filepath.Join(themeDir, name+".json")
 		if _, err := os.Stat(themePath); err == nil {
 			// File exists, try to load it
@@ -340,3 +541,74 @@ func loadThemeFromFile(name string) (*Theme, error) {
 	
 	return nil, fmt.Errorf("theme file %s.json not found", name)
 }
+
+// ParseThemeFile parses a theme from the JSON file at path
+func ParseThemeFile(path string) (*Theme, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme file %s: %w", path, err)
+	}
+
+	var theme Theme
+	if err := json.Unmarshal(data, &theme); err != nil {
+		return nil, fmt.Errorf("failed to parse theme JSON: %w", err)
+	}
+
+	return &theme, nil
+}
+
+// FontFamily returns the theme font followed by its fallbacks, in the
+// order characters are looked up
+func (t *Theme) FontFamily() []string {
+	family := make([]string, 0, len(t.FontFallbacks)+1)
+	if t.Font != "" {
+		family = append(family, t.Font)
+	}
+	return append(family, t.FontFallbacks...)
+}
+
+// ValidateTheme validates a theme structure
+func ValidateTheme(theme *Theme) error {
+	if theme.Name == "" {
+		return fmt.Errorf("theme name cannot be empty")
+	}
+
+	if theme.FontSize <= 0 {
+		return fmt.Errorf("theme font size must be positive")
+	}
+
+	if theme.BorderRadius < 0 {
+		return fmt.Errorf("theme border radius must be non-negative")
+	}
+
+	for _, font := range theme.FontFamily() {
+		switch strings.ToLower(filepath.Ext(font)) {
+		case "", ".ttf", ".otf", ".ttc", ".otc":
+		default:
+			return fmt.Errorf("theme font %s must be a TrueType or OpenType file", font)
+		}
+	}
+
+	if theme.BorderWidth < 0 || theme.KeyPadding < 0 || theme.ShadowBlur < 0 {
+		return fmt.Errorf("theme border width, key padding and shadow blur must be non-negative")
+	}
+
+	colors := map[string][4]float32{
+		"background_color":  theme.BackgroundColor,
+		"key_color":         theme.KeyColor,
+		"key_pressed_color": theme.KeyPressedColor,
+		"key_hover_color":   theme.KeyHoverColor,
+		"text_color":        theme.TextColor,
+		"border_color":      theme.BorderColor,
+		"shadow_color":      theme.ShadowColor,
+	}
+	for name, color := range colors {
+		for i, val := range color {
+			if val < 0 || val > 1 {
+				return fmt.Errorf("%s component %d must be between 0 and 1", name, i)
+			}
+		}
+	}
+
+	return nil
+}
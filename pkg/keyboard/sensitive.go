@@ -0,0 +1,34 @@
+package keyboard
+
+// Sensitive reports whether text typed into fields of this type must not
+// leak: passwords, PINs, and fields hinted as hidden or sensitive
+func (ct ContentType) Sensitive() bool {
+	switch ct.Purpose {
+	case ContentPurposePassword, ContentPurposePIN:
+		return true
+	}
+	return ct.Hint&(ContentHintHiddenText|ContentHintSensitiveData) != 0
+}
+
+// SetSensitive forces sensitive mode on, or with false leaves it to follow
+// the content type of the focused text field
+func (kb *Keyboard) SetSensitive(sensitive bool) {
+	kb.mutex.Lock()
+	defer kb.mutex.Unlock()
+	kb.forceSensitive = sensitive
+}
+
+// Sensitive reports whether sensitive mode is on. While it is, keys must
+// not be previewed, predicted, learned from, logged or recorded; key
+// events carry the same flag.
+func (kb *Keyboard) Sensitive() bool {
+	kb.mutex.RLock()
+	defer kb.mutex.RUnlock()
+	return kb.sensitive()
+}
+
+// sensitive reports whether sensitive mode is on. Callers must hold the
+// mutex.
+func (kb *Keyboard) sensitive() bool {
+	return kb.forceSensitive || kb.contentType.Sensitive()
+}
@@ -0,0 +1,54 @@
+package keyboard
+
+import "testing"
+
+func TestSensitiveMode(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType ContentType
+		force       bool
+		want        bool
+	}{
+		{"normal field", ContentType{}, false, false},
+		{"password", ContentType{Purpose: ContentPurposePassword}, false, true},
+		{"pin", ContentType{Purpose: ContentPurposePIN}, false, true},
+		{"digits", ContentType{Purpose: ContentPurposeDigits}, false, false},
+		{"hidden text", ContentType{Hint: ContentHintHiddenText}, false, true},
+		{"sensitive data", ContentType{Purpose: ContentPurposeName, Hint: ContentHintSensitiveData}, false, true},
+		{"other hints", ContentType{Hint: ContentHintSpellcheck | ContentHintMultiline}, false, false},
+		{"forced on", ContentType{}, true, true},
+		{"forced on a password", ContentType{Purpose: ContentPurposePassword}, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			kb, err := New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Keep the QWERTY layout, whatever the field
+			kb.SetContentLayouts(nil)
+			kb.SetSensitive(tt.force)
+			if err := kb.SetContentType(tt.contentType); err != nil {
+				t.Fatal(err)
+			}
+			if got := kb.Sensitive(); got != tt.want {
+				t.Errorf("Sensitive() = %v, want %v", got, tt.want)
+			}
+
+			var events []KeyEvent
+			unsubscribe := kb.Subscribe(func(event KeyEvent) { events = append(events, event) })
+			defer unsubscribe()
+			kb.PressKey("a")
+			kb.ReleaseKey("a")
+			if len(events) != 2 || events[0].Sensitive != tt.want || events[1].Sensitive != tt.want {
+				t.Errorf("key events = %+v, want Sensitive %v", events, tt.want)
+			}
+
+			// Turning sensitive mode off leaves it to the content type
+			kb.SetSensitive(false)
+			if got := kb.Sensitive(); got != tt.contentType.Sensitive() {
+				t.Errorf("Sensitive() unforced = %v, want %v", got, tt.contentType.Sensitive())
+			}
+		})
+	}
+}
@@ -3,12 +3,14 @@ package main
 import (
 	"encoding/json"
 	"fmt"
+	"image/png"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+	"github.com/iotcore/osk-iotcore/ui"
 )
 
 // TestSchemaValidation validates all JSON layouts and themes against the struct definitions
@@ -312,7 +314,7 @@ func testLayoutRendering(t *testing.T, kb *keyboard.Keyboard, layoutName string)
 	}
 
 	// Simulate headless screenshot functionality
-	if err := simulateHeadlessScreenshot(t, layout, layoutName); err != nil {
+	if err := simulateHeadlessScreenshot(t, layout, kb.GetTheme(), layoutName); err != nil {
 		t.Errorf("Headless screenshot simulation failed for layout %s: %v", layoutName, err)
 	}
 
@@ -320,9 +322,9 @@ func testLayoutRendering(t *testing.T, kb *keyboard.Keyboard, layoutName string)
 		layoutName, layout.Width, layout.Height, len(layout.Keys))
 }
 
-// simulateHeadlessScreenshot simulates the headless screenshot functionality
-func simulateHeadlessScreenshot(t *testing.T, layout *keyboard.Layout, layoutName string) error {
-	// Create a simple pixel buffer to simulate rendering
+// simulateHeadlessScreenshot renders the layout headlessly and saves the
+// result as a PNG in the test's temporary directory
+func simulateHeadlessScreenshot(t *testing.T, layout *keyboard.Layout, theme *keyboard.Theme, layoutName string) error {
 	width := layout.Width
 	height := layout.Height
 	
@@ -330,19 +332,6 @@ func simulateHeadlessScreenshot(t *testing.T, layout *keyboard.Layout, layoutNam
 		return fmt.Errorf("invalid dimensions: %dx%d", width, height)
 	}
 
-	// Simulate creating a pixel buffer
-	pixelBuffer := make([]uint32, width*height)
-	
-	// Fill background
-	backgroundColor := uint32(0xFF202020) // Dark gray
-	for i := range pixelBuffer {
-		pixelBuffer[i] = backgroundColor
-	}
-
-	// Simulate rendering each key
-	keyColor := uint32(0xFFF0F0F0)      // Light gray
-	borderColor := uint32(0xFF808080)   // Medium gray
-	
 	for i, key := range layout.Keys {
 		// Validate key bounds
 		if key.X < 0 || key.Y < 0 || key.Width <= 0 || key.Height <= 0 {
@@ -354,33 +343,31 @@ func simulateHeadlessScreenshot(t *testing.T, layout *keyboard.Layout, layoutNam
 		if key.X+key.Width > width || key.Y+key.Height > height {
 			return fmt.Errorf("key %d extends beyond layout bounds", i)
 		}
+	}
 
-		// Simulate drawing the key
-		for y := key.Y; y < key.Y+key.Height && y < height; y++ {
-			for x := key.X; x < key.X+key.Width && x < width; x++ {
-				if y >= 0 && x >= 0 {
-					// Draw border
-					if x == key.X || x == key.X+key.Width-1 || 
-					   y == key.Y || y == key.Y+key.Height-1 {
-						pixelBuffer[y*width+x] = borderColor
-					} else {
-						pixelBuffer[y*width+x] = keyColor
-					}
-				}
-			}
-		}
+	img, err := ui.Snapshot(layout, theme)
+	if err != nil {
+		return fmt.Errorf("failed to render snapshot: %w", err)
 	}
+	background := img.RGBAAt(0, 0)
+
+	screenshotPath := filepath.Join(t.TempDir(), layoutName+".png")
+	file, err := os.Create(screenshotPath)
+	if err != nil {
+		return fmt.Errorf("failed to create screenshot: %w", err)
+	}
+	defer file.Close()
+	if err := png.Encode(file, img); err != nil {
+		return fmt.Errorf("failed to encode screenshot: %w", err)
+	}
+	t.Logf("Screenshot saved to: %s", screenshotPath)
 
-	// Simulate saving screenshot (in real implementation, this would save to file)
-	screenshotPath := fmt.Sprintf("screenshots/%s.png", layoutName)
-	t.Logf("Simulated screenshot saved to: %s", screenshotPath)
-	
-	// In a real implementation, you would save the pixel buffer as an image
-	// For now, we just verify the buffer has been modified
 	nonBackgroundPixels := 0
-	for _, pixel := range pixelBuffer {
-		if pixel != backgroundColor {
-			nonBackgroundPixels++
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if img.RGBAAt(x, y) != background {
+				nonBackgroundPixels++
+			}
 		}
 	}
 	
@@ -388,7 +375,7 @@ func simulateHeadlessScreenshot(t *testing.T, layout *keyboard.Layout, layoutNam
 		return fmt.Errorf("no keys were rendered (all pixels are background color)")
 	}
 
-	t.Logf("✓ Headless screenshot simulation successful (%d non-background pixels)", 
+	t.Logf("✓ Headless screenshot successful (%d non-background pixels)", 
 		nonBackgroundPixels)
 	
 	return nil
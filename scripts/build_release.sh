@@ -62,14 +62,14 @@ if check_cross_compile_env "arm64" "aarch64-linux-gnu-gcc"; then
     
     go build -tags="cgo" -ldflags="-s -w" -o $ROOT_DIR/dist/osk-lin-arm64 $ROOT_DIR/cmd/oskway || {
         echo "ARM64 CGO build failed, trying without CGO..."
-        CGO_ENABLED=0 go build -tags="!cgo" -ldflags="-s -w" -o $ROOT_DIR/dist/osk-lin-arm64 $ROOT_DIR/cmd/oskway
-        echo "⚠ ARM64 built without CGO (Wayland functionality may be limited)"
+        CGO_ENABLED=0 go build -tags="purego" -ldflags="-s -w" -o $ROOT_DIR/dist/osk-lin-arm64 $ROOT_DIR/cmd/oskway
+        echo "⚠ ARM64 built without CGO (pure-Go Wayland client, no X11 support)"
     }
 else
     echo "Cross-compiler not available. Building ARM64 without CGO..."
     echo "For full functionality, install: sudo apt-get install gcc-aarch64-linux-gnu"
-    GOOS=linux GOARCH=arm64 CGO_ENABLED=0 go build -tags="!cgo" -ldflags="-s -w" -o $ROOT_DIR/dist/osk-lin-arm64 $ROOT_DIR/cmd/oskway
-    echo "⚠ ARM64 built without CGO (Wayland functionality may be limited)"
+    GOOS=linux GOARCH=arm64 CGO_ENABLED=0 go build -tags="purego" -ldflags="-s -w" -o $ROOT_DIR/dist/osk-lin-arm64 $ROOT_DIR/cmd/oskway
+    echo "⚠ ARM64 built without CGO (pure-Go Wayland client, no X11 support)"
 fi
 echo "✓ ARM64 build completed"
 
@@ -3,10 +3,15 @@
 package ui
 
 import (
+	"context"
 	"fmt"
+	"image"
 	"log"
+	"math"
 	"sync"
+	"time"
 
+	"github.com/iotcore/osk-iotcore/internal/inject"
 	"github.com/iotcore/osk-iotcore/internal/render"
 	"github.com/iotcore/osk-iotcore/internal/wayland"
 	"github.com/iotcore/osk-iotcore/pkg/keyboard"
@@ -19,9 +24,35 @@ type App struct {
 	waylandClient wayland.WaylandClient
 	eventDispatcher *wayland.EventDispatcher
 	running    bool
+	cancel     context.CancelFunc
 	mutex      sync.RWMutex
 	widgets    []Widget
 	keyboardWidget *KeyboardWidget
+	timers       *timerQueue
+	framePending bool
+	// clock is the time source of the timers; the mock client may bring
+	// a virtual one
+	clock wayland.Clock
+
+	// surfaceConfig places the keyboard surface; surfaceWidth and
+	// surfaceHeight are the size last configured by the compositor
+	surfaceConfig *wayland.SurfaceConfig
+	surfaceWidth  int
+	surfaceHeight int
+	// scale is the scale of the output the keyboard is shown on. Frames
+	// are drawn at scale times the surface size; widgets lay out and hit
+	// test in surface pixels.
+	scale float64
+
+	// keyOutput is the chain of key output backends to try; keyInjector
+	// delivers the pressed keys to the focused application
+	keyOutput   []string
+	keyInjector inject.KeyInjector
+	detachKeys  func()
+	// inputMethod shows the keyboard while a text input is focused and
+	// commits text to it; hidden is set while the surface is unmapped
+	inputMethod *wayland.InputMethod
+	hidden      bool
 }
 
 // NewApp creates a new application instance
@@ -29,38 +60,52 @@ func NewApp(kb *keyboard.Keyboard) *App {
 	return &App{
 		keyboard: kb,
 		widgets:  make([]Widget, 0),
+		timers:   newTimerQueue(wayland.SystemClock),
+		clock:    wayland.SystemClock,
+		scale:    1,
 	}
 }
 
-// Run starts the application main loop
-func (app *App) Run() error {
+// Run initializes the application and runs the main loop until ctx is
+// cancelled, Stop is called or the Wayland connection fails
+func (app *App) Run(ctx context.Context) error {
 	if err := app.initialize(); err != nil {
 		return fmt.Errorf("failed to initialize app: %w", err)
 	}
 	defer app.cleanup()
 
 	log.Println("Starting oskway keyboard application...")
-	
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	app.mutex.Lock()
 	app.running = true
+	app.cancel = cancel
 	app.mutex.Unlock()
 
-	// Main event loop
-	for app.isRunning() {
-		if err := app.processEvents(); err != nil {
-			log.Printf("Error processing events: %v", err)
-		}
+	defer func() {
+		app.mutex.Lock()
+		app.running = false
+		app.cancel = nil
+		app.mutex.Unlock()
+	}()
 
-		if err := app.render(); err != nil {
-			log.Printf("Error rendering: %v", err)
-		}
+	return app.loop(ctx)
+}
 
-		if err := app.waylandClient.Dispatch(); err != nil {
-			log.Printf("Error dispatching Wayland events: %v", err)
-		}
-	}
+// SetSurfaceConfig sets how the keyboard surface is placed. It must be
+// called before Run; by default the keyboard is docked along the bottom of
+// the output.
+func (app *App) SetSurfaceConfig(config wayland.SurfaceConfig) {
+	app.surfaceConfig = &config
+}
 
-	return nil
+// SetKeyOutput sets the chain of key output backends tried in order when
+// the application starts. It must be called before Run; by default keys
+// are typed through the Wayland virtual keyboard.
+func (app *App) SetKeyOutput(backends []string) {
+	app.keyOutput = backends
 }
 
 // Stop stops the application
@@ -68,15 +113,126 @@ func (app *App) Stop() {
 	app.mutex.Lock()
 	defer app.mutex.Unlock()
 	app.running = false
+	if app.cancel != nil {
+		app.cancel()
+	}
 }
 
-// isRunning returns whether the application is running
-func (app *App) isRunning() bool {
+// IsRunning returns whether the application main loop is running
+func (app *App) IsRunning() bool {
 	app.mutex.RLock()
 	defer app.mutex.RUnlock()
 	return app.running
 }
 
+// AfterFunc schedules fn to run on the main loop after d. It may be called
+// from any goroutine.
+func (app *App) AfterFunc(d time.Duration, fn func()) *Timer {
+	return app.timers.add(d, fn)
+}
+
+// loop waits on the Wayland connection, internal events, timers and ctx at
+// the same time. Frames are drawn only when a frame callback arrives, and
+// callbacks are requested only while a widget is damaged.
+func (app *App) loop(ctx context.Context) error {
+	for {
+		if err := app.flush(); err != nil {
+			return err
+		}
+
+		// The timeout is stopped after each wait, so the clock does not
+		// keep one waiter for every pass of the loop
+		var timeout <-chan time.Time
+		stopTimeout := func() bool { return false }
+		if d, ok := app.timers.next(app.clock.Now()); ok {
+			timeout, stopTimeout = app.clock.NewTimer(d)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-app.waylandClient.Events():
+			if err := app.waylandClient.Dispatch(); err != nil {
+				return fmt.Errorf("failed to dispatch Wayland events: %w", err)
+			}
+
+		case <-app.eventDispatcher.Events():
+			app.handleEvents()
+
+		case <-timeout:
+			app.timers.run(app.clock.Now())
+
+		case <-app.timers.changed():
+			// Recompute the next deadline
+
+		case <-app.waylandClient.Frames():
+			app.handleFrame()
+		}
+		stopTimeout()
+	}
+}
+
+// flush handles the Wayland events already read, requests a frame if one
+// is needed and sends the pending requests, as the loop does before it
+// waits
+func (app *App) flush() error {
+	if err := app.waylandClient.DispatchPending(); err != nil {
+		return fmt.Errorf("failed to dispatch Wayland events: %w", err)
+	}
+	if err := app.requestFrame(); err != nil {
+		log.Printf("Error requesting frame: %v", err)
+	}
+	if err := app.waylandClient.Flush(); err != nil {
+		return fmt.Errorf("failed to flush Wayland client: %w", err)
+	}
+	return nil
+}
+
+// handleEvents passes the queued input and shell events to their handlers
+func (app *App) handleEvents() {
+	if err := app.eventDispatcher.DispatchQueued(); err != nil {
+		log.Printf("Error processing events: %v", err)
+	}
+}
+
+// handleFrame draws the next frame when a frame callback arrives
+func (app *App) handleFrame() {
+	app.framePending = false
+	if err := app.render(); err != nil {
+		log.Printf("Error rendering: %v", err)
+	}
+}
+
+// setClock makes the timers and the main loop keep time with clock
+func (app *App) setClock(clock wayland.Clock) {
+	app.clock = clock
+	app.timers.setClock(clock)
+}
+
+// requestFrame asks for a frame callback when a widget is damaged and no
+// callback is outstanding
+func (app *App) requestFrame() error {
+	if app.hidden || app.framePending || !app.damaged() {
+		return nil
+	}
+	if err := app.waylandClient.RequestFrame(); err != nil {
+		return err
+	}
+	app.framePending = true
+	return nil
+}
+
+// damaged reports whether any widget needs redrawing
+func (app *App) damaged() bool {
+	for _, widget := range app.widgets {
+		if len(widget.Damage()) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 // initialize sets up the application
 func (app *App) initialize() error {
 	// Initialize Wayland client
@@ -85,12 +241,18 @@ func (app *App) initialize() error {
 		return fmt.Errorf("failed to create Wayland client: %w", err)
 	}
 	app.waylandClient = client
+	if clocked, ok := client.(clockedClient); ok {
+		app.setClock(clocked.Clock())
+	}
 
-	// Initialize event dispatcher
+	// Initialize event dispatcher; the client feeds it seat input
 	app.eventDispatcher = wayland.NewEventDispatcher()
+	client.SetEventDispatcher(app.eventDispatcher)
 
-	// Initialize renderer (OpenGL by default)
-	app.renderer = &render.OpenGLRenderer{}
+	// Frames are drawn in software and presented through shared-memory
+	// buffers; the OpenGL backend cannot present yet
+	layout := app.keyboard.GetLayout()
+	app.renderer = render.NewSoftwareRenderer(layout.Width, layout.Height)
 	if err := app.renderer.Initialize(); err != nil {
 		return fmt.Errorf("failed to initialize renderer: %w", err)
 	}
@@ -102,11 +264,58 @@ func (app *App) initialize() error {
 	// Setup event handlers
 	app.setupEventHandlers()
 
+	// Create the surface frames are presented on; its configure is
+	// handled once the main loop starts
+	config := wayland.DefaultSurfaceConfig(layout.Width, layout.Height)
+	if app.surfaceConfig != nil {
+		config = *app.surfaceConfig
+	}
+	if err := client.CreateSurface(config); err != nil {
+		return fmt.Errorf("failed to create surface: %w", err)
+	}
+
+	// The keyboard is still useful to look at without a way to type, so no
+	// key output backend being available is not an error
+	keyOutput := app.keyOutput
+	if len(keyOutput) == 0 {
+		keyOutput = inject.DefaultBackends
+	}
+	injector, backend, err := inject.Open(keyOutput, inject.Config{Wayland: client})
+	if err != nil {
+		log.Printf("Typing disabled: %v", err)
+	} else {
+		log.Printf("Typing through the %s key output backend", backend)
+		app.keyInjector = injector
+		app.detachKeys = inject.Attach(app.keyboard, injector)
+	}
+
+	// With an input method the keyboard is shown only while a text input
+	// is focused; without one it is always shown
+	im, err := client.InputMethod()
+	if err != nil {
+		log.Printf("Input method unavailable, keeping the keyboard shown: %v", err)
+	} else {
+		app.inputMethod = im
+		app.hidden = true
+		if committer, ok := app.keyInjector.(textCommitter); ok {
+			committer.SetInputMethod(im)
+		}
+	}
+
 	return nil
 }
 
 // cleanup cleans up application resources
 func (app *App) cleanup() {
+	if app.keyInjector != nil {
+		app.detachKeys()
+		app.keyInjector.Close()
+		app.keyInjector = nil
+	}
+	if app.inputMethod != nil {
+		app.inputMethod.Close()
+		app.inputMethod = nil
+	}
 	if app.renderer != nil {
 		app.renderer.Close()
 	}
@@ -115,110 +324,198 @@ func (app *App) cleanup() {
 	}
 }
 
-// processEvents processes pending events
-func (app *App) processEvents() error {
-	select {
-	case event := <-app.eventDispatcher.EventChannel():
-		return app.handleEvent(event)
-	default:
+// handlePointerButton handles pointer button events (mouse clicks)
+func (app *App) handlePointerButton(event *wayland.PointerButtonEvent) error {
+	// Forward to keyboard widget
+	return app.keyboardWidget.HandlePointerButton(event)
+}
+
+// handleKeyboardEvent handles keyboard events
+func (app *App) handleKeyboardEvent(event *wayland.KeyboardEvent) error {
+	// Forward to keyboard widget
+	return app.keyboardWidget.HandleKeyboardEvent(event)
+}
+
+// handleShellEvent sizes the frame to the configured surface, keeping the
+// keyboard centred along its bottom edge, and stops when the compositor
+// closes the surface
+func (app *App) handleShellEvent(shellEvent *wayland.ShellEvent) error {
+	if shellEvent.Closed {
+		log.Println("Keyboard surface closed by the compositor")
+		app.Stop()
 		return nil
 	}
+
+	width, height := app.keyboardWidget.GetSize()
+	if shellEvent.Width > 0 {
+		width = int(shellEvent.Width)
+	}
+	if shellEvent.Height > 0 {
+		height = int(shellEvent.Height)
+	}
+	if width == app.surfaceWidth && height == app.surfaceHeight {
+		return nil
+	}
+	app.surfaceWidth, app.surfaceHeight = width, height
+
+	kbWidth, kbHeight := app.keyboardWidget.GetSize()
+	app.keyboardWidget.SetPosition((width-kbWidth)/2, height-kbHeight)
+	return nil
 }
 
-// handleEvent handles a single event
-func (app *App) handleEvent(event *wayland.Event) error {
-	switch event.Type {
-	case wayland.EventTypePointer:
-		return app.handlePointerEvent(event)
-	case wayland.EventTypeKeyboard:
-		return app.handleKeyboardEvent(event)
-	case wayland.EventTypeTouch:
-		return app.handleTouchEvent(event)
+// handleScaleEvent redraws the keyboard at the scale of the output it is
+// shown on
+func (app *App) handleScaleEvent(scaleEvent *wayland.ScaleEvent) error {
+	if scaleEvent.Scale == app.scale {
+		return nil
 	}
+	app.scale = scaleEvent.Scale
+	app.keyboardWidget.Invalidate()
 	return nil
 }
 
-// handlePointerEvent handles pointer events (mouse clicks)
-func (app *App) handlePointerEvent(event *wayland.Event) error {
-	pointerEvent, ok := event.Data.(*wayland.PointerEvent)
-	if !ok {
-		return fmt.Errorf("invalid pointer event data")
+// handleInputMethodEvent shows the keyboard while a text input is focused,
+// passing its content type to the keyboard to pick the layout for it, and
+// hides it otherwise. If
+// another input method serves the seat the keyboard is shown for good.
+func (app *App) handleInputMethodEvent(imEvent *wayland.InputMethodEvent) error {
+	if imEvent.Unavailable {
+		log.Println("Another input method serves the seat, keeping the keyboard shown")
+		if committer, ok := app.keyInjector.(textCommitter); ok {
+			committer.SetInputMethod(nil)
+		}
+		app.inputMethod.Close()
+		app.inputMethod = nil
+		return app.setVisible(true)
 	}
 
-	// Forward to keyboard widget
-	return app.keyboardWidget.HandlePointerEvent(pointerEvent)
+	contentType := imEvent.ContentType
+	if !imEvent.Active {
+		contentType = keyboard.ContentType{}
+	}
+	// A missing layout for the field leaves the current one shown
+	if err := app.keyboard.SetContentType(contentType); err != nil {
+		log.Printf("Failed to switch layout for %s field: %v", contentType.Purpose, err)
+	}
+	return app.setVisible(imEvent.Active)
 }
 
-// handleKeyboardEvent handles keyboard events
-func (app *App) handleKeyboardEvent(event *wayland.Event) error {
-	keyboardEvent, ok := event.Data.(*wayland.KeyboardEvent)
-	if !ok {
-		return fmt.Errorf("invalid keyboard event data")
+// setVisible maps or unmaps the keyboard surface. A shown keyboard is
+// redrawn in full, and any frame callback lost while it was hidden is
+// abandoned.
+func (app *App) setVisible(visible bool) error {
+	if visible != app.hidden {
+		return nil
 	}
-
-	// Forward to keyboard widget
-	return app.keyboardWidget.HandleKeyboardEvent(keyboardEvent)
+	if err := app.waylandClient.SetVisible(visible); err != nil {
+		return fmt.Errorf("failed to change keyboard visibility: %w", err)
+	}
+	app.hidden = !visible
+	if visible {
+		app.framePending = false
+		app.keyboardWidget.Invalidate()
+	}
+	return nil
 }
 
-// handleTouchEvent handles touch events
-func (app *App) handleTouchEvent(event *wayland.Event) error {
-	touchEvent, ok := event.Data.(*wayland.TouchEvent)
-	if !ok {
-		return fmt.Errorf("invalid touch event data")
+// surfaceSize returns the size frames are drawn at: the configured surface
+// size, or the keyboard size before the first configure
+func (app *App) surfaceSize() (int, int) {
+	if app.surfaceWidth > 0 && app.surfaceHeight > 0 {
+		return app.surfaceWidth, app.surfaceHeight
 	}
+	return app.keyboardWidget.GetSize()
+}
 
+// handleTouchEvent handles touch events
+func (app *App) handleTouchEvent(event *wayland.TouchEvent) error {
 	// Forward to keyboard widget
-	return app.keyboardWidget.HandleTouchEvent(touchEvent)
+	return app.keyboardWidget.HandleTouchEvent(event)
 }
 
-// render renders the application
+// render redraws the damaged parts of the application and reports the
+// damage to the compositor. Nothing is drawn when no widget changed.
 func (app *App) render() error {
-	// Render all widgets
+	// A callback requested before the keyboard was hidden must not map it
+	if app.hidden {
+		return nil
+	}
+
+	var damage []image.Rectangle
+	for _, widget := range app.widgets {
+		damage = append(damage, widget.Damage()...)
+	}
+	if len(damage) == 0 {
+		return nil
+	}
+
+	width, height := app.surfaceSize()
+	if err := app.waylandClient.SetScale(app.scale); err != nil {
+		return fmt.Errorf("failed to set frame scale: %w", err)
+	}
+	if err := app.renderer.BeginFrame(scaled(width, app.scale), scaled(height, app.scale)); err != nil {
+		return fmt.Errorf("failed to begin frame: %w", err)
+	}
+
+	// Render all widgets, scaled from surface to buffer pixels
+	app.renderer.PushTransform(render.Scale(float32(app.scale), float32(app.scale)))
 	for _, widget := range app.widgets {
 		if err := widget.Render(); err != nil {
 			return fmt.Errorf("failed to render widget: %w", err)
 		}
 	}
+	app.renderer.PopTransform()
 
-	// Flush renderer
-	if err := app.waylandClient.Flush(); err != nil {
-		return fmt.Errorf("failed to flush Wayland client: %w", err)
+	if err := app.renderer.EndFrame(); err != nil {
+		return fmt.Errorf("failed to end frame: %w", err)
 	}
 
-	return nil
-}
+	for _, region := range damage {
+		if err := app.waylandClient.Damage(region.Min.X, region.Min.Y, region.Dx(), region.Dy()); err != nil {
+			return fmt.Errorf("failed to report damage: %w", err)
+		}
+	}
 
-// setupEventHandlers sets up event handlers for the application
-func (app *App) setupEventHandlers() {
-	// Register event handlers with the event dispatcher
-	app.eventDispatcher.RegisterHandler(wayland.EventTypePointer, &PointerEventHandler{app: app})
-	app.eventDispatcher.RegisterHandler(wayland.EventTypeKeyboard, &KeyboardEventHandler{app: app})
-	app.eventDispatcher.RegisterHandler(wayland.EventTypeTouch, &TouchEventHandler{app: app})
-}
+	// Renderers that draw into memory are presented through shm buffers
+	if canvas, ok := app.renderer.(imageRenderer); ok {
+		if err := app.waylandClient.Attach(canvas.Image()); err != nil {
+			return fmt.Errorf("failed to present frame: %w", err)
+		}
+	}
 
-// PointerEventHandler handles pointer events
-type PointerEventHandler struct {
-	app *App
+	return nil
 }
 
-func (h *PointerEventHandler) HandleEvent(event *wayland.Event) error {
-	return h.app.handlePointerEvent(event)
+// scaled returns a length in surface pixels in the pixels of a frame drawn
+// at scale
+func scaled(length int, scale float64) int {
+	return int(math.Round(float64(length) * scale))
 }
 
-// KeyboardEventHandler handles keyboard events
-type KeyboardEventHandler struct {
-	app *App
+// imageRenderer is implemented by renderers that draw into memory
+type imageRenderer interface {
+	Image() *image.RGBA
 }
 
-func (h *KeyboardEventHandler) HandleEvent(event *wayland.Event) error {
-	return h.app.handleKeyboardEvent(event)
+// textCommitter is implemented by key injectors that commit text through
+// the input method while a text input is focused
+type textCommitter interface {
+	SetInputMethod(im *wayland.InputMethod)
 }
 
-// TouchEventHandler handles touch events
-type TouchEventHandler struct {
-	app *App
+// clockedClient is implemented by clients that keep their own time, as the
+// mock client does when tests give it a virtual clock
+type clockedClient interface {
+	Clock() wayland.Clock
 }
 
-func (h *TouchEventHandler) HandleEvent(event *wayland.Event) error {
-	return h.app.handleTouchEvent(event)
+// setupEventHandlers sets up event handlers for the application
+func (app *App) setupEventHandlers() {
+	// Register event handlers with the event dispatcher
+	wayland.Subscribe(app.eventDispatcher, app.handlePointerButton)
+	wayland.Subscribe(app.eventDispatcher, app.handleKeyboardEvent)
+	wayland.Subscribe(app.eventDispatcher, app.handleTouchEvent)
+	wayland.Subscribe(app.eventDispatcher, app.handleShellEvent)
+	wayland.Subscribe(app.eventDispatcher, app.handleScaleEvent)
+	wayland.Subscribe(app.eventDispatcher, app.handleInputMethodEvent)
 }
@@ -0,0 +1,559 @@
+package ui
+
+import (
+	"context"
+	"image"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/iotcore/osk-iotcore/internal/render"
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+	"github.com/iotcore/osk-iotcore/internal/wayland/waylandtest"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// newTestApp wires an App to a mock client and a recording renderer
+// without touching the environment
+func newTestApp(t *testing.T) (*App, *recordingRenderer) {
+	t.Helper()
+	kb := newTestKeyboard(t)
+	r := newRecordingRenderer(render.CapRoundedRects | render.CapClipping | render.CapTransforms)
+
+	app := NewApp(kb)
+	app.renderer = r
+	app.waylandClient = wayland.NewMockClient()
+	app.eventDispatcher = wayland.NewEventDispatcher()
+	app.keyboardWidget = NewKeyboardWidget(kb, r)
+	app.widgets = append(app.widgets, app.keyboardWidget)
+	app.setupEventHandlers()
+	return app, r
+}
+
+// runLoop runs the main loop until ctx is done and fails the test if it
+// does not return promptly
+func runLoop(t *testing.T, app *App, ctx context.Context) {
+	t.Helper()
+	done := make(chan error, 1)
+	go func() { done <- app.loop(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("loop: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("loop did not stop after cancellation")
+	}
+}
+
+func TestLoopRendersOnlyOnFrameCallbacks(t *testing.T) {
+	app, r := newTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The first frame is drawn once; afterwards the loop idles
+	app.AfterFunc(200*time.Millisecond, cancel)
+	runLoop(t, app, ctx)
+
+	if r.calls["BeginFrame"] != 1 {
+		t.Errorf("BeginFrame called %d times while idle, want 1", r.calls["BeginFrame"])
+	}
+}
+
+func TestLoopHandlesEventsAndTimersWithoutWayland(t *testing.T) {
+	app, r := newTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var key *keyboard.Key
+	for _, k := range app.keyboard.GetLayout().Keys {
+		if k.ID == "g" {
+			key = k
+		}
+	}
+
+	// Internal events are handled as soon as they are sent, not when the
+	// next Wayland event arrives
+	app.AfterFunc(50*time.Millisecond, func() {
+		app.eventDispatcher.SendEvent(&wayland.PointerButtonEvent{X: int32(key.X + 5), Y: int32(key.Y + 5), Button: 1, State: 1})
+	})
+
+	fired := time.Time{}
+	start := time.Now()
+	app.AfterFunc(100*time.Millisecond, func() { fired = time.Now() })
+	app.AfterFunc(200*time.Millisecond, cancel)
+	stopped := app.AfterFunc(150*time.Millisecond, func() { t.Error("stopped timer fired") })
+	if !stopped.Stop() {
+		t.Error("Stop should report a pending timer")
+	}
+
+	runLoop(t, app, ctx)
+
+	if app.keyboard.GetKeyState("g") != keyboard.KeyStatePressed {
+		t.Error("pointer event was not delivered to the keyboard widget")
+	}
+	if r.calls["BeginFrame"] != 2 {
+		t.Errorf("BeginFrame called %d times, want the first frame and one for the key press", r.calls["BeginFrame"])
+	}
+	if fired.IsZero() || fired.Sub(start) < 100*time.Millisecond {
+		t.Errorf("timer fired at %v, want after 100ms", fired.Sub(start))
+	}
+}
+
+// presentingClient records the frames attached to the surface
+type presentingClient struct {
+	*wayland.MockClient
+	frames []*image.RGBA
+}
+
+func (c *presentingClient) Attach(img *image.RGBA) error {
+	c.frames = append(c.frames, img)
+	return c.MockClient.Attach(img)
+}
+
+func TestLoopPresentsSoftwareFrames(t *testing.T) {
+	app, _ := newTestApp(t)
+	layout := app.keyboard.GetLayout()
+	renderer := render.NewSoftwareRenderer(layout.Width, layout.Height)
+	if err := renderer.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	client := &presentingClient{MockClient: wayland.NewMockClient()}
+	app.renderer = renderer
+	app.waylandClient = client
+	app.keyboardWidget = NewKeyboardWidget(app.keyboard, renderer)
+	app.widgets = []Widget{app.keyboardWidget}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	app.AfterFunc(50*time.Millisecond, func() {
+		app.keyboard.PressKey("g")
+		app.AfterFunc(200*time.Millisecond, cancel)
+	})
+	runLoop(t, app, ctx)
+
+	if len(client.frames) != 2 {
+		t.Fatalf("presented %d frames, want the first frame and one for the key press", len(client.frames))
+	}
+	if got := client.frames[0].Bounds().Size(); got != image.Pt(layout.Width, layout.Height) {
+		t.Errorf("presented a %v frame, want %dx%d", got, layout.Width, layout.Height)
+	}
+}
+
+// newScriptedApp wires an App to a mock client on a virtual clock, so that
+// tests script the input and decide when time passes
+func newScriptedApp(t *testing.T) (*App, *wayland.MockClient, *wayland.VirtualClock, *recordingRenderer) {
+	t.Helper()
+	app, r := newTestApp(t)
+	clock := wayland.NewVirtualClock()
+	client := wayland.NewMockClient()
+	client.SetClock(clock)
+	client.SetEventDispatcher(app.eventDispatcher)
+	app.waylandClient = client
+	app.setClock(clock)
+	return app, client, clock, r
+}
+
+// settle does what the main loop would at the current virtual time, until
+// nothing is left to do. Timers run once the input due has been handled.
+func settle(t *testing.T, app *App) {
+	t.Helper()
+	for {
+		if err := app.flush(); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-app.waylandClient.Events():
+			if err := app.waylandClient.Dispatch(); err != nil {
+				t.Fatal(err)
+			}
+		case <-app.eventDispatcher.Events():
+			app.handleEvents()
+		case <-app.waylandClient.Frames():
+			app.handleFrame()
+		default:
+			if d, ok := app.timers.next(app.clock.Now()); !ok || d > 0 {
+				return
+			}
+			app.timers.run(app.clock.Now())
+		}
+	}
+}
+
+// advance moves the virtual clock forward by d, stopping at every deadline
+// on the way to settle the app
+func advance(t *testing.T, app *App, clock *wayland.VirtualClock, d time.Duration) {
+	t.Helper()
+	end := clock.Now().Add(d)
+	for {
+		settle(t, app)
+		now := clock.Now()
+		if !now.Before(end) {
+			return
+		}
+		step := end.Sub(now)
+		if next, ok := clock.Next(); ok && next < step {
+			step = next
+		}
+		if next, ok := app.timers.next(now); ok && next < step {
+			step = next
+		}
+		clock.Advance(step)
+	}
+}
+
+func TestScriptedInputAndTimersRunOnVirtualClock(t *testing.T) {
+	app, client, clock, r := newScriptedApp(t)
+	var key *keyboard.Key
+	for _, k := range app.keyboard.GetLayout().Keys {
+		if k.ID == "g" {
+			key = k
+		}
+	}
+	x, y := int32(key.X+5), int32(key.Y+5)
+
+	client.Queue(100*time.Millisecond, &wayland.PointerButtonEvent{X: x, Y: y, Button: 1, State: 1})
+	client.Queue(300*time.Millisecond, &wayland.PointerButtonEvent{X: x, Y: y, Button: 1, State: 0})
+	var states []keyboard.KeyState
+	for _, at := range []time.Duration{99, 100, 299, 300} {
+		app.AfterFunc(at*time.Millisecond, func() { states = append(states, app.keyboard.GetKeyState("g")) })
+	}
+
+	// The first frame is drawn one frame interval in, and the next only
+	// once the press damaged the key
+	advance(t, app, clock, 50*time.Millisecond)
+	if r.calls["BeginFrame"] != 1 {
+		t.Fatalf("drew %d frames by 50ms, want 1", r.calls["BeginFrame"])
+	}
+	flushes := client.Flushes()
+	advance(t, app, clock, 450*time.Millisecond)
+
+	// Input falls due before timers with the same deadline
+	want := []keyboard.KeyState{
+		keyboard.KeyStateReleased, keyboard.KeyStatePressed, keyboard.KeyStatePressed, keyboard.KeyStateReleased,
+	}
+	if !slices.Equal(states, want) {
+		t.Errorf("key states = %v, want %v", states, want)
+	}
+	if r.calls["BeginFrame"] != 3 {
+		t.Errorf("drew %d frames, want the first one and one each for the press and release", r.calls["BeginFrame"])
+	}
+	if client.Flushes() == flushes {
+		t.Error("the client was not flushed while handling the input")
+	}
+}
+
+func TestLoopStopsTheTimeoutsItReplaces(t *testing.T) {
+	app, _, clock, _ := newScriptedApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Each timer moves the earliest deadline, so the loop waits on a new
+	// timeout every time
+	waiting := make(chan int, 1)
+	go func() {
+		for i := range 50 {
+			app.AfterFunc(time.Hour-time.Duration(i)*time.Minute, func() {})
+			for len(app.timers.changed()) > 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		waiting <- clock.Waiting()
+		cancel()
+	}()
+	runLoop(t, app, ctx)
+
+	// Left are the current timeout and the first frame callback
+	if n := <-waiting; n > 2 {
+		t.Errorf("clock holds %d waiters, want the replaced timeouts removed", n)
+	}
+}
+
+func TestShellConfigureDocksKeyboardAlongBottom(t *testing.T) {
+	app, r := newTestApp(t)
+	width, height := app.keyboardWidget.GetSize()
+
+	configure := &wayland.ShellEvent{Width: uint32(width + 200), Height: uint32(height + 50)}
+	if err := app.eventDispatcher.DispatchEvent(configure); err != nil {
+		t.Fatalf("DispatchEvent: %v", err)
+	}
+	if x, y := app.keyboardWidget.GetPosition(); x != 100 || y != 50 {
+		t.Errorf("keyboard at %d,%d, want centred on the bottom edge at 100,50", x, y)
+	}
+	if err := app.render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if w, h := app.surfaceSize(); w != width+200 || h != height+50 || r.calls["BeginFrame"] != 1 {
+		t.Errorf("drew %d frames at %dx%d, want one at the configured size", r.calls["BeginFrame"], w, h)
+	}
+
+	app.running = true
+	closed := &wayland.ShellEvent{Closed: true}
+	if err := app.eventDispatcher.DispatchEvent(closed); err != nil {
+		t.Fatalf("DispatchEvent: %v", err)
+	}
+	if app.IsRunning() {
+		t.Error("closing the surface should stop the app")
+	}
+}
+
+func TestScaleEventDrawsLargerFramesWithoutMovingKeys(t *testing.T) {
+	app, _ := newTestApp(t)
+	width, height := app.keyboardWidget.GetSize()
+	renderer := render.NewSoftwareRenderer(width, height)
+	if err := renderer.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	client := wayland.NewMockClient()
+	app.renderer = renderer
+	app.waylandClient = client
+	app.keyboardWidget = NewKeyboardWidget(app.keyboard, renderer)
+	app.widgets = []Widget{app.keyboardWidget}
+
+	if err := app.eventDispatcher.DispatchEvent(&wayland.ScaleEvent{Scale: 2}); err != nil {
+		t.Fatalf("DispatchEvent: %v", err)
+	}
+	if err := app.render(); err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if got := renderer.Image().Bounds().Size(); got != image.Pt(2*width, 2*height) || client.Scale() != 2 {
+		t.Errorf("drew a %v frame at scale %v, want %dx%d at 2", got, client.Scale(), 2*width, 2*height)
+	}
+
+	// Input stays in surface pixels
+	var key *keyboard.Key
+	for _, k := range app.keyboard.GetLayout().Keys {
+		if k.ID == "g" {
+			key = k
+		}
+	}
+	press := &wayland.PointerButtonEvent{X: int32(key.X + 5), Y: int32(key.Y + 5), Button: 1, State: 1}
+	if err := app.eventDispatcher.DispatchEvent(press); err != nil {
+		t.Fatalf("DispatchEvent: %v", err)
+	}
+	if state := app.keyboard.GetKeyState("g"); state != keyboard.KeyStatePressed {
+		t.Errorf("key g is %v after a press on it, want pressed", state)
+	}
+}
+
+// visibilityClient records the visibility requested for the surface
+type visibilityClient struct {
+	*wayland.MockClient
+	visible []bool
+}
+
+func (c *visibilityClient) SetVisible(visible bool) error {
+	c.visible = append(c.visible, visible)
+	return c.MockClient.SetVisible(visible)
+}
+
+// focusField reports a text field of the given purpose gaining or losing
+// focus through the input method
+func focusField(t *testing.T, app *App, active bool, purpose keyboard.ContentPurpose) {
+	t.Helper()
+	err := app.eventDispatcher.DispatchEvent(&wayland.InputMethodEvent{
+		Active: active, ContentType: keyboard.ContentType{Purpose: purpose},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestInputMethodFocusShowsAndHidesKeyboard(t *testing.T) {
+	app, r := newTestApp(t)
+	client := &visibilityClient{MockClient: wayland.NewMockClient()}
+	app.waylandClient = client
+	app.hidden = true
+
+	if err := app.render(); err != nil {
+		t.Fatal(err)
+	}
+	if r.calls["BeginFrame"] != 0 {
+		t.Error("hidden keyboard was drawn")
+	}
+
+	focus := func(active bool, purpose keyboard.ContentPurpose) {
+		t.Helper()
+		focusField(t, app, active, purpose)
+	}
+
+	focus(true, keyboard.ContentPurposeEmail)
+	if got := app.keyboard.ContentType().Purpose; got != keyboard.ContentPurposeEmail {
+		t.Errorf("content purpose = %d, want email", got)
+	}
+	if err := app.render(); err != nil {
+		t.Fatal(err)
+	}
+	if r.calls["BeginFrame"] != 1 {
+		t.Error("shown keyboard was not drawn")
+	}
+
+	// Surrounding text updates for the same field change nothing
+	focus(true, keyboard.ContentPurposeEmail)
+	focus(false, keyboard.ContentPurposeNormal)
+	if got := app.keyboard.ContentType(); got != (keyboard.ContentType{}) {
+		t.Errorf("content type = %+v after focus loss, want none", got)
+	}
+	if want := []bool{true, false}; !slices.Equal(client.visible, want) {
+		t.Errorf("SetVisible calls = %v, want %v", client.visible, want)
+	}
+}
+
+func TestContentPurposeSelectsLayoutAndRestoresUserChoice(t *testing.T) {
+	// Layouts other than the built-in QWERTY are loaded from the assets
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(".."); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	app, _ := newTestApp(t)
+	if err := app.keyboard.SwitchLayout("style_one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.render(); err != nil {
+		t.Fatal(err)
+	}
+	app.keyboardWidget.SetPosition(100, 200)
+	expectLayout := func(want string) {
+		t.Helper()
+		if got := app.keyboard.GetCurrentLayoutName(); got != want {
+			t.Errorf("layout = %s, want %s", got, want)
+		}
+	}
+
+	focusField(t, app, true, keyboard.ContentPurposePIN)
+	expectLayout("pin")
+	if err := app.render(); err != nil {
+		t.Fatal(err)
+	}
+	// The smaller pad stays centred on the bottom edge of the keyboard
+	x, y := app.keyboardWidget.GetPosition()
+	if w, h := app.keyboardWidget.GetSize(); w != 310 || h != 290 || x != 345 || y != 210 {
+		t.Errorf("keyboard at %dx%d+%d+%d, want 310x290+345+210", w, h, x, y)
+	}
+
+	focusField(t, app, true, keyboard.ContentPurposeEmail)
+	expectLayout("web")
+	focusField(t, app, true, keyboard.ContentPurposeNormal)
+	expectLayout("style_one")
+
+	// A layout the user picks over the field's layout is kept
+	focusField(t, app, true, keyboard.ContentPurposePhone)
+	expectLayout("phone")
+	if err := app.keyboard.SwitchLayout("dvorak"); err != nil {
+		t.Fatal(err)
+	}
+	focusField(t, app, false, keyboard.ContentPurposeNormal)
+	expectLayout("dvorak")
+
+	// Fields can be configured to keep the user's layout
+	app.keyboard.SetContentLayouts([]keyboard.ContentLayout{{Purpose: keyboard.ContentPurposeEmail}})
+	focusField(t, app, true, keyboard.ContentPurposeEmail)
+	expectLayout("dvorak")
+}
+
+func TestRunShowsKeyboardAndTypesOnTheCompositor(t *testing.T) {
+	compositor := waylandtest.NewCompositor(t)
+	compositor.Setenv()
+
+	kb := newTestKeyboard(t)
+	layout := kb.GetLayout()
+	var key *keyboard.Key
+	for _, k := range layout.Keys {
+		if k.ID == "g" {
+			key = k
+		}
+	}
+	if key == nil {
+		t.Fatal("layout has no g key")
+	}
+
+	app := NewApp(kb)
+	app.SetKeyOutput([]string{"wayland"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx) }()
+
+	// With an input method the keyboard stays hidden until a text input
+	// is focused
+	compositor.Wait(func() bool {
+		state, ok := compositor.LayerSurface()
+		return ok && state.Acked
+	})
+	if frames := compositor.Frames(); len(frames) != 0 {
+		t.Fatalf("presented %d frames before a text input was focused", len(frames))
+	}
+	compositor.FocusTextInput("", uint32(keyboard.ContentPurposeNormal))
+	compositor.Wait(func() bool { return compositor.LastFrame() != nil })
+	if got := compositor.LastFrame().Image.Bounds(); got.Dx() != waylandtest.OutputWidth || got.Dy() != layout.Height {
+		t.Errorf("presented a %v frame, want %dx%d", got, waylandtest.OutputWidth, layout.Height)
+	}
+
+	// The keyboard is centred along the bottom of the output, and the
+	// tapped key is committed through the input method
+	left := (waylandtest.OutputWidth - layout.Width) / 2
+	compositor.Tap(float64(left+key.X+key.Width/2), float64(key.Y+key.Height/2))
+	compositor.Wait(func() bool { return len(compositor.Commits()) > 0 })
+	if commits := compositor.Commits(); commits[0].Text != "g" {
+		t.Errorf("committed %+v, want g", commits)
+	}
+
+	compositor.UnfocusTextInput()
+	compositor.Wait(func() bool {
+		state, _ := compositor.LayerSurface()
+		return !state.Mapped
+	})
+
+	compositor.CloseLayerSurface()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after the surface was closed")
+	}
+}
+
+func TestRunShowsKeyboardAgainWhenFocusReturns(t *testing.T) {
+	compositor := waylandtest.NewCompositor(t)
+	compositor.Setenv()
+
+	app := NewApp(newTestKeyboard(t))
+	app.SetKeyOutput([]string{"wayland"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- app.Run(ctx) }()
+
+	compositor.Wait(func() bool {
+		state, ok := compositor.LayerSurface()
+		return ok && state.Acked
+	})
+	compositor.FocusTextInput("", uint32(keyboard.ContentPurposeNormal))
+	compositor.Wait(func() bool { return compositor.LastFrame() != nil })
+	compositor.UnfocusTextInput()
+	compositor.Wait(func() bool {
+		state, _ := compositor.LayerSurface()
+		return !state.Mapped
+	})
+	shown := len(compositor.Frames())
+
+	// The surface is unmapped, so the frame that maps it again cannot
+	// wait for a frame callback
+	compositor.FocusTextInput("", uint32(keyboard.ContentPurposeNormal))
+	compositor.Wait(func() bool {
+		state, _ := compositor.LayerSurface()
+		return state.Mapped && len(compositor.Frames()) > shown
+	})
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return after it was cancelled")
+	}
+}
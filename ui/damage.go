@@ -0,0 +1,59 @@
+package ui
+
+import (
+	"image"
+)
+
+// maxDamageRegions is the number of separate regions kept before they are
+// collapsed into their bounding box
+const maxDamageRegions = 8
+
+// DamageTracker accumulates the regions of a surface that changed since the
+// last frame. Overlapping regions are merged so each pixel is redrawn once.
+type DamageTracker struct {
+	regions []image.Rectangle
+}
+
+// Add marks r as needing a redraw
+func (d *DamageTracker) Add(r image.Rectangle) {
+	if r.Empty() {
+		return
+	}
+
+	// Merge with every region r overlaps, repeating as the union grows
+	for merged := true; merged; {
+		merged = false
+		for i, region := range d.regions {
+			if region.Overlaps(r) {
+				r = r.Union(region)
+				d.regions = append(d.regions[:i], d.regions[i+1:]...)
+				merged = true
+				break
+			}
+		}
+	}
+	d.regions = append(d.regions, r)
+
+	if len(d.regions) > maxDamageRegions {
+		bounds := d.regions[0]
+		for _, region := range d.regions[1:] {
+			bounds = bounds.Union(region)
+		}
+		d.regions = append(d.regions[:0], bounds)
+	}
+}
+
+// Empty reports whether nothing needs redrawing
+func (d *DamageTracker) Empty() bool {
+	return len(d.regions) == 0
+}
+
+// Regions returns a copy of the damaged regions
+func (d *DamageTracker) Regions() []image.Rectangle {
+	return append([]image.Rectangle(nil), d.regions...)
+}
+
+// Reset clears all damage after a frame has been drawn
+func (d *DamageTracker) Reset() {
+	d.regions = d.regions[:0]
+}
@@ -0,0 +1,47 @@
+package ui
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDamageTrackerMergesOverlappingRegions(t *testing.T) {
+	var d DamageTracker
+	if !d.Empty() {
+		t.Fatal("new tracker should be empty")
+	}
+
+	d.Add(image.Rect(0, 0, 10, 10))
+	d.Add(image.Rect(50, 50, 60, 60))
+	d.Add(image.Rect(5, 5, 20, 20))
+	d.Add(image.Rectangle{})
+
+	regions := d.Regions()
+	if len(regions) != 2 {
+		t.Fatalf("regions = %v, want 2", regions)
+	}
+	if !image.Rect(0, 0, 20, 20).In(regions[0]) && !image.Rect(0, 0, 20, 20).In(regions[1]) {
+		t.Errorf("overlapping regions were not merged: %v", regions)
+	}
+
+	// A region bridging the two merges everything
+	d.Add(image.Rect(15, 15, 55, 55))
+	if regions := d.Regions(); len(regions) != 1 || regions[0] != image.Rect(0, 0, 60, 60) {
+		t.Errorf("regions = %v, want [(0,0)-(60,60)]", regions)
+	}
+
+	d.Reset()
+	if !d.Empty() {
+		t.Error("Reset should clear all damage")
+	}
+}
+
+func TestDamageTrackerCollapsesManyRegions(t *testing.T) {
+	var d DamageTracker
+	for i := 0; i <= maxDamageRegions; i++ {
+		d.Add(image.Rect(i*20, 0, i*20+10, 10))
+	}
+	if regions := d.Regions(); len(regions) != 1 {
+		t.Errorf("regions = %v, want a single bounding box", regions)
+	}
+}
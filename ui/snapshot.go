@@ -0,0 +1,38 @@
+package ui
+
+import (
+	"fmt"
+	"image"
+
+	"github.com/iotcore/osk-iotcore/internal/render"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// Snapshot renders a layout with the given theme headlessly using the
+// software renderer. It needs no display server and is used for
+// screenshots and visual regression tests.
+func Snapshot(layout *keyboard.Layout, theme *keyboard.Theme) (*image.RGBA, error) {
+	renderer := render.NewSoftwareRenderer(layout.Width, layout.Height)
+	if err := renderer.Initialize(); err != nil {
+		return nil, fmt.Errorf("failed to initialize software renderer: %w", err)
+	}
+	defer renderer.Close()
+
+	widget := &KeyboardWidget{
+		renderer: renderer,
+		width:    layout.Width,
+		height:   layout.Height,
+	}
+
+	if err := renderer.BeginFrame(layout.Width, layout.Height); err != nil {
+		return nil, err
+	}
+	if err := widget.renderLayout(layout, theme); err != nil {
+		return nil, err
+	}
+	if err := renderer.EndFrame(); err != nil {
+		return nil, err
+	}
+
+	return renderer.Image(), nil
+}
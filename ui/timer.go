@@ -0,0 +1,136 @@
+package ui
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+)
+
+// Timer is a callback scheduled on the application main loop, used for key
+// repeat, long-press detection and animations
+type Timer struct {
+	when  time.Time
+	fn    func()
+	index int
+	queue *timerQueue
+}
+
+// Stop cancels the timer. It reports whether the timer was still pending.
+func (t *Timer) Stop() bool {
+	q := t.queue
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if t.index < 0 {
+		return false
+	}
+	heap.Remove(&q.timers, t.index)
+	q.notify()
+	return true
+}
+
+// timerQueue orders pending timers by deadline on its clock. Timers may be
+// added from any goroutine; the main loop is woken when the earliest
+// deadline changes.
+type timerQueue struct {
+	mutex  sync.Mutex
+	clock  wayland.Clock
+	timers timerHeap
+	wake   chan struct{}
+}
+
+// newTimerQueue creates an empty timer queue keeping time with clock
+func newTimerQueue(clock wayland.Clock) *timerQueue {
+	return &timerQueue{clock: clock, wake: make(chan struct{}, 1)}
+}
+
+// setClock makes the queue keep time with clock. Timers already pending
+// keep their deadlines.
+func (q *timerQueue) setClock(clock wayland.Clock) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.clock = clock
+	q.notify()
+}
+
+// add schedules fn to run after d
+func (q *timerQueue) add(d time.Duration, fn func()) *Timer {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	t := &Timer{when: q.clock.Now().Add(d), fn: fn, queue: q}
+	heap.Push(&q.timers, t)
+	if t.index == 0 {
+		q.notify()
+	}
+	return t
+}
+
+// next returns the time until the earliest deadline, if any
+func (q *timerQueue) next(now time.Time) (time.Duration, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if len(q.timers) == 0 {
+		return 0, false
+	}
+	return q.timers[0].when.Sub(now), true
+}
+
+// run calls every timer due at now, in deadline order. Callbacks run
+// without the lock held so they may schedule further timers.
+func (q *timerQueue) run(now time.Time) {
+	for {
+		q.mutex.Lock()
+		if len(q.timers) == 0 || q.timers[0].when.After(now) {
+			q.mutex.Unlock()
+			return
+		}
+		t := heap.Pop(&q.timers).(*Timer)
+		q.mutex.Unlock()
+
+		t.fn()
+	}
+}
+
+// changed returns a channel signalled when the earliest deadline changes
+func (q *timerQueue) changed() <-chan struct{} {
+	return q.wake
+}
+
+// notify wakes the main loop. Callers must hold the mutex.
+func (q *timerQueue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+}
+
+// timerHeap implements heap.Interface ordered by deadline
+type timerHeap []*Timer
+
+func (h timerHeap) Len() int           { return len(h) }
+func (h timerHeap) Less(i, j int) bool { return h[i].when.Before(h[j].when) }
+
+func (h timerHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *timerHeap) Push(x any) {
+	t := x.(*Timer)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *timerHeap) Pop() any {
+	old := *h
+	t := old[len(old)-1]
+	old[len(old)-1] = nil
+	t.index = -1
+	*h = old[:len(old)-1]
+	return t
+}
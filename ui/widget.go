@@ -2,6 +2,7 @@ package ui
 
 import (
 	"fmt"
+	"image"
 
 	"github.com/iotcore/osk-iotcore/internal/render"
 	"github.com/iotcore/osk-iotcore/internal/wayland"
@@ -10,12 +11,24 @@ import (
 
 // Widget represents a UI widget
 type Widget interface {
+	// Damage returns the surface regions that changed since the last
+	// Render. Nothing needs to be drawn when it is empty.
+	Damage() []image.Rectangle
+	// Render redraws the damaged regions and clears the damage
 	Render() error
-	HandlePointerEvent(event *wayland.PointerEvent) error
+	HandlePointerButton(event *wayland.PointerButtonEvent) error
 	HandleKeyboardEvent(event *wayland.KeyboardEvent) error
 	HandleTouchEvent(event *wayland.TouchEvent) error
 }
 
+const (
+	// labelMargin is the horizontal space kept between a label and the
+	// edge of its key
+	labelMargin = 4
+	// minLabelFontSize is the smallest size labels are shrunk to
+	minLabelFontSize = 6
+)
+
 // KeyboardWidget represents the on-screen keyboard widget
 type KeyboardWidget struct {
 	keyboard *keyboard.Keyboard
@@ -24,6 +37,21 @@ type KeyboardWidget struct {
 	y        int
 	width    int
 	height   int
+
+	// damage holds regions to redraw; drawn* record what the last frame
+	// showed so changes can be detected
+	damage      DamageTracker
+	drawnLayout *keyboard.Layout
+	drawnTheme  *keyboard.Theme
+	drawnStates map[*keyboard.Key]keyboard.KeyState
+	// sensitive hides which keys are pressed, so the keyboard does not show
+	// what is typed into a password
+	sensitive bool
+
+	// touches maps active touch points, and buttons held pointer buttons,
+	// to the key they pressed
+	touches map[int32]string
+	buttons map[uint32]string
 }
 
 // NewKeyboardWidget creates a new keyboard widget
@@ -39,19 +67,153 @@ func NewKeyboardWidget(kb *keyboard.Keyboard, renderer render.Renderer) *Keyboar
 	}
 }
 
-// Render renders the keyboard widget
+// Damage returns the surface regions that changed since the last Render
+func (kw *KeyboardWidget) Damage() []image.Rectangle {
+	kw.trackDamage(kw.keyboard.GetLayout(), kw.keyboard.GetTheme())
+	return kw.damage.Regions()
+}
+
+// Invalidate marks the whole widget as needing a redraw
+func (kw *KeyboardWidget) Invalidate() {
+	kw.damage.Add(kw.bounds())
+}
+
+// Render redraws the damaged parts of the keyboard widget. It draws nothing
+// when no key state, layout or theme changed since the last frame.
 func (kw *KeyboardWidget) Render() error {
-	layout := kw.keyboard.GetLayout()
-	theme := kw.keyboard.GetTheme()
+	layout, theme := kw.keyboard.GetLayout(), kw.keyboard.GetTheme()
+	kw.trackDamage(layout, theme)
+	if kw.damage.Empty() {
+		return nil
+	}
+
+	if err := kw.renderLayout(layout, theme, kw.damage.Regions()...); err != nil {
+		return err
+	}
+
+	kw.damage.Reset()
+	kw.drawnLayout, kw.drawnTheme = layout, theme
+	kw.drawnStates = make(map[*keyboard.Key]keyboard.KeyState, len(layout.Keys))
+	for _, key := range layout.Keys {
+		kw.drawnStates[key] = kw.shownState(key)
+	}
+	return nil
+}
+
+// trackDamage compares the layout, theme and key states with the last
+// frame and adds damage for whatever changed
+func (kw *KeyboardWidget) trackDamage(layout *keyboard.Layout, theme *keyboard.Theme) {
+	kw.sensitive = kw.keyboard.Sensitive()
+	if layout != kw.drawnLayout || theme != kw.drawnTheme {
+		// The area of a larger previous layout is cleared too
+		kw.Invalidate()
+		kw.fitLayout(layout)
+		kw.Invalidate()
+		return
+	}
+	for _, key := range layout.Keys {
+		if state, drawn := kw.drawnStates[key]; !drawn || state != kw.shownState(key) {
+			kw.damage.Add(kw.keyBounds(key, theme).Add(image.Pt(kw.x, kw.y)))
+		}
+	}
+}
+
+// fitLayout resizes the widget to a layout of a different size, keeping
+// its bottom edge and horizontal centre in place
+func (kw *KeyboardWidget) fitLayout(layout *keyboard.Layout) {
+	if layout.Width == kw.width && layout.Height == kw.height {
+		return
+	}
+	kw.x += (kw.width - layout.Width) / 2
+	kw.y += kw.height - layout.Height
+	kw.width, kw.height = layout.Width, layout.Height
+}
+
+// shownState returns the state key is drawn in: released while in
+// sensitive mode
+func (kw *KeyboardWidget) shownState(key *keyboard.Key) keyboard.KeyState {
+	if kw.sensitive {
+		return keyboard.KeyStateReleased
+	}
+	return key.State
+}
+
+// bounds returns the widget rectangle on the surface
+func (kw *KeyboardWidget) bounds() image.Rectangle {
+	return image.Rect(kw.x, kw.y, kw.x+kw.width, kw.y+kw.height)
+}
+
+// keyBounds returns the area a key touches relative to the widget,
+// including its border and shadow
+func (kw *KeyboardWidget) keyBounds(key *keyboard.Key, theme *keyboard.Theme) image.Rectangle {
+	rect := image.Rect(key.X, key.Y, key.X+key.Width, key.Y+key.Height)
+	bounds := rect.Inset(-(theme.BorderWidth/2 + 1))
+	if theme.ShadowEnabled {
+		shadow := rect.Add(image.Pt(theme.ShadowOffset[0], theme.ShadowOffset[1]))
+		bounds = bounds.Union(shadow.Inset(-(theme.ShadowBlur + 2)))
+	}
+	return bounds
+}
+
+// renderLayout renders a layout with a theme at the widget position. When
+// regions are given and the renderer supports clipping, only keys touching
+// them are redrawn and drawing is clipped to each region; otherwise the
+// whole widget is drawn.
+func (kw *KeyboardWidget) renderLayout(layout *keyboard.Layout, theme *keyboard.Theme, regions ...image.Rectangle) error {
+	caps := kw.renderer.Capabilities()
+
+	if err := kw.renderer.SetFont(theme.FontFamily(), float32(theme.FontSize)); err != nil {
+		return fmt.Errorf("failed to set font: %w", err)
+	}
+
+	if len(regions) == 0 || !caps.Has(render.CapClipping) {
+		return kw.renderRegion(layout, theme, kw.bounds())
+	}
+	for _, region := range regions {
+		if err := kw.renderRegion(layout, theme, region); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderRegion redraws the background and the keys touching region, a
+// rectangle on the surface
+func (kw *KeyboardWidget) renderRegion(layout *keyboard.Layout, theme *keyboard.Theme, region image.Rectangle) error {
+	caps := kw.renderer.Capabilities()
+
+	full := region.Eq(kw.bounds())
+	if !full {
+		kw.renderer.PushClip(region.Min.X, region.Min.Y, region.Dx(), region.Dy())
+		defer kw.renderer.PopClip()
+	}
+
+	// Translucent themes blend over the previous frame unless it is cleared
+	if err := kw.renderer.Clear([4]float32{0, 0, 0, 0}); err != nil {
+		return fmt.Errorf("failed to clear: %w", err)
+	}
+
+	// Position the keyboard through the transform stack when available,
+	// otherwise offset every draw call manually
+	offsetX, offsetY := kw.x, kw.y
+	if caps.Has(render.CapTransforms) {
+		kw.renderer.PushTransform(render.Translate(float32(kw.x), float32(kw.y)))
+		defer kw.renderer.PopTransform()
+		offsetX, offsetY = 0, 0
+	}
 
 	// Render background
-	if err := kw.renderBackground(theme); err != nil {
+	if err := kw.renderBackground(theme, offsetX, offsetY); err != nil {
 		return fmt.Errorf("failed to render background: %w", err)
 	}
 
-	// Render each key
+	// Render each key touching the region
+	local := region.Sub(image.Pt(kw.x, kw.y))
 	for _, key := range layout.Keys {
-		if err := kw.renderKey(key, theme); err != nil {
+		if !full && !kw.keyBounds(key, theme).Overlaps(local) {
+			continue
+		}
+		if err := kw.renderKey(key, theme, offsetX, offsetY); err != nil {
 			return fmt.Errorf("failed to render key %s: %w", key.ID, err)
 		}
 	}
@@ -60,66 +222,138 @@ func (kw *KeyboardWidget) Render() error {
 }
 
 // renderBackground renders the keyboard background
-func (kw *KeyboardWidget) renderBackground(theme *keyboard.Theme) error {
-	// Background rendering would be implemented here
-	// This is a placeholder for the actual rendering logic
-	return nil
+func (kw *KeyboardWidget) renderBackground(theme *keyboard.Theme, offsetX, offsetY int) error {
+	return kw.renderer.FillRoundedRect(offsetX, offsetY, kw.width, kw.height, 0, theme.BackgroundColor)
 }
 
 // renderKey renders a single key
-func (kw *KeyboardWidget) renderKey(key *keyboard.Key, theme *keyboard.Theme) error {
+func (kw *KeyboardWidget) renderKey(key *keyboard.Key, theme *keyboard.Theme, offsetX, offsetY int) error {
+	caps := kw.renderer.Capabilities()
+
 	// Choose color based on key state
 	var color [4]float32
-	switch key.State {
-	case keyboard.KeyStatePressed:
+	switch kw.shownState(key) {
+	case keyboard.KeyStatePressed, keyboard.KeyStateRepeating:
 		color = theme.KeyPressedColor
 	default:
 		color = theme.KeyColor
 	}
 
-	// Render key background (would use actual graphics API)
-	// This is a placeholder for the actual rendering logic
-	// TODO: Use the color variable when implementing actual rendering
-	_ = color
+	// Key face inset by the theme padding
+	pad := theme.KeyPadding
+	x, y := offsetX+key.X+pad, offsetY+key.Y+pad
+	w, h := key.Width-2*pad, key.Height-2*pad
+	if w <= 0 || h <= 0 {
+		x, y, w, h = offsetX+key.X, offsetY+key.Y, key.Width, key.Height
+	}
+
+	var radius float32
+	if caps.Has(render.CapRoundedRects) {
+		radius = float32(theme.BorderRadius)
+	}
+
+	if theme.ShadowEnabled && caps.Has(render.CapShadows) {
+		if err := kw.renderer.DrawShadow(x+theme.ShadowOffset[0], y+theme.ShadowOffset[1], w, h,
+			radius, float32(theme.ShadowBlur), theme.ShadowColor); err != nil {
+			return err
+		}
+	}
 
-	// Render key label
-	textX := key.X + key.Width/2
-	textY := key.Y + key.Height/2
-	return kw.renderer.RenderText(textX, textY, key.Label, theme.TextColor)
+	if err := kw.renderer.FillRoundedRect(x, y, w, h, radius, color); err != nil {
+		return err
+	}
+
+	if theme.BorderWidth > 0 {
+		if err := kw.renderer.StrokeRoundedRect(x, y, w, h, radius, float32(theme.BorderWidth), theme.BorderColor); err != nil {
+			return err
+		}
+	}
+
+	// Keep labels inside the key face
+	if caps.Has(render.CapClipping) {
+		kw.renderer.PushClip(x, y, w, h)
+		defer kw.renderer.PopClip()
+	}
+
+	return kw.renderLabel(key, theme, offsetX+key.X+key.Width/2, offsetY+key.Y+key.Height/2, w)
+}
+
+// renderLabel renders a key label centred on (x, y), shrinking the font
+// when the label is wider than the key face
+func (kw *KeyboardWidget) renderLabel(key *keyboard.Key, theme *keyboard.Theme, x, y, faceWidth int) error {
+	available := faceWidth - 2*labelMargin
+	textWidth, _ := kw.renderer.MeasureText(key.Label)
+	if available <= 0 || textWidth <= available {
+		return kw.renderer.RenderText(x, y, key.Label, theme.TextColor)
+	}
+
+	size := float32(theme.FontSize) * float32(available) / float32(textWidth)
+	if size < minLabelFontSize {
+		size = minLabelFontSize
+	}
+	if err := kw.renderer.SetFont(theme.FontFamily(), size); err != nil {
+		return err
+	}
+	defer kw.renderer.SetFont(theme.FontFamily(), float32(theme.FontSize))
+
+	return kw.renderer.RenderText(x, y, key.Label, theme.TextColor)
 }
 
-// HandlePointerEvent handles pointer events for the keyboard widget
-func (kw *KeyboardWidget) HandlePointerEvent(event *wayland.PointerEvent) error {
-	if event.Button == 1 { // Left mouse button
-		// Find which key was clicked
+// HandlePointerButton handles pointer button events for the keyboard
+// widget. A key is pressed by the left button going down on it and
+// released when that button is, wherever the pointer has moved to.
+func (kw *KeyboardWidget) HandlePointerButton(event *wayland.PointerButtonEvent) error {
+	if event.Button != 1 { // Left mouse button
+		return nil
+	}
+	if event.State == 1 { // Button pressed
 		key := kw.findKeyAtPosition(int(event.X), int(event.Y))
-		if key != nil {
-			if event.State == 1 { // Button pressed
-				return kw.keyboard.PressKey(key.ID)
-			} else { // Button released
-				return kw.keyboard.ReleaseKey(key.ID)
-			}
+		if key == nil {
+			return nil
+		}
+		if kw.buttons == nil {
+			kw.buttons = make(map[uint32]string)
 		}
+		kw.buttons[event.Button] = key.ID
+		return kw.keyboard.PressKey(key.ID)
 	}
-	return nil
+	keyID, exists := kw.buttons[event.Button]
+	if !exists {
+		return nil
+	}
+	delete(kw.buttons, event.Button)
+	return kw.keyboard.ReleaseKey(keyID)
 }
 
-// HandleKeyboardEvent handles keyboard events for the keyboard widget
+// HandleKeyboardEvent handles keyboard events for the keyboard widget.
+// Physical key presses do not change the on-screen keyboard, and are not
+// logged: they may be typing a password.
 func (kw *KeyboardWidget) HandleKeyboardEvent(event *wayland.KeyboardEvent) error {
-	// This could be used for physical keyboard input to update the virtual keyboard
-	// For now, we'll just log it
-	fmt.Printf("Keyboard event: key=%d, state=%d\n", event.Key, event.State)
 	return nil
 }
 
-// HandleTouchEvent handles touch events for the keyboard widget
+// HandleTouchEvent handles touch events for the keyboard widget. A key is
+// pressed when a touch point goes down on it and released when that point
+// lifts or is cancelled, wherever it has moved to.
 func (kw *KeyboardWidget) HandleTouchEvent(event *wayland.TouchEvent) error {
-	// Find which key was touched
-	key := kw.findKeyAtPosition(int(event.X), int(event.Y))
-	if key != nil {
-		// For touch events, we'll press on touch down and release on touch up
-		// This is a simplified implementation
+	switch event.State {
+	case wayland.TouchStateDown:
+		key := kw.findKeyAtPosition(int(event.X), int(event.Y))
+		if key == nil {
+			return nil
+		}
+		if kw.touches == nil {
+			kw.touches = make(map[int32]string)
+		}
+		kw.touches[event.ID] = key.ID
 		return kw.keyboard.PressKey(key.ID)
+	case wayland.TouchStateUp, wayland.TouchStateCancel:
+		keyID, exists := kw.touches[event.ID]
+		if !exists {
+			return nil
+		}
+		delete(kw.touches, event.ID)
+		return kw.keyboard.ReleaseKey(keyID)
 	}
 	return nil
 }
@@ -143,8 +377,10 @@ func (kw *KeyboardWidget) findKeyAtPosition(x, y int) *keyboard.Key {
 
 // SetPosition sets the position of the keyboard widget
 func (kw *KeyboardWidget) SetPosition(x, y int) {
+	kw.Invalidate()
 	kw.x = x
 	kw.y = y
+	kw.Invalidate()
 }
 
 // GetPosition returns the position of the keyboard widget
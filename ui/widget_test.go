@@ -0,0 +1,410 @@
+package ui
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/internal/render"
+	"github.com/iotcore/osk-iotcore/internal/wayland"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+)
+
+// recordingRenderer is a render.Renderer that records draw calls
+type recordingRenderer struct {
+	caps      render.Capabilities
+	calls     map[string]int
+	radius    []float32
+	fontSizes []float32
+}
+
+func newRecordingRenderer(caps render.Capabilities) *recordingRenderer {
+	return &recordingRenderer{caps: caps, calls: make(map[string]int)}
+}
+
+func (r *recordingRenderer) Initialize() error                  { return nil }
+func (r *recordingRenderer) Close()                             {}
+func (r *recordingRenderer) Capabilities() render.Capabilities  { return r.caps }
+func (r *recordingRenderer) BeginFrame(width, height int) error { r.calls["BeginFrame"]++; return nil }
+func (r *recordingRenderer) EndFrame() error                    { r.calls["EndFrame"]++; return nil }
+func (r *recordingRenderer) Clear(color [4]float32) error       { r.calls["Clear"]++; return nil }
+func (r *recordingRenderer) PushClip(x, y, width, height int)   { r.calls["PushClip"]++ }
+func (r *recordingRenderer) PopClip()                           { r.calls["PopClip"]++ }
+func (r *recordingRenderer) PushTransform(t render.Transform)   { r.calls["PushTransform"]++ }
+func (r *recordingRenderer) PopTransform()                      { r.calls["PopTransform"]++ }
+func (r *recordingRenderer) MeasureText(text string) (int, int) { return 7 * len(text), 13 }
+func (r *recordingRenderer) DrawTexture(*render.Texture, int, int, int, int) error {
+	r.calls["DrawTexture"]++
+	return nil
+}
+
+func (r *recordingRenderer) FillRoundedRect(x, y, width, height int, radius float32, color [4]float32) error {
+	r.calls["FillRoundedRect"]++
+	r.radius = append(r.radius, radius)
+	return nil
+}
+
+func (r *recordingRenderer) StrokeRoundedRect(x, y, width, height int, radius, lineWidth float32, color [4]float32) error {
+	r.calls["StrokeRoundedRect"]++
+	return nil
+}
+
+func (r *recordingRenderer) DrawShadow(x, y, width, height int, radius, blur float32, color [4]float32) error {
+	r.calls["DrawShadow"]++
+	return nil
+}
+
+func (r *recordingRenderer) SetFont(family []string, size float32) error {
+	r.calls["SetFont"]++
+	r.fontSizes = append(r.fontSizes, size)
+	return nil
+}
+
+func (r *recordingRenderer) RenderText(x, y int, text string, color [4]float32) error {
+	r.calls["RenderText"]++
+	return nil
+}
+
+func testTheme() *keyboard.Theme {
+	return &keyboard.Theme{
+		Name:          "test",
+		FontSize:      16,
+		BorderRadius:  6,
+		BorderWidth:   1,
+		KeyPadding:    2,
+		ShadowEnabled: true,
+		ShadowBlur:    4,
+	}
+}
+
+func testLayout() *keyboard.Layout {
+	return &keyboard.Layout{
+		Name:   "test",
+		Width:  200,
+		Height: 100,
+		Keys: []*keyboard.Key{
+			{ID: "a", Label: "A", Code: 30, X: 0, Y: 0, Width: 50, Height: 50},
+			{ID: "b", Label: "B", Code: 48, X: 60, Y: 0, Width: 50, Height: 50},
+		},
+	}
+}
+
+func TestKeyboardWidgetDrawsKeysThroughRenderer(t *testing.T) {
+	r := newRecordingRenderer(render.CapRoundedRects | render.CapShadows | render.CapClipping | render.CapTransforms)
+	kw := &KeyboardWidget{renderer: r, width: 200, height: 100}
+
+	if err := kw.renderLayout(testLayout(), testTheme()); err != nil {
+		t.Fatalf("renderLayout: %v", err)
+	}
+
+	want := map[string]int{
+		"FillRoundedRect":   3, // background and two keys
+		"StrokeRoundedRect": 2,
+		"DrawShadow":        2,
+		"RenderText":        2,
+		"PushClip":          2,
+		"PopClip":           2,
+		"PushTransform":     1,
+		"PopTransform":      1,
+	}
+	for call, n := range want {
+		if r.calls[call] != n {
+			t.Errorf("%s called %d times, want %d", call, r.calls[call], n)
+		}
+	}
+}
+
+func TestKeyboardWidgetDegradesWithoutCapabilities(t *testing.T) {
+	r := newRecordingRenderer(0)
+	kw := &KeyboardWidget{renderer: r, width: 200, height: 100}
+
+	if err := kw.renderLayout(testLayout(), testTheme()); err != nil {
+		t.Fatalf("renderLayout: %v", err)
+	}
+
+	for _, call := range []string{"DrawShadow", "PushClip", "PushTransform"} {
+		if r.calls[call] != 0 {
+			t.Errorf("%s used although the renderer does not support it", call)
+		}
+	}
+	for i, radius := range r.radius {
+		if radius != 0 {
+			t.Errorf("fill %d used radius %v without rounded rect support", i, radius)
+		}
+	}
+	if r.calls["RenderText"] != 2 {
+		t.Errorf("RenderText called %d times, want 2", r.calls["RenderText"])
+	}
+}
+
+func TestKeyboardWidgetShrinksWideLabels(t *testing.T) {
+	r := newRecordingRenderer(render.CapClipping)
+	kw := &KeyboardWidget{renderer: r, width: 200, height: 100}
+
+	layout := testLayout()
+	layout.Keys[0].Label = "Backspace"
+	if err := kw.renderLayout(layout, testTheme()); err != nil {
+		t.Fatalf("renderLayout: %v", err)
+	}
+
+	// Theme size for the frame, a reduced size for the wide label, then a
+	// restore to the theme size
+	if len(r.fontSizes) != 3 {
+		t.Fatalf("SetFont sizes = %v, want 3 calls", r.fontSizes)
+	}
+	if r.fontSizes[1] >= 16 || r.fontSizes[1] < minLabelFontSize {
+		t.Errorf("shrunk label size = %v, want between %d and 16", r.fontSizes[1], minLabelFontSize)
+	}
+	if r.fontSizes[2] != 16 {
+		t.Errorf("font size not restored, got %v", r.fontSizes[2])
+	}
+}
+
+func newTestKeyboard(t *testing.T) *keyboard.Keyboard {
+	t.Helper()
+	kb, err := keyboard.New()
+	if err != nil {
+		t.Fatalf("keyboard.New: %v", err)
+	}
+	return kb
+}
+
+func TestKeyboardWidgetRedrawsOnlyDamagedKeys(t *testing.T) {
+	kb := newTestKeyboard(t)
+	r := newRecordingRenderer(render.CapRoundedRects | render.CapShadows | render.CapClipping | render.CapTransforms)
+	kw := NewKeyboardWidget(kb, r)
+	keys := len(kb.GetLayout().Keys)
+
+	// The first frame draws the background and every key
+	if err := kw.Render(); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := r.calls["FillRoundedRect"]; got != keys+1 {
+		t.Errorf("first frame filled %d shapes, want %d", got, keys+1)
+	}
+
+	// Nothing changed, so nothing is drawn
+	r.calls = make(map[string]int)
+	if damage := kw.Damage(); len(damage) != 0 {
+		t.Errorf("unexpected damage %v", damage)
+	}
+	if err := kw.Render(); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if len(r.calls) != 0 {
+		t.Errorf("idle frame made draw calls: %v", r.calls)
+	}
+
+	// Pressing a key damages that key and redraws it with its neighbours
+	if err := kb.PressKey("g"); err != nil {
+		t.Fatal(err)
+	}
+	damage := kw.Damage()
+	if len(damage) != 1 {
+		t.Fatalf("damage = %v, want one region", damage)
+	}
+	for _, key := range kb.GetLayout().Keys {
+		if key.ID == "g" && !image.Rect(key.X, key.Y, key.X+key.Width, key.Y+key.Height).In(damage[0]) {
+			t.Errorf("damage %v does not cover key g", damage[0])
+		}
+	}
+	if err := kw.Render(); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := r.calls["FillRoundedRect"]; got < 2 || got > 10 {
+		t.Errorf("pressing one key filled %d shapes, want the background and a few keys", got)
+	}
+	if r.calls["PushClip"] != r.calls["PopClip"] {
+		t.Error("unbalanced clips")
+	}
+
+	// A theme change damages the whole widget
+	r.calls = make(map[string]int)
+	if err := kb.LoadTheme("dark"); err != nil {
+		t.Fatal(err)
+	}
+	if err := kw.Render(); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if got := r.calls["FillRoundedRect"]; got != keys+1 {
+		t.Errorf("theme change filled %d shapes, want %d", got, keys+1)
+	}
+}
+
+func TestKeyboardWidgetHidesPressedKeysWhenSensitive(t *testing.T) {
+	kb := newTestKeyboard(t)
+	r := newRecordingRenderer(render.CapRoundedRects | render.CapClipping)
+	kw := NewKeyboardWidget(kb, r)
+	if err := kw.Render(); err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+
+	var events []keyboard.KeyEvent
+	kb.Subscribe(func(event keyboard.KeyEvent) { events = append(events, event) })
+
+	if err := kb.SetContentType(keyboard.ContentType{Purpose: keyboard.ContentPurposePassword}); err != nil {
+		t.Fatal(err)
+	}
+	if err := kb.PressKey("g"); err != nil {
+		t.Fatal(err)
+	}
+	if damage := kw.Damage(); len(damage) != 0 {
+		t.Errorf("key press in a password field damaged %v", damage)
+	}
+
+	// Forcing sensitive mode keeps it on after the field loses focus
+	kb.SetSensitive(true)
+	if err := kb.SetContentType(keyboard.ContentType{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := kb.ReleaseKey("g"); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || !events[0].Sensitive || !events[1].Sensitive {
+		t.Errorf("key events = %+v, want two sensitive events", events)
+	}
+
+	// Leaving sensitive mode shows the keys as they are
+	kb.SetSensitive(false)
+	if err := kb.PressKey("g"); err != nil {
+		t.Fatal(err)
+	}
+	if len(kw.Damage()) != 1 || events[2].Sensitive {
+		t.Error("key press after sensitive mode was not shown")
+	}
+}
+
+func TestKeyboardWidgetPartialRedrawMatchesFullRedraw(t *testing.T) {
+	kb := newTestKeyboard(t)
+	layout := kb.GetLayout()
+
+	partial := render.NewSoftwareRenderer(layout.Width, layout.Height)
+	if err := partial.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	kw := NewKeyboardWidget(kb, partial)
+	frame := func(r *render.SoftwareRenderer, w *KeyboardWidget) {
+		t.Helper()
+		if err := r.BeginFrame(layout.Width, layout.Height); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Render(); err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+		if err := r.EndFrame(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	frame(partial, kw)
+	if err := kb.PressKey("g"); err != nil {
+		t.Fatal(err)
+	}
+	frame(partial, kw)
+
+	full := render.NewSoftwareRenderer(layout.Width, layout.Height)
+	if err := full.Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	frame(full, NewKeyboardWidget(kb, full))
+
+	if !bytes.Equal(partial.Image().Pix, full.Image().Pix) {
+		t.Error("partial redraw differs from a full redraw of the same state")
+	}
+}
+
+func TestKeyboardWidgetReleasesTouchedKeyOnLift(t *testing.T) {
+	kb := newTestKeyboard(t)
+	kw := NewKeyboardWidget(kb, newRecordingRenderer(0))
+
+	var g, h *keyboard.Key
+	for _, key := range kb.GetLayout().Keys {
+		switch key.ID {
+		case "g":
+			g = key
+		case "h":
+			h = key
+		}
+	}
+	if g == nil || h == nil {
+		t.Fatal("layout has no g or h key")
+	}
+
+	touch := func(state uint32, key *keyboard.Key) {
+		t.Helper()
+		event := &wayland.TouchEvent{ID: 7, State: state}
+		if key != nil {
+			event.X, event.Y = int32(key.X+key.Width/2), int32(key.Y+key.Height/2)
+		}
+		if err := kw.HandleTouchEvent(event); err != nil {
+			t.Fatalf("HandleTouchEvent: %v", err)
+		}
+	}
+
+	// The point slides onto another key before lifting; the key it went
+	// down on is the one released
+	touch(wayland.TouchStateDown, g)
+	if kb.GetKeyState("g") != keyboard.KeyStatePressed {
+		t.Fatal("touch down did not press g")
+	}
+	touch(wayland.TouchStateMotion, h)
+	touch(wayland.TouchStateUp, h)
+	if kb.GetKeyState("g") != keyboard.KeyStateReleased {
+		t.Error("touch up did not release g")
+	}
+	if kb.GetKeyState("h") != keyboard.KeyStateReleased {
+		t.Error("h should never have been pressed")
+	}
+
+	touch(wayland.TouchStateDown, h)
+	touch(wayland.TouchStateCancel, nil)
+	if kb.GetKeyState("h") != keyboard.KeyStateReleased {
+		t.Error("touch cancel did not release h")
+	}
+}
+
+func TestKeyboardWidgetReleasesClickedKeyOnButtonRelease(t *testing.T) {
+	kb := newTestKeyboard(t)
+	kw := NewKeyboardWidget(kb, newRecordingRenderer(0))
+
+	var g, h *keyboard.Key
+	for _, key := range kb.GetLayout().Keys {
+		switch key.ID {
+		case "g":
+			g = key
+		case "h":
+			h = key
+		}
+	}
+	if g == nil || h == nil {
+		t.Fatal("layout has no g or h key")
+	}
+
+	button := func(state uint32, x, y int) {
+		t.Helper()
+		event := &wayland.PointerButtonEvent{Button: 1, State: state, X: int32(x), Y: int32(y)}
+		if err := kw.HandlePointerButton(event); err != nil {
+			t.Fatalf("HandlePointerButton: %v", err)
+		}
+	}
+
+	// Released over another key, or off every key, the button releases
+	// the key it pressed
+	button(1, g.X+g.Width/2, g.Y+g.Height/2)
+	if kb.GetKeyState("g") != keyboard.KeyStatePressed {
+		t.Fatal("button press did not press g")
+	}
+	button(0, h.X+h.Width/2, h.Y+h.Height/2)
+	if kb.GetKeyState("g") != keyboard.KeyStateReleased {
+		t.Error("button release over h did not release g")
+	}
+	if kb.GetKeyState("h") != keyboard.KeyStateReleased {
+		t.Error("h should never have been pressed")
+	}
+
+	button(1, h.X+h.Width/2, h.Y+h.Height/2)
+	button(0, -10, -10)
+	if kb.GetKeyState("h") != keyboard.KeyStateReleased {
+		t.Error("button release off the keyboard did not release h")
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"testing"
+
+	"github.com/iotcore/osk-iotcore/internal/render"
+	"github.com/iotcore/osk-iotcore/internal/visualtest"
+	"github.com/iotcore/osk-iotcore/pkg/keyboard"
+	"github.com/iotcore/osk-iotcore/ui"
+)
+
+var updateGoldens = flag.Bool("update-goldens", false, "regenerate golden images in assets/reference/out")
+
+// TestVisualRegression renders every layout with every theme and compares
+// the result against the golden images in assets/reference/out. Labels drawn
+// with the missing-glyph box fail the test, so no golden image can record
+// one as correct.
+//
+// Regenerate the golden images after an intentional visual change with:
+//
+//	go test -run TestVisualRegression -update-goldens .
+func TestVisualRegression(t *testing.T) {
+	golden := visualtest.Golden{
+		Dir:         "assets/reference/out",
+		ArtifactDir: "assets/reference/out/diffs",
+		Update:      *updateGoldens,
+		Options:     visualtest.DefaultOptions(),
+	}
+
+	kb, err := keyboard.New()
+	if err != nil {
+		t.Fatalf("Failed to initialize keyboard: %v", err)
+	}
+
+	layouts, err := kb.ListAvailableLayouts()
+	if err != nil {
+		t.Fatalf("Failed to list layouts: %v", err)
+	}
+	themes, err := kb.ListAvailableThemes()
+	if err != nil {
+		t.Fatalf("Failed to list themes: %v", err)
+	}
+
+	fonts := render.NewFontManager(render.NewTextureManager())
+	for _, layoutName := range layouts {
+		for _, themeName := range themes {
+			name := fmt.Sprintf("%s_%s", layoutName, themeName)
+			t.Run(name, func(t *testing.T) {
+				if err := kb.SwitchLayout(layoutName); err != nil {
+					t.Fatalf("Failed to switch to layout %s: %v", layoutName, err)
+				}
+				if err := kb.LoadTheme(themeName); err != nil {
+					t.Fatalf("Failed to load theme %s: %v", themeName, err)
+				}
+
+				assertLabelsHaveGlyphs(t, fonts, kb.GetLayout(), kb.GetTheme())
+				if t.Failed() {
+					return
+				}
+
+				img, err := ui.Snapshot(kb.GetLayout(), kb.GetTheme())
+				if err != nil {
+					t.Fatalf("Failed to render snapshot: %v", err)
+				}
+
+				golden.Assert(t, name, img)
+			})
+		}
+	}
+}